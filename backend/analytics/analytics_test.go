@@ -0,0 +1,238 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"orbit/backend/events"
+	"orbit/backend/simulation"
+)
+
+func TestKMeansSeparatesObviousGroups(t *testing.T) {
+	points := [][]float64{{0, 0}, {0.1, 0}, {0, 0.1}, {10, 10}, {10.1, 10}, {10, 10.1}}
+	assignments, centroids := KMeans(points, 2, 20, rand.New(rand.NewSource(1)))
+
+	if len(centroids) != 2 {
+		t.Fatalf("expected 2 centroids, got %d", len(centroids))
+	}
+	if assignments[0] != assignments[1] || assignments[1] != assignments[2] {
+		t.Fatalf("expected first group together: %v", assignments)
+	}
+	if assignments[3] != assignments[4] || assignments[4] != assignments[5] || assignments[0] == assignments[3] {
+		t.Fatalf("expected second group separate: %v", assignments)
+	}
+}
+
+func TestBehaviorClustererGroupsMovingAndStoppedTrucks(t *testing.T) {
+	clusterer := NewBehaviorClusterer(nil, BehaviorClusterOptions{K: 2, Window: 10})
+
+	for i := 0; i < 10; i++ {
+		clusterer.Sample([]simulation.Truck{
+			{ID: "fast-1", Speed: 25, Status: simulation.TruckStatusEnRoute},
+			{ID: "fast-2", Speed: 24, Status: simulation.TruckStatusEnRoute},
+			{ID: "parked-1", Speed: 20, Status: simulation.TruckStatusIdle},
+			{ID: "parked-2", Speed: 22, Status: simulation.TruckStatusLoading},
+		})
+	}
+	clusterer.Recompute(time.Now())
+
+	report := clusterer.Report()
+	if len(report.Clusters) != 2 {
+		t.Fatalf("expected 2 clusters, got %+v", report.Clusters)
+	}
+	a := report.Assignments
+	if a["fast-1"] != a["fast-2"] || a["parked-1"] != a["parked-2"] || a["fast-1"] == a["parked-1"] {
+		t.Fatalf("unexpected assignments: %v", a)
+	}
+}
+
+func TestLeaderboardRanksTrucksWithinWindow(t *testing.T) {
+	base := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	trip := func(truck string, end time.Time, meters float64, took, planned time.Duration, efficiency float64) simulation.Trip {
+		return simulation.Trip{
+			TruckID:         truck,
+			Start:           end.Add(-took),
+			End:             end,
+			DistanceMeters:  meters,
+			PlannedDuration: planned,
+			Efficiency:      efficiency,
+		}
+	}
+	trips := []simulation.Trip{
+		trip("truck-a", base, 1000, time.Hour, time.Hour, 0.9),
+		trip("truck-a", base.Add(time.Hour), 1000, 2*time.Hour, time.Hour, 0.7),
+		trip("truck-b", base.Add(time.Hour), 5000, time.Hour, time.Hour, 1),
+		trip("truck-c", base.Add(-48*time.Hour), 90000, time.Hour, time.Hour, 1),
+	}
+	since := base.Add(-24 * time.Hour)
+
+	byDistance, err := Leaderboard(trips, since, LeaderboardDistance, 0)
+	if err != nil {
+		t.Fatalf("leaderboard: %v", err)
+	}
+	if len(byDistance) != 2 || byDistance[0].TruckID != "truck-b" || byDistance[1].Value != 2000 || byDistance[1].Rank != 2 {
+		t.Fatalf("unexpected distance ranking: %+v", byDistance)
+	}
+
+	byOnTime, _ := Leaderboard(trips, since, LeaderboardOnTime, 1)
+	if len(byOnTime) != 1 || byOnTime[0].TruckID != "truck-b" || byOnTime[0].Value != 100 {
+		t.Fatalf("unexpected on-time ranking: %+v", byOnTime)
+	}
+
+	byEfficiency, _ := Leaderboard(trips, since, LeaderboardEfficiency, 0)
+	if byEfficiency[1].TruckID != "truck-a" || math.Abs(byEfficiency[1].Value-0.8) > 1e-9 {
+		t.Fatalf("unexpected efficiency ranking: %+v", byEfficiency)
+	}
+
+	if _, err := Leaderboard(trips, since, "speed", 0); err == nil {
+		t.Fatalf("expected an error for an unknown metric")
+	}
+}
+
+func TestSpeedComplianceRanksWorstOffendersFirst(t *testing.T) {
+	trucks := []simulation.Truck{
+		{ID: "truck-a", SpeedLimit: 25, SpeedViolations: 1, OverLimitSeconds: 30},
+		{ID: "truck-b", SpeedLimit: 25, SpeedViolations: 3, OverLimitSeconds: 90},
+		{ID: "truck-c", SpeedLimit: 25, Governed: true, GovernorInterventions: 2},
+		{ID: "truck-d"},
+	}
+
+	report := SpeedCompliance(trucks, false)
+	if report.Trucks != 3 || report.Governed != 1 || report.TrucksInViolation != 2 || report.Violations != 4 || report.OverLimitSeconds != 120 {
+		t.Fatalf("unexpected totals: %+v", report)
+	}
+	if len(report.Entries) != 3 || report.Entries[0].TruckID != "truck-b" || report.Entries[2].TruckID != "truck-c" {
+		t.Fatalf("unexpected ranking: %+v", report.Entries)
+	}
+
+	if violators := SpeedCompliance(trucks, true); len(violators.Entries) != 2 || violators.Trucks != 3 {
+		t.Fatalf("expected only violators listed, got %+v", violators)
+	}
+}
+
+func TestProximityDetectorMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	var trucks []simulation.Truck
+	for i := 0; i < 500; i++ {
+		trucks = append(trucks, simulation.Truck{
+			ID:     fmt.Sprintf("truck-%04d", i),
+			Lat:    60 + rng.Float64()*0.02,
+			Lon:    10 + rng.Float64()*0.04,
+			Status: simulation.TruckStatusEnRoute,
+		})
+	}
+	trucks = append(trucks, simulation.Truck{ID: "parked", Lat: trucks[0].Lat, Lon: trucks[0].Lon, Status: simulation.TruckStatusIdle})
+
+	bus := events.NewBus()
+	sub := bus.Subscribe("test", events.SubscribeOptions{QueueSize: 10000})
+	defer sub.Close()
+	detector := NewProximityDetector(nil, ProximityOptions{Distance: 75}).WithEventBus(bus)
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	started := detector.Check(trucks, start)
+
+	want := 0
+	for i := 0; i < 500; i++ {
+		for j := i + 1; j < 500; j++ {
+			a := simulation.Point{Lat: trucks[i].Lat, Lon: trucks[i].Lon}
+			b := simulation.Point{Lat: trucks[j].Lat, Lon: trucks[j].Lon}
+			if simulation.GreatCircleDistance(a, b) <= 75 {
+				want++
+			}
+		}
+	}
+	if want == 0 || len(started) != want || len(detector.Conflicts()) != want || len(sub.C()) != want {
+		t.Fatalf("expected %d conflicts and events, got %d new, %d current, %d events", want, len(started), len(detector.Conflicts()), len(sub.C()))
+	}
+	for _, c := range detector.Conflicts() {
+		if c.TruckA == "parked" || c.TruckB == "parked" {
+			t.Fatalf("expected stationary trucks to be ignored, got %+v", c)
+		}
+	}
+
+	if again := detector.Check(trucks, start.Add(time.Second)); len(again) != 0 {
+		t.Fatalf("expected ongoing conflicts not to be reported again, got %d", len(again))
+	}
+	if c := detector.Conflicts()[0]; !c.Since.Equal(start) {
+		t.Fatalf("expected conflicts to keep their start time, got %s", c.Since)
+	}
+}
+
+func TestClusterForZoomMergesNearbyTrucksOnlyWhenZoomedOut(t *testing.T) {
+	trucks := []simulation.Truck{
+		{ID: "a", Lat: 40.0, Lon: -74.0},
+		{ID: "b", Lat: 40.001, Lon: -74.001},
+		{ID: "c", Lat: 40.002, Lon: -73.999},
+		{ID: "d", Lat: 51.5, Lon: -0.1},
+	}
+
+	far := ClusterForZoom(trucks, 4, 40)
+	if len(far) != 2 || far[0].Count != 3 || far[1].TruckID != "d" {
+		t.Fatalf("expected the New York trucks in one cluster and London alone, got %+v", far)
+	}
+	if got := far[0]; math.Abs(got.Lat-40.001) > 1e-9 || got.Bounds.MinLon != -74.001 || got.Bounds.MaxLat != 40.002 || got.TruckID != "" {
+		t.Fatalf("unexpected centroid or bounds %+v", got)
+	}
+
+	near := ClusterForZoom(trucks, 18, 40)
+	if len(near) != 4 {
+		t.Fatalf("expected every truck on its own at street level, got %+v", near)
+	}
+}
+
+func TestDensityCountsTrucksPerCell(t *testing.T) {
+	bounds := simulation.BoundingBox{MinLat: 0, MaxLat: 1, MinLon: 0, MaxLon: 1}
+	trucks := []simulation.Truck{
+		{ID: "a", Lat: 0.1, Lon: 0.1},
+		{ID: "b", Lat: 0.2, Lon: 0.2},
+		{ID: "c", Lat: 0.6, Lon: 0.1},
+		{ID: "d", Lat: 1, Lon: 1},
+		{ID: "e", Lat: 2, Lon: 0.5},
+	}
+
+	grid := Density(trucks, bounds, metersPerDegreeLat/4)
+	if grid.Rows != 4 || grid.Cols != 4 || len(grid.Counts) != 4 || len(grid.Counts[0]) != 4 {
+		t.Fatalf("expected a 4x4 grid, got %dx%d", grid.Rows, grid.Cols)
+	}
+	if grid.Counts[0][0] != 2 || grid.Counts[2][0] != 1 || grid.Counts[3][3] != 1 || grid.Max != 2 || grid.Outside != 1 {
+		t.Fatalf("unexpected counts %+v", grid)
+	}
+}
+
+func TestPunctualityScoresDelaysAndHeadways(t *testing.T) {
+	base := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	trip := func(i int, depart, arrive time.Duration) simulation.TimetableRecord {
+		scheduled := base.Add(time.Duration(i) * 10 * time.Minute)
+		return simulation.TimetableRecord{
+			Route: "line-1", Departure: i, ScheduledDeparture: scheduled, TruckID: "truck-0001",
+			ActualDeparture: scheduled.Add(depart), Completed: true,
+			Arrivals: []simulation.StopArrival{{Waypoint: 1, Scheduled: scheduled.Add(5 * time.Minute), Actual: scheduled.Add(5*time.Minute + arrive)}},
+		}
+	}
+	records := []simulation.TimetableRecord{
+		trip(0, 0, time.Minute),
+		trip(1, 6*time.Minute, 8*time.Minute),
+		{Route: "line-1", Departure: 2, ScheduledDeparture: base.Add(20 * time.Minute), Missed: true},
+		trip(3, 0, -2*time.Minute),
+	}
+
+	report := Punctuality(records, base, DefaultPunctuality)
+	if len(report) != 1 {
+		t.Fatalf("expected one route, got %+v", report)
+	}
+	p := report[0]
+	if p.Departures != 3 || p.Missed != 1 || p.Arrivals != 3 {
+		t.Fatalf("unexpected counts %+v", p)
+	}
+	if math.Abs(p.OnTimeDepartures-2.0/3) > 1e-9 || math.Abs(p.OnTimeArrivals-1.0/3) > 1e-9 || p.MaxArrivalDelay != 480 {
+		t.Fatalf("unexpected punctuality %+v", p)
+	}
+	if p.ScheduledHeadway != 900 || p.MeanHeadway != 900 || p.HeadwayVariation <= 0 {
+		t.Fatalf("unexpected headways %+v", p)
+	}
+	if got := Punctuality(records, base.Add(time.Hour), DefaultPunctuality); len(got) != 0 {
+		t.Fatalf("expected nothing after the window, got %+v", got)
+	}
+}
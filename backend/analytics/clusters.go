@@ -0,0 +1,203 @@
+package analytics
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"orbit/backend/simulation"
+)
+
+// BehaviorClusterOptions configures how truck behaviour is sampled and clustered.
+type BehaviorClusterOptions struct {
+	K              int
+	SampleInterval time.Duration
+	Window         int
+	Seed           int64
+}
+
+// Cluster describes one behaviour group discovered in the sampling window.
+type Cluster struct {
+	ID              int
+	Size            int
+	AverageSpeed    float64
+	StoppedFraction float64
+}
+
+// ClusterReport is the latest clustering result.
+type ClusterReport struct {
+	ComputedAt  time.Time
+	Clusters    []Cluster
+	Assignments map[string]int
+}
+
+type behaviorSample struct {
+	speed   float64
+	stopped bool
+}
+
+// BehaviorClusterer periodically samples the fleet and groups trucks by speed and stop patterns with
+// k-means, giving anomaly-detection demos labelled structure to find.
+type BehaviorClusterer struct {
+	sim  *simulation.Manager
+	opts BehaviorClusterOptions
+
+	mu      sync.RWMutex
+	samples map[string][]behaviorSample
+	report  ClusterReport
+}
+
+// NewBehaviorClusterer creates a clusterer with defaults for unset options.
+func NewBehaviorClusterer(sim *simulation.Manager, opts BehaviorClusterOptions) *BehaviorClusterer {
+	if opts.K <= 0 {
+		opts.K = 4
+	}
+	if opts.SampleInterval <= 0 {
+		opts.SampleInterval = 5 * time.Second
+	}
+	if opts.Window <= 0 {
+		opts.Window = 60
+	}
+	if opts.Seed == 0 {
+		opts.Seed = 1
+	}
+	return &BehaviorClusterer{sim: sim, opts: opts, samples: make(map[string][]behaviorSample)}
+}
+
+// Run samples and re-clusters on every interval until the context is cancelled.
+func (c *BehaviorClusterer) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.opts.SampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Sample(c.sim.Trucks())
+			c.Recompute(time.Now())
+		}
+	}
+}
+
+// Sample records one observation per truck, discarding samples older than the window and trucks that
+// have left the fleet.
+func (c *BehaviorClusterer) Sample(trucks []simulation.Truck) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seen := make(map[string]struct{}, len(trucks))
+	for _, t := range trucks {
+		seen[t.ID] = struct{}{}
+		sample := behaviorSample{speed: t.Speed, stopped: t.Status != simulation.TruckStatusEnRoute}
+		if sample.stopped {
+			sample.speed = 0
+		}
+		history := append(c.samples[t.ID], sample)
+		if len(history) > c.opts.Window {
+			history = history[len(history)-c.opts.Window:]
+		}
+		c.samples[t.ID] = history
+	}
+	for id := range c.samples {
+		if _, ok := seen[id]; !ok {
+			delete(c.samples, id)
+		}
+	}
+}
+
+// Recompute clusters the trucks on their windowed average speed and stopped fraction.
+func (c *BehaviorClusterer) Recompute(now time.Time) {
+	c.mu.RLock()
+	ids := make([]string, 0, len(c.samples))
+	for id := range c.samples {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	raw := make([][]float64, len(ids))
+	for i, id := range ids {
+		raw[i] = behaviorFeatures(c.samples[id])
+	}
+	c.mu.RUnlock()
+
+	assignments, _ := KMeans(standardize(raw), c.opts.K, 50, rand.New(rand.NewSource(c.opts.Seed)))
+
+	report := ClusterReport{ComputedAt: now, Assignments: make(map[string]int, len(ids))}
+	byCluster := make(map[int][]int)
+	for i, id := range ids {
+		report.Assignments[id] = assignments[i]
+		byCluster[assignments[i]] = append(byCluster[assignments[i]], i)
+	}
+	for cluster := 0; cluster < c.opts.K; cluster++ {
+		members := byCluster[cluster]
+		if len(members) == 0 {
+			continue
+		}
+		summary := Cluster{ID: cluster, Size: len(members)}
+		for _, i := range members {
+			summary.AverageSpeed += raw[i][0]
+			summary.StoppedFraction += raw[i][1]
+		}
+		summary.AverageSpeed /= float64(len(members))
+		summary.StoppedFraction /= float64(len(members))
+		report.Clusters = append(report.Clusters, summary)
+	}
+
+	c.mu.Lock()
+	c.report = report
+	c.mu.Unlock()
+}
+
+// Report returns the most recent clustering result.
+func (c *BehaviorClusterer) Report() ClusterReport {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.report
+}
+
+func behaviorFeatures(samples []behaviorSample) []float64 {
+	if len(samples) == 0 {
+		return []float64{0, 0}
+	}
+	speed, stopped := 0.0, 0.0
+	for _, s := range samples {
+		speed += s.speed
+		if s.stopped {
+			stopped++
+		}
+	}
+	n := float64(len(samples))
+	return []float64{speed / n, stopped / n}
+}
+
+// standardize scales each feature to zero mean and unit variance so no feature dominates the distance.
+func standardize(points [][]float64) [][]float64 {
+	if len(points) == 0 {
+		return nil
+	}
+	dims := len(points[0])
+	out := make([][]float64, len(points))
+	for i := range out {
+		out[i] = make([]float64, dims)
+	}
+	for d := 0; d < dims; d++ {
+		mean := 0.0
+		for _, p := range points {
+			mean += p[d]
+		}
+		mean /= float64(len(points))
+		variance := 0.0
+		for _, p := range points {
+			variance += (p[d] - mean) * (p[d] - mean)
+		}
+		std := math.Sqrt(variance / float64(len(points)))
+		for i, p := range points {
+			if std > 0 {
+				out[i][d] = (p[d] - mean) / std
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,67 @@
+package analytics
+
+import (
+	"sort"
+
+	"orbit/backend/simulation"
+)
+
+// SpeedComplianceEntry summarises one truck's driving against its speed limit.
+type SpeedComplianceEntry struct {
+	TruckID               string
+	SpeedLimit            float64
+	Governed              bool
+	Violations            int
+	OverLimitSeconds      float64
+	GovernorInterventions int
+}
+
+// SpeedComplianceReport totals speed compliance across the fleet.
+type SpeedComplianceReport struct {
+	Trucks            int
+	Governed          int
+	TrucksInViolation int
+	Violations        int
+	OverLimitSeconds  float64
+	// Entries lists trucks with a speed limit, worst offenders first.
+	Entries []SpeedComplianceEntry
+}
+
+// SpeedCompliance builds the report from truck snapshots. Trucks without a speed limit are left out. With
+// violatorsOnly, entries are limited to trucks with at least one violation; totals still cover every truck.
+func SpeedCompliance(trucks []simulation.Truck, violatorsOnly bool) SpeedComplianceReport {
+	var report SpeedComplianceReport
+	for _, t := range trucks {
+		if t.SpeedLimit <= 0 {
+			continue
+		}
+		report.Trucks++
+		if t.Governed {
+			report.Governed++
+		}
+		if t.SpeedViolations > 0 {
+			report.TrucksInViolation++
+		}
+		report.Violations += t.SpeedViolations
+		report.OverLimitSeconds += t.OverLimitSeconds
+		if violatorsOnly && t.SpeedViolations == 0 {
+			continue
+		}
+		report.Entries = append(report.Entries, SpeedComplianceEntry{
+			TruckID:               t.ID,
+			SpeedLimit:            t.SpeedLimit,
+			Governed:              t.Governed,
+			Violations:            t.SpeedViolations,
+			OverLimitSeconds:      t.OverLimitSeconds,
+			GovernorInterventions: t.GovernorInterventions,
+		})
+	}
+	sort.SliceStable(report.Entries, func(i, j int) bool {
+		a, b := report.Entries[i], report.Entries[j]
+		if a.OverLimitSeconds != b.OverLimitSeconds {
+			return a.OverLimitSeconds > b.OverLimitSeconds
+		}
+		return a.TruckID < b.TruckID
+	})
+	return report
+}
@@ -0,0 +1,60 @@
+package analytics
+
+import (
+	"math"
+
+	"orbit/backend/simulation"
+)
+
+// DensityGrid counts trucks in the cells of a regular grid laid over an area. Counts is row-major from
+// the south-west corner: Counts[row][col] covers latitudes from Bounds.MinLat+row*CellLat and longitudes
+// from Bounds.MinLon+col*CellLon. Outside counts the trucks that fell outside Bounds.
+type DensityGrid struct {
+	Bounds  simulation.BoundingBox
+	CellLat float64
+	CellLon float64
+	Rows    int
+	Cols    int
+	Counts  [][]int
+	Max     int
+	Outside int
+}
+
+// GridSize returns the rows and columns of a grid with cells cellMeters on a side over bounds. Longitude
+// cells are sized at the middle latitude of the area, so cells are close to square on a web map.
+func GridSize(bounds simulation.BoundingBox, cellMeters float64) (rows, cols int, cellLat, cellLon float64) {
+	cellLat = cellMeters / metersPerDegreeLat
+	midLat := (bounds.MinLat + bounds.MaxLat) / 2
+	cellLon = cellLat / math.Max(math.Cos(math.Min(math.Abs(midLat), 89)*math.Pi/180), 0.01)
+	rows = max(1, int(math.Ceil((bounds.MaxLat-bounds.MinLat)/cellLat)))
+	cols = max(1, int(math.Ceil((bounds.MaxLon-bounds.MinLon)/cellLon)))
+	return rows, cols, cellLat, cellLon
+}
+
+// Density counts trucks per grid cell of cellMeters over bounds. Trucks on the north or east edge of
+// bounds land in the last row or column.
+func Density(trucks []simulation.Truck, bounds simulation.BoundingBox, cellMeters float64) DensityGrid {
+	rows, cols, cellLat, cellLon := GridSize(bounds, cellMeters)
+	grid := DensityGrid{
+		Bounds:  bounds,
+		CellLat: cellLat,
+		CellLon: cellLon,
+		Rows:    rows,
+		Cols:    cols,
+		Counts:  make([][]int, rows),
+	}
+	for i := range grid.Counts {
+		grid.Counts[i] = make([]int, cols)
+	}
+	for _, t := range trucks {
+		if !bounds.Contains(simulation.Point{Lat: t.Lat, Lon: t.Lon}) {
+			grid.Outside++
+			continue
+		}
+		row := min(rows-1, int((t.Lat-bounds.MinLat)/cellLat))
+		col := min(cols-1, int((t.Lon-bounds.MinLon)/cellLon))
+		grid.Counts[row][col]++
+		grid.Max = max(grid.Max, grid.Counts[row][col])
+	}
+	return grid
+}
@@ -0,0 +1,110 @@
+// Package analytics derives aggregate views of the simulated fleet.
+package analytics
+
+import (
+	"math"
+	"math/rand"
+)
+
+// KMeans partitions points into k clusters using k-means++ seeding followed by Lloyd iterations. It returns
+// the cluster index for every point and the final centroids. The result is deterministic for a given rng.
+func KMeans(points [][]float64, k, iterations int, rng *rand.Rand) ([]int, [][]float64) {
+	if len(points) == 0 || k <= 0 {
+		return nil, nil
+	}
+	if k > len(points) {
+		k = len(points)
+	}
+
+	centroids := seedCentroids(points, k, rng)
+	assignments := make([]int, len(points))
+	for iter := 0; iter < iterations; iter++ {
+		changed := false
+		for i, p := range points {
+			if best := nearestCentroid(p, centroids); best != assignments[i] {
+				assignments[i] = best
+				changed = true
+			}
+		}
+
+		dims := len(points[0])
+		sums := make([][]float64, k)
+		counts := make([]int, k)
+		for c := range sums {
+			sums[c] = make([]float64, dims)
+		}
+		for i, p := range points {
+			c := assignments[i]
+			counts[c]++
+			for d := range p {
+				sums[c][d] += p[d]
+			}
+		}
+		for c := range centroids {
+			if counts[c] == 0 {
+				continue
+			}
+			for d := range sums[c] {
+				centroids[c][d] = sums[c][d] / float64(counts[c])
+			}
+		}
+
+		if !changed && iter > 0 {
+			break
+		}
+	}
+	return assignments, centroids
+}
+
+func seedCentroids(points [][]float64, k int, rng *rand.Rand) [][]float64 {
+	centroids := make([][]float64, 0, k)
+	centroids = append(centroids, clonePoint(points[rng.Intn(len(points))]))
+
+	distances := make([]float64, len(points))
+	for len(centroids) < k {
+		total := 0.0
+		for i, p := range points {
+			distances[i] = squaredDistance(p, centroids[nearestCentroid(p, centroids)])
+			total += distances[i]
+		}
+		if total == 0 {
+			centroids = append(centroids, clonePoint(points[rng.Intn(len(points))]))
+			continue
+		}
+
+		target := rng.Float64() * total
+		chosen := len(points) - 1
+		for i, d := range distances {
+			target -= d
+			if target <= 0 {
+				chosen = i
+				break
+			}
+		}
+		centroids = append(centroids, clonePoint(points[chosen]))
+	}
+	return centroids
+}
+
+func nearestCentroid(p []float64, centroids [][]float64) int {
+	best, bestDistance := 0, math.Inf(1)
+	for c, centroid := range centroids {
+		if d := squaredDistance(p, centroid); d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best
+}
+
+func squaredDistance(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		diff := a[i] - b[i]
+		sum += diff * diff
+	}
+	return sum
+}
+
+func clonePoint(p []float64) []float64 {
+	return append([]float64(nil), p...)
+}
@@ -0,0 +1,110 @@
+package analytics
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"orbit/backend/simulation"
+)
+
+// LeaderboardMetric selects how trucks are ranked.
+type LeaderboardMetric string
+
+const (
+	// LeaderboardDistance ranks by metres driven on completed trips.
+	LeaderboardDistance LeaderboardMetric = "distance"
+	// LeaderboardOnTime ranks by the percentage of trips finished within OnTimeTolerance of plan.
+	LeaderboardOnTime LeaderboardMetric = "onTime"
+	// LeaderboardEfficiency ranks by average trip efficiency.
+	LeaderboardEfficiency LeaderboardMetric = "efficiency"
+)
+
+// OnTimeTolerance is how far past its planned duration a trip may run and still count as on time.
+const OnTimeTolerance = 0.1
+
+// LeaderboardEntry is one ranked truck.
+type LeaderboardEntry struct {
+	Rank    int
+	TruckID string
+	Value   float64
+	Trips   int
+}
+
+type truckTotals struct {
+	distance   float64
+	onTime     int
+	efficiency float64
+	efficient  int
+	trips      int
+}
+
+// Leaderboard ranks trucks by the metric over trips that ended at or after since. Ties are broken by truck
+// ID and a positive limit truncates the result.
+func Leaderboard(trips []simulation.Trip, since time.Time, metric LeaderboardMetric, limit int) ([]LeaderboardEntry, error) {
+	switch metric {
+	case LeaderboardDistance, LeaderboardOnTime, LeaderboardEfficiency:
+	default:
+		return nil, fmt.Errorf("unknown leaderboard metric %q", metric)
+	}
+
+	totals := make(map[string]*truckTotals)
+	for _, trip := range trips {
+		if trip.End.Before(since) {
+			continue
+		}
+		t := totals[trip.TruckID]
+		if t == nil {
+			t = &truckTotals{}
+			totals[trip.TruckID] = t
+		}
+		t.trips++
+		t.distance += trip.DistanceMeters
+		if onTime(trip) {
+			t.onTime++
+		}
+		if trip.Efficiency > 0 {
+			t.efficiency += trip.Efficiency
+			t.efficient++
+		}
+	}
+
+	entries := make([]LeaderboardEntry, 0, len(totals))
+	for id, t := range totals {
+		entry := LeaderboardEntry{TruckID: id, Trips: t.trips}
+		switch metric {
+		case LeaderboardDistance:
+			entry.Value = t.distance
+		case LeaderboardOnTime:
+			entry.Value = 100 * float64(t.onTime) / float64(t.trips)
+		case LeaderboardEfficiency:
+			if t.efficient == 0 {
+				continue
+			}
+			entry.Value = t.efficiency / float64(t.efficient)
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Value != entries[j].Value {
+			return entries[i].Value > entries[j].Value
+		}
+		return entries[i].TruckID < entries[j].TruckID
+	})
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+	return entries, nil
+}
+
+func onTime(trip simulation.Trip) bool {
+	if trip.PlannedDuration <= 0 {
+		return true
+	}
+	allowed := time.Duration(float64(trip.PlannedDuration) * (1 + OnTimeTolerance))
+	return trip.End.Sub(trip.Start) <= allowed
+}
@@ -0,0 +1,198 @@
+package analytics
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"orbit/backend/events"
+	"orbit/backend/simulation"
+)
+
+var proximityConflicts = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "orbit_proximity_conflicts",
+	Help: "Pairs of moving trucks currently closer than the proximity distance.",
+})
+
+func init() {
+	prometheus.MustRegister(proximityConflicts)
+}
+
+// metersPerDegreeLat is the length of one degree of latitude, close enough for sizing grid cells.
+const metersPerDegreeLat = 111320.0
+
+// ProximityOptions configures near-miss detection.
+type ProximityOptions struct {
+	// Distance is how close, in metres, two trucks must be to count as a conflict.
+	Distance float64
+	// CheckInterval is how often the fleet is checked.
+	CheckInterval time.Duration
+}
+
+// Conflict is a pair of trucks within the proximity distance. TruckA sorts before TruckB.
+type Conflict struct {
+	TruckA         string
+	TruckB         string
+	DistanceMeters float64
+	// Since is the simulated time the pair first came within range.
+	Since time.Time
+}
+
+// ProximityDetector flags pairs of moving trucks that come within a set distance of each other. Trucks
+// are bucketed into a grid of cells one distance wide, so each check compares a truck only with the
+// trucks in its own and neighbouring cells instead of the whole fleet. Stationary trucks are ignored:
+// trucks queued at the same depot are close by design.
+type ProximityDetector struct {
+	sim  *simulation.Manager
+	opts ProximityOptions
+	bus  *events.Bus
+
+	mu        sync.RWMutex
+	conflicts map[[2]string]Conflict
+}
+
+// NewProximityDetector creates a detector with defaults for unset options: 50 metres checked every second.
+func NewProximityDetector(sim *simulation.Manager, opts ProximityOptions) *ProximityDetector {
+	if opts.Distance <= 0 {
+		opts.Distance = 50
+	}
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = time.Second
+	}
+	return &ProximityDetector{sim: sim, opts: opts, conflicts: make(map[[2]string]Conflict)}
+}
+
+// WithEventBus publishes a proximity event on bus whenever a new pair comes within range.
+func (d *ProximityDetector) WithEventBus(bus *events.Bus) *ProximityDetector {
+	d.bus = bus
+	return d
+}
+
+// Run checks the fleet on every interval until the context is cancelled.
+func (d *ProximityDetector) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.opts.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.Check(d.sim.Trucks(), d.sim.SimulatedTime())
+		}
+	}
+}
+
+type gridCell struct {
+	lat, lon int
+}
+
+// Check finds the pairs of moving trucks within range at simulated time now, replacing the current
+// conflicts, and returns the pairs that were not already in conflict.
+func (d *ProximityDetector) Check(trucks []simulation.Truck, now time.Time) []Conflict {
+	moving := make([]simulation.Truck, 0, len(trucks))
+	maxLat := 0.0
+	for _, t := range trucks {
+		if t.Status == simulation.TruckStatusEnRoute {
+			moving = append(moving, t)
+			maxLat = math.Max(maxLat, math.Abs(t.Lat))
+		}
+	}
+
+	// Longitude cells are sized for the highest latitude in the fleet, where a degree is shortest, so the
+	// neighbouring cells always cover the distance.
+	cellLat := d.opts.Distance / metersPerDegreeLat
+	cellLon := cellLat / math.Max(math.Cos(math.Min(maxLat, 89)*math.Pi/180), 0.01)
+	grid := make(map[gridCell][]int, len(moving))
+	cells := make([]gridCell, len(moving))
+	for i, t := range moving {
+		cell := gridCell{lat: int(math.Floor(t.Lat / cellLat)), lon: int(math.Floor(t.Lon / cellLon))}
+		cells[i] = cell
+		grid[cell] = append(grid[cell], i)
+	}
+
+	found := make(map[[2]string]Conflict)
+	for i, t := range moving {
+		for dLat := -1; dLat <= 1; dLat++ {
+			for dLon := -1; dLon <= 1; dLon++ {
+				for _, j := range grid[gridCell{lat: cells[i].lat + dLat, lon: cells[i].lon + dLon}] {
+					other := moving[j]
+					if other.ID <= t.ID {
+						continue
+					}
+					distance := simulation.GreatCircleDistance(
+						simulation.Point{Lat: t.Lat, Lon: t.Lon},
+						simulation.Point{Lat: other.Lat, Lon: other.Lon},
+					)
+					if distance <= d.opts.Distance {
+						found[[2]string{t.ID, other.ID}] = Conflict{TruckA: t.ID, TruckB: other.ID, DistanceMeters: distance, Since: now}
+					}
+				}
+			}
+		}
+	}
+
+	d.mu.Lock()
+	var started []Conflict
+	for key, conflict := range found {
+		if previous, ok := d.conflicts[key]; ok {
+			conflict.Since = previous.Since
+			found[key] = conflict
+			continue
+		}
+		started = append(started, conflict)
+	}
+	d.conflicts = found
+	d.mu.Unlock()
+	proximityConflicts.Set(float64(len(found)))
+
+	sortConflicts(started)
+	if d.bus != nil {
+		positions := make(map[string]simulation.Point, len(moving))
+		for _, t := range moving {
+			positions[t.ID] = simulation.Point{Lat: t.Lat, Lon: t.Lon}
+		}
+		for _, c := range started {
+			a, b := positions[c.TruckA], positions[c.TruckB]
+			d.bus.Publish(events.Event{Type: events.TypeProximity, Payload: events.Proximity{
+				TruckA:         c.TruckA,
+				TruckB:         c.TruckB,
+				DistanceMeters: c.DistanceMeters,
+				Lat:            (a.Lat + b.Lat) / 2,
+				Lon:            (a.Lon + b.Lon) / 2,
+				SimulatedTime:  now,
+			}})
+		}
+	}
+	return started
+}
+
+// Conflicts returns the pairs currently in range, closest first.
+func (d *ProximityDetector) Conflicts() []Conflict {
+	d.mu.RLock()
+	conflicts := make([]Conflict, 0, len(d.conflicts))
+	for _, c := range d.conflicts {
+		conflicts = append(conflicts, c)
+	}
+	d.mu.RUnlock()
+	sortConflicts(conflicts)
+	sort.SliceStable(conflicts, func(i, j int) bool { return conflicts[i].DistanceMeters < conflicts[j].DistanceMeters })
+	return conflicts
+}
+
+// Distance returns the configured proximity distance in metres.
+func (d *ProximityDetector) Distance() float64 {
+	return d.opts.Distance
+}
+
+func sortConflicts(conflicts []Conflict) {
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].TruckA != conflicts[j].TruckA {
+			return conflicts[i].TruckA < conflicts[j].TruckA
+		}
+		return conflicts[i].TruckB < conflicts[j].TruckB
+	})
+}
@@ -0,0 +1,118 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"orbit/backend/simulation"
+)
+
+// PunctualityOptions sets how early or late a departure or arrival may be and still count as on time.
+type PunctualityOptions struct {
+	Early time.Duration
+	Late  time.Duration
+}
+
+// DefaultPunctuality is the common transit definition of on time: no more than one minute early and
+// less than five minutes late.
+var DefaultPunctuality = PunctualityOptions{Early: time.Minute, Late: 5 * time.Minute}
+
+// RoutePunctuality summarizes how a timetabled route kept to its schedule. Delays are in seconds and
+// negative when early. Headways are the gaps between consecutive departures that ran.
+type RoutePunctuality struct {
+	Route string
+	// Departures counts the departures that ran and Missed those no truck was waiting for.
+	Departures int
+	Missed     int
+	// OnTimeDepartures and OnTimeArrivals are fractions of the departures and scheduled stop arrivals.
+	OnTimeDepartures   float64
+	MeanDepartureDelay float64
+	Arrivals           int
+	OnTimeArrivals     float64
+	MeanArrivalDelay   float64
+	MaxArrivalDelay    float64
+	ScheduledHeadway   float64
+	MeanHeadway        float64
+	// HeadwayVariation is the standard deviation of the headways over their mean. Zero means evenly spaced
+	// departures, and values towards one mean trucks are bunching.
+	HeadwayVariation float64
+}
+
+// Punctuality summarizes, per route, the timetabled departures scheduled at or after since, ordered by
+// route name.
+func Punctuality(records []simulation.TimetableRecord, since time.Time, opts PunctualityOptions) []RoutePunctuality {
+	byRoute := make(map[string][]simulation.TimetableRecord)
+	for _, r := range records {
+		if !r.ScheduledDeparture.Before(since) {
+			byRoute[r.Route] = append(byRoute[r.Route], r)
+		}
+	}
+
+	onTime := func(scheduled, actual time.Time) bool {
+		delay := actual.Sub(scheduled)
+		return delay >= -opts.Early && delay < opts.Late
+	}
+	report := make([]RoutePunctuality, 0, len(byRoute))
+	for route, runs := range byRoute {
+		sort.Slice(runs, func(i, j int) bool { return runs[i].Departure < runs[j].Departure })
+		p := RoutePunctuality{Route: route}
+		var departed []simulation.TimetableRecord
+		var departureDelay, arrivalDelay float64
+		var departuresOnTime, arrivalsOnTime int
+		for _, r := range runs {
+			if r.Missed {
+				p.Missed++
+				continue
+			}
+			if r.ActualDeparture.IsZero() {
+				continue
+			}
+			departed = append(departed, r)
+			departureDelay += r.ActualDeparture.Sub(r.ScheduledDeparture).Seconds()
+			if onTime(r.ScheduledDeparture, r.ActualDeparture) {
+				departuresOnTime++
+			}
+			for _, a := range r.Arrivals {
+				delay := a.Actual.Sub(a.Scheduled).Seconds()
+				if p.Arrivals == 0 || delay > p.MaxArrivalDelay {
+					p.MaxArrivalDelay = delay
+				}
+				p.Arrivals++
+				arrivalDelay += delay
+				if onTime(a.Scheduled, a.Actual) {
+					arrivalsOnTime++
+				}
+			}
+		}
+		p.Departures = len(departed)
+		if p.Departures > 0 {
+			p.OnTimeDepartures = float64(departuresOnTime) / float64(p.Departures)
+			p.MeanDepartureDelay = departureDelay / float64(p.Departures)
+		}
+		if p.Arrivals > 0 {
+			p.OnTimeArrivals = float64(arrivalsOnTime) / float64(p.Arrivals)
+			p.MeanArrivalDelay = arrivalDelay / float64(p.Arrivals)
+		}
+		if len(departed) > 1 {
+			n := float64(len(departed) - 1)
+			headways := make([]float64, 0, len(departed)-1)
+			for i := 1; i < len(departed); i++ {
+				headway := departed[i].ActualDeparture.Sub(departed[i-1].ActualDeparture).Seconds()
+				headways = append(headways, headway)
+				p.MeanHeadway += headway / n
+				p.ScheduledHeadway += departed[i].ScheduledDeparture.Sub(departed[i-1].ScheduledDeparture).Seconds() / n
+			}
+			if p.MeanHeadway > 0 {
+				var variance float64
+				for _, h := range headways {
+					variance += (h - p.MeanHeadway) * (h - p.MeanHeadway) / n
+				}
+				p.HeadwayVariation = math.Sqrt(variance) / p.MeanHeadway
+			}
+		}
+		report = append(report, p)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Route < report[j].Route })
+	return report
+}
@@ -0,0 +1,90 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+
+	"orbit/backend/simulation"
+)
+
+// tileSize is the width in pixels of a web map tile; zoom z renders the world 256*2^z pixels wide.
+const tileSize = 256
+
+// ZoomCluster is a group of trucks drawn as one marker at a map zoom level. Lat/Lon is the centroid of
+// the trucks and Bounds their extent. TruckID is set when the cluster is a single truck.
+type ZoomCluster struct {
+	Count   int
+	Lat     float64
+	Lon     float64
+	Bounds  simulation.BoundingBox
+	TruckID string
+}
+
+// ClusterForZoom groups trucks that would be drawn within radius pixels of each other at a web map zoom
+// level, the way supercluster does: each truck in ID order that is not yet clustered takes every other
+// unclustered truck within radius of it. Clusters come back in the order they were formed.
+func ClusterForZoom(trucks []simulation.Truck, zoom int, radius float64) []ZoomCluster {
+	sorted := append([]simulation.Truck(nil), trucks...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	type cell struct{ x, y int }
+	world := tileSize * math.Exp2(float64(zoom))
+	xs := make([]float64, len(sorted))
+	ys := make([]float64, len(sorted))
+	grid := make(map[cell][]int)
+	for i, truck := range sorted {
+		xs[i], ys[i] = pixelOf(truck.Lat, truck.Lon, world)
+		c := cell{int(math.Floor(xs[i] / radius)), int(math.Floor(ys[i] / radius))}
+		grid[c] = append(grid[c], i)
+	}
+
+	clustered := make([]bool, len(sorted))
+	clusters := []ZoomCluster{}
+	for i, truck := range sorted {
+		if clustered[i] {
+			continue
+		}
+		clustered[i] = true
+		members := []int{i}
+		home := cell{int(math.Floor(xs[i] / radius)), int(math.Floor(ys[i] / radius))}
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				for _, j := range grid[cell{home.x + dx, home.y + dy}] {
+					if !clustered[j] && math.Hypot(xs[j]-xs[i], ys[j]-ys[i]) <= radius {
+						clustered[j] = true
+						members = append(members, j)
+					}
+				}
+			}
+		}
+
+		cluster := ZoomCluster{
+			Count:  len(members),
+			Bounds: simulation.BoundingBox{MinLat: truck.Lat, MaxLat: truck.Lat, MinLon: truck.Lon, MaxLon: truck.Lon},
+		}
+		for _, j := range members {
+			p := sorted[j]
+			cluster.Lat += p.Lat / float64(len(members))
+			cluster.Lon += p.Lon / float64(len(members))
+			cluster.Bounds.MinLat = math.Min(cluster.Bounds.MinLat, p.Lat)
+			cluster.Bounds.MaxLat = math.Max(cluster.Bounds.MaxLat, p.Lat)
+			cluster.Bounds.MinLon = math.Min(cluster.Bounds.MinLon, p.Lon)
+			cluster.Bounds.MaxLon = math.Max(cluster.Bounds.MaxLon, p.Lon)
+		}
+		if len(members) == 1 {
+			cluster.TruckID = truck.ID
+		}
+		clusters = append(clusters, cluster)
+	}
+	return clusters
+}
+
+// pixelOf places a position on a Web Mercator world map world pixels wide, with the origin at the
+// north-west corner.
+func pixelOf(lat, lon, world float64) (x, y float64) {
+	lat = math.Max(-simulation.MaxMercatorLatitude, math.Min(simulation.MaxMercatorLatitude, lat))
+	sin := math.Sin(lat * math.Pi / 180)
+	x = (lon + 180) / 360 * world
+	y = (0.5 - math.Log((1+sin)/(1-sin))/(4*math.Pi)) * world
+	return x, y
+}
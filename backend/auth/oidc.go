@@ -0,0 +1,391 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRoleClaim is the claim roles are read from unless OIDCOptions.RoleClaim says otherwise.
+	DefaultRoleClaim = "roles"
+	// DefaultLeeway is the clock skew allowed when checking exp and nbf.
+	DefaultLeeway = time.Minute
+	// keyRefreshInterval is the least time between JWKS fetches triggered by tokens signed with an unknown
+	// key, so forged key IDs cannot hammer the issuer.
+	keyRefreshInterval = time.Minute
+	requestTimeout     = 5 * time.Second
+)
+
+// ErrInvalidToken is returned for tokens that fail verification. The wrapped error says why.
+var ErrInvalidToken = errors.New("invalid token")
+
+// OIDCOptions configures an OIDCVerifier.
+type OIDCOptions struct {
+	// Issuer is the issuer URL. Its discovery document is served under /.well-known/openid-configuration,
+	// and tokens must carry it as iss.
+	Issuer string
+	// Audience must appear in a token's aud.
+	Audience string
+	// RoleClaim names the claim holding the caller's roles or groups, as a string or list of strings. Dots
+	// reach into nested objects, as in realm_access.roles. Defaults to DefaultRoleClaim.
+	RoleClaim string
+	// RoleMap maps claim values to roles, and the highest mapped role wins. When nil, the values viewer,
+	// operator, and admin map to themselves.
+	RoleMap map[string]Role
+	// DefaultRole is given to valid tokens that map to no role. RoleNone rejects them.
+	DefaultRole Role
+	// Leeway defaults to DefaultLeeway.
+	Leeway time.Duration
+	Client *http.Client
+}
+
+// OIDCVerifier authenticates callers by the JWTs an OpenID Connect provider issues. Signing keys are found
+// through OIDC discovery and refetched when a token names a key the verifier has not seen.
+type OIDCVerifier struct {
+	opts OIDCOptions
+
+	mu        sync.Mutex
+	jwksURI   string
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+// NewOIDCVerifier creates a verifier. Discovery happens on first use, or up front with Discover.
+func NewOIDCVerifier(opts OIDCOptions) *OIDCVerifier {
+	opts.Issuer = strings.TrimRight(opts.Issuer, "/")
+	if opts.RoleClaim == "" {
+		opts.RoleClaim = DefaultRoleClaim
+	}
+	if opts.RoleMap == nil {
+		opts.RoleMap = map[string]Role{"viewer": RoleViewer, "operator": RoleOperator, "admin": RoleAdmin}
+	}
+	if opts.Leeway <= 0 {
+		opts.Leeway = DefaultLeeway
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: requestTimeout}
+	}
+	return &OIDCVerifier{opts: opts}
+}
+
+// Discover fetches the issuer's discovery document and signing keys.
+func (v *OIDCVerifier) Discover(ctx context.Context) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.refreshLocked(ctx)
+}
+
+func (v *OIDCVerifier) refreshLocked(ctx context.Context) error {
+	v.fetchedAt = time.Now()
+	if v.jwksURI == "" {
+		var doc struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := v.getJSON(ctx, v.opts.Issuer+"/.well-known/openid-configuration", &doc); err != nil {
+			return fmt.Errorf("oidc discovery: %w", err)
+		}
+		if strings.TrimRight(doc.Issuer, "/") != v.opts.Issuer || doc.JWKSURI == "" {
+			return fmt.Errorf("oidc discovery: document is for issuer %q with jwks_uri %q", doc.Issuer, doc.JWKSURI)
+		}
+		v.jwksURI = doc.JWKSURI
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := v.getJSON(ctx, v.jwksURI, &set); err != nil {
+		return fmt.Errorf("oidc keys: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	v.keys = keys
+	return nil
+}
+
+func (v *OIDCVerifier) getJSON(ctx context.Context, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// key returns the signing key with the given ID, fetching the key set when the ID is unknown.
+func (v *OIDCVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if key, ok := v.lookupLocked(kid); ok {
+		return key, nil
+	}
+	if v.keys != nil && time.Since(v.fetchedAt) < keyRefreshInterval {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidToken, kid)
+	}
+	if err := v.refreshLocked(ctx); err != nil {
+		return nil, err
+	}
+	if key, ok := v.lookupLocked(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidToken, kid)
+}
+
+// lookupLocked finds a key by ID. A token without a key ID matches an issuer with a single key.
+func (v *OIDCVerifier) lookupLocked(kid string) (crypto.PublicKey, bool) {
+	if key, ok := v.keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+type tokenClaims struct {
+	Issuer    string          `json:"iss"`
+	Subject   string          `json:"sub"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *float64        `json:"exp"`
+	NotBefore *float64        `json:"nbf"`
+}
+
+// Authenticate verifies a JWT's signature, issuer, audience, and validity period, and maps its role claim
+// to a role.
+func (v *OIDCVerifier) Authenticate(ctx context.Context, token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, fmt.Errorf("%w: not a JWT", ErrInvalidToken)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Principal{}, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return Principal{}, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: signature encoding", ErrInvalidToken)
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var claims tokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Principal{}, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+	var raw map[string]any
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return Principal{}, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+	now := time.Now()
+	switch {
+	case strings.TrimRight(claims.Issuer, "/") != v.opts.Issuer:
+		return Principal{}, fmt.Errorf("%w: issued by %q", ErrInvalidToken, claims.Issuer)
+	case !audienceIncludes(claims.Audience, v.opts.Audience):
+		return Principal{}, fmt.Errorf("%w: not issued for audience %q", ErrInvalidToken, v.opts.Audience)
+	case claims.ExpiresAt == nil:
+		return Principal{}, fmt.Errorf("%w: no expiry", ErrInvalidToken)
+	case now.Add(-v.opts.Leeway).After(unixTime(*claims.ExpiresAt)):
+		return Principal{}, fmt.Errorf("%w: expired", ErrInvalidToken)
+	case claims.NotBefore != nil && now.Add(v.opts.Leeway).Before(unixTime(*claims.NotBefore)):
+		return Principal{}, fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	}
+
+	role := v.opts.DefaultRole
+	for _, value := range claimValues(raw, v.opts.RoleClaim) {
+		if mapped := v.opts.RoleMap[value]; mapped > role {
+			role = mapped
+		}
+	}
+	if role == RoleNone {
+		return Principal{}, fmt.Errorf("%w: no role in claim %q", ErrInvalidToken, v.opts.RoleClaim)
+	}
+	return Principal{Subject: claims.Subject, Role: role}, nil
+}
+
+func decodeSegment(segment string, out any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+// audienceIncludes reports whether aud, a string or a list of strings, includes want.
+func audienceIncludes(aud json.RawMessage, want string) bool {
+	var single string
+	if json.Unmarshal(aud, &single) == nil {
+		return single == want
+	}
+	var list []string
+	if json.Unmarshal(aud, &list) == nil {
+		for _, a := range list {
+			if a == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// claimValues returns the strings at a dotted claim path: a list of strings, or a single string split on
+// spaces the way OAuth scopes are.
+func claimValues(claims map[string]any, path string) []string {
+	var value any = claims
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	var h hash.Hash
+	var id crypto.Hash
+	switch alg[min(2, len(alg)):] {
+	case "256":
+		h, id = sha256.New(), crypto.SHA256
+	case "384":
+		h, id = sha512.New384(), crypto.SHA384
+	case "512":
+		h, id = sha512.New(), crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		switch {
+		case strings.HasPrefix(alg, "RS"):
+			return rsa.VerifyPKCS1v15(k, id, digest, signature)
+		case strings.HasPrefix(alg, "PS"):
+			return rsa.VerifyPSS(k, id, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(alg, "ES") || len(signature) != 2*size {
+			break
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if ecdsa.Verify(k, digest, r, s) {
+			return nil
+		}
+		return errors.New("bad signature")
+	}
+	return fmt.Errorf("algorithm %q does not match the signing key", alg)
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	decode := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, fmt.Errorf("bad RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testIssuer struct {
+	*httptest.Server
+	rsaKey    *rsa.PrivateKey
+	ecKey     *ecdsa.PrivateKey
+	keyFetchs atomic.Int32
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate EC key: %v", err)
+	}
+	iss := &testIssuer{rsaKey: rsaKey, ecKey: ecKey}
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"issuer": iss.URL, "jwks_uri": iss.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		iss.keyFetchs.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+		}})
+	})
+	iss.Server = httptest.NewServer(mux)
+	t.Cleanup(iss.Close)
+	return iss
+}
+
+func (iss *testIssuer) token(t *testing.T, alg, kid string, claims map[string]any) string {
+	t.Helper()
+	b64 := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := b64(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + b64(claims)
+	digest := sha256.Sum256([]byte(signed))
+	var signature []byte
+	switch alg {
+	case "RS256":
+		sig, err := rsa.SignPKCS1v15(rand.Reader, iss.rsaKey, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		signature = sig
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, iss.ecKey, digest[:])
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func (iss *testIssuer) claims(overrides map[string]any) map[string]any {
+	claims := map[string]any{
+		"iss": iss.URL,
+		"sub": "user-1",
+		"aud": []string{"orbit", "portal"},
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"realm_access": map[string]any{
+			"roles": []string{"portal-user", "orbit-ops"},
+		},
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(claims, k)
+			continue
+		}
+		claims[k] = v
+	}
+	return claims
+}
+
+func TestOIDCVerifierMapsClaimsToRoles(t *testing.T) {
+	iss := newTestIssuer(t)
+	verifier := NewOIDCVerifier(OIDCOptions{
+		Issuer:    iss.URL,
+		Audience:  "orbit",
+		RoleClaim: "realm_access.roles",
+		RoleMap:   map[string]Role{"orbit-ops": RoleOperator, "orbit-admin": RoleAdmin},
+	})
+	ctx := context.Background()
+
+	for _, alg := range []string{"RS256", "ES256"} {
+		kid := map[string]string{"RS256": "rsa-1", "ES256": "ec-1"}[alg]
+		principal, err := verifier.Authenticate(ctx, iss.token(t, alg, kid, iss.claims(nil)))
+		if err != nil || principal.Subject != "user-1" || principal.Role != RoleOperator {
+			t.Fatalf("%s: expected user-1 as operator, got %+v, %v", alg, principal, err)
+		}
+	}
+	if n := iss.keyFetchs.Load(); n != 1 {
+		t.Fatalf("expected the keys to be fetched once, got %d", n)
+	}
+
+	rejected := map[string]string{
+		"wrong audience": iss.token(t, "RS256", "rsa-1", iss.claims(map[string]any{"aud": "someone-else"})),
+		"wrong issuer":   iss.token(t, "RS256", "rsa-1", iss.claims(map[string]any{"iss": "https://elsewhere.example"})),
+		"expired":        iss.token(t, "RS256", "rsa-1", iss.claims(map[string]any{"exp": time.Now().Add(-time.Hour).Unix()})),
+		"no expiry":      iss.token(t, "RS256", "rsa-1", iss.claims(map[string]any{"exp": nil})),
+		"no role":        iss.token(t, "RS256", "rsa-1", iss.claims(map[string]any{"realm_access": map[string]any{"roles": []string{"portal-user"}}})),
+		"unsigned":       iss.token(t, "none", "rsa-1", iss.claims(nil)),
+		"key mismatch":   iss.token(t, "ES256", "rsa-1", iss.claims(nil)),
+		"unknown key":    iss.token(t, "RS256", "rsa-2", iss.claims(nil)),
+	}
+	for name, token := range rejected {
+		if _, err := verifier.Authenticate(ctx, token); !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("%s: expected ErrInvalidToken, got %v", name, err)
+		}
+	}
+	if n := iss.keyFetchs.Load(); n != 1 {
+		t.Fatalf("expected unknown keys not to refetch within a minute, got %d fetches", n)
+	}
+
+	fallback := NewOIDCVerifier(OIDCOptions{Issuer: iss.URL, Audience: "orbit", DefaultRole: RoleViewer})
+	if principal, err := fallback.Authenticate(ctx, iss.token(t, "RS256", "rsa-1", iss.claims(map[string]any{"roles": "admin"}))); err != nil || principal.Role != RoleAdmin {
+		t.Fatalf("expected the roles claim to name admin, got %+v, %v", principal, err)
+	}
+	if principal, err := fallback.Authenticate(ctx, iss.token(t, "RS256", "rsa-1", iss.claims(nil))); err != nil || principal.Role != RoleViewer {
+		t.Fatalf("expected the default role, got %+v, %v", principal, err)
+	}
+}
@@ -0,0 +1,52 @@
+// Package auth identifies API callers and the role they act in. Roles are ordered: an operator can do
+// everything a viewer can, and an admin everything an operator can.
+package auth
+
+import (
+	"context"
+	"fmt"
+)
+
+// Role is what a caller may do.
+type Role int
+
+const (
+	// RoleNone grants nothing.
+	RoleNone Role = iota
+	// RoleViewer reads fleet state and streams.
+	RoleViewer
+	// RoleOperator also changes the simulation: config, routes, trucks, and alerts.
+	RoleOperator
+	// RoleAdmin also reaches the /admin endpoints.
+	RoleAdmin
+)
+
+var roleNames = map[Role]string{RoleViewer: "viewer", RoleOperator: "operator", RoleAdmin: "admin"}
+
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return "none"
+}
+
+// ParseRole returns the role named viewer, operator, or admin.
+func ParseRole(s string) (Role, error) {
+	for role, name := range roleNames {
+		if name == s {
+			return role, nil
+		}
+	}
+	return RoleNone, fmt.Errorf("unknown role %q: want viewer, operator, or admin", s)
+}
+
+// Principal is an authenticated caller.
+type Principal struct {
+	Subject string
+	Role    Role
+}
+
+// Authenticator verifies a bearer token and returns who presented it.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (Principal, error)
+}
@@ -0,0 +1,97 @@
+// Package bench measures what a full fleet snapshot costs, the allocation behind each /api/trucks
+// response and WebSocket push, so the GC flags can be tried against a fleet size before deploying it. It
+// runs the same loop as BenchmarkTrucksSnapshot in the simulation package.
+package bench
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"orbit/backend/cmd/internal/tuning"
+	"orbit/backend/simulation"
+)
+
+// Main parses the bench flags from args and prints one line per fleet size.
+func Main(args []string) {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	var (
+		trucks   = fs.String("trucks", "1000,10000,100000", "comma-separated fleet sizes to measure")
+		seed     = fs.Int64("seed", 1, "simulation seed")
+		gcTuning = tuning.Flags(fs)
+	)
+	_ = fs.Parse(args)
+
+	sizes, err := parseSizes(*trucks)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -trucks: %v\n", err)
+		os.Exit(2)
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	// The ballast is sized for the largest fleet, as serve would size it.
+	if err := gcTuning.Apply(sizes[len(sizes)-1], logger); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to tune garbage collector: %v\n", err)
+		os.Exit(2)
+	}
+
+	for _, n := range sizes {
+		line, err := run(n, *seed)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "trucks=%d: %v\n", n, err)
+			os.Exit(1)
+		}
+		fmt.Println(line)
+	}
+}
+
+// parseSizes parses the -trucks list into ascending fleet sizes.
+func parseSizes(value string) ([]int, error) {
+	var sizes []int
+	for _, field := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+		if n <= 0 {
+			return nil, fmt.Errorf("fleet size %d must be positive", n)
+		}
+		if len(sizes) > 0 && n <= sizes[len(sizes)-1] {
+			return nil, fmt.Errorf("fleet sizes must be ascending")
+		}
+		sizes = append(sizes, n)
+	}
+	return sizes, nil
+}
+
+// run benchmarks snapshots of an n-truck fleet and formats the result like `go test -bench -benchmem`,
+// followed by the GC cycles and pause time spent while it ran.
+func run(n int, seed int64) (string, error) {
+	manager := simulation.NewManager(simulation.Config{NumTrucks: n, Seed: seed, UpdateInterval: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := manager.Start(ctx); err != nil {
+		return "", err
+	}
+	defer manager.Stop()
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	result := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = manager.Trucks()
+		}
+	})
+	runtime.ReadMemStats(&after)
+
+	return fmt.Sprintf("trucks=%d\t%s\t%s\t%d GCs\t%s GC pause",
+		n, result, result.MemString(), after.NumGC-before.NumGC, time.Duration(after.PauseTotalNs-before.PauseTotalNs)), nil
+}
@@ -0,0 +1,199 @@
+// Package ctl inspects and converts the files Orbit writes: recordings from /admin/simulation/recording and
+// -state-file snapshots, compressed or not.
+package ctl
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"orbit/backend/simulation"
+	"orbit/backend/zstdfile"
+)
+
+// Main runs the ctl subcommand named by args[0], exiting non-zero on failure.
+func Main(args []string) {
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch args[0] {
+	case "inspect":
+		err = inspectCmd(args[1:])
+	case "convert":
+		err = convertCmd(args[1:])
+	case "help", "-h", "-help", "--help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "orbitctl: unknown command %q\n", args[0])
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "orbitctl %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: orbitctl <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	fmt.Fprintln(os.Stderr, "  inspect FILE      summarize a recording or state file")
+	fmt.Fprintln(os.Stderr, "  convert IN OUT    copy a recording or state file, compressing it when OUT ends in .zst")
+}
+
+func inspectCmd(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expected one file")
+	}
+	return inspect(os.Stdout, fs.Arg(0))
+}
+
+func convertCmd(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	_ = fs.Parse(args)
+	if fs.NArg() != 2 {
+		return fmt.Errorf("expected an input and an output file")
+	}
+	return convert(os.Stdout, fs.Arg(0), fs.Arg(1))
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// fileSummary is what inspect decodes from either kind of file. A state file has a Version; a recording
+// has only Runs and Ticks.
+type fileSummary struct {
+	Version int
+	Run     simulation.RunInfo
+	Clock   time.Time
+	Ticks   int64
+
+	Trucks      []json.RawMessage
+	Trips       []json.RawMessage
+	Shipments   []json.RawMessage
+	History     []json.RawMessage
+	Recording   []simulation.RecordedRun
+	Runs        []simulation.RecordedRun
+	Completions []json.RawMessage
+}
+
+func inspect(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	r, compressed, err := zstdfile.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	counted := &countingReader{r: r}
+	var sum fileSummary
+	if err := json.NewDecoder(counted).Decode(&sum); err != nil {
+		return fmt.Errorf("decode %s: %w", path, err)
+	}
+	_, _ = io.Copy(io.Discard, counted)
+
+	fmt.Fprintf(w, "file:        %s\n", path)
+	if compressed {
+		fmt.Fprintf(w, "size:        %d bytes zstd, %d bytes JSON (%.1fx)\n", info.Size(), counted.n, float64(counted.n)/float64(max(info.Size(), 1)))
+	} else {
+		fmt.Fprintf(w, "size:        %d bytes JSON, uncompressed\n", info.Size())
+	}
+
+	runs := sum.Runs
+	if sum.Version > 0 {
+		runs = sum.Recording
+		fmt.Fprintf(w, "kind:        state (version %d)\n", sum.Version)
+		fmt.Fprintf(w, "run:         %s (#%d, seed %d)\n", sum.Run.ID, sum.Run.Number, sum.Run.Seed)
+		fmt.Fprintf(w, "clock:       %s after %d ticks\n", sum.Clock.Format(time.RFC3339), sum.Ticks)
+		fmt.Fprintf(w, "trucks:      %d\n", len(sum.Trucks))
+		fmt.Fprintf(w, "trips:       %d\n", len(sum.Trips))
+		fmt.Fprintf(w, "completions: %d\n", len(sum.Completions))
+		fmt.Fprintf(w, "shipments:   %d\n", len(sum.Shipments))
+		fmt.Fprintf(w, "changes:     %d config changes\n", len(sum.History))
+	} else if sum.Runs != nil {
+		fmt.Fprintf(w, "kind:        recording of %d ticks\n", sum.Ticks)
+	} else {
+		return fmt.Errorf("%s is neither a recording nor a state file", path)
+	}
+	fmt.Fprintf(w, "runs:        %d\n", len(runs))
+	for _, run := range runs {
+		fmt.Fprintf(w, "  tick %-8d seed %-20d %d trucks every %s from %s\n",
+			run.Tick, run.Config.Seed, run.Config.NumTrucks, run.Config.UpdateInterval, run.Config.StartTime.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// convert streams in to out, decompressing in when it is compressed and compressing out when its name ends
+// in .zst. out is written through a temporary file and renamed into place.
+func convert(w io.Writer, in, out string) error {
+	src, err := os.Open(in)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	r, _, err := zstdfile.NewReader(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tmp := out + ".tmp"
+	dst, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	fail := func(err error) error {
+		dst.Close()
+		os.Remove(tmp)
+		return err
+	}
+	zw, err := zstdfile.NewWriter(dst, zstdfile.Compressed(out))
+	if err != nil {
+		return fail(err)
+	}
+	n, err := io.Copy(zw, r)
+	if err != nil {
+		return fail(err)
+	}
+	if err := zw.Close(); err != nil {
+		return fail(err)
+	}
+	info, err := dst.Stat()
+	if err != nil {
+		return fail(err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, out); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "wrote %s: %d bytes of JSON in %d bytes\n", out, n, info.Size())
+	return nil
+}
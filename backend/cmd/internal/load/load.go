@@ -0,0 +1,204 @@
+// Package load drives a constant rate of GET requests at a running server and reports the latencies, the
+// same attack scripts/loadtest.sh runs through vegeta, without needing vegeta installed.
+package load
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Main parses the load flags from args, runs the attack, and prints a report. It exits non-zero when no
+// request succeeded.
+func Main(args []string) {
+	fs := flag.NewFlagSet("load", flag.ExitOnError)
+	var (
+		rate        = fs.Int("rate", 2500, "requests per second to sustain")
+		duration    = fs.Duration("duration", 60*time.Second, "how long to run the attack")
+		target      = fs.String("target", "http://localhost:8080/api/trucks", "URL to GET")
+		timeout     = fs.Duration("timeout", 30*time.Second, "timeout of each request")
+		maxInFlight = fs.Int("max-in-flight", 1000, "requests in flight at once; the rate drops when the server falls this far behind")
+	)
+	_ = fs.Parse(args)
+
+	if *rate <= 0 || *duration <= 0 || *maxInFlight <= 0 {
+		fmt.Fprintln(os.Stderr, "-rate, -duration, and -max-in-flight must be positive")
+		os.Exit(2)
+	}
+	req, err := http.NewRequest(http.MethodGet, *target, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid target: %v\n", err)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	client := &http.Client{
+		Timeout:   *timeout,
+		Transport: &http.Transport{MaxIdleConnsPerHost: *maxInFlight},
+	}
+	start := time.Now()
+	results := attack(ctx, client, req, *rate, *duration, *maxInFlight)
+	rep := summarize(results, time.Since(start))
+	rep.print(os.Stdout)
+	if rep.successes == 0 {
+		os.Exit(1)
+	}
+}
+
+// result is the outcome of one request.
+type result struct {
+	latency time.Duration
+	status  int
+	bytes   int64
+	err     error
+}
+
+// attack sends req rate times a second for duration, or until ctx is cancelled, and returns every result.
+// Requests are scheduled on a fixed timetable rather than after the previous one returns, so a slow server
+// sees the same rate until maxInFlight requests are waiting on it.
+func attack(ctx context.Context, client *http.Client, req *http.Request, rate int, duration time.Duration, maxInFlight int) []result {
+	interval := time.Second / time.Duration(rate)
+	total := int(duration / interval)
+	results := make([]result, 0, total)
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		inFlight = make(chan struct{}, maxInFlight)
+	)
+
+	start := time.Now()
+	for i := 0; i < total; i++ {
+		if wait := time.Until(start.Add(time.Duration(i) * interval)); wait > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(wait):
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		inFlight <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := send(client, req.Clone(ctx))
+			<-inFlight
+			mu.Lock()
+			results = append(results, res)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func send(client *http.Client, req *http.Request) result {
+	began := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return result{latency: time.Since(began), err: err}
+	}
+	defer resp.Body.Close()
+	n, err := io.Copy(io.Discard, resp.Body)
+	return result{latency: time.Since(began), status: resp.StatusCode, bytes: n, err: err}
+}
+
+// report summarizes an attack the way `vegeta report` does.
+type report struct {
+	requests  int
+	successes int
+	elapsed   time.Duration
+	latencies []time.Duration
+	bytes     int64
+	statuses  map[int]int
+	errors    map[string]int
+}
+
+func summarize(results []result, elapsed time.Duration) report {
+	rep := report{requests: len(results), elapsed: elapsed, statuses: make(map[int]int), errors: make(map[string]int)}
+	for _, res := range results {
+		rep.latencies = append(rep.latencies, res.latency)
+		rep.bytes += res.bytes
+		rep.statuses[res.status]++
+		switch {
+		case res.err != nil:
+			msg := res.err.Error()
+			var urlErr *url.Error
+			if errors.As(res.err, &urlErr) {
+				msg = urlErr.Err.Error()
+			}
+			rep.errors[msg]++
+		case res.status >= 200 && res.status < 400:
+			rep.successes++
+		default:
+			rep.errors[http.StatusText(res.status)]++
+		}
+	}
+	sort.Slice(rep.latencies, func(i, j int) bool { return rep.latencies[i] < rep.latencies[j] })
+	return rep
+}
+
+func (r report) percentile(p float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	return r.latencies[int(p*float64(len(r.latencies)-1))]
+}
+
+func (r report) print(w io.Writer) {
+	var sum time.Duration
+	for _, l := range r.latencies {
+		sum += l
+	}
+	var mean time.Duration
+	if n := len(r.latencies); n > 0 {
+		mean = sum / time.Duration(n)
+	}
+	fmt.Fprintf(w, "Requests      [total, rate]           %d, %.2f\n", r.requests, float64(r.requests)/r.elapsed.Seconds())
+	fmt.Fprintf(w, "Duration      [total]                 %s\n", r.elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "Latencies     [mean, 50, 90, 99, max] %s, %s, %s, %s, %s\n",
+		mean, r.percentile(0.5), r.percentile(0.9), r.percentile(0.99), r.percentile(1))
+	fmt.Fprintf(w, "Bytes In      [total]                 %d\n", r.bytes)
+	ratio := 0.0
+	if r.requests > 0 {
+		ratio = 100 * float64(r.successes) / float64(r.requests)
+	}
+	fmt.Fprintf(w, "Success       [ratio]                 %.2f%%\n", ratio)
+
+	codes := make([]int, 0, len(r.statuses))
+	for code := range r.statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	fmt.Fprint(w, "Status Codes  [code:count]            ")
+	for i, code := range codes {
+		if i > 0 {
+			fmt.Fprint(w, "  ")
+		}
+		fmt.Fprintf(w, "%d:%d", code, r.statuses[code])
+	}
+	fmt.Fprintln(w)
+
+	if len(r.errors) > 0 {
+		msgs := make([]string, 0, len(r.errors))
+		for msg := range r.errors {
+			msgs = append(msgs, msg)
+		}
+		sort.Strings(msgs)
+		fmt.Fprintln(w, "Error Set:")
+		for _, msg := range msgs {
+			fmt.Fprintf(w, "%s (%d)\n", msg, r.errors[msg])
+		}
+	}
+}
@@ -0,0 +1,796 @@
+// Package serve runs the Orbit simulation and its HTTP API until interrupted.
+package serve
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/automaxprocs/maxprocs"
+
+	"orbit/backend/analytics"
+	"orbit/backend/auth"
+	"orbit/backend/cmd/internal/tuning"
+	"orbit/backend/demo"
+	"orbit/backend/events"
+	"orbit/backend/history"
+	"orbit/backend/ids"
+	"orbit/backend/osrm"
+	"orbit/backend/profiles"
+	"orbit/backend/redact"
+	"orbit/backend/roadnetwork"
+	"orbit/backend/server"
+	"orbit/backend/simulation"
+	"orbit/backend/telemetry"
+	"orbit/backend/version"
+	"orbit/backend/webui"
+)
+
+// Main parses the server flags from args, starts the simulation and HTTP server, and blocks until SIGINT or
+// SIGTERM.
+func Main(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	var (
+		addrDefault        = envString("ORBIT_ADDR", ":8080")
+		trucksDefault      = envInt("ORBIT_TRUCKS", 2000)
+		tickRateDefault    = envDuration("ORBIT_TICK_RATE", time.Second)
+		boundingBoxDefault = os.Getenv("ORBIT_BOUNDING_BOX")
+		osrmURLDefault     = os.Getenv("ORBIT_OSRM_URL")
+		roadNetworkDefault = os.Getenv("ORBIT_ROAD_NETWORK")
+		oidcIssuerDefault  = os.Getenv("ORBIT_OIDC_ISSUER")
+		oidcAudDefault     = os.Getenv("ORBIT_OIDC_AUDIENCE")
+		addr               = fs.String("addr", addrDefault, "HTTP listen address")
+		showVersion        = fs.Bool("version", false, "print the version and exit")
+		serveUI            = fs.Bool("serve-ui", true, "serve the dashboard at / when the binary was built with it embedded")
+		enableAdmin        = fs.Bool("enable-admin", false, "enable admin endpoints like pprof")
+		trucks             = fs.Int("trucks", trucksDefault, "number of trucks to simulate")
+		updateInterval     = fs.Duration("update-interval", tickRateDefault, "simulation update interval")
+		tickRate           = fs.String("tick-rate", "", "alias for update-interval; overrides when set")
+		osrmURL            = fs.String("osrm-url", osrmURLDefault, "OSRM server to plan road-following routes with; trucks drive a straight line until the route arrives, and keep it when planning fails")
+		osrmQPS            = fs.Float64("osrm-qps", osrm.DefaultQPS, "most requests per second sent to the OSRM server")
+		osrmWorkers        = fs.Int("osrm-workers", simulation.DefaultPlanWorkers, "OSRM route requests in flight at once")
+		roadNetwork        = fs.String("road-network", roadNetworkDefault, "GeoJSON road network extract to plan street-following routes on offline")
+		boundingBox        = fs.String("bounding-box", boundingBoxDefault, "optional bounding boxes for routes as minLat,minLon,maxLat,maxLon, separated by semicolons for several regions")
+		regions            = fs.String("regions", "", "semicolon-separated regions with their own update interval as name=minLat,minLon,maxLat,maxLon@interval")
+		maxConfigPosts     = fs.Int("max-config-posts", 4, "maximum concurrent config POSTs before returning 503 (0 disables)")
+		maxTrucks          = fs.Int("max-trucks", 100000, "largest fleet the config and fleet APIs may request; larger requests get 422 (0 disables)")
+		minUpdateInterval  = fs.Duration("min-update-interval", 10*time.Millisecond, "shortest update interval the config API may set; shorter requests get 422 (0 disables)")
+		maxWaypoints       = fs.Int("max-waypoints", 1000, "most waypoints a route sent to the API may have; longer routes get 422 (0 disables)")
+		workers            = fs.Int("workers", 0, "simulation worker goroutines (defaults to GOMAXPROCS)")
+		gcTuning           = tuning.Flags(fs)
+		startOffset        = fs.Duration("start-offset", 0, "offset of the simulated clock from wall-clock time at startup, e.g. -6h for a night-time demo")
+		stateFile          = fs.String("state-file", "", "file the fleet state is saved to on shutdown and resumed from on startup")
+		drainTimeout       = fs.Duration("drain-timeout", 0, "on shutdown, let moving trucks finish their current leg for up to this long before stopping (0 stops at once)")
+		counterFile        = fs.String("counter-file", "", "file cumulative counters such as distance and routes completed are saved to and restored from across restarts")
+		counterInterval    = fs.Duration("counter-save-interval", 30*time.Second, "how often counters are saved to -counter-file")
+		initFrom           = fs.String("init-from", "", "state file or recording to start the fleet from when no -state-file exists yet")
+		replayFile         = fs.String("replay", "", "recording to play back on the wall clock before continuing live")
+		replayDuration     = fs.Duration("replay-duration", 0, "fit the whole -replay recording into this much wall-clock time, e.g. 20m")
+		replaySpeed        = fs.Float64("replay-speed", 0, "simulated seconds of the -replay recording played per second (default: its recorded time scale)")
+		replayLoop         = fs.Bool("replay-loop", false, "start the -replay recording over when it ends instead of continuing live")
+		seed               = fs.Int64("seed", 42, "seed for the first simulation run")
+		rotateSeed         = fs.Bool("rotate-seed", false, "derive a new seed for every run after the first instead of reusing -seed")
+		timeScale          = fs.Float64("time-scale", 1, "simulated seconds per real second, e.g. 10 for ten times real time")
+		truckMix           = fs.String("truck-mix", "", "fleet mix by truck type as weights or counts, e.g. van=60,box=25,semi=10,tanker=5")
+		dwell              = fs.String("dwell", "", "dwell durations per stationary status, e.g. loading=10m,unloading=15m,maintenance=2h")
+		waypoints          = fs.Int("waypoints", 2, "waypoints per route including start and end; extra waypoints are delivery stops inside the bounding box")
+		waypointDwell      = fs.Duration("waypoint-dwell", 0, "how long trucks pause as idle at each intermediate waypoint")
+		routeWaypointDwell = fs.String("route-waypoint-dwell", "", "semicolon-separated per-route waypoint dwell overrides as routeID=duration")
+		odMatrix           = fs.String("od", "", "semicolon-separated origin-destination demand as lat,lon>lat,lon=weight; sets the start and end points")
+		depots             = fs.String("depots", "", "semicolon-separated depots as name=lat,lon or name=lat,lon/docks; trucks start at and return to depots")
+		dockCapacity       = fs.Int("dock-capacity", 0, "docks per depot for loading and unloading; trucks queue when all are busy (0 means unlimited)")
+		suspendAfter       = fs.Duration("suspend-after", 0, "stop processing trucks that will stay idle or stationary at least this long in simulated time until they are due to move (0 disables)")
+		maxDriveTime       = fs.Duration("max-drive-time", 0, "simulated driving time before a mandatory rest break, e.g. 11h (0 disables hours of service)")
+		maintenanceEvery   = fs.Int("maintenance-every", 0, "send trucks to maintenance after this many completed routes (0 disables)")
+		idFormat           = fs.String("id-format", "ulid", "identifier format for events and event subscriptions: ulid, uuid, or sequence; follow sessions always use random UUIDs")
+		tankCapacity       = fs.Float64("tank-capacity", 400, "fuel tank size in litres")
+		fuelPerKm          = fs.Float64("fuel-per-km", 0.35, "fuel consumption in litres per kilometre at cruising speed")
+		refuelThreshold    = fs.Float64("refuel-threshold", 0.15, "tank fraction below which trucks stop to refuel")
+		electricShare      = fs.Float64("electric-share", 0, "fraction of the fleet generated as electric trucks")
+		speedLimit         = fs.Float64("speed-limit", 0, "speed limit for every truck in metres per second; enables compliance reporting (0 disables)")
+		governedShare      = fs.Float64("governed-share", 0, "fraction of trucks fitted with a governor that holds them at -speed-limit")
+		chargingStations   = fs.String("charging-stations", "", "semicolon-separated lat,lon charging station locations (defaults to start points)")
+		clusterK           = fs.Int("cluster-k", 0, "number of behaviour clusters to compute for /api/analytics/clusters (0 disables)")
+		clusterInterval    = fs.Duration("cluster-interval", 5*time.Second, "how often truck behaviour is sampled for clustering")
+		clusterWindow      = fs.Int("cluster-window", 60, "number of recent samples per truck used for clustering")
+		proximityDistance  = fs.Float64("proximity-distance", 0, "flag moving trucks closer than this many metres as proximity conflicts (0 disables)")
+		proximityInterval  = fs.Duration("proximity-interval", time.Second, "how often trucks are checked for proximity conflicts")
+		redactHeaders      = fs.String("redact-headers", "", "comma-separated request headers to redact from logs in addition to Authorization, Cookie, and API keys")
+		redactFields       = fs.String("redact-fields", "", "comma-separated log fields and query parameters to redact in addition to tokens, secrets, and sessions")
+		logHeaders         = fs.Bool("log-request-headers", false, "include (redacted) request headers in request logs")
+		requireDemoToken   = fs.Bool("require-demo-token", false, "reject API requests without a valid demo token (needs ORBIT_DEMO_TOKEN_SECRET)")
+		oidcIssuer         = fs.String("oidc-issuer", oidcIssuerDefault, "OpenID Connect issuer whose JWTs authenticate API requests; unset leaves the API open")
+		oidcAudience       = fs.String("oidc-audience", oidcAudDefault, "audience OIDC tokens must be issued for")
+		oidcRoleClaim      = fs.String("oidc-role-claim", auth.DefaultRoleClaim, "token claim holding the caller's roles or groups; dots reach into nested claims")
+		oidcRoles          = fs.String("oidc-roles", "", "comma-separated claim values mapped to roles as value=viewer|operator|admin (default: the role names themselves)")
+		oidcDefaultRole    = fs.String("oidc-default-role", "", "role given to valid tokens that map to none (default: reject them)")
+		metricsExporter    = fs.String("metrics-exporter", "prometheus", "metrics export: prometheus (scrape /metrics) or otlp (also push over OTLP/HTTP)")
+		otlpEndpoint       = fs.String("otlp-endpoint", "", "OTLP/HTTP metrics endpoint URL (defaults to OTEL_EXPORTER_OTLP_* environment variables)")
+		otlpInterval       = fs.Duration("otlp-interval", 15*time.Second, "how often metrics are pushed over OTLP")
+		maxStreams         = fs.Int("max-streams", 0, "maximum open streams, including /api/playback; more are closed with a jittered retry hint (0 disables)")
+		reconnectWindow    = fs.Duration("reconnect-window", 10*time.Second, "window over which WebSocket clients are told to reconnect when the server shuts down")
+		maxSnapshotGets    = fs.Int("max-snapshot-gets", 100, "maximum concurrent truck snapshot GETs before returning 503 (0 disables)")
+		snapshotEncoders   = fs.Int("snapshot-encoders", 0, "maximum truck snapshots encoded at once; others queue (0 uses half of GOMAXPROCS)")
+		historyRetention   = fs.Duration("history-retention", 0, "simulated time of compressed position history kept per truck, e.g. 24h (0 disables)")
+		historyInterval    = fs.Duration("history-interval", 10*time.Second, "how often truck positions are sampled into the position history")
+		historyFile        = fs.String("history-file", "", "file the position history is saved to on shutdown and restored from on startup")
+		trackDepth         = fs.Int("track-depth", simulation.DefaultTrackDepth, "recent positions kept per truck for /api/trucks/{id}/history and /ws/trucks trails")
+		eventRetention     = fs.Duration("event-retention", 10*time.Minute, "how long simulation events are kept for /ws/events clients to replay")
+		maxAlerts          = fs.Int("max-alerts", 1000, "alerts kept for /api/alerts; the oldest resolved alert makes room first")
+		profileFile        = fs.String("profile-file", "", "file /ws/trucks subscription profiles are saved to and loaded from (empty keeps them in memory)")
+		clockSkew          = fs.Duration("clock-skew", 0, "largest offset of a truck's clock from the simulated clock; each truck gets a stable offset up to this either way")
+		clockDrift         = fs.Float64("clock-drift-ppm", 0, "largest drift of a truck's clock in parts per million, either way")
+		gpsNoise           = fs.Float64("gps-noise", 0, "standard deviation in metres of the error added to reported truck positions")
+		gpsDropout         = fs.Float64("gps-dropout", 0, "probability that a truck update yields no new position fix")
+		maxRestarts        = fs.Int("max-restarts", 0, "restarts of the simulation loop from its last snapshot after an internal error (0 lets the error crash the process)")
+		restartBackoff     = fs.Duration("restart-backoff", time.Second, "wait before the first simulation restart; doubles for each further restart")
+		restartSnapshot    = fs.Duration("restart-snapshot-interval", 10*time.Second, "how often the simulation state is snapshotted for restarts")
+	)
+	_ = fs.Parse(args)
+	if *showVersion {
+		fmt.Println(version.Get())
+		return
+	}
+
+	interval := *updateInterval
+	if *tickRate != "" {
+		parsed, err := time.ParseDuration(*tickRate)
+		if err != nil {
+			slog.Error("failed to parse tick rate", "err", err)
+			os.Exit(1)
+		}
+		interval = parsed
+	}
+
+	redactor := redact.New(strings.Split(*redactHeaders, ","), strings.Split(*redactFields, ","))
+	logger := slog.New(redact.NewHandler(slog.Default().Handler(), redactor))
+
+	// Align GOMAXPROCS with container CPU quotas before sizing worker pools.
+	if _, err := maxprocs.Set(maxprocs.Logger(func(format string, args ...interface{}) {
+		logger.Info(fmt.Sprintf(format, args...))
+	})); err != nil {
+		logger.Warn("failed to set GOMAXPROCS from CPU quota", "err", err)
+	}
+
+	if err := gcTuning.Apply(*trucks, logger); err != nil {
+		logger.Error("failed to tune garbage collector", "err", err)
+		os.Exit(1)
+	}
+
+	simCfg := simulation.Config{NumTrucks: *trucks, UpdateInterval: interval, Workers: *workers, TimeScale: *timeScale}
+	simCfg.Seed = *seed
+	simCfg.RotateSeed = *rotateSeed
+	simCfg.MaintenanceEvery = *maintenanceEvery
+	simCfg.WaypointsPerRoute = *waypoints
+	simCfg.WaypointDwell = *waypointDwell
+	simCfg.DockCapacity = *dockCapacity
+	simCfg.MaxDriveTime = *maxDriveTime
+	simCfg.SuspendAfter = *suspendAfter
+	simCfg.TrackDepth = *trackDepth
+	simCfg.TankCapacity = *tankCapacity
+	simCfg.FuelPerKm = *fuelPerKm
+	simCfg.RefuelThreshold = *refuelThreshold
+	simCfg.ElectricShare = *electricShare
+	simCfg.SpeedLimit = *speedLimit
+	simCfg.Noise = simulation.NoiseModel{SigmaMeters: *gpsNoise, DropoutProbability: *gpsDropout}
+	simCfg.ClockSkew = simulation.ClockSkew{MaxOffset: *clockSkew, MaxDriftPPM: *clockDrift}
+	simCfg.GovernedShare = *governedShare
+	if *chargingStations != "" {
+		stations, err := parsePoints(*chargingStations)
+		if err != nil {
+			logger.Error("failed to parse charging stations", "err", err)
+			os.Exit(1)
+		}
+		simCfg.ChargingStations = stations
+	}
+	if *odMatrix != "" {
+		starts, ends, matrix, err := parseODMatrix(*odMatrix)
+		if err != nil {
+			logger.Error("failed to parse origin-destination matrix", "err", err)
+			os.Exit(1)
+		}
+		simCfg.StartPoints, simCfg.EndPoints, simCfg.ODMatrix = starts, ends, matrix
+	}
+	if *depots != "" {
+		parsed, err := parseDepots(*depots)
+		if err != nil {
+			logger.Error("failed to parse depots", "err", err)
+			os.Exit(1)
+		}
+		simCfg.Depots = parsed
+	}
+	if *regions != "" {
+		parsed, err := parseRegions(*regions)
+		if err != nil {
+			logger.Error("failed to parse regions", "err", err)
+			os.Exit(1)
+		}
+		simCfg.Regions = parsed
+	}
+	if *truckMix != "" {
+		mix, err := parseTruckMix(*truckMix)
+		if err != nil {
+			logger.Error("failed to parse truck mix", "err", err)
+			os.Exit(1)
+		}
+		simCfg.TypeMix = mix
+	}
+	if *dwell != "" {
+		durations, err := parseDwell(*dwell)
+		if err != nil {
+			logger.Error("failed to parse dwell durations", "err", err)
+			os.Exit(1)
+		}
+		simCfg.Dwell = durations
+	}
+	if *routeWaypointDwell != "" {
+		durations, err := parseRouteDwell(*routeWaypointDwell)
+		if err != nil {
+			logger.Error("failed to parse route waypoint dwell", "err", err)
+			os.Exit(1)
+		}
+		simCfg.RouteWaypointDwell = durations
+	}
+	if *startOffset != 0 {
+		simCfg.StartTime = time.Now().Add(*startOffset)
+	}
+	if *boundingBox != "" {
+		bounds, err := parseBoundingBoxes(*boundingBox)
+		if err != nil {
+			logger.Error("failed to parse bounding box", "err", err)
+			os.Exit(1)
+		}
+		simCfg.RouteBounds = bounds
+	}
+	sim := simulation.NewManager(simCfg)
+	if *osrmURL != "" && *roadNetwork != "" {
+		logger.Error("-osrm-url and -road-network cannot be combined")
+		os.Exit(1)
+	}
+	var plans *simulation.PlanQueue
+	if *osrmURL != "" {
+		plans = simulation.NewPlanQueue(osrm.New(*osrmURL, osrm.Options{QPS: *osrmQPS}), simulation.PlanQueueOptions{Workers: *osrmWorkers})
+		sim.WithPlanQueue(plans)
+		logger.Info("planning routes with OSRM", "url", *osrmURL, "qps", *osrmQPS, "workers", *osrmWorkers)
+	}
+	if *roadNetwork != "" {
+		graph, err := roadnetwork.LoadFile(*roadNetwork)
+		if err != nil {
+			logger.Error("failed to load road network", "path", *roadNetwork, "err", err)
+			os.Exit(1)
+		}
+		sim.WithRoutePlanner(roadnetwork.NewPlanner(graph))
+		logger.Info("planning routes on road network", "path", *roadNetwork, "nodes", graph.Nodes(), "edges", graph.Edges())
+	}
+	var replay *simulation.Recording
+	if *replayFile != "" {
+		if *stateFile != "" || *initFrom != "" {
+			logger.Error("-replay cannot be combined with -state-file or -init-from")
+			os.Exit(1)
+		}
+		rec, err := loadRecording(*replayFile)
+		if err != nil {
+			logger.Error("failed to load recording", "path", *replayFile, "err", err)
+			os.Exit(1)
+		}
+		replay = &rec
+	}
+	if *stateFile != "" {
+		loaded, err := loadFile(*stateFile, sim.LoadState)
+		if err != nil {
+			logger.Error("failed to load simulation state", "path", *stateFile, "err", err)
+			os.Exit(1)
+		}
+		if loaded {
+			logger.Info("resumed simulation state", "path", *stateFile, "run_id", sim.RunID())
+			*initFrom = ""
+		}
+	}
+	if *initFrom != "" {
+		if err := initFromFile(sim, *initFrom); err != nil {
+			logger.Error("failed to initialize from previous run", "path", *initFrom, "err", err)
+			os.Exit(1)
+		}
+		logger.Info("initialized fleet from previous run", "path", *initFrom, "simulated_time", sim.SimulatedTime())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if plans != nil {
+		go plans.Run(ctx)
+	}
+
+	countersCtx, stopCounters := context.WithCancel(context.Background())
+	defer stopCounters()
+	var countersDone chan struct{}
+	if *counterFile != "" {
+		store := simulation.FileCounterStore{Path: *counterFile}
+		values, err := store.LoadCounters()
+		if err != nil {
+			logger.Error("failed to load counters", "path", *counterFile, "err", err)
+			os.Exit(1)
+		}
+		simulation.RestoreCounters(values)
+		countersDone = make(chan struct{})
+		go func() {
+			defer close(countersDone)
+			simulation.PersistCounters(countersCtx, store, *counterInterval, func(err error) {
+				logger.Warn("failed to save counters", "path", *counterFile, "err", err)
+			})
+		}()
+	}
+
+	idGen, err := ids.New(*idFormat)
+	if err != nil {
+		logger.Error("invalid id format", "err", err)
+		os.Exit(1)
+	}
+
+	subscriptionProfiles, err := profiles.NewStore(*profileFile)
+	if err != nil {
+		logger.Error("failed to load subscription profiles", "path", *profileFile, "err", err)
+		os.Exit(1)
+	}
+
+	alerts := events.NewInbox(*maxAlerts)
+	bus := events.NewBus().WithIDGenerator(idGen).WithRetention(*eventRetention, 0).WithInbox(alerts)
+	sim.WithEventBus(bus)
+	if *maxRestarts > 0 {
+		sim.WithSupervision(simulation.SupervisionPolicy{
+			MaxRestarts:      *maxRestarts,
+			Backoff:          *restartBackoff,
+			SnapshotInterval: *restartSnapshot,
+			OnFailure: func(err error, restarting bool) {
+				logger.Error("simulation loop failed", "err", err, "restarting", restarting)
+			},
+		})
+	}
+
+	if replay != nil {
+		timing := simulation.ReplayTiming{Duration: *replayDuration, Speed: *replaySpeed, Loop: *replayLoop}
+		go func() {
+			logger.Info("playing recording", "path", *replayFile, "ticks", replay.Ticks, "duration", *replayDuration, "speed", *replaySpeed, "loop", *replayLoop)
+			if err := sim.Play(ctx, *replay, timing); err != nil {
+				logger.Error("failed to play recording", "path", *replayFile, "err", err)
+				cancel()
+				return
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			if err := sim.Start(ctx); err != nil {
+				logger.Error("failed to start simulation", "err", err)
+				cancel()
+				return
+			}
+			logger.Info("recording played out; continuing live", "simulated_time", sim.SimulatedTime())
+		}()
+	} else if err := sim.Start(ctx); err != nil {
+		logger.Error("failed to start simulation", "err", err)
+		os.Exit(1)
+	}
+
+	srv := server.NewServer(sim).
+		WithLogger(logger).
+		WithConcurrencyLimits(*maxConfigPosts, *maxSnapshotGets).
+		WithSnapshotEncoders(*snapshotEncoders).
+		WithIDGenerator(idGen).
+		WithEventBus(bus).
+		WithAlerts(alerts).
+		WithProfiles(subscriptionProfiles).
+		WithStreamLimit(*maxStreams).
+		WithLimits(server.Limits{MaxTrucks: *maxTrucks, MinUpdateInterval: *minUpdateInterval, MaxWaypoints: *maxWaypoints})
+	if *enableAdmin {
+		srv = srv.WithAdminEnabled()
+	}
+	if assets, ok := webui.Assets(); ok && *serveUI {
+		srv = srv.WithUI(assets)
+		logger.Info("serving embedded dashboard")
+	}
+	if *logHeaders {
+		srv = srv.WithRequestHeaderLogging()
+	}
+	if secret := os.Getenv("ORBIT_DEMO_TOKEN_SECRET"); secret != "" {
+		srv = srv.WithDemoTokens(demo.NewSigner([]byte(secret)), *requireDemoToken)
+	} else if *requireDemoToken {
+		logger.Error("require-demo-token needs ORBIT_DEMO_TOKEN_SECRET")
+		os.Exit(1)
+	}
+	if *oidcIssuer != "" {
+		verifier, err := newOIDCVerifier(*oidcIssuer, *oidcAudience, *oidcRoleClaim, *oidcRoles, *oidcDefaultRole)
+		if err != nil {
+			logger.Error("invalid OIDC settings", "err", err)
+			os.Exit(1)
+		}
+		if err := verifier.Discover(ctx); err != nil {
+			logger.Warn("OIDC discovery failed; retrying on the first request", "issuer", *oidcIssuer, "err", err)
+		}
+		srv = srv.WithAuthenticator(verifier)
+		logger.Info("authenticating API requests with OIDC", "issuer", *oidcIssuer, "audience", *oidcAudience)
+	}
+	if *clusterK > 0 {
+		clusterer := analytics.NewBehaviorClusterer(sim, analytics.BehaviorClusterOptions{
+			K:              *clusterK,
+			SampleInterval: *clusterInterval,
+			Window:         *clusterWindow,
+		})
+		go clusterer.Run(ctx)
+		srv = srv.WithBehaviorClusters(clusterer)
+	}
+
+	if *proximityDistance > 0 {
+		detector := analytics.NewProximityDetector(sim, analytics.ProximityOptions{Distance: *proximityDistance, CheckInterval: *proximityInterval}).
+			WithEventBus(bus)
+		go detector.Run(ctx)
+		srv = srv.WithProximityDetector(detector)
+	}
+	var positions *history.Store
+	if *historyRetention > 0 {
+		positions = history.NewStore(sim, history.Options{Retention: *historyRetention, SampleInterval: *historyInterval})
+		if *historyFile != "" {
+			loaded, err := loadFile(*historyFile, positions.Load)
+			if err != nil {
+				logger.Error("failed to load position history", "path", *historyFile, "err", err)
+				os.Exit(1)
+			}
+			if loaded {
+				logger.Info("restored position history", "path", *historyFile, "trucks", positions.Stats().Trucks)
+			}
+		}
+		go positions.Run(ctx)
+		srv = srv.WithPositionHistory(positions)
+	}
+
+	shutdownTelemetry := func(context.Context) error { return nil }
+	switch *metricsExporter {
+	case "prometheus":
+	case "otlp":
+		shutdown, err := telemetry.StartOTLP(ctx, prometheus.DefaultGatherer, telemetry.OTLPOptions{Endpoint: *otlpEndpoint, Interval: *otlpInterval})
+		if err != nil {
+			logger.Error("failed to start OTLP metrics exporter", "err", err)
+			os.Exit(1)
+		}
+		shutdownTelemetry = shutdown
+		logger.Info("pushing metrics over OTLP", "endpoint", *otlpEndpoint, "interval", *otlpInterval)
+	default:
+		logger.Error("unknown metrics exporter", "exporter", *metricsExporter)
+		os.Exit(1)
+	}
+
+	httpServer := &http.Server{Addr: *addr, Handler: srv.Routes()}
+
+	go func() {
+		logger.Info("starting server", "addr", *addr, "admin_enabled", *enableAdmin)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("server stopped unexpectedly", "err", err)
+			cancel()
+		}
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case <-signals:
+		logger.Info("shutting down server")
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+
+	srv.CloseStreams(shutdownCtx, *reconnectWindow)
+	_ = httpServer.Shutdown(shutdownCtx)
+	if *drainTimeout > 0 {
+		drainCtx, drainCancel := context.WithTimeout(context.Background(), *drainTimeout)
+		if err := sim.Drain(drainCtx); err != nil {
+			logger.Warn("trucks still moving after the drain timeout; stopping them mid-leg", "timeout", *drainTimeout)
+		} else {
+			logger.Info("drained the fleet")
+		}
+		drainCancel()
+	}
+	sim.Stop()
+	if positions != nil {
+		// Record where the trucks stopped.
+		positions.Record(sim.SimulatedTime(), sim.Trucks())
+	}
+	stopCounters()
+	if countersDone != nil {
+		<-countersDone
+	}
+	if *stateFile != "" {
+		if err := saveFile(*stateFile, sim.SaveState); err != nil {
+			logger.Error("failed to save simulation state", "path", *stateFile, "err", err)
+		}
+	}
+	if positions != nil && *historyFile != "" {
+		if err := saveFile(*historyFile, positions.Save); err != nil {
+			logger.Error("failed to save position history", "path", *historyFile, "err", err)
+		}
+	}
+	if err := shutdownTelemetry(shutdownCtx); err != nil {
+		logger.Warn("failed to flush OTLP metrics", "err", err)
+	}
+}
+
+func envString(key, fallback string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return fallback
+}
+
+func envInt(key string, fallback int) int {
+	if val := os.Getenv(key); val != "" {
+		parsed, err := strconv.Atoi(val)
+		if err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	if val := os.Getenv(key); val != "" {
+		parsed, err := time.ParseDuration(val)
+		if err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+// parseBoundingBoxes parses one or more bounding boxes separated by semicolons.
+func parseBoundingBoxes(value string) ([]simulation.BoundingBox, error) {
+	var bounds []simulation.BoundingBox
+	for _, entry := range strings.Split(value, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		bbox, err := parseBoundingBox(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", entry, err)
+		}
+		bounds = append(bounds, bbox)
+	}
+	return bounds, nil
+}
+
+func parseBoundingBox(value string) (simulation.BoundingBox, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 4 {
+		return simulation.BoundingBox{}, fmt.Errorf("expected 4 comma-separated values, got %d", len(parts))
+	}
+
+	toFloat := func(v string) (float64, error) {
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	}
+
+	minLat, err := toFloat(parts[0])
+	if err != nil {
+		return simulation.BoundingBox{}, errors.New("invalid min latitude")
+	}
+	minLon, err := toFloat(parts[1])
+	if err != nil {
+		return simulation.BoundingBox{}, errors.New("invalid min longitude")
+	}
+	maxLat, err := toFloat(parts[2])
+	if err != nil {
+		return simulation.BoundingBox{}, errors.New("invalid max latitude")
+	}
+	maxLon, err := toFloat(parts[3])
+	if err != nil {
+		return simulation.BoundingBox{}, errors.New("invalid max longitude")
+	}
+
+	return simulation.BoundingBox{MinLat: minLat, MinLon: minLon, MaxLat: maxLat, MaxLon: maxLon}, nil
+}
+
+func parseDwell(value string) (map[simulation.TruckStatus]time.Duration, error) {
+	durations := make(map[simulation.TruckStatus]time.Duration)
+	for _, entry := range strings.Split(value, ",") {
+		status, raw, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return nil, fmt.Errorf("expected status=duration, got %q", entry)
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration for %s: %w", status, err)
+		}
+		durations[simulation.TruckStatus(status)] = d
+	}
+	return durations, nil
+}
+
+// newOIDCVerifier builds the verifier from the -oidc flags. An audience is required so that tokens issued
+// for other applications are not accepted.
+func newOIDCVerifier(issuer, audience, roleClaim, roles, defaultRole string) (*auth.OIDCVerifier, error) {
+	if audience == "" {
+		return nil, fmt.Errorf("-oidc-audience is required with -oidc-issuer")
+	}
+	opts := auth.OIDCOptions{Issuer: issuer, Audience: audience, RoleClaim: roleClaim}
+	if roles != "" {
+		opts.RoleMap = make(map[string]auth.Role)
+		for _, entry := range strings.Split(roles, ",") {
+			value, name, ok := strings.Cut(strings.TrimSpace(entry), "=")
+			if !ok {
+				return nil, fmt.Errorf("expected value=role, got %q", entry)
+			}
+			role, err := auth.ParseRole(name)
+			if err != nil {
+				return nil, err
+			}
+			opts.RoleMap[value] = role
+		}
+	}
+	if defaultRole != "" {
+		role, err := auth.ParseRole(defaultRole)
+		if err != nil {
+			return nil, err
+		}
+		opts.DefaultRole = role
+	}
+	return auth.NewOIDCVerifier(opts), nil
+}
+
+func parseTruckMix(value string) (map[simulation.TruckType]int, error) {
+	mix := make(map[simulation.TruckType]int)
+	for _, entry := range strings.Split(value, ",") {
+		typ, raw, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return nil, fmt.Errorf("expected type=weight, got %q", entry)
+		}
+		weight, err := strconv.Atoi(raw)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight for %s", typ)
+		}
+		mix[simulation.TruckType(typ)] = weight
+	}
+	return mix, nil
+}
+
+// parseRouteDwell parses routeID=duration entries. Route IDs contain commas, so entries are separated by
+// semicolons and split on the last "=".
+func parseRouteDwell(value string) (map[string]time.Duration, error) {
+	durations := make(map[string]time.Duration)
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		i := strings.LastIndex(entry, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("expected routeID=duration, got %q", entry)
+		}
+		d, err := time.ParseDuration(entry[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration for %s: %w", entry[:i], err)
+		}
+		durations[entry[:i]] = d
+	}
+	return durations, nil
+}
+
+func parsePoints(value string) ([]simulation.Point, error) {
+	var points []simulation.Point
+	for _, entry := range strings.Split(value, ";") {
+		lat, lon, ok := strings.Cut(strings.TrimSpace(entry), ",")
+		if !ok {
+			return nil, fmt.Errorf("expected lat,lon, got %q", entry)
+		}
+		latVal, err := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid latitude in %q", entry)
+		}
+		lonVal, err := strconv.ParseFloat(strings.TrimSpace(lon), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid longitude in %q", entry)
+		}
+		points = append(points, simulation.Point{Lat: latVal, Lon: lonVal})
+	}
+	return points, nil
+}
+
+// parseODMatrix parses "origin>destination=weight" entries separated by semicolons, where both ends are
+// lat,lon. Each distinct origin becomes a start point and each distinct destination an end point.
+func parseODMatrix(value string) ([]simulation.Point, []simulation.Point, [][]float64, error) {
+	var starts, ends []simulation.Point
+	weights := make(map[[2]int]float64)
+	index := func(points *[]simulation.Point, p simulation.Point) int {
+		for i, existing := range *points {
+			if existing == p {
+				return i
+			}
+		}
+		*points = append(*points, p)
+		return len(*points) - 1
+	}
+	for _, entry := range strings.Split(value, ";") {
+		pair, weight, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		from, to, okPair := strings.Cut(pair, ">")
+		if !ok || !okPair {
+			return nil, nil, nil, fmt.Errorf("expected origin>destination=weight, got %q", entry)
+		}
+		origin, err := parsePoints(from)
+		if err != nil || len(origin) != 1 {
+			return nil, nil, nil, fmt.Errorf("invalid origin in %q", entry)
+		}
+		destination, err := parsePoints(to)
+		if err != nil || len(destination) != 1 {
+			return nil, nil, nil, fmt.Errorf("invalid destination in %q", entry)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(weight), 64)
+		if err != nil || w < 0 {
+			return nil, nil, nil, fmt.Errorf("invalid weight in %q", entry)
+		}
+		weights[[2]int{index(&starts, origin[0]), index(&ends, destination[0])}] += w
+	}
+
+	matrix := make([][]float64, len(starts))
+	for i := range matrix {
+		matrix[i] = make([]float64, len(ends))
+	}
+	for key, w := range weights {
+		matrix[key[0]][key[1]] = w
+	}
+	return starts, ends, matrix, nil
+}
+
+// parseDepots parses "name=lat,lon" entries separated by semicolons, each optionally followed by "/docks".
+func parseRegions(value string) ([]simulation.Region, error) {
+	var regions []simulation.Region
+	for _, entry := range strings.Split(value, ";") {
+		name, rest, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("expected name=minLat,minLon,maxLat,maxLon@interval, got %q", entry)
+		}
+		box, interval, ok := strings.Cut(rest, "@")
+		if !ok {
+			return nil, fmt.Errorf("missing @interval in %q", entry)
+		}
+		bounds, err := parseBoundingBox(box)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", entry, err)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(interval))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid interval in %q", entry)
+		}
+		regions = append(regions, simulation.Region{Name: strings.TrimSpace(name), Bounds: bounds, UpdateInterval: d})
+	}
+	return regions, nil
+}
+
+func parseDepots(value string) ([]simulation.Depot, error) {
+	var depots []simulation.Depot
+	for _, entry := range strings.Split(value, ";") {
+		name, location, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("expected name=lat,lon, got %q", entry)
+		}
+		depot := simulation.Depot{Name: strings.TrimSpace(name)}
+		location, docks, hasDocks := strings.Cut(location, "/")
+		if hasDocks {
+			capacity, err := strconv.Atoi(strings.TrimSpace(docks))
+			if err != nil || capacity < 0 {
+				return nil, fmt.Errorf("invalid dock count in %q", entry)
+			}
+			depot.Capacity = capacity
+		}
+		points, err := parsePoints(location)
+		if err != nil {
+			return nil, err
+		}
+		depot.Location = points[0]
+		depots = append(depots, depot)
+	}
+	return depots, nil
+}
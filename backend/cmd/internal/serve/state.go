@@ -0,0 +1,93 @@
+package serve
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+
+	"orbit/backend/simulation"
+	"orbit/backend/zstdfile"
+)
+
+// loadFile reads path, compressed or not, with load. It reports false when the file does not exist yet.
+func loadFile(path string, load func(io.Reader) error) (bool, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	r, _, err := zstdfile.NewReader(f)
+	if err != nil {
+		return true, err
+	}
+	defer r.Close()
+	return true, load(r)
+}
+
+// saveFile writes path with save, first next to it and then renamed into place, so a crash mid-write
+// never leaves a truncated file behind. A path ending in .zst is written compressed.
+func saveFile(path string, save func(io.Writer) error) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w, err := zstdfile.NewWriter(f, zstdfile.Compressed(path))
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if err := save(w); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// initFromFile seeds the fleet from a previous run. path is either a state file written by -state-file or
+// a recording from /admin/simulation/recording, which is replayed up to its last tick. Either may be
+// compressed.
+func initFromFile(sim *simulation.Manager, path string) error {
+	data, err := zstdfile.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var probe struct {
+		Runs json.RawMessage
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return err
+	}
+	if probe.Runs == nil {
+		return sim.LoadState(bytes.NewReader(data))
+	}
+	var rec simulation.Recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return err
+	}
+	return sim.ResumeFromRecording(rec)
+}
+
+// loadRecording reads a recording from /admin/simulation/recording, compressed or not.
+func loadRecording(path string) (simulation.Recording, error) {
+	var rec simulation.Recording
+	data, err := zstdfile.ReadFile(path)
+	if err != nil {
+		return rec, err
+	}
+	err = json.Unmarshal(data, &rec)
+	return rec, err
+}
@@ -0,0 +1,88 @@
+// Package tuning applies the garbage collector flags shared by orbit serve and orbit bench.
+package tuning
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// ballastBytesPerTruck approximates the live heap a truck contributes to a full snapshot cycle.
+const ballastBytesPerTruck = 4 << 10
+
+// heapBallast is kept reachable for the lifetime of the process so the GC paces against a larger heap.
+var heapBallast []byte
+
+// GC holds the -gc-percent, -memory-limit and -heap-ballast flags.
+type GC struct {
+	Percent     int
+	MemoryLimit string
+	Ballast     bool
+}
+
+// Flags registers the GC flags on fs.
+func Flags(fs *flag.FlagSet) *GC {
+	var t GC
+	fs.IntVar(&t.Percent, "gc-percent", 0, "GOGC-style garbage collection target percentage (0 keeps the runtime default)")
+	fs.StringVar(&t.MemoryLimit, "memory-limit", "", "GOMEMLIMIT-style soft memory limit such as 2GiB (empty keeps the runtime default)")
+	fs.BoolVar(&t.Ballast, "heap-ballast", false, "allocate a heap ballast sized for the configured fleet to reduce GC frequency")
+	return &t
+}
+
+// Apply configures the garbage collector. A zero Percent or empty MemoryLimit keeps the runtime defaults,
+// which still honour GOGC and GOMEMLIMIT from the environment.
+func (t GC) Apply(numTrucks int, logger *slog.Logger) error {
+	if t.Percent != 0 {
+		previous := debug.SetGCPercent(t.Percent)
+		logger.Info("configured GC percent", "gc_percent", t.Percent, "previous", previous)
+	}
+	if t.MemoryLimit != "" {
+		limit, err := parseByteSize(t.MemoryLimit)
+		if err != nil {
+			return fmt.Errorf("invalid memory limit: %w", err)
+		}
+		debug.SetMemoryLimit(limit)
+		logger.Info("configured memory limit", "bytes", limit)
+	}
+	if t.Ballast && numTrucks > 0 {
+		heapBallast = make([]byte, numTrucks*ballastBytesPerTruck)
+		logger.Info("allocated heap ballast", "bytes", len(heapBallast))
+	}
+	return nil
+}
+
+// parseByteSize parses sizes like 512MiB, 2GiB, or a plain byte count.
+func parseByteSize(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	units := []struct {
+		suffix string
+		factor int64
+	}{
+		{"TiB", 1 << 40},
+		{"GiB", 1 << 30},
+		{"MiB", 1 << 20},
+		{"KiB", 1 << 10},
+		{"B", 1},
+	}
+
+	factor := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(value, u.suffix) {
+			factor = u.factor
+			value = strings.TrimSuffix(value, u.suffix)
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("size must be positive")
+	}
+	return n * factor, nil
+}
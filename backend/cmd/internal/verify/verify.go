@@ -0,0 +1,179 @@
+// Package verify runs two simulations with identical configuration in step mode and reports the first tick
+// at which their snapshots diverge. With -replay it replays a recording downloaded from
+// /admin/simulation/recording instead.
+package verify
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	"orbit/backend/simulation"
+	"orbit/backend/zstdfile"
+)
+
+// Main parses the verify flags from args and runs the check, exiting non-zero on a divergence.
+func Main(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	var (
+		trucks    = fs.Int("trucks", 200, "number of trucks to simulate")
+		seed      = fs.Int64("seed", 42, "simulation seed")
+		ticks     = fs.Int("ticks", 1000, "number of ticks to compare")
+		interval  = fs.Duration("update-interval", time.Second, "simulated time per tick")
+		waypoints = fs.Int("waypoints", 5, "waypoints per route")
+		loop      = fs.Bool("loop", false, "loop routes instead of shuffling waypoints")
+		startTime = fs.String("start-time", "2024-01-01T00:00:00Z", "simulated clock start (RFC 3339)")
+		replay    = fs.String("replay", "", "recording file to replay for its recorded number of ticks")
+	)
+	_ = fs.Parse(args)
+
+	if *replay != "" {
+		rec, err := loadRecording(*replay)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid recording: %v\n", err)
+			os.Exit(2)
+		}
+		newManager := func() (*simulation.Manager, error) {
+			m := simulation.NewManager(simulation.Config{})
+			return m, m.Replay(rec)
+		}
+		divergence, err := verify(newManager, int(rec.Ticks))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "verification failed: %v\n", err)
+			os.Exit(2)
+		}
+		if divergence != nil {
+			fmt.Println(divergence)
+			os.Exit(1)
+		}
+		fmt.Printf("ok: %d runs replayed identically across %d ticks\n", len(rec.Runs), rec.Ticks)
+		return
+	}
+
+	start, err := time.Parse(time.RFC3339, *startTime)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid start time: %v\n", err)
+		os.Exit(2)
+	}
+
+	cfg := simulation.Config{
+		NumTrucks:         *trucks,
+		Seed:              *seed,
+		UpdateInterval:    *interval,
+		WaypointsPerRoute: *waypoints,
+		LoopRoutes:        *loop,
+		StartTime:         start,
+	}
+
+	newManager := func() (*simulation.Manager, error) { return simulation.NewManager(cfg), nil }
+	divergence, err := verify(newManager, *ticks)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "verification failed: %v\n", err)
+		os.Exit(2)
+	}
+	if divergence != nil {
+		fmt.Println(divergence)
+		os.Exit(1)
+	}
+	fmt.Printf("ok: %d trucks identical across %d ticks\n", cfg.NumTrucks, *ticks)
+}
+
+type divergence struct {
+	tick     int
+	truckID  string
+	previous *simulation.Truck
+	left     *simulation.Truck
+	right    *simulation.Truck
+}
+
+func (d *divergence) String() string {
+	msg := fmt.Sprintf("divergence at tick %d, truck %q\n", d.tick, d.truckID)
+	if d.previous != nil {
+		msg += fmt.Sprintf("  previous: %+v\n", *d.previous)
+	}
+	msg += fmt.Sprintf("  run A:    %s\n", describe(d.left))
+	msg += fmt.Sprintf("  run B:    %s", describe(d.right))
+	return msg
+}
+
+func describe(t *simulation.Truck) string {
+	if t == nil {
+		return "<missing>"
+	}
+	return fmt.Sprintf("%+v", *t)
+}
+
+func loadRecording(path string) (simulation.Recording, error) {
+	var rec simulation.Recording
+	data, err := zstdfile.ReadFile(path)
+	if err != nil {
+		return rec, err
+	}
+	err = json.Unmarshal(data, &rec)
+	return rec, err
+}
+
+// verify steps two simulations in lockstep and compares the snapshot after every tick.
+func verify(newManager func() (*simulation.Manager, error), ticks int) (*divergence, error) {
+	a, err := newManager()
+	if err != nil {
+		return nil, err
+	}
+	b, err := newManager()
+	if err != nil {
+		return nil, err
+	}
+
+	var previous []simulation.Truck
+	for tick := 1; tick <= ticks; tick++ {
+		if err := a.StepOnce(1); err != nil {
+			return nil, err
+		}
+		if err := b.StepOnce(1); err != nil {
+			return nil, err
+		}
+
+		left := a.Trucks()
+		right := b.Trucks()
+		if d := compare(tick, previous, left, right); d != nil {
+			return d, nil
+		}
+		previous = left
+	}
+	return nil, nil
+}
+
+func compare(tick int, previous, left, right []simulation.Truck) *divergence {
+	n := len(left)
+	if len(right) > n {
+		n = len(right)
+	}
+
+	for i := 0; i < n; i++ {
+		var l, r *simulation.Truck
+		if i < len(left) {
+			l = &left[i]
+		}
+		if i < len(right) {
+			r = &right[i]
+		}
+		if l != nil && r != nil && reflect.DeepEqual(*l, *r) {
+			continue
+		}
+
+		d := &divergence{tick: tick, left: l, right: r}
+		if l != nil {
+			d.truckID = l.ID
+		} else {
+			d.truckID = r.ID
+		}
+		if i < len(previous) {
+			d.previous = &previous[i]
+		}
+		return d
+	}
+	return nil
+}
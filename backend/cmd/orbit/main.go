@@ -0,0 +1,64 @@
+// Command orbit is the single Orbit binary. Its subcommands are:
+//
+//	orbit serve [flags]    run the simulation and HTTP API (same as orbitserver)
+//	orbit verify [flags]   check that two identically configured runs do not diverge (same as orbitverify)
+//	orbit ctl <command>    inspect and convert recordings and state files (same as orbitctl)
+//	orbit load [flags]     drive a steady request rate at a running server (like scripts/loadtest.sh)
+//	orbit bench [flags]    measure fleet snapshot cost and GC pressure for given fleet sizes
+//	orbit version          print the build version
+//
+// Run `orbit <command> -h` for the flags of a subcommand.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"orbit/backend/cmd/internal/bench"
+	"orbit/backend/cmd/internal/ctl"
+	"orbit/backend/cmd/internal/load"
+	"orbit/backend/cmd/internal/serve"
+	"orbit/backend/cmd/internal/verify"
+	"orbit/backend/version"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	args := os.Args[2:]
+	switch os.Args[1] {
+	case "serve":
+		serve.Main(args)
+	case "verify":
+		verify.Main(args)
+	case "ctl":
+		ctl.Main(args)
+	case "load":
+		load.Main(args)
+	case "bench":
+		bench.Main(args)
+	case "version", "-version", "--version":
+		fmt.Println(version.Get())
+	case "help", "-h", "-help", "--help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "orbit: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: orbit <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	fmt.Fprintln(os.Stderr, "  serve     run the simulation and HTTP API")
+	fmt.Fprintln(os.Stderr, "  verify    check that two identically configured runs do not diverge")
+	fmt.Fprintln(os.Stderr, "  ctl       inspect and convert recordings and state files")
+	fmt.Fprintln(os.Stderr, "  load      drive a steady request rate at a running server")
+	fmt.Fprintln(os.Stderr, "  bench     measure fleet snapshot cost and GC pressure")
+	fmt.Fprintln(os.Stderr, "  version   print the build version")
+}
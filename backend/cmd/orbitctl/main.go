@@ -0,0 +1,13 @@
+// Command orbitctl inspects recordings and state files and converts them to and from Zstandard
+// compression. It is the same as `orbit ctl`.
+package main
+
+import (
+	"os"
+
+	"orbit/backend/cmd/internal/ctl"
+)
+
+func main() {
+	ctl.Main(os.Args[1:])
+}
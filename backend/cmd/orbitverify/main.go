@@ -0,0 +1,13 @@
+// Command orbitverify runs two simulations with identical configuration in step mode and reports the
+// first tick at which their snapshots diverge. It is the same as `orbit verify`.
+package main
+
+import (
+	"os"
+
+	"orbit/backend/cmd/internal/verify"
+)
+
+func main() {
+	verify.Main(os.Args[1:])
+}
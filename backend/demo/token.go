@@ -0,0 +1,110 @@
+// Package demo issues and verifies signed, time-limited demo tokens. A token grants read-only access to
+// the trucks inside a bounding box or to a fixed set of trucks, so public demo links can be handed out
+// without standing up full authentication.
+package demo
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"orbit/backend/simulation"
+)
+
+var (
+	// ErrInvalidToken is returned for malformed tokens and tokens with a bad signature.
+	ErrInvalidToken = errors.New("invalid demo token")
+	// ErrExpiredToken is returned for correctly signed tokens past their expiry.
+	ErrExpiredToken = errors.New("demo token expired")
+)
+
+// Grant is the access carried by a token. An empty BoundingBox and TruckIDs grants the whole fleet.
+type Grant struct {
+	ExpiresAt   time.Time               `json:"exp"`
+	BoundingBox *simulation.BoundingBox `json:"bbox,omitempty"`
+	TruckIDs    []string                `json:"trucks,omitempty"`
+}
+
+// Allows reports whether the grant covers the truck.
+func (g Grant) Allows(truck simulation.Truck) bool {
+	if g.BoundingBox != nil {
+		b := g.BoundingBox
+		if truck.Lat < b.MinLat || truck.Lat > b.MaxLat || truck.Lon < b.MinLon || truck.Lon > b.MaxLon {
+			return false
+		}
+	}
+	if len(g.TruckIDs) > 0 {
+		for _, id := range g.TruckIDs {
+			if id == truck.ID {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Filter returns the trucks the grant covers, reusing the backing array of trucks.
+func (g Grant) Filter(trucks []simulation.Truck) []simulation.Truck {
+	filtered := trucks[:0]
+	for _, truck := range trucks {
+		if g.Allows(truck) {
+			filtered = append(filtered, truck)
+		}
+	}
+	return filtered
+}
+
+// Signer signs and verifies tokens with an HMAC-SHA256 shared secret.
+type Signer struct {
+	secret []byte
+}
+
+// NewSigner creates a Signer for the given secret.
+func NewSigner(secret []byte) *Signer {
+	return &Signer{secret: append([]byte(nil), secret...)}
+}
+
+// Sign encodes the grant as "<payload>.<signature>", both base64url without padding.
+func (s *Signer) Sign(g Grant) (string, error) {
+	payload, err := json.Marshal(g)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.mac(encoded)), nil
+}
+
+// Verify checks the token signature and expiry against now and returns its grant.
+func (s *Signer) Verify(token string, now time.Time) (Grant, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return Grant{}, ErrInvalidToken
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, s.mac(encoded)) {
+		return Grant{}, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Grant{}, ErrInvalidToken
+	}
+	var g Grant
+	if err := json.Unmarshal(payload, &g); err != nil {
+		return Grant{}, ErrInvalidToken
+	}
+	if !now.Before(g.ExpiresAt) {
+		return Grant{}, ErrExpiredToken
+	}
+	return g, nil
+}
+
+func (s *Signer) mac(encoded string) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(encoded))
+	return h.Sum(nil)
+}
@@ -0,0 +1,59 @@
+package demo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"orbit/backend/simulation"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	grant := Grant{
+		ExpiresAt:   now.Add(time.Hour),
+		BoundingBox: &simulation.BoundingBox{MinLat: 0, MaxLat: 1, MinLon: 0, MaxLon: 1},
+	}
+
+	token, err := signer.Sign(grant)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	got, err := signer.Verify(token, now)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if !got.ExpiresAt.Equal(grant.ExpiresAt) || *got.BoundingBox != *grant.BoundingBox {
+		t.Fatalf("unexpected grant %+v", got)
+	}
+
+	if _, err := signer.Verify(token, now.Add(time.Hour)); !errors.Is(err, ErrExpiredToken) {
+		t.Fatalf("expected expired token, got %v", err)
+	}
+	if _, err := NewSigner([]byte("other")).Verify(token, now); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected invalid signature, got %v", err)
+	}
+	if _, err := signer.Verify("garbage", now); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected invalid token, got %v", err)
+	}
+}
+
+func TestGrantFilter(t *testing.T) {
+	trucks := []simulation.Truck{
+		{ID: "a", Lat: 0.5, Lon: 0.5},
+		{ID: "b", Lat: 2, Lon: 2},
+		{ID: "c", Lat: 0.2, Lon: 0.2},
+	}
+
+	inBox := Grant{BoundingBox: &simulation.BoundingBox{MinLat: 0, MaxLat: 1, MinLon: 0, MaxLon: 1}}
+	if got := inBox.Filter(append([]simulation.Truck(nil), trucks...)); len(got) != 2 || got[0].ID != "a" || got[1].ID != "c" {
+		t.Fatalf("unexpected bbox filter %+v", got)
+	}
+
+	fleet := Grant{TruckIDs: []string{"b"}}
+	if got := fleet.Filter(append([]simulation.Truck(nil), trucks...)); len(got) != 1 || got[0].ID != "b" {
+		t.Fatalf("unexpected fleet filter %+v", got)
+	}
+}
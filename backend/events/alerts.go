@@ -0,0 +1,190 @@
+package events
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Severity ranks how much attention an event needs. Events at SeverityWarning or above become alerts.
+type Severity string
+
+// Event severities, from least to most severe.
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// TypeAlertUpdated is published when an operator acknowledges or resolves an alert, with an AlertUpdated
+// payload.
+const TypeAlertUpdated = "alertUpdated"
+
+// AlertUpdated describes an alert moving to a new state.
+type AlertUpdated struct {
+	AlertID string     `json:"alertId"`
+	State   AlertState `json:"state"`
+	By      string     `json:"by,omitempty"`
+}
+
+var severityRank = map[Severity]int{SeverityInfo: 0, SeverityWarning: 1, SeverityCritical: 2}
+
+// ParseSeverity returns the severity named s.
+func ParseSeverity(s string) (Severity, error) {
+	if _, ok := severityRank[Severity(s)]; !ok {
+		return "", errors.New("severity must be info, warning, or critical")
+	}
+	return Severity(s), nil
+}
+
+// AtLeast reports whether s is as severe as min.
+func (s Severity) AtLeast(min Severity) bool {
+	return severityRank[s] >= severityRank[min]
+}
+
+// SeverityOf returns the default severity of an event: two trucks closing in on each other is critical, a
+// truck going into maintenance is a warning, and everything else is informational.
+func SeverityOf(typ string, payload any) Severity {
+	switch typ {
+	case TypeProximity:
+		return SeverityCritical
+	case TypeStatusChanged:
+		if p, ok := payload.(StatusChanged); ok && p.To == "maintenance" {
+			return SeverityWarning
+		}
+	}
+	return SeverityInfo
+}
+
+// AlertState is where an alert is in the operator workflow: open, then acknowledged, then resolved. An
+// open alert can also be resolved directly.
+type AlertState string
+
+// Alert states.
+const (
+	AlertOpen         AlertState = "open"
+	AlertAcknowledged AlertState = "acknowledged"
+	AlertResolved     AlertState = "resolved"
+)
+
+// ErrAlertNotFound and ErrAlertTransition are returned by Inbox.Acknowledge and Inbox.Resolve.
+var (
+	ErrAlertNotFound   = errors.New("alert not found")
+	ErrAlertTransition = errors.New("alert is already past that state")
+)
+
+// Alert is an event that needs an operator, with its acknowledgement state. ID is the event ID.
+type Alert struct {
+	ID             string     `json:"id"`
+	Type           string     `json:"type"`
+	Severity       Severity   `json:"severity"`
+	State          AlertState `json:"state"`
+	Time           time.Time  `json:"time"`
+	RunID          string     `json:"runId,omitempty"`
+	Payload        any        `json:"payload"`
+	AcknowledgedAt *time.Time `json:"acknowledgedAt,omitempty"`
+	AcknowledgedBy string     `json:"acknowledgedBy,omitempty"`
+	ResolvedAt     *time.Time `json:"resolvedAt,omitempty"`
+	ResolvedBy     string     `json:"resolvedBy,omitempty"`
+}
+
+const defaultMaxAlerts = 1000
+
+// Inbox keeps the alerts raised on a bus and tracks their acknowledgement. Attach it with Bus.WithInbox.
+type Inbox struct {
+	mu     sync.RWMutex
+	alerts []*Alert
+	byID   map[string]*Alert
+	max    int
+	bus    *Bus
+}
+
+// NewInbox creates an inbox holding up to max alerts (1000 when max is not positive). Once full, the oldest
+// resolved alert makes room, or the oldest alert when none is resolved.
+func NewInbox(max int) *Inbox {
+	if max <= 0 {
+		max = defaultMaxAlerts
+	}
+	return &Inbox{byID: make(map[string]*Alert), max: max}
+}
+
+// WithInbox records every event published at SeverityWarning or above as an open alert in inbox.
+func (b *Bus) WithInbox(inbox *Inbox) *Bus {
+	b.inbox = inbox
+	inbox.bus = b
+	return b
+}
+
+func (in *Inbox) record(evt Event) {
+	if !evt.Severity.AtLeast(SeverityWarning) {
+		return
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if len(in.alerts) >= in.max {
+		drop := 0
+		for i, alert := range in.alerts {
+			if alert.State == AlertResolved {
+				drop = i
+				break
+			}
+		}
+		delete(in.byID, in.alerts[drop].ID)
+		in.alerts = append(in.alerts[:drop], in.alerts[drop+1:]...)
+	}
+	alert := &Alert{ID: evt.ID, Type: evt.Type, Severity: evt.Severity, State: AlertOpen, Time: evt.Time, RunID: evt.RunID, Payload: evt.Payload}
+	in.alerts = append(in.alerts, alert)
+	in.byID[alert.ID] = alert
+}
+
+// Alerts returns alerts at min severity or above, newest first. A non-empty state limits them to that
+// state.
+func (in *Inbox) Alerts(state AlertState, min Severity) []Alert {
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	alerts := []Alert{}
+	for i := len(in.alerts) - 1; i >= 0; i-- {
+		alert := in.alerts[i]
+		if (state == "" || alert.State == state) && alert.Severity.AtLeast(min) {
+			alerts = append(alerts, *alert)
+		}
+	}
+	return alerts
+}
+
+// Acknowledge marks an open alert as being handled by an operator.
+func (in *Inbox) Acknowledge(id, by string) (Alert, error) {
+	return in.transition(id, by, AlertAcknowledged)
+}
+
+// Resolve closes an open or acknowledged alert.
+func (in *Inbox) Resolve(id, by string) (Alert, error) {
+	return in.transition(id, by, AlertResolved)
+}
+
+func (in *Inbox) transition(id, by string, to AlertState) (Alert, error) {
+	in.mu.Lock()
+	alert, ok := in.byID[id]
+	if !ok {
+		in.mu.Unlock()
+		return Alert{}, ErrAlertNotFound
+	}
+	if alert.State == AlertResolved || alert.State == to {
+		in.mu.Unlock()
+		return Alert{}, ErrAlertTransition
+	}
+	now := time.Now()
+	alert.State = to
+	if to == AlertAcknowledged {
+		alert.AcknowledgedAt, alert.AcknowledgedBy = &now, by
+	} else {
+		alert.ResolvedAt, alert.ResolvedBy = &now, by
+	}
+	updated := *alert
+	in.mu.Unlock()
+
+	if in.bus != nil {
+		in.bus.Publish(Event{Type: TypeAlertUpdated, Time: now, RunID: updated.RunID, Payload: AlertUpdated{AlertID: id, State: to, By: by}})
+	}
+	return updated, nil
+}
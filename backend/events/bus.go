@@ -0,0 +1,277 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"orbit/backend/ids"
+)
+
+// OverflowPolicy determines what happens when a subscriber queue is full.
+type OverflowPolicy string
+
+const (
+	// OverflowDropNewest discards the event being published.
+	OverflowDropNewest OverflowPolicy = "drop-newest"
+	// OverflowDropOldest discards the oldest queued event to make room.
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowBlock waits for queue space, stalling the publisher.
+	OverflowBlock OverflowPolicy = "block"
+)
+
+const defaultQueueSize = 256
+
+// Event is a single notification published on the bus.
+type Event struct {
+	ID      string
+	Type    string
+	Time    time.Time
+	Payload any
+	// RunID is the simulation run the event belongs to.
+	RunID string
+	// Severity defaults to SeverityOf the event when published.
+	Severity Severity
+}
+
+// SubscribeOptions configures the queue backing a subscription.
+type SubscribeOptions struct {
+	QueueSize int
+	Overflow  OverflowPolicy
+	// Types limits the subscription to events of these types. Empty means every type.
+	Types []string
+	// ReplaySince first delivers retained events published within this window, so a subscriber attached
+	// mid-run can backfill. Only the most recent QueueSize of them are replayed. It needs WithRetention.
+	ReplaySince time.Duration
+}
+
+// Subscription receives events from the bus until closed.
+type Subscription struct {
+	name   string
+	policy OverflowPolicy
+	types  map[string]struct{}
+	ch     chan Event
+	done   chan struct{}
+	bus    *Bus
+
+	mu        sync.Mutex
+	closeOnce sync.Once
+}
+
+// Bus fans events out to subscribers, each with its own bounded queue.
+type Bus struct {
+	mu    sync.RWMutex
+	subs  map[*Subscription]struct{}
+	idGen ids.Generator
+	runID func() string
+	inbox *Inbox
+
+	historyMu  sync.Mutex
+	history    []Event
+	retention  time.Duration
+	maxHistory int
+}
+
+const defaultMaxHistory = 10000
+
+// NewBus creates an empty event bus that assigns ULIDs to events.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*Subscription]struct{}), idGen: ids.NewULIDGenerator()}
+}
+
+// WithIDGenerator replaces the generator used for event IDs.
+func (b *Bus) WithIDGenerator(gen ids.Generator) *Bus {
+	if gen != nil {
+		b.idGen = gen
+	}
+	return b
+}
+
+// WithRunID stamps published events that carry no RunID with the value returned by runID.
+func (b *Bus) WithRunID(runID func() string) *Bus {
+	b.runID = runID
+	return b
+}
+
+// WithRetention keeps published events for window, up to max events (10000 when max is not positive), so
+// new subscribers can replay them.
+func (b *Bus) WithRetention(window time.Duration, max int) *Bus {
+	if max <= 0 {
+		max = defaultMaxHistory
+	}
+	b.historyMu.Lock()
+	b.retention, b.maxHistory = window, max
+	b.historyMu.Unlock()
+	return b
+}
+
+// Subscribe registers a named subscriber. Unknown overflow policies fall back to drop-newest so that a
+// misconfigured subscriber can never stall publishers.
+func (b *Bus) Subscribe(name string, opts SubscribeOptions) *Subscription {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaultQueueSize
+	}
+	switch opts.Overflow {
+	case OverflowDropOldest, OverflowBlock:
+	default:
+		opts.Overflow = OverflowDropNewest
+	}
+
+	sub := &Subscription{
+		name:   name,
+		policy: opts.Overflow,
+		ch:     make(chan Event, opts.QueueSize),
+		done:   make(chan struct{}),
+		bus:    b,
+	}
+	if len(opts.Types) > 0 {
+		sub.types = make(map[string]struct{}, len(opts.Types))
+		for _, typ := range opts.Types {
+			sub.types[typ] = struct{}{}
+		}
+	}
+
+	// Registering under the write lock means no publish is in flight, so replayed and live events neither
+	// overlap nor leave a gap.
+	b.mu.Lock()
+	if opts.ReplaySince > 0 {
+		for _, evt := range b.retained(time.Now().Add(-opts.ReplaySince), sub.wants, opts.QueueSize) {
+			sub.offer(evt)
+		}
+	}
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	return sub
+}
+
+// retained returns up to limit of the most recent retained events published after since that match,
+// oldest first.
+func (b *Bus) retained(since time.Time, match func(Event) bool, limit int) []Event {
+	b.historyMu.Lock()
+	defer b.historyMu.Unlock()
+	var matched []Event
+	for i := len(b.history) - 1; i >= 0 && len(matched) < limit && b.history[i].Time.After(since); i-- {
+		if match(b.history[i]) {
+			matched = append(matched, b.history[i])
+		}
+	}
+	for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+		matched[i], matched[j] = matched[j], matched[i]
+	}
+	return matched
+}
+
+// retain records evt and drops events that have fallen out of the retention window or over the cap.
+func (b *Bus) retain(evt Event) {
+	b.historyMu.Lock()
+	defer b.historyMu.Unlock()
+	if b.retention <= 0 {
+		return
+	}
+	b.history = append(b.history, evt)
+	drop := 0
+	if over := len(b.history) - b.maxHistory; over > 0 {
+		drop = over
+	}
+	cutoff := evt.Time.Add(-b.retention)
+	for drop < len(b.history) && !b.history[drop].Time.After(cutoff) {
+		drop++
+	}
+	b.history = b.history[drop:]
+}
+
+// Publish delivers the event to every subscriber according to its overflow policy.
+func (b *Bus) Publish(evt Event) {
+	if evt.ID == "" {
+		evt.ID = b.idGen.NewID()
+	}
+	if evt.Time.IsZero() {
+		evt.Time = time.Now()
+	}
+	if evt.RunID == "" && b.runID != nil {
+		evt.RunID = b.runID()
+	}
+	if evt.Severity == "" {
+		evt.Severity = SeverityOf(evt.Type, evt.Payload)
+	}
+	if b.inbox != nil {
+		b.inbox.record(evt)
+	}
+	publishedEvents.WithLabelValues(evt.Type).Inc()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	b.retain(evt)
+	for sub := range b.subs {
+		if sub.wants(evt) {
+			sub.deliver(evt)
+		}
+	}
+}
+
+// C returns the channel events are delivered on. It is closed when the subscription is closed.
+func (s *Subscription) C() <-chan Event {
+	return s.ch
+}
+
+// Close unregisters the subscription and releases any publisher blocked on it.
+func (s *Subscription) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		s.bus.mu.Unlock()
+		close(s.ch)
+		queueDepth.DeleteLabelValues(s.name)
+	})
+}
+
+func (s *Subscription) wants(evt Event) bool {
+	if s.types == nil {
+		return true
+	}
+	_, ok := s.types[evt.Type]
+	return ok
+}
+
+// offer queues evt without blocking, counting it as dropped when the queue is full.
+func (s *Subscription) offer(evt Event) {
+	select {
+	case s.ch <- evt:
+	default:
+		droppedEvents.WithLabelValues(s.name, string(s.policy)).Inc()
+	}
+	queueDepth.WithLabelValues(s.name).Set(float64(len(s.ch)))
+}
+
+func (s *Subscription) deliver(evt Event) {
+	defer func() { queueDepth.WithLabelValues(s.name).Set(float64(len(s.ch))) }()
+
+	select {
+	case s.ch <- evt:
+		return
+	default:
+	}
+
+	switch s.policy {
+	case OverflowBlock:
+		select {
+		case s.ch <- evt:
+		case <-s.done:
+		}
+	case OverflowDropOldest:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		select {
+		case <-s.ch:
+			droppedEvents.WithLabelValues(s.name, string(s.policy)).Inc()
+		default:
+		}
+		select {
+		case s.ch <- evt:
+		default:
+			droppedEvents.WithLabelValues(s.name, string(s.policy)).Inc()
+		}
+	default:
+		droppedEvents.WithLabelValues(s.name, string(s.policy)).Inc()
+	}
+}
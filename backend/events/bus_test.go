@@ -0,0 +1,188 @@
+package events
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"orbit/backend/ids"
+)
+
+func drain(sub *Subscription) []string {
+	var types []string
+	for {
+		select {
+		case evt := <-sub.C():
+			types = append(types, evt.Type)
+		default:
+			return types
+		}
+	}
+}
+
+func TestDropNewestKeepsEarliestEvents(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe("newest", SubscribeOptions{QueueSize: 2, Overflow: OverflowDropNewest})
+	defer sub.Close()
+
+	for _, typ := range []string{"a", "b", "c"} {
+		bus.Publish(Event{Type: typ})
+	}
+
+	got := drain(sub)
+	if len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Fatalf("unexpected events: %v", got)
+	}
+}
+
+func TestDropOldestKeepsLatestEvents(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe("oldest", SubscribeOptions{QueueSize: 2, Overflow: OverflowDropOldest})
+	defer sub.Close()
+
+	for _, typ := range []string{"a", "b", "c"} {
+		bus.Publish(Event{Type: typ})
+	}
+
+	got := drain(sub)
+	if len(got) != 2 || got[0] != "b" || got[1] != "c" {
+		t.Fatalf("unexpected events: %v", got)
+	}
+}
+
+func TestBlockingSubscriberReleasedOnClose(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe("block", SubscribeOptions{QueueSize: 1, Overflow: OverflowBlock})
+
+	bus.Publish(Event{Type: "a"})
+
+	published := make(chan struct{})
+	go func() {
+		bus.Publish(Event{Type: "b"})
+		close(published)
+	}()
+
+	select {
+	case <-published:
+		t.Fatalf("expected publish to block on a full queue")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	sub.Close()
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatalf("expected blocked publisher to be released on close")
+	}
+}
+
+func TestSlowSubscriberDoesNotStallOthers(t *testing.T) {
+	bus := NewBus()
+	slow := bus.Subscribe("slow", SubscribeOptions{QueueSize: 1})
+	defer slow.Close()
+	fast := bus.Subscribe("fast", SubscribeOptions{QueueSize: 10})
+	defer fast.Close()
+
+	for i := 0; i < 5; i++ {
+		bus.Publish(Event{Type: "tick"})
+	}
+
+	if got := len(drain(fast)); got != 5 {
+		t.Fatalf("expected fast subscriber to receive all events, got %d", got)
+	}
+}
+
+func TestPublishAssignsIDs(t *testing.T) {
+	bus := NewBus().WithIDGenerator(ids.NewSequenceGenerator("evt-"))
+	sub := bus.Subscribe("ids", SubscribeOptions{QueueSize: 2})
+	defer sub.Close()
+
+	bus.Publish(Event{Type: "a"})
+	bus.Publish(Event{Type: "b"})
+
+	first, second := <-sub.C(), <-sub.C()
+	if first.ID == "" || first.ID >= second.ID {
+		t.Fatalf("expected increasing event ids, got %q then %q", first.ID, second.ID)
+	}
+}
+
+func TestPublishStampsRunID(t *testing.T) {
+	bus := NewBus().WithRunID(func() string { return "run-1" })
+	sub := bus.Subscribe("runs", SubscribeOptions{QueueSize: 2})
+	defer sub.Close()
+
+	bus.Publish(Event{Type: "a"})
+	bus.Publish(Event{Type: "b", RunID: "run-0"})
+
+	first, second := <-sub.C(), <-sub.C()
+	if first.RunID != "run-1" || second.RunID != "run-0" {
+		t.Fatalf("unexpected run ids %q and %q", first.RunID, second.RunID)
+	}
+}
+
+func TestLateSubscriberReplaysRetainedEvents(t *testing.T) {
+	bus := NewBus().WithRetention(time.Hour, 0)
+	now := time.Now()
+	bus.Publish(Event{Type: "expired", Time: now.Add(-2 * time.Hour)})
+	bus.Publish(Event{Type: "old", Time: now.Add(-30 * time.Minute)})
+	bus.Publish(Event{Type: "a", Time: now.Add(-2 * time.Minute)})
+	bus.Publish(Event{Type: "b", Time: now.Add(-time.Minute)})
+
+	sub := bus.Subscribe("backfill", SubscribeOptions{QueueSize: 10, ReplaySince: 10 * time.Minute})
+	defer sub.Close()
+	bus.Publish(Event{Type: "c"})
+	if got := drain(sub); len(got) != 3 || got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Fatalf("expected replayed events before live ones, got %v", got)
+	}
+
+	small := bus.Subscribe("small", SubscribeOptions{QueueSize: 2, ReplaySince: 24 * time.Hour})
+	defer small.Close()
+	if got := drain(small); len(got) != 2 || got[0] != "b" || got[1] != "c" {
+		t.Fatalf("expected only the newest events to fit the queue, got %v", got)
+	}
+
+	live := bus.Subscribe("live", SubscribeOptions{QueueSize: 10})
+	defer live.Close()
+	if got := drain(live); len(got) != 0 {
+		t.Fatalf("expected no replay without ReplaySince, got %v", got)
+	}
+}
+
+func TestInboxTracksAlertsAboveInfo(t *testing.T) {
+	inbox := NewInbox(2)
+	bus := NewBus().WithInbox(inbox)
+	sub := bus.Subscribe("updates", SubscribeOptions{Types: []string{TypeAlertUpdated}})
+	defer sub.Close()
+
+	bus.Publish(Event{Type: TypeStatusChanged, Payload: StatusChanged{To: "idle"}})
+	bus.Publish(Event{ID: "a", Type: TypeStatusChanged, Payload: StatusChanged{To: "maintenance"}})
+	bus.Publish(Event{ID: "b", Type: TypeProximity, Payload: Proximity{}})
+	if got := inbox.Alerts("", SeverityInfo); len(got) != 2 || got[0].ID != "b" || got[0].Severity != SeverityCritical || got[1].Severity != SeverityWarning {
+		t.Fatalf("expected the warning and critical events newest first, got %+v", got)
+	}
+	if got := inbox.Alerts("", SeverityCritical); len(got) != 1 {
+		t.Fatalf("expected one critical alert, got %+v", got)
+	}
+
+	if _, err := inbox.Acknowledge("a", "ops"); err != nil {
+		t.Fatalf("acknowledge: %v", err)
+	}
+	if _, err := inbox.Acknowledge("a", "ops"); !errors.Is(err, ErrAlertTransition) {
+		t.Fatalf("expected acknowledging twice to fail, got %v", err)
+	}
+	alert, err := inbox.Resolve("a", "lead")
+	if err != nil || alert.State != AlertResolved || alert.AcknowledgedBy != "ops" || alert.ResolvedBy != "lead" {
+		t.Fatalf("unexpected resolved alert %+v, %v", alert, err)
+	}
+	if _, err := inbox.Resolve("missing", ""); !errors.Is(err, ErrAlertNotFound) {
+		t.Fatalf("expected an unknown alert to fail, got %v", err)
+	}
+	if got := drain(sub); len(got) != 2 {
+		t.Fatalf("expected two alert updates, got %v", got)
+	}
+
+	bus.Publish(Event{ID: "c", Type: TypeProximity, Payload: Proximity{}})
+	if got := inbox.Alerts("", SeverityInfo); len(got) != 2 || got[0].ID != "c" || got[1].ID != "b" {
+		t.Fatalf("expected the resolved alert to make room, got %+v", got)
+	}
+}
@@ -0,0 +1,24 @@
+package events
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	publishedEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "orbit_events_published_total",
+		Help: "Events published on the internal bus by type.",
+	}, []string{"type"})
+
+	droppedEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "orbit_events_dropped_total",
+		Help: "Events dropped because a subscriber queue was full.",
+	}, []string{"subscriber", "policy"})
+
+	queueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "orbit_event_queue_depth",
+		Help: "Number of events waiting in a subscriber queue.",
+	}, []string{"subscriber"})
+)
+
+func init() {
+	prometheus.MustRegister(publishedEvents, droppedEvents, queueDepth)
+}
@@ -0,0 +1,116 @@
+package events
+
+import "time"
+
+// Simulation event types published by the simulation manager.
+const (
+	// TypeTruckCreated is published when a truck joins the fleet, with a TruckCreated payload.
+	TypeTruckCreated = "truckCreated"
+	// TypeWaypointReached is published when a truck reaches any waypoint, with a WaypointReached payload.
+	TypeWaypointReached = "waypointReached"
+	// TypeRouteCompleted is published when a truck reaches the final waypoint of its route, with a
+	// RouteCompleted payload.
+	TypeRouteCompleted = "routeCompleted"
+	// TypeStatusChanged is published when a truck changes status, with a StatusChanged payload.
+	TypeStatusChanged = "statusChanged"
+	// TypeProximity is published when two moving trucks come within the proximity distance, with a
+	// Proximity payload.
+	TypeProximity = "proximity"
+	// TypeTruckHandedOff is published when a truck passes to another fleet at an exchange point, with a
+	// TruckHandedOff payload.
+	TypeTruckHandedOff = "truckHandedOff"
+)
+
+// TruckCreated describes a truck added to the fleet.
+type TruckCreated struct {
+	TruckID   string  `json:"truckId"`
+	TruckType string  `json:"truckType,omitempty"`
+	RouteID   string  `json:"routeId"`
+	Lat       float64 `json:"lat"`
+	Lon       float64 `json:"lon"`
+	// SimulatedTime is the simulated clock when the event happened.
+	SimulatedTime time.Time `json:"simulatedTime"`
+}
+
+// Stop carries the annotation of the waypoint a truck arrived at, for planned-vs-actual analytics.
+type Stop struct {
+	StopType       string     `json:"stopType,omitempty"`
+	PlannedArrival *time.Time `json:"plannedArrival,omitempty"`
+	// ArrivalDelaySeconds is the arrival less the planned arrival, negative when the truck is early.
+	ArrivalDelaySeconds *float64 `json:"arrivalDelaySeconds,omitempty"`
+	Notes               string   `json:"notes,omitempty"`
+}
+
+// WaypointReached describes a truck arriving at a waypoint. Waypoint is its index in the route. Stop is
+// set when the waypoint is annotated.
+type WaypointReached struct {
+	TruckID       string    `json:"truckId"`
+	RouteID       string    `json:"routeId"`
+	Waypoint      int       `json:"waypoint"`
+	Lat           float64   `json:"lat"`
+	Lon           float64   `json:"lon"`
+	SimulatedTime time.Time `json:"simulatedTime"`
+	*Stop
+}
+
+// RouteCompleted describes a truck arriving at the end of its route. Stop is set when the final waypoint is
+// annotated.
+type RouteCompleted struct {
+	TruckID       string    `json:"truckId"`
+	RouteID       string    `json:"routeId"`
+	Lat           float64   `json:"lat"`
+	Lon           float64   `json:"lon"`
+	SimulatedTime time.Time `json:"simulatedTime"`
+	*Stop
+}
+
+// StatusChanged describes a truck moving from one status to another.
+type StatusChanged struct {
+	TruckID       string    `json:"truckId"`
+	From          string    `json:"from"`
+	To            string    `json:"to"`
+	SimulatedTime time.Time `json:"simulatedTime"`
+}
+
+// Proximity describes two trucks closer than the configured distance. TruckA sorts before TruckB, and
+// Lat/Lon is the midpoint between them.
+type Proximity struct {
+	TruckA         string    `json:"truckA"`
+	TruckB         string    `json:"truckB"`
+	DistanceMeters float64   `json:"distanceMeters"`
+	Lat            float64   `json:"lat"`
+	Lon            float64   `json:"lon"`
+	SimulatedTime  time.Time `json:"simulatedTime"`
+}
+
+// TruckHandedOff describes a truck passing from one fleet to another. TruckID is its new ID and
+// PreviousTruckID the ID it had; PreviousFleet is empty for the home fleet.
+type TruckHandedOff struct {
+	TruckID         string    `json:"truckId"`
+	PreviousTruckID string    `json:"previousTruckId"`
+	Fleet           string    `json:"fleet"`
+	PreviousFleet   string    `json:"previousFleet,omitempty"`
+	Exchange        string    `json:"exchange"`
+	Lat             float64   `json:"lat"`
+	Lon             float64   `json:"lon"`
+	SimulatedTime   time.Time `json:"simulatedTime"`
+}
+
+// Involves reports whether a simulation event payload concerns the given truck.
+func Involves(payload any, truckID string) bool {
+	switch p := payload.(type) {
+	case TruckCreated:
+		return p.TruckID == truckID
+	case WaypointReached:
+		return p.TruckID == truckID
+	case RouteCompleted:
+		return p.TruckID == truckID
+	case StatusChanged:
+		return p.TruckID == truckID
+	case Proximity:
+		return p.TruckA == truckID || p.TruckB == truckID
+	case TruckHandedOff:
+		return p.TruckID == truckID || p.PreviousTruckID == truckID
+	}
+	return false
+}
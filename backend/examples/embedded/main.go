@@ -0,0 +1,127 @@
+// Command embedded shows how to run the Orbit simulation as a library: it plugs in its own movement model
+// as a simulation.RoutePlanner, streams events to its own sink, and serves an extra endpoint next to the
+// built-in API. Copy it as a starting point; go test keeps it compiling against the current packages.
+//
+//	go run ./backend/examples/embedded -addr :8080
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"orbit/backend/events"
+	"orbit/backend/server"
+	"orbit/backend/simulation"
+)
+
+// gridPlanner is a movement model for a city laid out on a street grid: trucks drive along one axis and
+// then the other instead of cutting across blocks. Any type with a Plan method can replace the default
+// random waypoints.
+type gridPlanner struct{}
+
+func (gridPlanner) Plan(start, end simulation.Point) ([]simulation.Point, error) {
+	corner := simulation.Point{Lat: start.Lat, Lon: end.Lon}
+	return []simulation.Point{start, corner, end}, nil
+}
+
+// ndjsonSink writes every simulation event as a line of JSON and counts arrivals per truck. Replace the
+// writer with a Kafka producer, a database, or a file to forward events elsewhere.
+type ndjsonSink struct {
+	sub *events.Subscription
+
+	mu       sync.Mutex
+	enc      *json.Encoder
+	arrivals map[string]int
+}
+
+func newNDJSONSink(bus *events.Bus, w io.Writer) *ndjsonSink {
+	return &ndjsonSink{
+		sub:      bus.Subscribe("ndjson-sink", events.SubscribeOptions{QueueSize: 1024, Overflow: events.OverflowDropOldest}),
+		enc:      json.NewEncoder(w),
+		arrivals: make(map[string]int),
+	}
+}
+
+// run drains the subscription until ctx is done.
+func (s *ndjsonSink) run(ctx context.Context) {
+	defer s.sub.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case evt := <-s.sub.C():
+			s.write(evt)
+		}
+	}
+}
+
+func (s *ndjsonSink) write(evt events.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := evt.Payload.(events.WaypointReached); ok {
+		s.arrivals[p.TruckID]++
+	}
+	_ = s.enc.Encode(evt)
+}
+
+// handleArrivals serves GET /api/example/arrivals, the arrivals the sink has seen per truck.
+func (s *ndjsonSink) handleArrivals(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.arrivals)
+}
+
+// setup wires the simulation, the sink, and the server together.
+func setup(out io.Writer) (*simulation.Manager, *server.Server, *ndjsonSink) {
+	bus := events.NewBus()
+	sim := simulation.NewManager(simulation.Config{
+		NumTrucks:      20,
+		Seed:           1,
+		UpdateInterval: time.Second,
+		StartPoints:    []simulation.Point{{Lat: 40.7128, Lon: -74.0060}},
+		EndPoints:      []simulation.Point{{Lat: 40.7306, Lon: -73.9866}, {Lat: 40.7061, Lon: -73.9969}},
+	}).WithRoutePlanner(gridPlanner{}).WithEventBus(bus)
+	sink := newNDJSONSink(bus, out)
+	srv := server.NewServer(sim).WithEventBus(bus).WithRoute("/api/example/arrivals", sink.handleArrivals)
+	return sim, srv, sink
+}
+
+func main() {
+	addr := flag.String("addr", ":8080", "HTTP listen address")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	sim, srv, sink := setup(os.Stdout)
+	go sink.run(ctx)
+	if err := sim.Start(ctx); err != nil {
+		slog.Error("failed to start the simulation", "err", err)
+		os.Exit(1)
+	}
+	defer sim.Stop()
+
+	httpServer := &http.Server{Addr: *addr, Handler: srv.Routes()}
+	go func() {
+		<-ctx.Done()
+		_ = httpServer.Shutdown(context.Background())
+	}()
+	slog.Info("serving the embedded simulation", "addr", *addr)
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		slog.Error("server failed", "err", err)
+		os.Exit(1)
+	}
+}
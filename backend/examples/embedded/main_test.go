@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEmbeddedSimulation(t *testing.T) {
+	var out bytes.Buffer
+	sim, srv, sink := setup(&out)
+	if err := sim.StepOnce(60); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sink.run(ctx)
+		close(done)
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(sink.sub.C()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	if out.Len() == 0 {
+		t.Fatal("expected the sink to write events")
+	}
+
+	route, ok := sim.TruckRoute("truck-0001")
+	if !ok || len(route.Waypoints) != 3 || route.Waypoints[1].Lat != route.Waypoints[0].Lat {
+		t.Fatalf("expected a grid route around one corner, got %+v", route)
+	}
+
+	rr := httptest.NewRecorder()
+	srv.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/example/arrivals", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", rr.Code)
+	}
+	var arrivals map[string]int
+	if err := json.Unmarshal(rr.Body.Bytes(), &arrivals); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(arrivals) == 0 {
+		t.Fatal("expected arrivals counted by the sink")
+	}
+}
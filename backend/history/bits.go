@@ -0,0 +1,59 @@
+package history
+
+import "io"
+
+// bitWriter appends bits most significant first.
+type bitWriter struct {
+	buf []byte
+	// free is the number of unused low bits in the last byte of buf.
+	free int
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if bit {
+		w.writeBits(1, 1)
+	} else {
+		w.writeBits(0, 1)
+	}
+}
+
+// writeBits writes the low n bits of v.
+func (w *bitWriter) writeBits(v uint64, n int) {
+	for n > 0 {
+		if w.free == 0 {
+			w.buf = append(w.buf, 0)
+			w.free = 8
+		}
+		take := min(n, w.free)
+		chunk := byte(v>>(n-take)) & byte(1<<take-1)
+		w.buf[len(w.buf)-1] |= chunk << (w.free - take)
+		w.free -= take
+		n -= take
+	}
+}
+
+type bitReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *bitReader) readBit() (bool, error) {
+	v, err := r.readBits(1)
+	return v == 1, err
+}
+
+func (r *bitReader) readBits(n int) (uint64, error) {
+	if r.pos+n > len(r.buf)*8 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	var v uint64
+	for n > 0 {
+		offset := r.pos % 8
+		take := min(n, 8-offset)
+		chunk := r.buf[r.pos/8] >> (8 - offset - take) & byte(1<<take-1)
+		v = v<<take | uint64(chunk)
+		r.pos += take
+		n -= take
+	}
+	return v, nil
+}
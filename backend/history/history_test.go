@@ -0,0 +1,282 @@
+package history
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"orbit/backend/simulation"
+)
+
+func TestSeriesRoundTripsAndCompresses(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	var want []Sample
+	var series Series
+	lat, lon := 47.6062, -122.3321
+	at := start
+	for i := 0; i < 8640; i++ {
+		// Mostly steady ten second samples with occasional jitter and parked stretches.
+		at = at.Add(10 * time.Second)
+		if i%97 == 0 {
+			at = at.Add(time.Duration(rng.Intn(3000)-1500) * time.Millisecond)
+		}
+		if i%500 > 50 {
+			lat += 0.0001 * rng.Float64()
+			lon -= 0.0001 * rng.Float64()
+		}
+		sample := Sample{Time: at, Lat: lat, Lon: lon}
+		if err := series.Append(sample); err != nil {
+			t.Fatalf("append %d: %v", i, err)
+		}
+		want = append(want, sample)
+	}
+
+	got, err := series.Samples()
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d samples, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if !got[i].Time.Equal(want[i].Time) || got[i].Lat != want[i].Lat || got[i].Lon != want[i].Lon {
+			t.Fatalf("sample %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+
+	raw := len(want) * 24
+	if series.Size()*2 > raw {
+		t.Fatalf("expected at least 2x compression of %d raw bytes, got %d", raw, series.Size())
+	}
+
+	if err := series.Append(Sample{Time: start}); err == nil {
+		t.Fatalf("expected an out-of-order sample to be rejected")
+	}
+}
+
+func TestStoreRetainsWindowAndRestartsAfterReset(t *testing.T) {
+	store := NewStore(nil, Options{Retention: time.Hour, BlockSpan: 10 * time.Minute})
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i <= 120; i++ {
+		at := start.Add(time.Duration(i) * time.Minute)
+		trucks := []simulation.Truck{{ID: "truck-0001", Lat: float64(i) / 1000, UpdatedAt: at}}
+		if i < 30 {
+			trucks = append(trucks, simulation.Truck{ID: "truck-0002", UpdatedAt: at})
+		}
+		store.Record(at, trucks)
+		// A second record at the same time adds nothing.
+		store.Record(at, trucks)
+	}
+
+	samples, ok := store.Range("truck-0001", time.Time{}, time.Time{})
+	if !ok {
+		t.Fatalf("expected history for truck-0001")
+	}
+	if oldest := samples[0].Time; oldest.Before(start.Add(time.Hour-10*time.Minute)) || oldest.After(start.Add(time.Hour)) {
+		t.Fatalf("expected about an hour of history, oldest sample at %s", oldest)
+	}
+	if last := samples[len(samples)-1]; !last.Time.Equal(start.Add(2*time.Hour)) || last.Lat != 0.12 {
+		t.Fatalf("unexpected newest sample %+v", last)
+	}
+	window, _ := store.Range("truck-0001", start.Add(90*time.Minute), start.Add(99*time.Minute))
+	if len(window) != 10 {
+		t.Fatalf("expected 10 samples in the window, got %d", len(window))
+	}
+	if _, ok := store.Range("truck-0002", time.Time{}, time.Time{}); ok {
+		t.Fatalf("expected history of a departed truck to expire")
+	}
+
+	store.Record(start, []simulation.Truck{{ID: "truck-0001", UpdatedAt: start}})
+	if samples, _ := store.Range("truck-0001", time.Time{}, time.Time{}); len(samples) != 1 {
+		t.Fatalf("expected history to restart when the clock goes back, got %d samples", len(samples))
+	}
+	if stats := store.Stats(); stats.Trucks != 1 || stats.Samples != 1 || stats.Bytes == 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestBytesPerSampleReflectsCompression(t *testing.T) {
+	// An uncompressed sample is 24 bytes.
+	if b := BytesPerSample(); b <= 0 || b >= 24 {
+		t.Fatalf("expected compressed samples under 24 bytes, got %.1f", b)
+	}
+}
+
+func TestFramesGroupSamplesByTime(t *testing.T) {
+	store := NewStore(nil, Options{})
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		at := start.Add(time.Duration(i) * 10 * time.Second)
+		trucks := []simulation.Truck{{ID: "truck-0002", Lat: float64(i), UpdatedAt: at}}
+		if i%2 == 0 {
+			trucks = append(trucks, simulation.Truck{ID: "truck-0001", Lon: float64(i), UpdatedAt: at})
+		}
+		store.Record(at, trucks)
+	}
+
+	frames := store.Frames(start.Add(10*time.Second), start.Add(40*time.Second), 3)
+	if len(frames) != 3 {
+		t.Fatalf("expected the limit to cap the frames at 3, got %d", len(frames))
+	}
+	for i, frame := range frames {
+		if want := start.Add(time.Duration(i+1) * 10 * time.Second); !frame.Time.Equal(want) {
+			t.Fatalf("frame %d: expected time %s, got %s", i, want, frame.Time)
+		}
+	}
+	if got := frames[1].Positions; len(got) != 2 || got[0].TruckID != "truck-0001" || got[0].Lon != 2 || got[1].Lat != 2 {
+		t.Fatalf("expected both trucks ordered by ID in the shared frame, got %+v", got)
+	}
+	if got := frames[0].Positions; len(got) != 1 || got[0].TruckID != "truck-0002" {
+		t.Fatalf("expected only the truck sampled at that time, got %+v", got)
+	}
+	if all := store.Frames(time.Time{}, time.Time{}, 0); len(all) != 5 {
+		t.Fatalf("expected open bounds to return every frame, got %d", len(all))
+	}
+}
+
+func TestFramesShareTheSampleInstantDespiteClockSkew(t *testing.T) {
+	store := NewStore(nil, Options{BlockSpan: time.Minute})
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 30; i++ {
+		at := start.Add(time.Duration(i) * 10 * time.Second)
+		store.Record(at, []simulation.Truck{
+			{ID: "truck-0001", Lat: float64(i), UpdatedAt: at.Add(-1234 * time.Millisecond)},
+			{ID: "truck-0002", Lat: float64(i), UpdatedAt: at.Add(567 * time.Millisecond)},
+		})
+		// A truck that has not moved on since its last sample is not sampled again.
+		if i > 0 {
+			store.Record(at.Add(time.Second), []simulation.Truck{{ID: "truck-0001", Lat: float64(i), UpdatedAt: at.Add(-1234 * time.Millisecond)}})
+		}
+	}
+
+	frames := store.Frames(start.Add(25*time.Second), time.Time{}, 4)
+	if len(frames) != 4 {
+		t.Fatalf("expected 4 frames, got %d", len(frames))
+	}
+	for i, frame := range frames {
+		if want := start.Add(time.Duration(i+3) * 10 * time.Second); !frame.Time.Equal(want) || len(frame.Positions) != 2 {
+			t.Fatalf("frame %d: expected both trucks at %s, got %+v", i, want, frame)
+		}
+	}
+	// Pages that cross block boundaries pick up where the previous one stopped.
+	var seen int
+	for cursor := (time.Time{}); ; {
+		page := store.Frames(cursor, time.Time{}, 7)
+		seen += len(page)
+		if len(page) < 7 {
+			break
+		}
+		cursor = page[len(page)-1].Time.Add(time.Millisecond)
+	}
+	if seen != 30 {
+		t.Fatalf("expected 30 frames across the pages, got %d", seen)
+	}
+}
+
+func TestStoreKeepsAParkedTruckAsARun(t *testing.T) {
+	store := NewStore(nil, Options{BlockSpan: time.Hour})
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 100; i++ {
+		at := start.Add(time.Duration(i) * time.Second)
+		lat := 1.0
+		if i >= 90 {
+			lat = float64(i)
+		}
+		store.Record(at, []simulation.Truck{{ID: "truck-0001", Lat: lat, UpdatedAt: at}})
+	}
+
+	samples, _ := store.Range("truck-0001", time.Time{}, time.Time{})
+	// The first 90 samples are one stop, kept as where it began and ended.
+	if len(samples) != 12 {
+		t.Fatalf("expected the parked stretch to compact to 2 samples plus 10 moving ones, got %d", len(samples))
+	}
+	if !samples[1].Time.Equal(start.Add(89*time.Second)) || samples[1].Lat != 1 || samples[2].Lat != 90 {
+		t.Fatalf("expected the run to end at the last parked sample, got %+v", samples[:3])
+	}
+	if stats := store.Stats(); stats.Samples != 12 {
+		t.Fatalf("expected 12 samples in the stats, got %+v", stats)
+	}
+
+	// A truck that is still parked ends its history with the latest sample.
+	for i := 100; i < 110; i++ {
+		at := start.Add(time.Duration(i) * time.Second)
+		store.Record(at, []simulation.Truck{{ID: "truck-0001", Lat: 99, UpdatedAt: at}})
+	}
+	samples, _ = store.Range("truck-0001", start.Add(95*time.Second), time.Time{})
+	if last := samples[len(samples)-1]; len(samples) != 6 || !last.Time.Equal(start.Add(109*time.Second)) {
+		t.Fatalf("expected the open run to end at the newest sample, got %+v", samples)
+	}
+	frames := store.Frames(start.Add(100*time.Second), time.Time{}, 0)
+	if len(frames) != 1 || !frames[0].Time.Equal(start.Add(109*time.Second)) {
+		t.Fatalf("expected playback to reach the newest sample of the run, got %+v", frames)
+	}
+}
+
+func TestSnapshotIsUnchangedByLaterRecords(t *testing.T) {
+	store := NewStore(nil, Options{})
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	record := func(from, to int) {
+		for i := from; i < to; i++ {
+			at := start.Add(time.Duration(i) * 10 * time.Second)
+			store.Record(at, []simulation.Truck{{ID: "truck-0001", Lat: float64(i), UpdatedAt: at}})
+		}
+	}
+	record(0, 10)
+	snap := store.Snapshot()
+	before := snap.trucks["truck-0001"][0]
+	record(10, 20)
+
+	if before.Len() != 10 {
+		t.Fatalf("expected the snapshot's block to keep 10 samples, got %d", before.Len())
+	}
+	if samples, _ := store.Range("truck-0001", time.Time{}, time.Time{}); len(samples) != 20 {
+		t.Fatalf("expected the store to carry on to 20 samples, got %d", len(samples))
+	}
+	if store.trucks["truck-0001"][0] == before {
+		t.Fatalf("expected the store to append to a copy of the shared block")
+	}
+}
+
+func TestStoreSaveAndLoadRoundTrip(t *testing.T) {
+	store := NewStore(nil, Options{BlockSpan: time.Minute})
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 30; i++ {
+		at := start.Add(time.Duration(i) * 10 * time.Second)
+		store.Record(at, []simulation.Truck{
+			{ID: "truck-0001", Lat: float64(i), UpdatedAt: at},
+			{ID: "truck-0002", Lat: 5, UpdatedAt: at},
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := store.Save(&buf); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	saved := buf.String()
+	loaded := NewStore(nil, Options{BlockSpan: time.Minute})
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got, want := loaded.Stats(), store.Stats(); got != want {
+		t.Fatalf("expected stats %+v after loading, got %+v", want, got)
+	}
+	for _, id := range []string{"truck-0001", "truck-0002"} {
+		want, _ := store.Range(id, time.Time{}, time.Time{})
+		got, _ := loaded.Range(id, time.Time{}, time.Time{})
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: expected %+v, got %+v", id, want, got)
+		}
+	}
+
+	corrupt := strings.Replace(saved, `"count":6`, `"count":60`, 1)
+	if err := loaded.Load(strings.NewReader(corrupt)); err == nil {
+		t.Fatalf("expected a corrupt block to be rejected")
+	}
+	if got := loaded.Stats(); got != store.Stats() {
+		t.Fatalf("expected a failed load to keep the history, got %+v", got)
+	}
+}
@@ -0,0 +1,19 @@
+package history
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	historySamples = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "orbit_position_history_samples",
+		Help: "Truck positions held in the compressed position history.",
+	})
+
+	historyBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "orbit_position_history_bytes",
+		Help: "Compressed size of the position history in bytes.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(historySamples, historyBytes)
+}
@@ -0,0 +1,287 @@
+package history
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sample is one recorded truck position.
+type Sample struct {
+	Time time.Time
+	Lat  float64
+	Lon  float64
+}
+
+// Series stores a truck's samples compressed the way Facebook's Gorilla database does: timestamps as
+// deltas of deltas and coordinates XORed with the previous value, so a truck sampled at a steady interval
+// costs a few bits per timestamp and a handful of bytes per position. Timestamps are kept to the
+// millisecond. Samples must be appended in time order.
+type Series struct {
+	w     bitWriter
+	count int
+
+	first     int64
+	last      int64
+	lastDelta int64
+	lat, lon  xorState
+
+	// shared is set once a snapshot or reader holds the series. Appending to a shared series would change
+	// what they see, so the store appends to a copy instead.
+	shared atomic.Bool
+}
+
+// Append adds a sample. It fails when the sample is older than the last one.
+func (s *Series) Append(sample Sample) error {
+	t := sample.Time.UnixMilli()
+	if s.count == 0 {
+		s.w.writeBits(uint64(t), 64)
+		s.w.writeBits(math.Float64bits(sample.Lat), 64)
+		s.w.writeBits(math.Float64bits(sample.Lon), 64)
+		s.first, s.last = t, t
+		s.lat = xorState{prev: math.Float64bits(sample.Lat), leading: noWindow}
+		s.lon = xorState{prev: math.Float64bits(sample.Lon), leading: noWindow}
+		s.count = 1
+		return nil
+	}
+	if t < s.last {
+		return fmt.Errorf("sample at %s is before the last sample", sample.Time)
+	}
+
+	delta := t - s.last
+	writeDeltaOfDelta(&s.w, delta-s.lastDelta)
+	s.lat.write(&s.w, sample.Lat)
+	s.lon.write(&s.w, sample.Lon)
+	s.last, s.lastDelta = t, delta
+	s.count++
+	return nil
+}
+
+// Len returns the number of samples in the series.
+func (s *Series) Len() int {
+	return s.count
+}
+
+// Size returns the compressed size of the series in bytes.
+func (s *Series) Size() int {
+	return len(s.w.buf)
+}
+
+// First returns the time of the oldest sample.
+func (s *Series) First() time.Time {
+	return time.UnixMilli(s.first).UTC()
+}
+
+// Last returns the time of the newest sample.
+func (s *Series) Last() time.Time {
+	return time.UnixMilli(s.last).UTC()
+}
+
+// position returns the newest sample's coordinates.
+func (s *Series) position() (lat, lon float64) {
+	return math.Float64frombits(s.lat.prev), math.Float64frombits(s.lon.prev)
+}
+
+// clone returns an unshared copy that later appends to s do not touch.
+func (s *Series) clone() *Series {
+	return &Series{
+		w:         bitWriter{buf: append([]byte(nil), s.w.buf...), free: s.w.free},
+		count:     s.count,
+		first:     s.first,
+		last:      s.last,
+		lastDelta: s.lastDelta,
+		lat:       s.lat,
+		lon:       s.lon,
+	}
+}
+
+// Samples decodes every sample in the series, oldest first.
+func (s *Series) Samples() ([]Sample, error) {
+	samples := make([]Sample, 0, s.count)
+	if s.count == 0 {
+		return samples, nil
+	}
+
+	r := bitReader{buf: s.w.buf}
+	t, err := r.readBits(64)
+	if err != nil {
+		return nil, err
+	}
+	latBits, err := r.readBits(64)
+	if err != nil {
+		return nil, err
+	}
+	lonBits, err := r.readBits(64)
+	if err != nil {
+		return nil, err
+	}
+	ts := int64(t)
+	samples = append(samples, Sample{Time: time.UnixMilli(ts).UTC(), Lat: math.Float64frombits(latBits), Lon: math.Float64frombits(lonBits)})
+
+	lat := xorState{prev: latBits, leading: noWindow}
+	lon := xorState{prev: lonBits, leading: noWindow}
+	var delta int64
+	for i := 1; i < s.count; i++ {
+		dod, err := readDeltaOfDelta(&r)
+		if err != nil {
+			return nil, err
+		}
+		delta += dod
+		ts += delta
+		latValue, err := lat.read(&r)
+		if err != nil {
+			return nil, err
+		}
+		lonValue, err := lon.read(&r)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, Sample{Time: time.UnixMilli(ts).UTC(), Lat: latValue, Lon: lonValue})
+	}
+	return samples, nil
+}
+
+// dodBuckets are the signed widths a delta of delta is stored in, each behind a prefix of that many one
+// bits followed by a zero. Anything wider is stored in full behind four one bits.
+var dodBuckets = []int{7, 9, 12}
+
+func writeDeltaOfDelta(w *bitWriter, dod int64) {
+	if dod == 0 {
+		w.writeBit(false)
+		return
+	}
+	for i, width := range dodBuckets {
+		if dod >= -(1<<(width-1)) && dod < 1<<(width-1) {
+			w.writeBits(1<<(i+2)-2, i+2)
+			w.writeBits(uint64(dod), width)
+			return
+		}
+	}
+	w.writeBits(0xf, 4)
+	w.writeBits(uint64(dod), 64)
+}
+
+func readDeltaOfDelta(r *bitReader) (int64, error) {
+	for i := 0; i <= len(dodBuckets); i++ {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if !bit {
+			if i == 0 {
+				return 0, nil
+			}
+			width := dodBuckets[i-1]
+			v, err := r.readBits(width)
+			if err != nil {
+				return 0, err
+			}
+			// Sign-extend from width bits.
+			return int64(v<<(64-width)) >> (64 - width), nil
+		}
+	}
+	v, err := r.readBits(64)
+	return int64(v), err
+}
+
+// noWindow marks an xorState that has not stored a meaningful-bit window yet.
+const noWindow = 0xff
+
+// xorState encodes successive floats as the XOR with the previous value, reusing the previous window of
+// meaningful bits when the new XOR fits inside it.
+type xorState struct {
+	prev     uint64
+	leading  uint8
+	trailing uint8
+}
+
+func (s *xorState) write(w *bitWriter, v float64) {
+	value := math.Float64bits(v)
+	x := value ^ s.prev
+	s.prev = value
+	if x == 0 {
+		w.writeBit(false)
+		return
+	}
+	w.writeBit(true)
+
+	leading := uint8(min(bits.LeadingZeros64(x), 31))
+	trailing := uint8(bits.TrailingZeros64(x))
+	if s.leading != noWindow && leading >= s.leading && trailing >= s.trailing {
+		w.writeBit(false)
+		w.writeBits(x>>s.trailing, 64-int(s.leading)-int(s.trailing))
+		return
+	}
+
+	s.leading, s.trailing = leading, trailing
+	meaningful := 64 - int(leading) - int(trailing)
+	w.writeBit(true)
+	w.writeBits(uint64(leading), 5)
+	// 64 meaningful bits do not fit in six bits and are written as zero.
+	w.writeBits(uint64(meaningful), 6)
+	w.writeBits(x>>trailing, meaningful)
+}
+
+func (s *xorState) read(r *bitReader) (float64, error) {
+	changed, err := r.readBit()
+	if err != nil {
+		return 0, err
+	}
+	if !changed {
+		return math.Float64frombits(s.prev), nil
+	}
+
+	newWindow, err := r.readBit()
+	if err != nil {
+		return 0, err
+	}
+	if newWindow {
+		leading, err := r.readBits(5)
+		if err != nil {
+			return 0, err
+		}
+		meaningful, err := r.readBits(6)
+		if err != nil {
+			return 0, err
+		}
+		if meaningful == 0 {
+			meaningful = 64
+		}
+		s.leading = uint8(leading)
+		s.trailing = uint8(64 - leading - meaningful)
+	} else if s.leading == noWindow {
+		return 0, fmt.Errorf("corrupt series: value reuses a window before one was set")
+	}
+
+	x, err := r.readBits(64 - int(s.leading) - int(s.trailing))
+	if err != nil {
+		return 0, err
+	}
+	s.prev ^= x << s.trailing
+	return math.Float64frombits(s.prev), nil
+}
+
+var bytesPerSample struct {
+	once  sync.Once
+	value float64
+}
+
+// BytesPerSample is the average compressed size of a sample, measured once by compressing a track of a
+// truck driving at 25 m/s and sampled every 10 seconds. Use it to size retention before recording.
+func BytesPerSample() float64 {
+	bytesPerSample.once.Do(func() {
+		const samples = 1000
+		var s Series
+		start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		for i := 0; i < samples; i++ {
+			// 250 m a sample, heading north-east.
+			step := float64(i) * 250 / 111320
+			_ = s.Append(Sample{Time: start.Add(time.Duration(i) * 10 * time.Second), Lat: 47.6 + step*0.8, Lon: -122.3 + step*0.6})
+		}
+		bytesPerSample.value = float64(s.Size()) / samples
+	})
+	return bytesPerSample.value
+}
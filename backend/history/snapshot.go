@@ -0,0 +1,142 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// historyFileVersion is written at the head of a saved history and bumped when the format changes.
+const historyFileVersion = 1
+
+// Snapshot is the store's history at one instant. It shares the store's compressed blocks rather than
+// copying them: Record appends to a copy of any block a snapshot holds, so the snapshot stays fixed while
+// the store carries on.
+type Snapshot struct {
+	trucks map[string][]*Series
+	idle   map[string]Sample
+}
+
+// Snapshot takes the store's current history. It holds the lock only to copy each truck's block list.
+func (s *Store) Snapshot() *Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snap := &Snapshot{trucks: make(map[string][]*Series, len(s.trucks)), idle: make(map[string]Sample, len(s.idle))}
+	for id, blocks := range s.trucks {
+		if n := len(blocks); n > 0 {
+			blocks[n-1].shared.Store(true)
+		}
+		snap.trucks[id] = append([]*Series(nil), blocks...)
+	}
+	for id, sample := range s.idle {
+		snap.idle[id] = sample
+	}
+	return snap
+}
+
+// savedTruck is one truck's history in a saved file. Blocks keep their compressed encoding.
+type savedTruck struct {
+	ID     string       `json:"id"`
+	Blocks []savedBlock `json:"blocks"`
+	Idle   *Sample      `json:"idle,omitempty"`
+}
+
+type savedBlock struct {
+	Count int    `json:"count"`
+	Data  []byte `json:"data"`
+}
+
+// Save writes the snapshot as JSON lines: a header, then one line per truck in ID order.
+func (snap *Snapshot) Save(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(struct {
+		Version int `json:"version"`
+	}{historyFileVersion}); err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(snap.trucks))
+	for id := range snap.trucks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		truck := savedTruck{ID: id}
+		for _, block := range snap.trucks[id] {
+			truck.Blocks = append(truck.Blocks, savedBlock{Count: block.count, Data: block.w.buf})
+		}
+		if sample, ok := snap.idle[id]; ok {
+			truck.Idle = &sample
+		}
+		if err := enc.Encode(truck); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save writes the store's history to w without holding up Record while it encodes.
+func (s *Store) Save(w io.Writer) error {
+	return s.Snapshot().Save(w)
+}
+
+// Load replaces the store's history with one written by Save. Every block is decoded and checked before
+// anything is replaced, so a corrupt file leaves the store as it was. Each truck is sampled again on the
+// next Record, and a truck whose history is newer than the simulated clock starts afresh.
+func (s *Store) Load(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := dec.Decode(&header); err != nil {
+		return fmt.Errorf("read history header: %w", err)
+	}
+	if header.Version != historyFileVersion {
+		return fmt.Errorf("unsupported history version %d", header.Version)
+	}
+
+	trucks := make(map[string][]*Series)
+	idle := make(map[string]Sample)
+	for {
+		var truck savedTruck
+		if err := dec.Decode(&truck); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("read history: %w", err)
+		}
+		var blocks []*Series
+		for i, saved := range truck.Blocks {
+			samples, err := (&Series{w: bitWriter{buf: saved.Data}, count: saved.Count}).Samples()
+			if err != nil {
+				return fmt.Errorf("truck %s block %d: %w", truck.ID, i, err)
+			}
+			// Appending again rebuilds the encoder state and rejects samples out of order.
+			block := &Series{}
+			for _, sample := range samples {
+				if err := block.Append(sample); err != nil {
+					return fmt.Errorf("truck %s block %d: %w", truck.ID, i, err)
+				}
+			}
+			if n := len(blocks); n > 0 && block.count > 0 && block.First().Before(blocks[n-1].Last()) {
+				return fmt.Errorf("truck %s block %d overlaps the block before it", truck.ID, i)
+			}
+			if block.count > 0 {
+				blocks = append(blocks, block)
+			}
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		trucks[truck.ID] = blocks
+		if truck.Idle != nil && truck.Idle.Time.After(blocks[len(blocks)-1].Last()) {
+			idle[truck.ID] = Sample{Time: truck.Idle.Time.UTC(), Lat: truck.Idle.Lat, Lon: truck.Idle.Lon}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trucks, s.idle, s.updated = trucks, idle, make(map[string]time.Time)
+	s.publishLocked()
+	return nil
+}
@@ -0,0 +1,367 @@
+package history
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"orbit/backend/simulation"
+)
+
+// Options configures how position history is sampled and how long it is kept.
+type Options struct {
+	// Retention is how much simulated time of history is kept per truck.
+	Retention time.Duration
+	// SampleInterval is how often the fleet is sampled in wall-clock time.
+	SampleInterval time.Duration
+	// BlockSpan is the simulated time covered by one compressed block. Expired history is dropped a
+	// block at a time, so retention overshoots by up to one block.
+	BlockSpan time.Duration
+}
+
+// Stats summarises what a Store holds.
+type Stats struct {
+	Trucks  int
+	Samples int
+	Bytes   int
+}
+
+// Store keeps compressed position history for every truck. A truck standing still is kept as a run: the
+// sample where it stopped and the latest sample at the same place, with the samples in between dropped.
+type Store struct {
+	sim  *simulation.Manager
+	opts Options
+
+	mu     sync.RWMutex
+	trucks map[string][]*Series
+	// idle holds the latest sample of a truck that has not moved since its last stored sample. It closes
+	// the run when the truck moves on or its block fills up.
+	idle map[string]Sample
+	// updated is each truck's UpdatedAt when it was last sampled, so a truck that has not moved on is
+	// not sampled again.
+	updated map[string]time.Time
+}
+
+// NewStore creates a store with defaults for unset options: a day of retention, a 10 second sample
+// interval, and two-hour blocks.
+func NewStore(sim *simulation.Manager, opts Options) *Store {
+	if opts.Retention <= 0 {
+		opts.Retention = 24 * time.Hour
+	}
+	if opts.SampleInterval <= 0 {
+		opts.SampleInterval = 10 * time.Second
+	}
+	if opts.BlockSpan <= 0 {
+		opts.BlockSpan = 2 * time.Hour
+	}
+	return &Store{
+		sim:     sim,
+		opts:    opts,
+		trucks:  make(map[string][]*Series),
+		idle:    make(map[string]Sample),
+		updated: make(map[string]time.Time),
+	}
+}
+
+// Run samples the fleet every SampleInterval until ctx is cancelled.
+func (s *Store) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.SampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Record(s.sim.SimulatedTime(), s.sim.Trucks())
+		}
+	}
+}
+
+// Record appends each truck's position as sampled at the simulated time at. Every truck sampled together
+// shares that time, whatever its own clock says, so trucks whose clocks are skewed still land in one
+// frame. Trucks whose UpdatedAt has not changed since their last sample are skipped, as is a second
+// sample at the same time. A truck at the same place as its last sample only extends its idle run. When
+// at goes backwards, as after a simulation reset, the truck starts a fresh history. History older than
+// the retention window is dropped, including that of trucks that have left the fleet.
+func (s *Store) Record(at time.Time, trucks []simulation.Truck) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range trucks {
+		blocks := s.trucks[t.ID]
+		if n := len(blocks); n > 0 {
+			last := blocks[n-1].Last()
+			if pending, ok := s.idle[t.ID]; ok {
+				last = pending.Time
+			}
+			if at.Before(last) {
+				blocks = nil
+				delete(s.idle, t.ID)
+			} else if !at.After(last) || t.UpdatedAt.Equal(s.updated[t.ID]) {
+				continue
+			}
+		}
+		s.updated[t.ID] = t.UpdatedAt
+
+		sample := Sample{Time: at, Lat: t.Lat, Lon: t.Lon}
+		if n := len(blocks); n > 0 {
+			tail := blocks[n-1]
+			if lat, lon := tail.position(); lat == t.Lat && lon == t.Lon && at.Sub(tail.First()) < s.opts.BlockSpan {
+				s.idle[t.ID] = sample
+				continue
+			}
+			// The truck moved on or its block is full: close the run where it last stood.
+			if pending, ok := s.idle[t.ID]; ok {
+				blocks = s.appendLocked(blocks, pending)
+				delete(s.idle, t.ID)
+			}
+		}
+		s.trucks[t.ID] = s.appendLocked(blocks, sample)
+	}
+
+	cutoff := at.Add(-s.opts.Retention)
+	for id, blocks := range s.trucks {
+		expired := 0
+		for expired < len(blocks) && blocks[expired].Last().Before(cutoff) {
+			expired++
+		}
+		if expired == len(blocks) {
+			delete(s.trucks, id)
+			delete(s.idle, id)
+			delete(s.updated, id)
+			continue
+		}
+		s.trucks[id] = blocks[expired:]
+	}
+	s.publishLocked()
+}
+
+// appendLocked adds a sample to the truck's newest block, starting a new one once the block spans
+// BlockSpan. A block held by a snapshot or reader is copied first.
+func (s *Store) appendLocked(blocks []*Series, sample Sample) []*Series {
+	if n := len(blocks); n == 0 || sample.Time.Sub(blocks[n-1].First()) >= s.opts.BlockSpan {
+		blocks = append(blocks, &Series{})
+	}
+	tail := blocks[len(blocks)-1]
+	if tail.shared.Load() {
+		tail = tail.clone()
+		blocks[len(blocks)-1] = tail
+	}
+	_ = tail.Append(sample)
+	return blocks
+}
+
+// publishLocked updates the history gauges.
+func (s *Store) publishLocked() {
+	stats := s.statsLocked()
+	historySamples.Set(float64(stats.Samples))
+	historyBytes.Set(float64(stats.Bytes))
+}
+
+// Range returns a truck's samples between from and to inclusive, oldest first. A zero bound is open. A
+// stretch where the truck stood still comes back as its first and last samples. It reports false when the
+// truck has no history.
+func (s *Store) Range(truckID string, from, to time.Time) ([]Sample, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	blocks := s.trucks[truckID]
+	if len(blocks) == 0 {
+		return nil, false
+	}
+
+	inWindow := func(t time.Time) bool {
+		return (from.IsZero() || !t.Before(from)) && (to.IsZero() || !t.After(to))
+	}
+	samples := []Sample{}
+	for _, block := range blocks {
+		if (!from.IsZero() && block.Last().Before(from)) || (!to.IsZero() && block.First().After(to)) {
+			continue
+		}
+		decoded, err := block.Samples()
+		if err != nil {
+			continue
+		}
+		for _, sample := range decoded {
+			if inWindow(sample.Time) {
+				samples = append(samples, sample)
+			}
+		}
+	}
+	if pending, ok := s.idle[truckID]; ok && inWindow(pending.Time) {
+		samples = append(samples, pending)
+	}
+	return samples, true
+}
+
+// Position is one truck's place in a Frame.
+type Position struct {
+	TruckID string
+	Lat     float64
+	Lon     float64
+}
+
+// Frame holds the positions of every truck sampled at one instant, ordered by truck ID.
+type Frame struct {
+	Time      time.Time
+	Positions []Position
+}
+
+// Frames returns the fleet's samples between from and to inclusive grouped by time, oldest first, and at
+// most limit of them when limit is positive. A zero bound is open. Trucks sampled together share a frame,
+// so a frame only holds the trucks that moved on since the previous sample.
+//
+// The lock is held only to take each truck's blocks in the window. The trucks' samples are then merged
+// by time, decoding a block only when the merge reaches it, and the merge stops at the limit, so a page
+// costs about one block per truck however long the window is.
+func (s *Store) Frames(from, to time.Time, limit int) []Frame {
+	var merge trackMerge
+	s.mu.RLock()
+	for id, blocks := range s.trucks {
+		var window []*Series
+		for i, block := range blocks {
+			if !from.IsZero() && block.Last().Before(from) {
+				continue
+			}
+			if !to.IsZero() && block.First().After(to) {
+				break
+			}
+			if i == len(blocks)-1 {
+				// Record appends to a copy of the newest block from now on.
+				block.shared.Store(true)
+			}
+			window = append(window, block)
+		}
+		cursor := &trackCursor{truckID: id, blocks: window, from: from, to: to}
+		if pending, ok := s.idle[id]; ok {
+			cursor.idle = &pending
+		}
+		if len(window) > 0 || cursor.idle != nil {
+			merge = append(merge, cursor)
+		}
+	}
+	s.mu.RUnlock()
+
+	live := merge[:0]
+	for _, c := range merge {
+		if c.advance() {
+			live = append(live, c)
+		}
+	}
+	merge = live
+	heap.Init(&merge)
+
+	var frames []Frame
+	for len(merge) > 0 {
+		c := merge[0]
+		sample := c.current()
+		if n := len(frames); n == 0 || !frames[n-1].Time.Equal(sample.Time) {
+			if limit > 0 && n == limit {
+				break
+			}
+			frames = append(frames, Frame{Time: sample.Time})
+		}
+		frame := &frames[len(frames)-1]
+		frame.Positions = append(frame.Positions, Position{TruckID: c.truckID, Lat: sample.Lat, Lon: sample.Lon})
+		if c.advance() {
+			heap.Fix(&merge, 0)
+		} else {
+			heap.Pop(&merge)
+		}
+	}
+	if frames == nil {
+		frames = []Frame{}
+	}
+	return frames
+}
+
+// trackCursor walks one truck's samples between from and to, decoding its blocks one at a time and
+// finishing with the end of its idle run, if any.
+type trackCursor struct {
+	truckID  string
+	blocks   []*Series
+	idle     *Sample
+	from, to time.Time
+
+	samples []Sample
+	next    int
+}
+
+// advance moves to the next sample in the window, reporting false once there is none.
+func (c *trackCursor) advance() bool {
+	for {
+		for c.next < len(c.samples) {
+			sample := c.samples[c.next]
+			c.next++
+			if !c.from.IsZero() && sample.Time.Before(c.from) {
+				continue
+			}
+			if !c.to.IsZero() && sample.Time.After(c.to) {
+				c.blocks, c.samples, c.idle = nil, nil, nil
+				return false
+			}
+			return true
+		}
+		if len(c.blocks) == 0 {
+			if c.idle == nil {
+				return false
+			}
+			c.samples, c.next, c.idle = []Sample{*c.idle}, 0, nil
+			continue
+		}
+		decoded, err := c.blocks[0].Samples()
+		c.blocks = c.blocks[1:]
+		if err != nil {
+			decoded = nil
+		}
+		c.samples, c.next = decoded, 0
+	}
+}
+
+// current returns the sample advance moved to.
+func (c *trackCursor) current() Sample {
+	return c.samples[c.next-1]
+}
+
+// trackMerge is a heap of cursors ordered by their current sample's time, then by truck ID, so frames
+// come out oldest first with their positions ordered by truck.
+type trackMerge []*trackCursor
+
+func (m trackMerge) Len() int { return len(m) }
+
+func (m trackMerge) Less(i, j int) bool {
+	a, b := m[i].current().Time, m[j].current().Time
+	if !a.Equal(b) {
+		return a.Before(b)
+	}
+	return m[i].truckID < m[j].truckID
+}
+
+func (m trackMerge) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
+
+func (m *trackMerge) Push(x any) { *m = append(*m, x.(*trackCursor)) }
+
+func (m *trackMerge) Pop() any {
+	old := *m
+	c := old[len(old)-1]
+	*m = old[:len(old)-1]
+	return c
+}
+
+// Stats reports the number of trucks, samples and compressed bytes held.
+func (s *Store) Stats() Stats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.statsLocked()
+}
+
+func (s *Store) statsLocked() Stats {
+	stats := Stats{Trucks: len(s.trucks), Samples: len(s.idle)}
+	for _, blocks := range s.trucks {
+		for _, block := range blocks {
+			stats.Samples += block.Len()
+			stats.Bytes += block.Size()
+		}
+	}
+	return stats
+}
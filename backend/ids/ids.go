@@ -0,0 +1,118 @@
+// Package ids provides pluggable identifier generators for events and sessions.
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Generator produces unique identifiers.
+type Generator interface {
+	NewID() string
+}
+
+// New returns the generator for the named format: "ulid", "uuid", or "sequence".
+func New(format string) (Generator, error) {
+	switch format {
+	case "", "ulid":
+		return NewULIDGenerator(), nil
+	case "uuid":
+		return UUIDGenerator{}, nil
+	case "sequence":
+		return NewSequenceGenerator(""), nil
+	default:
+		return nil, fmt.Errorf("unknown id format %q", format)
+	}
+}
+
+// UUIDGenerator produces random version 4 UUIDs.
+type UUIDGenerator struct{}
+
+// NewID returns a random UUID.
+func (UUIDGenerator) NewID() string {
+	return uuid.NewString()
+}
+
+// SequenceGenerator produces monotonically increasing decimal IDs with an optional prefix.
+type SequenceGenerator struct {
+	prefix string
+	next   atomic.Uint64
+}
+
+// NewSequenceGenerator creates a sequence starting at 1.
+func NewSequenceGenerator(prefix string) *SequenceGenerator {
+	return &SequenceGenerator{prefix: prefix}
+}
+
+// NewID returns the next sequence number, zero-padded so IDs sort lexically.
+func (g *SequenceGenerator) NewID() string {
+	return fmt.Sprintf("%s%020d", g.prefix, g.next.Add(1))
+}
+
+// crockford is the ULID base32 alphabet.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDGenerator produces lexically sortable ULIDs. IDs generated within the same millisecond increment
+// the random component so ordering is preserved.
+type ULIDGenerator struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	lastMs  uint64
+	entropy [10]byte
+}
+
+// NewULIDGenerator creates a generator using the wall clock and crypto/rand entropy.
+func NewULIDGenerator() *ULIDGenerator {
+	return &ULIDGenerator{now: time.Now}
+}
+
+// NewID returns a 26-character ULID.
+func (g *ULIDGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(g.now().UnixMilli())
+	if ms <= g.lastMs {
+		ms = g.lastMs
+		incrementEntropy(&g.entropy)
+	} else {
+		g.lastMs = ms
+		_, _ = rand.Read(g.entropy[:])
+	}
+
+	var raw [16]byte
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], ms)
+	copy(raw[:6], ts[2:])
+	copy(raw[6:], g.entropy[:])
+	return encodeULID(raw)
+}
+
+func incrementEntropy(entropy *[10]byte) {
+	for i := len(entropy) - 1; i >= 0; i-- {
+		entropy[i]++
+		if entropy[i] != 0 {
+			return
+		}
+	}
+}
+
+// encodeULID renders 128 bits as 26 Crockford base32 characters, most significant bits first.
+func encodeULID(raw [16]byte) string {
+	hi := binary.BigEndian.Uint64(raw[:8])
+	lo := binary.BigEndian.Uint64(raw[8:])
+
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
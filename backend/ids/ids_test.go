@@ -0,0 +1,56 @@
+package ids
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestULIDsSortByCreationOrder(t *testing.T) {
+	gen := NewULIDGenerator()
+	fixed := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	gen.now = func() time.Time { return fixed }
+
+	generated := make([]string, 0, 100)
+	for i := 0; i < 100; i++ {
+		if i == 50 {
+			fixed = fixed.Add(time.Millisecond)
+		}
+		generated = append(generated, gen.NewID())
+	}
+
+	if len(generated[0]) != 26 {
+		t.Fatalf("expected 26 character ULID, got %q", generated[0])
+	}
+	if !sort.StringsAreSorted(generated) {
+		t.Fatalf("expected ULIDs to sort in creation order")
+	}
+	seen := make(map[string]struct{}, len(generated))
+	for _, id := range generated {
+		if _, dup := seen[id]; dup {
+			t.Fatalf("duplicate id %s", id)
+		}
+		seen[id] = struct{}{}
+	}
+}
+
+func TestULIDEncodesTimestampPrefix(t *testing.T) {
+	gen := NewULIDGenerator()
+	gen.now = func() time.Time { return time.UnixMilli(0) }
+	if id := gen.NewID(); id[:10] != "0000000000" {
+		t.Fatalf("expected zero timestamp prefix, got %s", id)
+	}
+}
+
+func TestNewSelectsFormat(t *testing.T) {
+	seq, err := New("sequence")
+	if err != nil {
+		t.Fatalf("sequence: %v", err)
+	}
+	if a, b := seq.NewID(), seq.NewID(); a >= b {
+		t.Fatalf("expected increasing sequence ids, got %s then %s", a, b)
+	}
+	if _, err := New("snowflake"); err == nil {
+		t.Fatalf("expected unknown format to fail")
+	}
+}
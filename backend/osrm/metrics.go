@@ -0,0 +1,19 @@
+package osrm
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "orbit_osrm_routes_total",
+		Help: "Routes asked of the OSRM planner by outcome: ok, cached, no_route, error, or rejected by the open circuit breaker.",
+	}, []string{"result"})
+
+	breakerOpen = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "orbit_osrm_circuit_open",
+		Help: "1 while the OSRM planner's circuit breaker is open after repeated failures, 0 otherwise.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(requests, breakerOpen)
+}
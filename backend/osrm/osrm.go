@@ -0,0 +1,242 @@
+// Package osrm plans road-following truck routes with an OSRM routing server.
+package osrm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"orbit/backend/simulation"
+)
+
+const (
+	// DefaultTimeout bounds a single route request.
+	DefaultTimeout = 2 * time.Second
+	// DefaultQPS is how many requests per second the planner sends at most.
+	DefaultQPS = 20
+	// DefaultRetries is how many times a request that failed for a transient reason is tried again.
+	DefaultRetries = 2
+	// DefaultFailureThreshold is how many consecutive failed routes open the circuit breaker.
+	DefaultFailureThreshold = 5
+	// DefaultCooldown is how long an open breaker stops calling the server before letting one probe
+	// through, so that an unreachable server costs a few timeouts rather than one per route.
+	DefaultCooldown = 30 * time.Second
+	// maxCachedRoutes bounds the route cache; it is cleared when full.
+	maxCachedRoutes = 4096
+	// retryBackoff is the wait before the first retry; it doubles for each one after.
+	retryBackoff = 100 * time.Millisecond
+)
+
+// Options tunes how a Planner talks to the server. Zero values take the defaults; a negative Retries
+// disables retries.
+type Options struct {
+	Timeout          time.Duration
+	QPS              float64
+	Retries          int
+	FailureThreshold int
+	Cooldown         time.Duration
+}
+
+// errNoRoute is OSRM's answer for points it cannot connect. The server is working, so it does not count
+// against the breaker and is not retried.
+var errNoRoute = errors.New("osrm: no route found")
+
+// Planner is a simulation.RoutePlanner backed by the OSRM route service. Routes are cached by their start
+// and end, since simulated fleets drive between a handful of endpoints over and over. Requests are spaced
+// to stay under the QPS limit, transient failures are retried, and a circuit breaker stops calling a
+// server that keeps failing. Plan blocks while it waits and retries, so run the planner behind a
+// simulation.PlanQueue rather than with Manager.WithRoutePlanner, which would hold the simulation lock.
+type Planner struct {
+	baseURL string
+	profile string
+	client  *http.Client
+	opts    Options
+
+	mu    sync.Mutex
+	cache map[[2]simulation.Point][]simulation.Point
+	// next is the earliest time the rate limit lets another request out.
+	next time.Time
+	// failures counts consecutive failed routes; the breaker is open until openUntil once it reaches the
+	// threshold, and then lets a single probe through.
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// New returns a planner for the OSRM server at baseURL, such as http://localhost:5000, using the driving
+// profile.
+func New(baseURL string, opts Options) *Planner {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.QPS <= 0 {
+		opts.QPS = DefaultQPS
+	}
+	if opts.Retries < 0 {
+		opts.Retries = 0
+	} else if opts.Retries == 0 {
+		opts.Retries = DefaultRetries
+	}
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = DefaultFailureThreshold
+	}
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = DefaultCooldown
+	}
+	return &Planner{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		profile: "driving",
+		client:  &http.Client{Timeout: opts.Timeout},
+		opts:    opts,
+		cache:   make(map[[2]simulation.Point][]simulation.Point),
+	}
+}
+
+type routeResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Routes  []struct {
+		Geometry struct {
+			Coordinates [][2]float64 `json:"coordinates"`
+		} `json:"geometry"`
+	} `json:"routes"`
+}
+
+// Plan returns the road route from start to end as waypoints along the simplified route geometry. The
+// route begins at start and finishes at end even where OSRM snaps them to the nearest road.
+func (p *Planner) Plan(start, end simulation.Point) ([]simulation.Point, error) {
+	key := [2]simulation.Point{start, end}
+	p.mu.Lock()
+	if cached, ok := p.cache[key]; ok {
+		p.mu.Unlock()
+		requests.WithLabelValues("cached").Inc()
+		return append([]simulation.Point(nil), cached...), nil
+	}
+	if !p.allowLocked(time.Now()) {
+		p.mu.Unlock()
+		requests.WithLabelValues("rejected").Inc()
+		return nil, fmt.Errorf("osrm: circuit open after repeated failures")
+	}
+	p.mu.Unlock()
+
+	waypoints, err := p.fetchWithRetries(start, end)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case errors.Is(err, errNoRoute):
+		p.recordLocked(true, time.Now())
+		requests.WithLabelValues("no_route").Inc()
+		return nil, err
+	case err != nil:
+		p.recordLocked(false, time.Now())
+		requests.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	p.recordLocked(true, time.Now())
+	requests.WithLabelValues("ok").Inc()
+	if len(p.cache) >= maxCachedRoutes {
+		p.cache = make(map[[2]simulation.Point][]simulation.Point)
+	}
+	p.cache[key] = waypoints
+	return append([]simulation.Point(nil), waypoints...), nil
+}
+
+// allowLocked reports whether the breaker lets a request through, claiming the probe when it is half open.
+func (p *Planner) allowLocked(now time.Time) bool {
+	if p.failures < p.opts.FailureThreshold {
+		return true
+	}
+	if now.Before(p.openUntil) || p.probing {
+		return false
+	}
+	p.probing = true
+	return true
+}
+
+// recordLocked updates the breaker with the outcome of a route request.
+func (p *Planner) recordLocked(ok bool, now time.Time) {
+	p.probing = false
+	if ok {
+		p.failures = 0
+		breakerOpen.Set(0)
+		return
+	}
+	p.failures++
+	if p.failures >= p.opts.FailureThreshold {
+		p.openUntil = now.Add(p.opts.Cooldown)
+		breakerOpen.Set(1)
+	}
+}
+
+// wait blocks until the rate limit lets the next request out.
+func (p *Planner) wait() {
+	p.mu.Lock()
+	now := time.Now()
+	at := p.next
+	if at.Before(now) {
+		at = now
+	}
+	p.next = at.Add(time.Duration(float64(time.Second) / p.opts.QPS))
+	p.mu.Unlock()
+	time.Sleep(time.Until(at))
+}
+
+func (p *Planner) fetchWithRetries(start, end simulation.Point) ([]simulation.Point, error) {
+	var err error
+	for attempt := 0; attempt <= p.opts.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(retryBackoff << (attempt - 1))
+		}
+		p.wait()
+		var waypoints []simulation.Point
+		var transient bool
+		waypoints, transient, err = p.fetch(start, end)
+		if err == nil || !transient {
+			return waypoints, err
+		}
+	}
+	return nil, err
+}
+
+// fetch requests one route. It reports whether a failure is worth retrying: network errors, timeouts,
+// rate limiting, and server errors are; bad requests and unroutable points are not.
+func (p *Planner) fetch(start, end simulation.Point) ([]simulation.Point, bool, error) {
+	url := fmt.Sprintf("%s/route/v1/%s/%f,%f;%f,%f?overview=simplified&geometries=geojson",
+		p.baseURL, p.profile, start.Lon, start.Lat, end.Lon, end.Lat)
+	resp, err := p.client.Get(url)
+	if err != nil {
+		return nil, true, fmt.Errorf("osrm: %w", err)
+	}
+	defer resp.Body.Close()
+
+	transient := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	var body routeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, transient, fmt.Errorf("osrm: %s: decode response: %w", resp.Status, err)
+	}
+	if body.Code == "NoRoute" || (body.Code == "Ok" && len(body.Routes) == 0) {
+		return nil, false, errNoRoute
+	}
+	if resp.StatusCode != http.StatusOK || body.Code != "Ok" {
+		return nil, transient, fmt.Errorf("osrm: %s: %s %s", resp.Status, body.Code, body.Message)
+	}
+
+	waypoints := []simulation.Point{start}
+	for _, c := range body.Routes[0].Geometry.Coordinates {
+		if p := (simulation.Point{Lat: c[1], Lon: c[0]}); p != waypoints[len(waypoints)-1] {
+			waypoints = append(waypoints, p)
+		}
+	}
+	if waypoints[len(waypoints)-1] != end {
+		waypoints = append(waypoints, end)
+	}
+	if len(waypoints) < 2 {
+		// Start and end coincide; drive nowhere rather than fail.
+		waypoints = append(waypoints, end)
+	}
+	return waypoints, false, nil
+}
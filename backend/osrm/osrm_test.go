@@ -0,0 +1,141 @@
+package osrm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"orbit/backend/simulation"
+)
+
+func TestPlannerFollowsRouteGeometry(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !strings.HasPrefix(r.URL.Path, "/route/v1/driving/-122.000000,47.000000;-122.100000,47.100000") {
+			t.Errorf("unexpected request path %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"code":"Ok","routes":[{"geometry":{"coordinates":[[-122.0001,47.0001],[-122.05,47.02],[-122.1,47.1]]}}]}`))
+	}))
+	defer srv.Close()
+
+	planner := New(srv.URL+"/", Options{})
+	start, end := simulation.Point{Lat: 47, Lon: -122}, simulation.Point{Lat: 47.1, Lon: -122.1}
+	route, err := planner.Plan(start, end)
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	want := []simulation.Point{start, {Lat: 47.0001, Lon: -122.0001}, {Lat: 47.02, Lon: -122.05}, end}
+	if len(route) != len(want) {
+		t.Fatalf("expected %v, got %v", want, route)
+	}
+	for i := range want {
+		if route[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, route)
+		}
+	}
+
+	route[1] = simulation.Point{}
+	again, err := planner.Plan(start, end)
+	if err != nil || again[1] != want[1] {
+		t.Fatalf("expected an untouched cached route, got %v (%v)", again, err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected the second plan to come from the cache, got %d requests", calls.Load())
+	}
+}
+
+func TestPlannerBreakerOpensAfterRepeatedFailures(t *testing.T) {
+	var calls atomic.Int32
+	var healthy atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if healthy.Load() {
+			_, _ = w.Write([]byte(`{"code":"Ok","routes":[{"geometry":{"coordinates":[[0,0],[1,1]]}}]}`))
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"code":"Unavailable"}`))
+	}))
+	defer srv.Close()
+
+	planner := New(srv.URL, Options{QPS: 1000, Retries: -1, FailureThreshold: 2, Cooldown: 50 * time.Millisecond})
+	plan := func(i int) error {
+		_, err := planner.Plan(simulation.Point{Lat: 47, Lon: -122}, simulation.Point{Lat: float64(i), Lon: 0})
+		return err
+	}
+	for i := 0; i < 4; i++ {
+		if err := plan(i); err == nil {
+			t.Fatalf("expected plan %d to fail", i)
+		}
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected the breaker to stop calling a failing server after 2 requests, got %d", calls.Load())
+	}
+
+	healthy.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if err := plan(10 + i); err != nil {
+			t.Fatalf("expected the probe after the cooldown to close the breaker: %v", err)
+		}
+	}
+	if calls.Load() != 4 {
+		t.Fatalf("expected one probe and one normal request, got %d requests", calls.Load())
+	}
+}
+
+func TestPlannerRetriesTransientFailuresOnly(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		switch {
+		case strings.Contains(r.URL.Path, ";0.000000,9.000000"):
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":"NoRoute","message":"Impossible route between points"}`))
+		case n < 3:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			_, _ = w.Write([]byte(`{"code":"Ok","routes":[{"geometry":{"coordinates":[[0,0],[1,1]]}}]}`))
+		}
+	}))
+	defer srv.Close()
+
+	planner := New(srv.URL, Options{QPS: 1000, FailureThreshold: 1})
+	if _, err := planner.Plan(simulation.Point{}, simulation.Point{Lat: 1, Lon: 1}); err != nil {
+		t.Fatalf("expected the third attempt to succeed: %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("expected two retries, got %d requests", calls.Load())
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := planner.Plan(simulation.Point{}, simulation.Point{Lat: 9}); err == nil {
+			t.Fatalf("expected an unroutable plan to fail")
+		}
+	}
+	// Unroutable points are neither retried nor held against the server.
+	if calls.Load() != 5 {
+		t.Fatalf("expected one request per unroutable plan, got %d requests", calls.Load())
+	}
+}
+
+func TestPlannerSpacesRequestsUnderTheRateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":"Ok","routes":[{"geometry":{"coordinates":[[0,0],[1,1]]}}]}`))
+	}))
+	defer srv.Close()
+
+	planner := New(srv.URL, Options{QPS: 20})
+	began := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := planner.Plan(simulation.Point{}, simulation.Point{Lat: float64(i + 1)}); err != nil {
+			t.Fatalf("plan %d failed: %v", i, err)
+		}
+	}
+	if elapsed := time.Since(began); elapsed < 90*time.Millisecond {
+		t.Fatalf("expected 3 requests at 20 per second to take at least 100ms, took %s", elapsed)
+	}
+}
@@ -0,0 +1,210 @@
+// Package profiles stores named /ws/trucks subscription profiles, so a wall display or kiosk can connect
+// with ?profile=ops-wall instead of carrying its filters and stream options in every URL. Profiles are
+// kept in a JSON file and survive restarts.
+package profiles
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for a profile that does not exist.
+	ErrNotFound = errors.New("profile not found")
+	// ErrInvalidName is returned for a name that is not 1 to 64 lowercase letters, digits, dots, dashes,
+	// or underscores starting with a letter or digit.
+	ErrInvalidName = errors.New("profile names must be 1 to 64 lowercase letters, digits, '.', '-', or '_'")
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// Filters narrow the trucks a subscription receives, with the meaning of the /api/trucks parameters of the
+// same names. A non-nil empty Fleet selects the home fleet.
+type Filters struct {
+	BBox    string  `json:"bbox,omitempty"`
+	Status  string  `json:"status,omitempty"`
+	Type    string  `json:"type,omitempty"`
+	Geohash string  `json:"geohash,omitempty"`
+	Fleet   *string `json:"fleet,omitempty"`
+}
+
+// Profile is a saved subscription. Fields limits each truck to the named fields, IntervalMs sets how often
+// snapshots are sent, and Projection, Polyline, Trail, and TrailEvery match the /ws/trucks parameters.
+// Owner is the subject of the caller who saved it, empty when the server has no authentication.
+type Profile struct {
+	Name       string    `json:"name"`
+	Owner      string    `json:"owner,omitempty"`
+	Filters    Filters   `json:"filters"`
+	Fields     []string  `json:"fields,omitempty"`
+	IntervalMs int       `json:"intervalMs,omitempty"`
+	Projection string    `json:"projection,omitempty"`
+	Polyline   bool      `json:"polyline,omitempty"`
+	Trail      int       `json:"trail,omitempty"`
+	TrailEvery int       `json:"trailEvery,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// ValidName reports whether name can name a profile.
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Query returns the profile as /ws/trucks query parameters.
+func (p Profile) Query() url.Values {
+	q := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	set("bbox", p.Filters.BBox)
+	set("status", p.Filters.Status)
+	set("type", p.Filters.Type)
+	set("geohash", p.Filters.Geohash)
+	if p.Filters.Fleet != nil {
+		q.Set("fleet", *p.Filters.Fleet)
+	}
+	set("fields", strings.Join(p.Fields, ","))
+	if p.IntervalMs > 0 {
+		q.Set("interval", (time.Duration(p.IntervalMs) * time.Millisecond).String())
+	}
+	set("projection", p.Projection)
+	if p.Polyline {
+		q.Set("polyline", "1")
+	}
+	if p.Trail > 0 {
+		q.Set("trail", strconv.Itoa(p.Trail))
+	}
+	if p.TrailEvery > 0 {
+		q.Set("trailEvery", strconv.Itoa(p.TrailEvery))
+	}
+	return q
+}
+
+// Store holds profiles in memory and, when it has a path, writes them to that file on every change.
+type Store struct {
+	path string
+
+	mu       sync.RWMutex
+	profiles map[string]Profile
+}
+
+// NewStore loads the profiles saved at path. An empty path keeps profiles in memory only, and a missing
+// file starts empty.
+func NewStore(path string) (*Store, error) {
+	s := &Store{path: path, profiles: make(map[string]Profile)}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var saved []Profile
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("read profiles %s: %w", path, err)
+	}
+	for _, p := range saved {
+		s.profiles[p.Name] = p
+	}
+	return s, nil
+}
+
+// Get returns the named profile.
+func (s *Store) Get(name string) (Profile, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.profiles[name]
+	return p, ok
+}
+
+// List returns every profile sorted by name.
+func (s *Store) List() []Profile {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sortedLocked()
+}
+
+// Put saves p, replacing any profile of the same name.
+func (s *Store) Put(p Profile) error {
+	if !ValidName(p.Name) {
+		return ErrInvalidName
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, existed := s.profiles[p.Name]
+	s.profiles[p.Name] = p
+	if err := s.saveLocked(); err != nil {
+		if existed {
+			s.profiles[p.Name] = previous
+		} else {
+			delete(s.profiles, p.Name)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes the named profile.
+func (s *Store) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, ok := s.profiles[name]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.profiles, name)
+	if err := s.saveLocked(); err != nil {
+		s.profiles[name] = previous
+		return err
+	}
+	return nil
+}
+
+func (s *Store) sortedLocked() []Profile {
+	list := make([]Profile, 0, len(s.profiles))
+	for _, p := range s.profiles {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// saveLocked writes the profiles through a temporary file in the same directory.
+func (s *Store) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.sortedLocked(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
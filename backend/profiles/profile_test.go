@@ -0,0 +1,48 @@
+package profiles
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestStorePersistsProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	home := ""
+	wall := Profile{Name: "ops-wall", Owner: "vera", Filters: Filters{Status: "en_route", Fleet: &home}, Fields: []string{"Lat", "Lon"}, IntervalMs: 5000}
+	if err := store.Put(wall); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := store.Put(Profile{Name: "kiosk"}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := store.Put(Profile{Name: "Ops Wall"}); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("expected an invalid name, got %v", err)
+	}
+
+	reloaded, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	list := reloaded.List()
+	if len(list) != 2 || list[0].Name != "kiosk" || list[1].Name != "ops-wall" || list[1].Owner != "vera" {
+		t.Fatalf("expected both profiles back sorted by name, got %+v", list)
+	}
+	if q := list[1].Query(); q.Get("status") != "en_route" || !q.Has("fleet") || q.Get("fields") != "Lat,Lon" || q.Get("interval") != "5s" || q.Has("trail") {
+		t.Fatalf("unexpected query %v", q)
+	}
+
+	if err := reloaded.Delete("kiosk"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := reloaded.Delete("kiosk"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if again, _ := NewStore(path); len(again.List()) != 1 {
+		t.Fatalf("expected the delete to persist, got %+v", again.List())
+	}
+}
@@ -0,0 +1,168 @@
+// Package redact scrubs sensitive values from structured logs before they are written.
+package redact
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Replacement is written in place of redacted values.
+const Replacement = "[REDACTED]"
+
+// DefaultHeaders are always redacted from logged HTTP headers.
+var DefaultHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// DefaultFields are always redacted from log attributes and logged query strings. A field is redacted when
+// its name contains one of them, so "token" also covers access_token and refreshToken.
+var DefaultFields = []string{"password", "secret", "token", "session", "authorization", "key"}
+
+// Hook rewrites an attribute before it is logged. Hooks run after the deny-lists and can scrub values
+// the deny-lists cannot express, such as coordinates or free-form identifiers.
+type Hook func(groups []string, a slog.Attr) slog.Attr
+
+// Redactor holds the header and field deny-lists plus any custom scrubbing hooks.
+type Redactor struct {
+	headers map[string]struct{}
+	fields  map[string]struct{}
+	hooks   []Hook
+}
+
+// New creates a Redactor that denies the defaults plus the given header and field names. Headers match by
+// name and fields by substring, both case-insensitively.
+func New(headers, fields []string) *Redactor {
+	r := &Redactor{headers: make(map[string]struct{}), fields: make(map[string]struct{})}
+	for _, h := range append(append([]string(nil), DefaultHeaders...), headers...) {
+		if h = strings.TrimSpace(h); h != "" {
+			r.headers[http.CanonicalHeaderKey(h)] = struct{}{}
+		}
+	}
+	for _, f := range append(append([]string(nil), DefaultFields...), fields...) {
+		if f = strings.TrimSpace(f); f != "" {
+			r.fields[strings.ToLower(f)] = struct{}{}
+		}
+	}
+	return r
+}
+
+// WithHook appends a scrubbing hook.
+func (r *Redactor) WithHook(h Hook) *Redactor {
+	if h != nil {
+		r.hooks = append(r.hooks, h)
+	}
+	return r
+}
+
+// Header returns a copy of h with denied header values replaced.
+func (r *Redactor) Header(h http.Header) http.Header {
+	out := h.Clone()
+	for name, values := range out {
+		if _, ok := r.headers[http.CanonicalHeaderKey(name)]; ok {
+			out[name] = redactAll(values)
+		}
+	}
+	return out
+}
+
+// Query returns a copy of v with denied parameters replaced.
+func (r *Redactor) Query(v url.Values) url.Values {
+	out := make(url.Values, len(v))
+	for name, values := range v {
+		if r.deniedField(name) {
+			out[name] = redactAll(values)
+			continue
+		}
+		out[name] = append([]string(nil), values...)
+	}
+	return out
+}
+
+// Attr applies the field deny-list and hooks to a single attribute, descending into groups.
+func (r *Redactor) Attr(groups []string, a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if r.deniedField(a.Key) {
+		a.Value = slog.StringValue(Replacement)
+	} else {
+		switch v := a.Value.Any().(type) {
+		case http.Header:
+			a.Value = slog.AnyValue(r.Header(v))
+		case url.Values:
+			a.Value = slog.AnyValue(r.Query(v))
+		}
+		if a.Value.Kind() == slog.KindGroup {
+			nested := append(append([]string(nil), groups...), a.Key)
+			attrs := a.Value.Group()
+			scrubbed := make([]slog.Attr, len(attrs))
+			for i, child := range attrs {
+				scrubbed[i] = r.Attr(nested, child)
+			}
+			a.Value = slog.GroupValue(scrubbed...)
+		}
+	}
+	for _, hook := range r.hooks {
+		a = hook(groups, a)
+	}
+	return a
+}
+
+func (r *Redactor) deniedField(name string) bool {
+	name = strings.ToLower(name)
+	for field := range r.fields {
+		if strings.Contains(name, field) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactAll(values []string) []string {
+	out := make([]string, len(values))
+	for i := range out {
+		out[i] = Replacement
+	}
+	return out
+}
+
+// Handler is a slog.Handler that redacts attributes before delegating to the next handler.
+type Handler struct {
+	next     slog.Handler
+	redactor *Redactor
+	groups   []string
+}
+
+// NewHandler wraps next so every record and pre-bound attribute passes through the redactor.
+func NewHandler(next slog.Handler, r *Redactor) *Handler {
+	return &Handler{next: next, redactor: r}
+}
+
+// Enabled reports whether the wrapped handler handles records at the given level.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle redacts the record's attributes and forwards it.
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	scrubbed := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(a slog.Attr) bool {
+		scrubbed.AddAttrs(h.redactor.Attr(h.groups, a))
+		return true
+	})
+	return h.next.Handle(ctx, scrubbed)
+}
+
+// WithAttrs redacts attributes bound to a derived logger.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	scrubbed := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		scrubbed[i] = h.redactor.Attr(h.groups, a)
+	}
+	return &Handler{next: h.next.WithAttrs(scrubbed), redactor: h.redactor, groups: h.groups}
+}
+
+// WithGroup tracks the group so hooks see the full attribute path.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	groups := append(append([]string(nil), h.groups...), name)
+	return &Handler{next: h.next.WithGroup(name), redactor: h.redactor, groups: groups}
+}
@@ -0,0 +1,74 @@
+package redact
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestHandlerRedactsDeniedFieldsHeadersAndQuery(t *testing.T) {
+	var buf bytes.Buffer
+	r := New([]string{"X-Driver-Phone"}, []string{"driver_name"})
+	logger := slog.New(NewHandler(slog.NewJSONHandler(&buf, nil), r))
+
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer abc123")
+	headers.Set("X-Driver-Phone", "+15551234567")
+	headers.Set("Accept", "application/json")
+
+	logger.With("token", "tok-1").Info("request",
+		"driver_name", "Jane Doe",
+		"headers", headers,
+		"query", url.Values{"session": {"s-42"}, "status": {"idle"}},
+		slog.Group("auth", "password", "hunter2"),
+	)
+
+	out := buf.String()
+	for _, secret := range []string{"abc123", "+15551234567", "Jane Doe", "s-42", "hunter2", "tok-1"} {
+		if strings.Contains(out, secret) {
+			t.Fatalf("expected %q to be redacted: %s", secret, out)
+		}
+	}
+	for _, kept := range []string{"application/json", "idle"} {
+		if !strings.Contains(out, kept) {
+			t.Fatalf("expected %q to be kept: %s", kept, out)
+		}
+	}
+}
+
+func TestHooksScrubCustomValues(t *testing.T) {
+	var buf bytes.Buffer
+	r := New(nil, nil).WithHook(func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == "lat" || a.Key == "lon" {
+			return slog.Float64(a.Key, 0)
+		}
+		return a
+	})
+	logger := slog.New(NewHandler(slog.NewTextHandler(&buf, nil), r))
+
+	logger.WithGroup("truck").Info("position", "lat", 52.52, "lon", 13.405)
+
+	if strings.Contains(buf.String(), "52.52") || strings.Contains(buf.String(), "13.405") {
+		t.Fatalf("expected coordinates to be scrubbed: %s", buf.String())
+	}
+}
+
+func TestFieldsMatchBySubstring(t *testing.T) {
+	r := New(nil, []string{"Phone"})
+	q := r.Query(url.Values{
+		"access_token":  {"eyJhbGciOi"},
+		"refreshToken":  {"r-1"},
+		"client_secret": {"c-1"},
+		"X-Api-Key":     {"k-1"},
+		"driver_phone":  {"+15551234567"},
+		"status":        {"idle"},
+	})
+	for name, values := range q {
+		if redacted := values[0] == Replacement; redacted == (name == "status") {
+			t.Fatalf("%s: unexpected value %q", name, values[0])
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Package roadnetwork routes trucks along a road graph loaded from an OpenStreetMap extract, so that they
+// follow real streets without an external routing service.
+package roadnetwork
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+
+	"orbit/backend/simulation"
+)
+
+// cellDegrees is the size of the grid cells used to find the node nearest a point, about 1 km.
+const cellDegrees = 0.01
+
+type edge struct {
+	to     int
+	meters float64
+}
+
+type cell struct{ lat, lon int }
+
+// Graph is a directed road graph. Nodes are road vertices and edges the road segments between them,
+// weighted by their length.
+type Graph struct {
+	nodes []simulation.Point
+	edges [][]edge
+	index map[simulation.Point]int
+	grid  map[cell][]int
+}
+
+// NewGraph returns an empty graph.
+func NewGraph() *Graph {
+	return &Graph{index: make(map[simulation.Point]int), grid: make(map[cell][]int)}
+}
+
+// Nodes returns the number of nodes in the graph.
+func (g *Graph) Nodes() int {
+	return len(g.nodes)
+}
+
+// Edges returns the number of directed edges in the graph.
+func (g *Graph) Edges() int {
+	n := 0
+	for _, e := range g.edges {
+		n += len(e)
+	}
+	return n
+}
+
+// AddRoad adds a road through the given points. Points shared with roads already added become junctions.
+// A oneway road can only be driven in the order given.
+func (g *Graph) AddRoad(points []simulation.Point, oneway bool) {
+	for i := 1; i < len(points); i++ {
+		from, to := g.node(points[i-1]), g.node(points[i])
+		if from == to {
+			continue
+		}
+		meters := simulation.GreatCircleDistance(points[i-1], points[i])
+		g.edges[from] = append(g.edges[from], edge{to: to, meters: meters})
+		if !oneway {
+			g.edges[to] = append(g.edges[to], edge{to: from, meters: meters})
+		}
+	}
+}
+
+func (g *Graph) node(p simulation.Point) int {
+	if id, ok := g.index[p]; ok {
+		return id
+	}
+	id := len(g.nodes)
+	g.nodes = append(g.nodes, p)
+	g.edges = append(g.edges, nil)
+	g.index[p] = id
+	c := cellOf(p)
+	g.grid[c] = append(g.grid[c], id)
+	return id
+}
+
+func cellOf(p simulation.Point) cell {
+	return cell{lat: int(math.Floor(p.Lat / cellDegrees)), lon: int(math.Floor(p.Lon / cellDegrees))}
+}
+
+// Nearest returns the node closest to p, searching outwards ring by ring through the grid. It reports
+// false for an empty graph.
+func (g *Graph) Nearest(p simulation.Point) (int, bool) {
+	if len(g.nodes) == 0 {
+		return 0, false
+	}
+	center := cellOf(p)
+	best, bestMeters := -1, math.Inf(1)
+	for ring := 0; ; ring++ {
+		for dLat := -ring; dLat <= ring; dLat++ {
+			for dLon := -ring; dLon <= ring; dLon++ {
+				if max(abs(dLat), abs(dLon)) != ring {
+					continue
+				}
+				for _, id := range g.grid[cell{lat: center.lat + dLat, lon: center.lon + dLon}] {
+					if d := simulation.GreatCircleDistance(p, g.nodes[id]); d < bestMeters {
+						best, bestMeters = id, d
+					}
+				}
+			}
+		}
+		// Nodes in the next ring are at least ring cells away, so stop once that is further than the best
+		// node so far. Far from every road, fall back to scanning all nodes.
+		if best >= 0 && float64(ring)*cellDegrees*111320*math.Cos(p.Lat*math.Pi/180) > bestMeters {
+			return best, true
+		}
+		if ring > 200 {
+			break
+		}
+	}
+	for id, n := range g.nodes {
+		if d := simulation.GreatCircleDistance(p, n); d < bestMeters {
+			best, bestMeters = id, d
+		}
+	}
+	return best, true
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// ShortestPath returns the nodes along the shortest route from one node to another and its length in
+// metres, found with A* search using great-circle distance as the heuristic.
+func (g *Graph) ShortestPath(from, to int) ([]simulation.Point, float64, error) {
+	if from < 0 || from >= len(g.nodes) || to < 0 || to >= len(g.nodes) {
+		return nil, 0, fmt.Errorf("node out of range")
+	}
+	dist := map[int]float64{from: 0}
+	prev := make(map[int]int)
+	open := &queue{{node: from, priority: simulation.GreatCircleDistance(g.nodes[from], g.nodes[to])}}
+	done := make(map[int]bool)
+	for open.Len() > 0 {
+		current := heap.Pop(open).(item).node
+		if current == to {
+			break
+		}
+		if done[current] {
+			continue
+		}
+		done[current] = true
+		for _, e := range g.edges[current] {
+			d := dist[current] + e.meters
+			if known, ok := dist[e.to]; ok && known <= d {
+				continue
+			}
+			dist[e.to], prev[e.to] = d, current
+			heap.Push(open, item{node: e.to, priority: d + simulation.GreatCircleDistance(g.nodes[e.to], g.nodes[to])})
+		}
+	}
+	total, ok := dist[to]
+	if !ok {
+		return nil, 0, fmt.Errorf("no road route between %v and %v", g.nodes[from], g.nodes[to])
+	}
+	path := []simulation.Point{g.nodes[to]}
+	for n := to; n != from; {
+		n = prev[n]
+		path = append(path, g.nodes[n])
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, total, nil
+}
+
+type item struct {
+	node     int
+	priority float64
+}
+
+type queue []item
+
+func (q queue) Len() int           { return len(q) }
+func (q queue) Less(i, j int) bool { return q[i].priority < q[j].priority }
+func (q queue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *queue) Push(x any)        { *q = append(*q, x.(item)) }
+func (q *queue) Pop() any {
+	old := *q
+	it := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return it
+}
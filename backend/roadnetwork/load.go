@@ -0,0 +1,117 @@
+package roadnetwork
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"orbit/backend/simulation"
+)
+
+// nonRoads are OSM highway values that trucks cannot drive on.
+var nonRoads = map[string]bool{
+	"footway": true, "path": true, "cycleway": true, "steps": true, "pedestrian": true,
+	"bridleway": true, "corridor": true, "elevator": true, "platform": true, "proposed": true,
+	"construction": true,
+}
+
+type featureCollection struct {
+	Type     string `json:"type"`
+	Features []struct {
+		Geometry struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+		Properties map[string]any `json:"properties"`
+	} `json:"features"`
+}
+
+// LoadFile loads a road graph from a GeoJSON file. OSM PBF extracts are not read directly; convert them
+// first, for example with `osmium export extract.osm.pbf -o roads.geojson`.
+func LoadFile(path string) (*Graph, error) {
+	if strings.HasSuffix(path, ".pbf") {
+		return nil, fmt.Errorf("%s: PBF extracts are not supported; convert to GeoJSON with osmium export", filepath.Base(path))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadGeoJSON(f)
+}
+
+// LoadGeoJSON builds a road graph from a GeoJSON FeatureCollection of LineString and MultiLineString
+// roads, as exported from OSM. Features tagged with a highway value trucks cannot use, such as footway,
+// are skipped, and the oneway tag is honoured. Other geometries are ignored.
+func LoadGeoJSON(r io.Reader) (*Graph, error) {
+	var fc featureCollection
+	if err := json.NewDecoder(r).Decode(&fc); err != nil {
+		return nil, fmt.Errorf("decode GeoJSON: %w", err)
+	}
+	if fc.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("expected a GeoJSON FeatureCollection, got %q", fc.Type)
+	}
+
+	g := NewGraph()
+	for i, f := range fc.Features {
+		if highway, _ := f.Properties["highway"].(string); nonRoads[highway] {
+			continue
+		}
+		var lines [][][]float64
+		switch f.Geometry.Type {
+		case "LineString":
+			var line [][]float64
+			if err := json.Unmarshal(f.Geometry.Coordinates, &line); err != nil {
+				return nil, fmt.Errorf("feature %d: %w", i, err)
+			}
+			lines = [][][]float64{line}
+		case "MultiLineString":
+			if err := json.Unmarshal(f.Geometry.Coordinates, &lines); err != nil {
+				return nil, fmt.Errorf("feature %d: %w", i, err)
+			}
+		default:
+			continue
+		}
+
+		forward, oneway := onewayOf(f.Properties["oneway"])
+		for _, line := range lines {
+			points := make([]simulation.Point, 0, len(line))
+			for _, c := range line {
+				if len(c) < 2 {
+					return nil, fmt.Errorf("feature %d: coordinate needs longitude and latitude", i)
+				}
+				points = append(points, simulation.Point{Lat: c[1], Lon: c[0]})
+			}
+			if !forward {
+				for a, b := 0, len(points)-1; a < b; a, b = a+1, b-1 {
+					points[a], points[b] = points[b], points[a]
+				}
+			}
+			g.AddRoad(points, oneway)
+		}
+	}
+	if g.Nodes() == 0 {
+		return nil, fmt.Errorf("no roads found")
+	}
+	return g, nil
+}
+
+// onewayOf interprets an OSM oneway tag. It reports whether the road runs in the order of its points and
+// whether it is one way.
+func onewayOf(tag any) (forward, oneway bool) {
+	switch v := tag.(type) {
+	case bool:
+		return true, v
+	case string:
+		switch v {
+		case "yes", "true", "1":
+			return true, true
+		case "-1", "reverse":
+			return false, true
+		}
+	}
+	return true, false
+}
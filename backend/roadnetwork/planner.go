@@ -0,0 +1,65 @@
+package roadnetwork
+
+import (
+	"fmt"
+	"sync"
+
+	"orbit/backend/simulation"
+)
+
+// maxCachedPaths bounds the path cache; it is cleared when full.
+const maxCachedPaths = 4096
+
+// Planner is a simulation.RoutePlanner that routes along a road graph. Start and end points are joined to
+// the nearest road nodes, and paths are cached by those nodes.
+type Planner struct {
+	graph *Graph
+
+	mu    sync.Mutex
+	cache map[[2]int][]simulation.Point
+}
+
+// NewPlanner returns a planner for the graph.
+func NewPlanner(g *Graph) *Planner {
+	return &Planner{graph: g, cache: make(map[[2]int][]simulation.Point)}
+}
+
+// Plan returns start, the road nodes along the shortest path between the nodes nearest start and end, and
+// end. It fails when the two nodes are not connected.
+func (p *Planner) Plan(start, end simulation.Point) ([]simulation.Point, error) {
+	from, ok := p.graph.Nearest(start)
+	if !ok {
+		return nil, fmt.Errorf("road network is empty")
+	}
+	to, _ := p.graph.Nearest(end)
+
+	key := [2]int{from, to}
+	p.mu.Lock()
+	path, ok := p.cache[key]
+	p.mu.Unlock()
+	if !ok {
+		var err error
+		path, _, err = p.graph.ShortestPath(from, to)
+		if err != nil {
+			return nil, err
+		}
+		p.mu.Lock()
+		if len(p.cache) >= maxCachedPaths {
+			p.cache = make(map[[2]int][]simulation.Point)
+		}
+		p.cache[key] = path
+		p.mu.Unlock()
+	}
+
+	waypoints := make([]simulation.Point, 0, len(path)+2)
+	waypoints = append(waypoints, start)
+	for _, n := range path {
+		if n != waypoints[len(waypoints)-1] {
+			waypoints = append(waypoints, n)
+		}
+	}
+	if end != waypoints[len(waypoints)-1] || len(waypoints) < 2 {
+		waypoints = append(waypoints, end)
+	}
+	return waypoints, nil
+}
@@ -0,0 +1,92 @@
+package roadnetwork
+
+import (
+	"strings"
+	"testing"
+
+	"orbit/backend/simulation"
+)
+
+const grid = `{"type":"FeatureCollection","features":[
+{"type":"Feature","properties":{"highway":"residential"},"geometry":{"type":"LineString","coordinates":[[0,0],[0.01,0],[0.02,0]]}},
+{"type":"Feature","properties":{"highway":"residential"},"geometry":{"type":"LineString","coordinates":[[0.02,0],[0.02,0.01]]}},
+{"type":"Feature","properties":{"highway":"primary","oneway":"yes"},"geometry":{"type":"LineString","coordinates":[[0,0],[0,0.01],[0.02,0.01]]}},
+{"type":"Feature","properties":{"highway":"footway"},"geometry":{"type":"LineString","coordinates":[[0,0],[0.02,0.01]]}},
+{"type":"Feature","properties":{},"geometry":{"type":"Point","coordinates":[5,5]}}
+]}`
+
+func loadGrid(t *testing.T) *Graph {
+	t.Helper()
+	g, err := LoadGeoJSON(strings.NewReader(grid))
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	return g
+}
+
+func TestLoadGeoJSONSkipsFootways(t *testing.T) {
+	g := loadGrid(t)
+	if g.Nodes() != 5 {
+		t.Fatalf("expected 5 nodes, got %d", g.Nodes())
+	}
+	// Two two-way residential roads with three segments, plus one-way primary with two.
+	if g.Edges() != 8 {
+		t.Fatalf("expected 8 edges, got %d", g.Edges())
+	}
+}
+
+func TestPlannerFollowsOneWayRoads(t *testing.T) {
+	planner := NewPlanner(loadGrid(t))
+	origin := simulation.Point{Lat: 0, Lon: 0}
+	corner := simulation.Point{Lat: 0.01, Lon: 0.02}
+
+	// The one-way road is the shorter way there, but only in its own direction.
+	there, err := planner.Plan(origin, corner)
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	if len(there) != 3 || there[1] != (simulation.Point{Lat: 0.01, Lon: 0}) {
+		t.Fatalf("expected the one-way road, got %v", there)
+	}
+	back, err := planner.Plan(corner, origin)
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	if len(back) != 4 || back[1] != (simulation.Point{Lat: 0, Lon: 0.02}) {
+		t.Fatalf("expected the two-way roads back, got %v", back)
+	}
+}
+
+func TestPlannerSnapsEndpointsToRoads(t *testing.T) {
+	planner := NewPlanner(loadGrid(t))
+	start := simulation.Point{Lat: -0.001, Lon: 0.0101}
+	end := simulation.Point{Lat: 0.011, Lon: 0.021}
+	route, err := planner.Plan(start, end)
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	if route[0] != start || route[len(route)-1] != end {
+		t.Fatalf("expected the route to start and end at the requested points, got %v", route)
+	}
+	if route[1] != (simulation.Point{Lat: 0, Lon: 0.01}) {
+		t.Fatalf("expected the start snapped to the nearest node, got %v", route[1])
+	}
+}
+
+func TestPlannerFailsBetweenDisconnectedRoads(t *testing.T) {
+	g := NewGraph()
+	g.AddRoad([]simulation.Point{{Lat: 0, Lon: 0}, {Lat: 0, Lon: 0.01}}, false)
+	g.AddRoad([]simulation.Point{{Lat: 1, Lon: 1}, {Lat: 1, Lon: 1.01}}, false)
+	if _, err := NewPlanner(g).Plan(simulation.Point{Lat: 0, Lon: 0}, simulation.Point{Lat: 1, Lon: 1}); err == nil {
+		t.Fatal("expected an error between disconnected roads")
+	}
+}
+
+func TestLoadRejectsPBFAndEmptyExtracts(t *testing.T) {
+	if _, err := LoadFile("extract.osm.pbf"); err == nil || !strings.Contains(err.Error(), "osmium") {
+		t.Fatalf("expected a conversion hint for PBF, got %v", err)
+	}
+	if _, err := LoadGeoJSON(strings.NewReader(`{"type":"FeatureCollection","features":[]}`)); err == nil {
+		t.Fatal("expected an error for an extract without roads")
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"orbit/backend/events"
+)
+
+// WithAlerts serves the alerts raised in inbox on /api/alerts, with acknowledge and resolve actions.
+func (s *Server) WithAlerts(inbox *events.Inbox) *Server {
+	s.alerts = inbox
+	return s
+}
+
+type alertsResponse struct {
+	Alerts []events.Alert `json:"alerts"`
+}
+
+type alertActionRequest struct {
+	By string `json:"by"`
+}
+
+// handleAlerts serves GET /api/alerts, newest first. ?state= limits it to open, acknowledged, or resolved
+// alerts and ?severity= drops alerts below that severity.
+func (s *Server) handleAlerts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	state := events.AlertState(query.Get("state"))
+	switch state {
+	case "", events.AlertOpen, events.AlertAcknowledged, events.AlertResolved:
+	default:
+		http.Error(w, "state must be open, acknowledged, or resolved", http.StatusBadRequest)
+		return
+	}
+	minSeverity := events.SeverityInfo
+	if v := query.Get("severity"); v != "" {
+		severity, err := events.ParseSeverity(v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		minSeverity = severity
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(alertsResponse{Alerts: s.alerts.Alerts(state, minSeverity)})
+}
+
+// handleAlert serves POST /api/alerts/{id}/acknowledge and POST /api/alerts/{id}/resolve. The optional
+// body names the operator as {"by": "..."}, which defaults to the authenticated caller.
+func (s *Server) handleAlert(w http.ResponseWriter, r *http.Request) {
+	id, action, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/alerts/"), "/")
+	if !ok || id == "" || (action != "acknowledge" && action != "resolve") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req alertActionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if principal, ok := principalFromContext(r.Context()); ok && req.By == "" {
+		req.By = principal.Subject
+	}
+
+	var (
+		alert events.Alert
+		err   error
+	)
+	if action == "acknowledge" {
+		alert, err = s.alerts.Acknowledge(id, req.By)
+	} else {
+		alert, err = s.alerts.Resolve(id, req.By)
+	}
+	switch {
+	case errors.Is(err, events.ErrAlertNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(alert)
+}
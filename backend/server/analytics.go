@@ -0,0 +1,261 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"orbit/backend/analytics"
+)
+
+type clusterSummary struct {
+	ID              int     `json:"id"`
+	Size            int     `json:"size"`
+	AverageSpeed    float64 `json:"averageSpeed"`
+	StoppedFraction float64 `json:"stoppedFraction"`
+}
+
+type clustersResponse struct {
+	ComputedAt  time.Time        `json:"computedAt"`
+	Clusters    []clusterSummary `json:"clusters"`
+	Assignments map[string]int   `json:"assignments"`
+}
+
+func (s *Server) handleClusters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if s.clusters == nil {
+		http.Error(w, "behaviour clustering is disabled", http.StatusNotFound)
+		return
+	}
+
+	report := s.clusters.Report()
+	resp := clustersResponse{
+		ComputedAt:  report.ComputedAt,
+		Clusters:    make([]clusterSummary, 0, len(report.Clusters)),
+		Assignments: report.Assignments,
+	}
+	if resp.Assignments == nil {
+		resp.Assignments = map[string]int{}
+	}
+	for _, c := range report.Clusters {
+		resp.Clusters = append(resp.Clusters, clusterSummary{
+			ID:              c.ID,
+			Size:            c.Size,
+			AverageSpeed:    c.AverageSpeed,
+			StoppedFraction: c.StoppedFraction,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+type leaderboardEntry struct {
+	Rank    int     `json:"rank"`
+	TruckID string  `json:"truckId"`
+	Value   float64 `json:"value"`
+	Trips   int     `json:"trips"`
+}
+
+type leaderboardResponse struct {
+	Metric  string             `json:"metric"`
+	Since   time.Time          `json:"since"`
+	Entries []leaderboardEntry `json:"entries"`
+}
+
+// handleLeaderboard ranks trucks over completed trips in a simulated-time window ending now.
+func (s *Server) handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	metric := analytics.LeaderboardMetric(query.Get("metric"))
+	if metric == "" {
+		metric = analytics.LeaderboardDistance
+	}
+	window := 24 * time.Hour
+	if v := query.Get("window"); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "window must be a positive duration", http.StatusBadRequest)
+			return
+		}
+		window = parsed
+	}
+	limit := 10
+	if v := query.Get("limit"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	since := s.sim.SimulatedTime().Add(-window)
+	entries, err := analytics.Leaderboard(s.sim.Trips(""), since, metric, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := leaderboardResponse{Metric: string(metric), Since: since, Entries: make([]leaderboardEntry, 0, len(entries))}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, leaderboardEntry{Rank: e.Rank, TruckID: e.TruckID, Value: e.Value, Trips: e.Trips})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+type speedComplianceEntry struct {
+	TruckID               string  `json:"truckId"`
+	SpeedLimit            float64 `json:"speedLimit"`
+	Governed              bool    `json:"governed"`
+	Violations            int     `json:"violations"`
+	OverLimitSeconds      float64 `json:"overLimitSeconds"`
+	GovernorInterventions int     `json:"governorInterventions"`
+}
+
+type speedComplianceResponse struct {
+	Trucks            int                    `json:"trucks"`
+	Governed          int                    `json:"governed"`
+	TrucksInViolation int                    `json:"trucksInViolation"`
+	Violations        int                    `json:"violations"`
+	OverLimitSeconds  float64                `json:"overLimitSeconds"`
+	Entries           []speedComplianceEntry `json:"entries"`
+}
+
+// handleSpeedCompliance reports violations of each truck's speed limit, worst offenders first.
+func (s *Server) handleSpeedCompliance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	report := analytics.SpeedCompliance(s.sim.Trucks(), query.Get("violatorsOnly") == "true")
+	entries := report.Entries
+	if v := query.Get("limit"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil && parsed > 0 && parsed < len(entries) {
+			entries = entries[:parsed]
+		}
+	}
+
+	resp := speedComplianceResponse{
+		Trucks:            report.Trucks,
+		Governed:          report.Governed,
+		TrucksInViolation: report.TrucksInViolation,
+		Violations:        report.Violations,
+		OverLimitSeconds:  report.OverLimitSeconds,
+		Entries:           make([]speedComplianceEntry, 0, len(entries)),
+	}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, speedComplianceEntry(e))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+type proximityConflict struct {
+	TruckA         string    `json:"truckA"`
+	TruckB         string    `json:"truckB"`
+	DistanceMeters float64   `json:"distanceMeters"`
+	Since          time.Time `json:"since"`
+}
+
+type proximityResponse struct {
+	DistanceMeters float64             `json:"distanceMeters"`
+	Conflicts      []proximityConflict `json:"conflicts"`
+}
+
+// handleProximity lists pairs of moving trucks currently within the proximity distance, closest first.
+func (s *Server) handleProximity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if s.proximity == nil {
+		http.Error(w, "proximity detection is disabled", http.StatusNotFound)
+		return
+	}
+
+	conflicts := s.proximity.Conflicts()
+	resp := proximityResponse{DistanceMeters: s.proximity.Distance(), Conflicts: make([]proximityConflict, 0, len(conflicts))}
+	for _, c := range conflicts {
+		resp.Conflicts = append(resp.Conflicts, proximityConflict(c))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+type routePunctualityEntry struct {
+	Route                     string  `json:"route"`
+	Departures                int     `json:"departures"`
+	Missed                    int     `json:"missed"`
+	OnTimeDepartures          float64 `json:"onTimeDepartures"`
+	MeanDepartureDelaySeconds float64 `json:"meanDepartureDelaySeconds"`
+	Arrivals                  int     `json:"arrivals"`
+	OnTimeArrivals            float64 `json:"onTimeArrivals"`
+	MeanArrivalDelaySeconds   float64 `json:"meanArrivalDelaySeconds"`
+	MaxArrivalDelaySeconds    float64 `json:"maxArrivalDelaySeconds"`
+	ScheduledHeadwaySeconds   float64 `json:"scheduledHeadwaySeconds"`
+	MeanHeadwaySeconds        float64 `json:"meanHeadwaySeconds"`
+	HeadwayVariation          float64 `json:"headwayVariation"`
+}
+
+type punctualityResponse struct {
+	Since  time.Time               `json:"since"`
+	Routes []routePunctualityEntry `json:"routes"`
+}
+
+// handlePunctuality reports how timetabled routes kept to schedule over a simulated-time window ending
+// now. early and late widen or narrow what counts as on time.
+func (s *Server) handlePunctuality(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	window := 24 * time.Hour
+	opts := analytics.DefaultPunctuality
+	for name, target := range map[string]*time.Duration{"window": &window, "early": &opts.Early, "late": &opts.Late} {
+		if v := query.Get(name); v != "" {
+			parsed, err := time.ParseDuration(v)
+			if err != nil || parsed <= 0 {
+				http.Error(w, name+" must be a positive duration", http.StatusBadRequest)
+				return
+			}
+			*target = parsed
+		}
+	}
+
+	since := s.sim.SimulatedTime().Add(-window)
+	report := analytics.Punctuality(s.sim.TimetableRecords(query.Get("route")), since, opts)
+	resp := punctualityResponse{Since: since, Routes: make([]routePunctualityEntry, 0, len(report))}
+	for _, p := range report {
+		resp.Routes = append(resp.Routes, routePunctualityEntry{
+			Route:                     p.Route,
+			Departures:                p.Departures,
+			Missed:                    p.Missed,
+			OnTimeDepartures:          p.OnTimeDepartures,
+			MeanDepartureDelaySeconds: p.MeanDepartureDelay,
+			Arrivals:                  p.Arrivals,
+			OnTimeArrivals:            p.OnTimeArrivals,
+			MeanArrivalDelaySeconds:   p.MeanArrivalDelay,
+			MaxArrivalDelaySeconds:    p.MaxArrivalDelay,
+			ScheduledHeadwaySeconds:   p.ScheduledHeadway,
+			MeanHeadwaySeconds:        p.MeanHeadway,
+			HeadwayVariation:          p.HeadwayVariation,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
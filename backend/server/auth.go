@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"orbit/backend/auth"
+)
+
+const (
+	principalKey contextKey = "principal"
+	// accessTokenParam carries the bearer token for WebSocket clients, since browsers cannot set headers
+	// on a WebSocket handshake.
+	accessTokenParam = "access_token"
+)
+
+// protectedPrefixes are the paths an authenticator guards. Probes, metrics, and the dashboard's static
+// files stay open.
+var protectedPrefixes = []string{"/api/", "/ws/", "/admin/", "/wfs", sensorThingsPrefix}
+
+// WithAuthenticator requires a bearer token on the API, WebSocket, and admin endpoints. Reads need the
+// viewer role, changes the operator role, and /admin the admin role. Demo tokens keep working alongside
+// it when enabled.
+func (s *Server) WithAuthenticator(a auth.Authenticator) *Server {
+	s.authenticator = a
+	return s
+}
+
+// requiredRole is the role a request needs. Viewers may save subscription profiles, since those change
+// nothing in the simulation.
+func requiredRole(r *http.Request) auth.Role {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/admin/"):
+		return auth.RoleAdmin
+	case r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions:
+		return auth.RoleViewer
+	case strings.HasPrefix(r.URL.Path, "/api/profiles/"):
+		return auth.RoleViewer
+	}
+	return auth.RoleOperator
+}
+
+func protectedPath(path string) bool {
+	for _, prefix := range protectedPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// bearerToken returns the token from the Authorization header or the access_token query parameter.
+func bearerToken(r *http.Request) string {
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return r.URL.Query().Get(accessTokenParam)
+}
+
+// authGate authenticates requests to protected paths in front of the router.
+func (s *Server) authGate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !protectedPath(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token := bearerToken(r)
+		if token == "" {
+			if s.demoSigner != nil && r.URL.Query().Get(demoTokenParam) != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="orbit"`)
+			http.Error(w, "bearer token required", http.StatusUnauthorized)
+			return
+		}
+
+		principal, err := s.authenticator.Authenticate(r.Context(), token)
+		if err != nil {
+			if errors.Is(err, auth.ErrInvalidToken) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="orbit", error="invalid_token"`)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			s.logger.Error("authentication failed", "err", err)
+			http.Error(w, "authentication unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if principal.Role < requiredRole(r) {
+			http.Error(w, "requires the "+requiredRole(r).String()+" role", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, withoutAccessToken(r.WithContext(context.WithValue(r.Context(), principalKey, principal))))
+	})
+}
+
+// withoutAccessToken drops the access_token parameter once it has been read, so handlers and the request
+// log never see the credential.
+func withoutAccessToken(r *http.Request) *http.Request {
+	q := r.URL.Query()
+	if !q.Has(accessTokenParam) {
+		return r
+	}
+	q.Del(accessTokenParam)
+	u := *r.URL
+	u.RawQuery = q.Encode()
+	r.URL = &u
+	r.RequestURI = u.RequestURI()
+	return r
+}
+
+// principalFromContext returns the authenticated caller, if any.
+func principalFromContext(ctx context.Context) (auth.Principal, bool) {
+	p, ok := ctx.Value(principalKey).(auth.Principal)
+	return p, ok
+}
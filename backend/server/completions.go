@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type routeCompletionResponse struct {
+	TruckID        string    `json:"truckId"`
+	RouteID        string    `json:"routeId"`
+	StartedAt      time.Time `json:"startedAt"`
+	CompletedAt    time.Time `json:"completedAt"`
+	ElapsedSeconds float64   `json:"elapsedSeconds"`
+	DistanceMeters float64   `json:"distanceMeters"`
+}
+
+type routeCompletionStats struct {
+	RouteID               string  `json:"routeId"`
+	Completions           int     `json:"completions"`
+	AverageElapsedSeconds float64 `json:"averageElapsedSeconds"`
+	AverageDistanceMeters float64 `json:"averageDistanceMeters"`
+}
+
+type routeCompletionsResponse struct {
+	RunID       string                    `json:"runId"`
+	Total       int                       `json:"total"`
+	ByRoute     []routeCompletionStats    `json:"byRoute"`
+	Completions []routeCompletionResponse `json:"completions"`
+}
+
+// handleRouteCompletions reports routes driven to their final waypoint, optionally filtered by routeId and
+// truckId. Statistics cover every matching completion; the list holds the most recent limit of them (100 by
+// default).
+func (s *Server) handleRouteCompletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 100
+	if v := query.Get("limit"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	completions := s.sim.RouteCompletions(query.Get("routeId"), query.Get("truckId"))
+	resp := routeCompletionsResponse{
+		RunID:       s.sim.RunID(),
+		Total:       len(completions),
+		ByRoute:     []routeCompletionStats{},
+		Completions: []routeCompletionResponse{},
+	}
+
+	byRoute := make(map[string]*routeCompletionStats)
+	for _, c := range completions {
+		stats, ok := byRoute[c.RouteID]
+		if !ok {
+			stats = &routeCompletionStats{RouteID: c.RouteID}
+			byRoute[c.RouteID] = stats
+		}
+		stats.Completions++
+		stats.AverageElapsedSeconds += c.Elapsed.Seconds()
+		stats.AverageDistanceMeters += c.DistanceMeters
+	}
+	for _, stats := range byRoute {
+		stats.AverageElapsedSeconds /= float64(stats.Completions)
+		stats.AverageDistanceMeters /= float64(stats.Completions)
+		resp.ByRoute = append(resp.ByRoute, *stats)
+	}
+	sort.Slice(resp.ByRoute, func(i, j int) bool { return resp.ByRoute[i].RouteID < resp.ByRoute[j].RouteID })
+
+	if len(completions) > limit {
+		completions = completions[len(completions)-limit:]
+	}
+	for _, c := range completions {
+		resp.Completions = append(resp.Completions, routeCompletionResponse{
+			TruckID:        c.TruckID,
+			RouteID:        c.RouteID,
+			StartedAt:      c.StartedAt,
+			CompletedAt:    c.CompletedAt,
+			ElapsedSeconds: c.Elapsed.Seconds(),
+			DistanceMeters: c.DistanceMeters,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"orbit/backend/demo"
+	"orbit/backend/simulation"
+)
+
+const (
+	demoTokenParam            = "token"
+	demoGrantKey   contextKey = "demo_grant"
+	defaultDemoTTL            = 24 * time.Hour
+)
+
+// demoPaths are the read-only endpoints a demo token can open.
+var demoPaths = map[string]bool{
+	"/api/info":   true,
+	"/api/trucks": true,
+	"/api/depots": true,
+	"/ws/trucks":  true,
+}
+
+// WithDemoTokens accepts signed demo tokens in the token query parameter. A valid token grants GET access
+// to the truck snapshot endpoints, filtered to the token's bounding box or trucks. When required is set,
+// every other API request is rejected too, so the server can be exposed publicly behind demo links.
+func (s *Server) WithDemoTokens(signer *demo.Signer, required bool) *Server {
+	s.demoSigner = signer
+	s.demoRequired = required
+	return s
+}
+
+// demoGate enforces demo token access in front of the router.
+func (s *Server) demoGate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get(demoTokenParam)
+		if _, ok := principalFromContext(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if token == "" {
+			if s.demoRequired && !demoExempt(r.URL.Path) {
+				http.Error(w, "demo token required", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		grant, err := s.demoSigner.Verify(token, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodGet || !demoPaths[r.URL.Path] {
+			http.Error(w, "demo tokens are read-only", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), demoGrantKey, grant)))
+	})
+}
+
+// demoExempt lists paths that stay open when demo tokens are required: probes, metrics, and admin
+// endpoints, which are only served when explicitly enabled.
+func demoExempt(path string) bool {
+	return path == "/healthz" || path == "/readyz" || path == "/metrics" || strings.HasPrefix(path, "/admin/")
+}
+
+// visibleTrucks narrows trucks to the demo grant on the request, if any.
+func visibleTrucks(r *http.Request, trucks []simulation.Truck) []simulation.Truck {
+	if grant, ok := r.Context().Value(demoGrantKey).(demo.Grant); ok {
+		return grant.Filter(trucks)
+	}
+	return trucks
+}
+
+type demoTokenRequest struct {
+	TTL         string              `json:"ttl"`
+	BoundingBox *boundingBoxPayload `json:"boundingBox"`
+	TruckIDs    []string            `json:"truckIds"`
+}
+
+type demoTokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// handleDemoTokens mints a demo token. The ttl defaults to 24h.
+func (s *Server) handleDemoTokens(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req demoTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ttl := defaultDemoTTL
+	if req.TTL != "" {
+		parsed, err := time.ParseDuration(req.TTL)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "ttl must be a positive duration", http.StatusBadRequest)
+			return
+		}
+		ttl = parsed
+	}
+
+	grant := demo.Grant{ExpiresAt: time.Now().Add(ttl).UTC().Truncate(time.Second), TruckIDs: req.TruckIDs}
+	if req.BoundingBox != nil {
+		if err := req.BoundingBox.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		grant.BoundingBox = &simulation.BoundingBox{
+			MinLat: req.BoundingBox.MinLat,
+			MaxLat: req.BoundingBox.MaxLat,
+			MinLon: req.BoundingBox.MinLon,
+			MaxLon: req.BoundingBox.MaxLon,
+		}
+	}
+
+	token, err := s.demoSigner.Sign(grant)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(demoTokenResponse{Token: token, ExpiresAt: grant.ExpiresAt})
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"orbit/backend/simulation"
+)
+
+type depotResponse struct {
+	Name     string           `json:"name"`
+	Location simulation.Point `json:"location"`
+	Capacity int              `json:"capacity"`
+	Docked   int              `json:"docked"`
+	Queued   int              `json:"queued"`
+}
+
+type depotsResponse struct {
+	Depots []depotResponse `json:"depots"`
+}
+
+func (s *Server) handleDepots(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp := depotsResponse{Depots: []depotResponse{}}
+	for _, d := range s.sim.Depots() {
+		resp.Depots = append(resp.Depots, depotResponse{
+			Name:     d.Name,
+			Location: d.Location,
+			Capacity: d.Capacity,
+			Docked:   d.Docked,
+			Queued:   d.Queued,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
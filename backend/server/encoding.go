@@ -0,0 +1,136 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxPooledBuffer keeps one oversized snapshot from pinning a large buffer in the pool.
+const maxPooledBuffer = 4 << 20
+
+var (
+	snapshotEncodeQueue = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "orbit_snapshot_encode_queue_depth",
+		Help: "Snapshot responses waiting for a free encoder.",
+	})
+
+	snapshotEncodeDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:                            "orbit_snapshot_encode_seconds",
+		Help:                            "Time spent encoding a truck snapshot.",
+		Buckets:                         prometheus.DefBuckets,
+		NativeHistogramBucketFactor:     1.1,
+		NativeHistogramMaxBucketNumber:  100,
+		NativeHistogramMinResetDuration: time.Hour,
+	})
+)
+
+func init() {
+	prometheus.MustRegister(snapshotEncodeQueue, snapshotEncodeDuration)
+}
+
+// snapshotEncoder serialises truck snapshots on a bounded number of encoders with pooled buffers, so a burst
+// of dashboard refreshes queues for encoding instead of competing with the simulation for every core.
+type snapshotEncoder struct {
+	slots   chan struct{}
+	buffers sync.Pool
+}
+
+// newSnapshotEncoder allows workers concurrent encodes; a non-positive count uses half of GOMAXPROCS.
+func newSnapshotEncoder(workers int) *snapshotEncoder {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0) / 2
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	return &snapshotEncoder{
+		slots:   make(chan struct{}, workers),
+		buffers: sync.Pool{New: func() any { return new(bytes.Buffer) }},
+	}
+}
+
+// encode waits for a free encoder and returns v as JSON. Callers must release the buffer once written.
+func (e *snapshotEncoder) encode(ctx context.Context, v any) (*bytes.Buffer, error) {
+	snapshotEncodeQueue.Inc()
+	select {
+	case e.slots <- struct{}{}:
+		snapshotEncodeQueue.Dec()
+	case <-ctx.Done():
+		snapshotEncodeQueue.Dec()
+		return nil, ctx.Err()
+	}
+	defer func() { <-e.slots }()
+
+	start := time.Now()
+	buf := e.buffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		e.release(buf)
+		return nil, err
+	}
+	snapshotEncodeDuration.Observe(time.Since(start).Seconds())
+	return buf, nil
+}
+
+func (e *snapshotEncoder) release(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	e.buffers.Put(buf)
+}
+
+// writeSnapshot encodes v through the snapshot encoder and writes it as a JSON response.
+func (s *Server) writeSnapshot(w http.ResponseWriter, r *http.Request, v any) {
+	s.writeSnapshotAs(w, r, "application/json", v)
+}
+
+// writeSnapshotAs is writeSnapshot with another JSON media type, such as GeoJSON's.
+func (s *Server) writeSnapshotAs(w http.ResponseWriter, r *http.Request, contentType string, v any) {
+	buf, err := s.encoder.encode(r.Context(), v)
+	if err != nil {
+		if r.Context().Err() == nil {
+			http.Error(w, "failed to encode snapshot", http.StatusInternalServerError)
+		}
+		return
+	}
+	defer s.encoder.release(buf)
+	w.Header().Set("Content-Type", contentType)
+	if n, err := w.Write(buf.Bytes()); err == nil {
+		recordPayload(protocolREST, "trucks", n)
+	}
+}
+
+// sendSnapshot encodes v through the snapshot encoder and sends it as a WebSocket text message.
+func (s *Server) sendSnapshot(ctx context.Context, conn *websocket.Conn, v any) error {
+	buf, err := s.encoder.encode(ctx, v)
+	if err != nil {
+		return err
+	}
+	defer s.encoder.release(buf)
+	if err := conn.WriteMessage(websocket.TextMessage, buf.Bytes()); err != nil {
+		return err
+	}
+	recordPayload(protocolWebSocket, "trucks", buf.Len())
+	return nil
+}
+
+// sendJSON sends v as a WebSocket text message and counts it towards stream's payload metrics.
+func sendJSON(conn *websocket.Conn, stream string, v any) error {
+	msg, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+		return err
+	}
+	recordPayload(protocolWebSocket, stream, len(msg))
+	return nil
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"orbit/backend/history"
+)
+
+// defaultHistoryInterval matches the -history-interval default.
+const defaultHistoryInterval = 10 * time.Second
+
+type estimateMemoryResponse struct {
+	TrucksBytes  int64 `json:"trucksBytes"`
+	HistoryBytes int64 `json:"historyBytes"`
+	TotalBytes   int64 `json:"totalBytes"`
+}
+
+type estimateCPUResponse struct {
+	TruckUpdatesPerSecond float64 `json:"truckUpdatesPerSecond"`
+	// Cores is the projected CPU spent advancing trucks. It is only measured once the fleet has ticked.
+	Cores    float64 `json:"cores"`
+	Measured bool    `json:"measured"`
+}
+
+type estimateMeasuredResponse struct {
+	Trucks                int     `json:"trucks"`
+	BytesPerTruck         float64 `json:"bytesPerTruck"`
+	BytesPerWaypoint      float64 `json:"bytesPerWaypoint"`
+	BytesPerHistorySample float64 `json:"bytesPerHistorySample"`
+	UpdateSeconds         float64 `json:"updateSeconds"`
+}
+
+type estimateResponse struct {
+	NumTrucks               int                      `json:"numTrucks"`
+	WaypointsPerRoute       float64                  `json:"waypointsPerRoute"`
+	HistoryRetentionSeconds float64                  `json:"historyRetentionSeconds"`
+	Memory                  estimateMemoryResponse   `json:"memory"`
+	CPU                     estimateCPUResponse      `json:"cpu"`
+	Measured                estimateMeasuredResponse `json:"measured"`
+}
+
+// handleSimulationEstimate serves GET /api/simulation/estimate, which projects the memory and CPU a fleet
+// would cost from the per-truck costs measured on the running one. ?numTrucks= and ?waypoints= default to
+// the running fleet; ?historyRetention= and ?historyInterval= size position history as the
+// -history-retention and -history-interval flags do.
+func (s *Server) handleSimulationEstimate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	fp := s.sim.Footprint()
+	query := r.URL.Query()
+	resp := estimateResponse{NumTrucks: fp.Trucks, WaypointsPerRoute: fp.WaypointsPerTruck}
+	if v := query.Get("numTrucks"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "numTrucks must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		resp.NumTrucks = n
+	}
+	if v := query.Get("waypoints"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 2 {
+			http.Error(w, "waypoints must be an integer of at least 2", http.StatusBadRequest)
+			return
+		}
+		resp.WaypointsPerRoute = float64(n)
+	}
+	retention, err := durationParam(query.Get("historyRetention"), 0)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("historyRetention: %v", err), http.StatusBadRequest)
+		return
+	}
+	interval, err := durationParam(query.Get("historyInterval"), defaultHistoryInterval)
+	if err != nil || interval <= 0 {
+		http.Error(w, "historyInterval must be a positive duration", http.StatusBadRequest)
+		return
+	}
+	resp.HistoryRetentionSeconds = retention.Seconds()
+
+	sampleBytes := history.BytesPerSample()
+	trucks := float64(resp.NumTrucks)
+	resp.Memory.TrucksBytes = int64(math.Ceil(trucks * (fp.BytesPerTruck + resp.WaypointsPerRoute*fp.BytesPerWaypoint)))
+	if retention > 0 {
+		// Samples are taken on the wall clock, and each covers interval × time scale of simulated time.
+		samples := math.Max(1, retention.Seconds()/(interval.Seconds()*math.Max(fp.TimeScale, 1e-9)))
+		resp.Memory.HistoryBytes = int64(math.Ceil(trucks * samples * sampleBytes))
+	}
+	resp.Memory.TotalBytes = resp.Memory.TrucksBytes + resp.Memory.HistoryBytes
+
+	if fp.TickInterval > 0 {
+		resp.CPU.TruckUpdatesPerSecond = trucks / fp.TickInterval.Seconds()
+	}
+	resp.CPU.Cores = resp.CPU.TruckUpdatesPerSecond * fp.UpdateSeconds
+	resp.CPU.Measured = fp.UpdateSeconds > 0
+
+	resp.Measured = estimateMeasuredResponse{
+		Trucks:                fp.Trucks,
+		BytesPerTruck:         fp.BytesPerTruck,
+		BytesPerWaypoint:      fp.BytesPerWaypoint,
+		BytesPerHistorySample: sampleBytes,
+		UpdateSeconds:         fp.UpdateSeconds,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// durationParam parses a Go duration such as 24h, returning def when v is empty.
+func durationParam(v string, def time.Duration) (time.Duration, error) {
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return d, nil
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"orbit/backend/events"
+)
+
+// WithEventBus streams simulation events from bus on /ws/events.
+func (s *Server) WithEventBus(bus *events.Bus) *Server {
+	s.eventBus = bus
+	return s
+}
+
+type eventMessage struct {
+	ID       string          `json:"id"`
+	Type     string          `json:"type"`
+	Time     time.Time       `json:"time"`
+	RunID    string          `json:"runId,omitempty"`
+	Severity events.Severity `json:"severity"`
+	Payload  any             `json:"payload"`
+}
+
+// handleEventsWebSocket streams bus events as they are published. The optional type query parameter
+// takes a comma-separated list of event types, truckId limits the stream to one truck, severity drops
+// events below that severity, and replay first sends retained events from that far back. The stream pings
+// the client while it is quiet and ends as soon as the client goes away.
+func (s *Server) handleEventsWebSocket(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := events.SubscribeOptions{Overflow: events.OverflowDropOldest}
+	if v := query.Get("type"); v != "" {
+		opts.Types = strings.Split(v, ",")
+	}
+	if v := query.Get("replay"); v != "" {
+		replay, err := time.ParseDuration(v)
+		if err != nil || replay <= 0 {
+			http.Error(w, "replay must be a positive duration", http.StatusBadRequest)
+			return
+		}
+		opts.ReplaySince = replay
+	}
+	truckID := query.Get("truckId")
+	minSeverity := events.SeverityInfo
+	if v := query.Get("severity"); v != "" {
+		severity, err := events.ParseSeverity(v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		minSeverity = severity
+	}
+
+	conn, ok := s.acceptStream(w, r)
+	if !ok {
+		return
+	}
+	defer s.streams.release()
+	defer conn.Close()
+
+	// Replayed events are old by design; only events published after subscribing count towards latency.
+	subscribed := time.Now()
+	sub := s.eventBus.Subscribe("ws-"+s.idGen.NewID(), opts)
+	defer sub.Close()
+
+	gone := watchClient(conn)
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-gone:
+			return
+		case <-s.streams.closing:
+			s.closeForRestart(conn)
+			return
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(closeWriteTimeout)); err != nil {
+				return
+			}
+		case evt, ok := <-sub.C():
+			if !ok {
+				return
+			}
+			if truckID != "" && !events.Involves(evt.Payload, truckID) || !evt.Severity.AtLeast(minSeverity) {
+				continue
+			}
+			msg := eventMessage{ID: evt.ID, Type: evt.Type, Time: evt.Time, RunID: evt.RunID, Severity: evt.Severity, Payload: evt.Payload}
+			if err := sendJSON(conn, "events", msg); err != nil {
+				s.logger.Error("event send failed", "err", err, "correlation_id", correlationIDFromContext(r.Context()))
+				return
+			}
+			if !evt.Time.Before(subscribed) {
+				deliveryLatency.WithLabelValues("events").Observe(time.Since(evt.Time).Seconds())
+			}
+		}
+	}
+}
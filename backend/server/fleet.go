@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"orbit/backend/simulation"
+)
+
+type fleetCommandFilter struct {
+	TruckIDs []string `json:"truckIds"`
+	Status   string   `json:"status"`
+}
+
+type fleetCommandRequest struct {
+	Command         string             `json:"command"`
+	Speed           float64            `json:"speed"`
+	DurationSeconds float64            `json:"durationSeconds"`
+	Filter          fleetCommandFilter `json:"filter"`
+}
+
+type fleetCommandResponse struct {
+	Command  string `json:"command"`
+	Affected int    `json:"affected"`
+}
+
+type fleetAddRequest struct {
+	Count int `json:"count"`
+}
+
+type fleetScaleResponse struct {
+	Added     []simulation.Truck `json:"added,omitempty"`
+	Removed   []string           `json:"removed,omitempty"`
+	FleetSize int                `json:"fleetSize"`
+}
+
+// handleFleetTrucks scales the running fleet: POST adds trucks and DELETE removes the trucks named by
+// repeated id parameters or the newest count trucks.
+func (s *Server) handleFleetTrucks(w http.ResponseWriter, r *http.Request) {
+	var resp fleetScaleResponse
+	switch r.Method {
+	case http.MethodPost:
+		var req fleetAddRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if rejectOverLimit(w, s.checkFleetLimit(s.sim.Config().NumTrucks+req.Count)) {
+			return
+		}
+		added, err := s.sim.AddTrucks(req.Count)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp.Added = added
+	case http.MethodDelete:
+		count := 0
+		if raw := r.URL.Query().Get("count"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed <= 0 {
+				http.Error(w, "invalid count", http.StatusBadRequest)
+				return
+			}
+			count = parsed
+		}
+		removed, err := s.sim.RemoveTrucks(r.URL.Query()["id"], count)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp.Removed = removed
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	resp.FleetSize = s.sim.Config().NumTrucks
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) handleFleetCommands(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req fleetCommandRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	cmd := simulation.FleetCommand{
+		Type:     simulation.FleetCommandType(req.Command),
+		SpeedCap: req.Speed,
+		HoldFor:  time.Duration(req.DurationSeconds * float64(time.Second)),
+		TruckIDs: req.Filter.TruckIDs,
+		Status:   simulation.TruckStatus(req.Filter.Status),
+	}
+	affected, err := s.sim.ApplyFleetCommand(cmd)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(fleetCommandResponse{Command: req.Command, Affected: affected})
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"orbit/backend/ids"
+	"orbit/backend/simulation"
+)
+
+const followSessionTTL = 5 * time.Minute
+
+type followSession struct {
+	TruckID   string
+	ExpiresAt time.Time
+}
+
+// followSessions tracks camera-follow sessions that have been created but not yet expired. A session ID
+// is all it takes to open /ws/follow, so IDs are always random UUIDs, whatever the server's ID generator.
+type followSessions struct {
+	mu       sync.Mutex
+	sessions map[string]followSession
+	idGen    ids.Generator
+}
+
+func newFollowSessions() *followSessions {
+	return &followSessions{sessions: make(map[string]followSession), idGen: ids.UUIDGenerator{}}
+}
+
+func (f *followSessions) create(truckID string, now time.Time) (string, followSession) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for id, session := range f.sessions {
+		if now.After(session.ExpiresAt) {
+			delete(f.sessions, id)
+		}
+	}
+
+	id := f.idGen.NewID()
+	session := followSession{TruckID: truckID, ExpiresAt: now.Add(followSessionTTL)}
+	f.sessions[id] = session
+	return id, session
+}
+
+func (f *followSessions) get(id string, now time.Time) (followSession, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	session, ok := f.sessions[id]
+	if !ok || now.After(session.ExpiresAt) {
+		delete(f.sessions, id)
+		return followSession{}, false
+	}
+	return session, true
+}
+
+type followSessionRequest struct {
+	TruckID string `json:"truckId"`
+}
+
+type followSessionResponse struct {
+	ID        string    `json:"id"`
+	TruckID   string    `json:"truckId"`
+	StreamURL string    `json:"streamUrl"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type followUpdate struct {
+	Truck     simulation.Truck   `json:"truck"`
+	LookAhead []simulation.Point `json:"lookAhead"`
+}
+
+func (s *Server) handleFollowSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req followSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TruckID == "" {
+		http.Error(w, "truckId is required", http.StatusBadRequest)
+		return
+	}
+	if _, ok := s.sim.Truck(req.TruckID); !ok {
+		http.Error(w, "truck not found", http.StatusNotFound)
+		return
+	}
+
+	id, session := s.follows.create(req.TruckID, time.Now())
+	resp := followSessionResponse{
+		ID:        id,
+		TruckID:   session.TruckID,
+		StreamURL: "/ws/follow?session=" + id,
+		ExpiresAt: session.ExpiresAt,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// handleFollowWebSocket streams a single truck with its look-ahead route once per simulation tick.
+func (s *Server) handleFollowWebSocket(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.follows.get(r.URL.Query().Get("session"), time.Now())
+	if !ok {
+		http.Error(w, "unknown or expired session", http.StatusNotFound)
+		return
+	}
+
+	conn, ok := s.acceptStream(w, r)
+	if !ok {
+		return
+	}
+	defer s.streams.release()
+	defer conn.Close()
+
+	ticker := time.NewTicker(s.sim.Config().UpdateInterval)
+	defer ticker.Stop()
+
+	delivery := &deliveryTracker{stream: "follow"}
+	for {
+		generated := s.sim.Generated()
+		truck, ok := s.sim.Truck(session.TruckID)
+		if !ok {
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "truck removed"))
+			return
+		}
+		route, _ := s.sim.RemainingRoute(session.TruckID)
+		if err := sendJSON(conn, "follow", followUpdate{Truck: truck, LookAhead: route}); err != nil {
+			s.logger.Error("follow send failed", "err", err, "correlation_id", correlationIDFromContext(r.Context()))
+			return
+		}
+		delivery.delivered(generated)
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.streams.closing:
+			s.closeForRestart(conn)
+			return
+		case <-ticker.C:
+		}
+	}
+}
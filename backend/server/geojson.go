@@ -0,0 +1,37 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// wantsGeoJSON reads ?format=geojson or an Accept header naming application/geo+json. An explicit format
+// wins over the header.
+func wantsGeoJSON(r *http.Request) (bool, error) {
+	switch strings.ToLower(r.URL.Query().Get("format")) {
+	case "geojson":
+		return true, nil
+	case "json":
+		return false, nil
+	case "":
+		return strings.Contains(r.Header.Get("Accept"), geoJSONMimeType), nil
+	default:
+		return false, fmt.Errorf("format must be json or geojson")
+	}
+}
+
+// truckCollection returns a page of trucks as a GeoJSON FeatureCollection of Point features.
+func truckCollection(resp paginatedResponse) geoJSONFeatureCollection {
+	collection := geoJSONFeatureCollection{
+		Type:           "FeatureCollection",
+		NumberMatched:  resp.Total,
+		NumberReturned: len(resp.Trucks),
+		TimeStamp:      resp.SimulatedTime,
+		Features:       make([]geoJSONFeature, 0, len(resp.Trucks)),
+	}
+	for _, truck := range resp.Trucks {
+		collection.Features = append(collection.Features, truckFeature(truck))
+	}
+	return collection
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"orbit/backend/analytics"
+)
+
+const (
+	defaultHeatmapCell = 1000
+	// maxHeatmapCells caps the grid so a tiny cell size over a wide area cannot build a huge response.
+	maxHeatmapCells = 250000
+)
+
+type heatmapResponse struct {
+	BoundingBox   boundingBoxPayload `json:"bbox"`
+	CellSize      float64            `json:"cellSize"`
+	CellLat       float64            `json:"cellLat"`
+	CellLon       float64            `json:"cellLon"`
+	Rows          int                `json:"rows"`
+	Cols          int                `json:"cols"`
+	Counts        [][]int            `json:"counts"`
+	Max           int                `json:"max"`
+	Total         int                `json:"total"`
+	Outside       int                `json:"outside"`
+	SimulatedTime time.Time          `json:"simulatedTime"`
+}
+
+// handleTruckHeatmap serves GET /api/trucks/heatmap, a grid of truck counts over the simulation bounds,
+// or over ?bbox= when given. ?cellSize= sets the cell size in meters, and the /api/trucks filters narrow
+// the trucks first. Counts run in rows from the south-west corner.
+func (s *Server) handleTruckHeatmap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	cellSize := float64(defaultHeatmapCell)
+	if v := query.Get("cellSize"); v != "" {
+		var err error
+		cellSize, err = strconv.ParseFloat(v, 64)
+		if err != nil || cellSize < 10 || cellSize > 1000000 {
+			http.Error(w, "cellSize must be between 10 and 1000000 meters", http.StatusBadRequest)
+			return
+		}
+	}
+	bounds := s.sim.Bounds()
+	if v := query.Get("bbox"); v != "" {
+		box, err := parseBBoxParam(v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		bounds = box
+	}
+	if rows, cols, _, _ := analytics.GridSize(bounds, cellSize); rows*cols > maxHeatmapCells {
+		http.Error(w, fmt.Sprintf("cellSize is too small for the area: %d cells, at most %d", rows*cols, maxHeatmapCells), http.StatusBadRequest)
+		return
+	}
+	trucks, err := s.filteredTrucks(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	grid := analytics.Density(trucks, bounds, cellSize)
+	s.writeSnapshot(w, r, heatmapResponse{
+		BoundingBox:   boundingBoxPayload{MinLat: bounds.MinLat, MaxLat: bounds.MaxLat, MinLon: bounds.MinLon, MaxLon: bounds.MaxLon},
+		CellSize:      cellSize,
+		CellLat:       grid.CellLat,
+		CellLon:       grid.CellLon,
+		Rows:          grid.Rows,
+		Cols:          grid.Cols,
+		Counts:        grid.Counts,
+		Max:           grid.Max,
+		Total:         len(trucks),
+		Outside:       grid.Outside,
+		SimulatedTime: s.sim.SimulatedTime(),
+	})
+}
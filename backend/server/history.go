@@ -0,0 +1,114 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"orbit/backend/simulation"
+)
+
+type configDiffResponse struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+type configChangeResponse struct {
+	ID         string                   `json:"id"`
+	AppliedAt  time.Time                `json:"appliedAt"`
+	Source     string                   `json:"source"`
+	RollbackOf string                   `json:"rollbackOf,omitempty"`
+	RunID      string                   `json:"runId"`
+	Diff       []configDiffResponse     `json:"diff"`
+	Config     simulationConfigResponse `json:"config"`
+}
+
+type configHistoryResponse struct {
+	Changes []configChangeResponse `json:"changes"`
+}
+
+// handleConfigHistory lists applied configurations, oldest first.
+func (s *Server) handleConfigHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp := configHistoryResponse{Changes: []configChangeResponse{}}
+	for _, change := range s.sim.ConfigHistory() {
+		resp.Changes = append(resp.Changes, configChangeToResponse(change))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// handleConfigHistoryItem serves GET /api/simulation/config/history/{id} and
+// POST /api/simulation/config/history/{id}/rollback.
+func (s *Server) handleConfigHistoryItem(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/simulation/config/history/")
+	id, action, _ := strings.Cut(rest, "/")
+	change, ok := s.sim.ConfigChangeByID(id)
+	if !ok {
+		http.Error(w, "config not found", http.StatusNotFound)
+		return
+	}
+
+	switch action {
+	case "":
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(configChangeToResponse(change))
+	case "rollback":
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		cfg, err := s.sim.RollbackConfig(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.logConfigChange(r)
+		s.respondWithConfig(w, cfg)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// logConfigChange ties the latest config change to the request's correlation ID in the request log.
+func (s *Server) logConfigChange(r *http.Request) {
+	history := s.sim.ConfigHistory()
+	if len(history) == 0 {
+		return
+	}
+	change := history[len(history)-1]
+	s.logger.Info("config changed",
+		"change_id", change.ID,
+		"source", change.Source,
+		"run_id", change.RunID,
+		"diff", change.Diff,
+		"correlation_id", correlationIDFromContext(r.Context()),
+	)
+}
+
+func configChangeToResponse(change simulation.ConfigChange) configChangeResponse {
+	resp := configChangeResponse{
+		ID:         change.ID,
+		AppliedAt:  change.AppliedAt,
+		Source:     change.Source,
+		RollbackOf: change.RollbackOf,
+		RunID:      change.RunID,
+		Diff:       make([]configDiffResponse, 0, len(change.Diff)),
+		Config:     simulationConfigToResponse(change.Config),
+	}
+	for _, d := range change.Diff {
+		resp.Diff = append(resp.Diff, configDiffResponse{Field: d.Field, From: d.From, To: d.To})
+	}
+	return resp
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"orbit/backend/simulation"
+)
+
+// Limits are hard ceilings on what API clients may ask of the host. They are checked before a request
+// reaches the simulation and rejected with 422 Unprocessable Entity, whatever the simulation itself would
+// accept. Zero fields are unlimited.
+type Limits struct {
+	// MaxTrucks caps the fleet size a config update or fleet scale-up may reach.
+	MaxTrucks int
+	// MinUpdateInterval is the shortest update interval a config update may set.
+	MinUpdateInterval time.Duration
+	// MaxWaypoints caps the waypoints of a route sent to the catalog or assigned to a truck.
+	MaxWaypoints int
+}
+
+// WithLimits enforces safety ceilings on the config, fleet, and route endpoints.
+func (s *Server) WithLimits(limits Limits) *Server {
+	s.limits = limits
+	return s
+}
+
+// checkConfigLimits rejects a config update beyond the limits.
+func (s *Server) checkConfigLimits(update simulation.ConfigUpdate) error {
+	if update.NumTrucks != nil {
+		if err := s.checkFleetLimit(*update.NumTrucks); err != nil {
+			return err
+		}
+	}
+	if update.UpdateInterval != nil && s.limits.MinUpdateInterval > 0 && *update.UpdateInterval < s.limits.MinUpdateInterval {
+		return fmt.Errorf("updateIntervalMs must be at least %d on this server", s.limits.MinUpdateInterval.Milliseconds())
+	}
+	return nil
+}
+
+// checkFleetLimit rejects a fleet of n trucks beyond the limits.
+func (s *Server) checkFleetLimit(n int) error {
+	if s.limits.MaxTrucks > 0 && n > s.limits.MaxTrucks {
+		return fmt.Errorf("fleet size %d exceeds this server's limit of %d trucks", n, s.limits.MaxTrucks)
+	}
+	return nil
+}
+
+// checkWaypointLimit rejects a route of n waypoints beyond the limits.
+func (s *Server) checkWaypointLimit(n int) error {
+	if s.limits.MaxWaypoints > 0 && n > s.limits.MaxWaypoints {
+		return fmt.Errorf("route has %d waypoints, more than this server's limit of %d", n, s.limits.MaxWaypoints)
+	}
+	return nil
+}
+
+// rejectOverLimit writes err as a 422 response when it is set, and reports whether it did.
+func rejectOverLimit(w http.ResponseWriter, err error) bool {
+	if err == nil {
+		return false
+	}
+	http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	return true
+}
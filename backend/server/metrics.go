@@ -0,0 +1,107 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"orbit/backend/version"
+)
+
+var apiLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:                            "orbit_api_latency_seconds",
+	Help:                            "Time spent serving HTTP handlers.",
+	Buckets:                         prometheus.DefBuckets,
+	NativeHistogramBucketFactor:     1.1,
+	NativeHistogramMaxBucketNumber:  100,
+	NativeHistogramMinResetDuration: time.Hour,
+}, []string{"method", "path", "status"})
+
+// deliveryLatency measures how stale streamed data is when it reaches a client: the time from the tick that
+// generated an update, or the publication of an event, until the write to the client completes.
+var deliveryLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:                            "orbit_delivery_latency_seconds",
+	Help:                            "Time from generating streamed data to completing its write to a client.",
+	Buckets:                         prometheus.ExponentialBuckets(0.001, 2, 14),
+	NativeHistogramBucketFactor:     1.1,
+	NativeHistogramMaxBucketNumber:  100,
+	NativeHistogramMinResetDuration: time.Hour,
+}, []string{"stream"})
+
+// payloadsDelivered and payloadBytes count what each consumer protocol is sent: truck snapshots over REST
+// and WebSocket, follow updates, and events. stream names the data as deliveryLatency does.
+var (
+	payloadsDelivered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "orbit_payloads_delivered_total",
+		Help: "Payloads written to consumers, by protocol and stream.",
+	}, []string{"protocol", "stream"})
+	payloadBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "orbit_payload_bytes_total",
+		Help: "Bytes of payload written to consumers, by protocol and stream.",
+	}, []string{"protocol", "stream"})
+)
+
+// Consumer protocols for payloadsDelivered and payloadBytes.
+const (
+	protocolREST      = "rest"
+	protocolWebSocket = "websocket"
+)
+
+// recordPayload counts one payload of n bytes delivered over protocol.
+func recordPayload(protocol, stream string, n int) {
+	payloadsDelivered.WithLabelValues(protocol, stream).Inc()
+	payloadBytes.WithLabelValues(protocol, stream).Add(float64(n))
+}
+
+// streamRejections counts WebSocket streams turned away by the stream limit.
+var streamRejections = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "orbit_stream_rejections_total",
+	Help: "WebSocket streams closed with a retry hint because the stream limit was reached.",
+})
+
+// targetInfo follows the OpenMetrics target_info convention so scrapes can be joined with service metadata.
+var targetInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "target_info",
+	Help: "Target metadata.",
+}, []string{"service_name", "service_version"})
+
+// buildInfo exposes the running build as labels on a constant 1.
+var buildInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "orbit_build_info",
+	Help: "Build metadata for the running Orbit server.",
+}, []string{"version", "revision", "goversion"})
+
+func init() {
+	info := version.Get()
+	targetInfo.WithLabelValues("orbit", info.Version).Set(1)
+	buildInfo.WithLabelValues(info.Version, info.Revision, info.GoVersion).Set(1)
+	prometheus.MustRegister(apiLatency, deliveryLatency, payloadsDelivered, payloadBytes, streamRejections, targetInfo, buildInfo, collectors.NewBuildInfoCollector())
+}
+
+// metricsHandler serves the default registry, negotiating OpenMetrics text or the protobuf format (which
+// carries native histograms and created timestamps) based on the scraper's Accept header.
+func metricsHandler() http.Handler {
+	return promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}),
+	)
+}
+
+// deliveryTracker observes delivery latency for one streaming connection. Each generation is observed once,
+// on the first write that carries it, so a stream that resends unchanged data while the simulation is
+// paused does not count the pause as latency.
+type deliveryTracker struct {
+	stream string
+	last   time.Time
+}
+
+func (d *deliveryTracker) delivered(generated time.Time) {
+	if generated.IsZero() || !generated.After(d.last) {
+		return
+	}
+	d.last = generated
+	deliveryLatency.WithLabelValues(d.stream).Observe(time.Since(generated).Seconds())
+}
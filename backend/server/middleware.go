@@ -16,6 +16,10 @@ type contextKey string
 
 const correlationIDKey contextKey = "correlation_id"
 
+// runIDHeader carries the simulation run ID on every response so payloads from different runs can be told
+// apart.
+const runIDHeader = "X-Orbit-Run-ID"
+
 func (s *Server) wrap(handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -26,22 +30,41 @@ func (s *Server) wrap(handler http.HandlerFunc) http.HandlerFunc {
 
 		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
 		recorder.Header().Set(s.correlationHeader, correlationID)
+		if runID := s.sim.RunID(); runID != "" {
+			recorder.Header().Set(runIDHeader, runID)
+		}
 
 		handler(recorder, r)
 
 		duration := time.Since(start)
-		s.logger.Info("request completed",
+		attrs := []any{
 			"path", r.URL.Path,
 			"method", r.Method,
 			"status", recorder.status,
 			"duration_ms", duration.Milliseconds(),
 			"correlation_id", correlationID,
-		)
+		}
+		if r.URL.RawQuery != "" {
+			attrs = append(attrs, "query", r.URL.Query())
+		}
+		if s.logHeaders {
+			attrs = append(attrs, "headers", r.Header)
+		}
+		s.logger.Info("request completed", attrs...)
 
 		apiLatency.WithLabelValues(r.Method, r.URL.Path, strconv.Itoa(recorder.status)).Observe(duration.Seconds())
 	}
 }
 
+// runHeader returns the run ID header for WebSocket handshakes, which bypass the response writer headers.
+func (s *Server) runHeader() http.Header {
+	header := http.Header{}
+	if runID := s.sim.RunID(); runID != "" {
+		header.Set(runIDHeader, runID)
+	}
+	return header
+}
+
 func (s *Server) extractOrCreateCorrelationID(r *http.Request) string {
 	if existing := r.Header.Get(s.correlationHeader); existing != "" {
 		return existing
@@ -59,6 +82,11 @@ func (r *statusRecorder) WriteHeader(statusCode int) {
 	r.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to flush a stream.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	if h, ok := r.ResponseWriter.(http.Hijacker); ok {
 		return h.Hijack()
@@ -72,3 +100,52 @@ func correlationIDFromContext(ctx context.Context) string {
 	}
 	return ""
 }
+
+// concurrencyLimiter bounds the number of in-flight requests for a route group.
+type concurrencyLimiter struct {
+	slots      chan struct{}
+	retryAfter time.Duration
+}
+
+func newConcurrencyLimiter(limit int, retryAfter time.Duration) *concurrencyLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &concurrencyLimiter{slots: make(chan struct{}, limit), retryAfter: retryAfter}
+}
+
+// limit rejects requests beyond the configured concurrency with 503 and a Retry-After hint.
+// When methods are provided only requests using those methods count against the limit.
+func (l *concurrencyLimiter) limit(handler http.HandlerFunc, methods ...string) http.HandlerFunc {
+	if l == nil {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(methods) > 0 && !containsMethod(methods, r.Method) {
+			handler(w, r)
+			return
+		}
+
+		select {
+		case l.slots <- struct{}{}:
+			defer func() { <-l.slots }()
+			handler(w, r)
+		default:
+			seconds := int(l.retryAfter.Round(time.Second).Seconds())
+			if seconds < 1 {
+				seconds = 1
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			http.Error(w, "too many concurrent requests", http.StatusServiceUnavailable)
+		}
+	}
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"orbit/backend/simulation"
+)
+
+const (
+	defaultNearestTrucks = 5
+	maxNearestTrucks     = 100
+)
+
+type nearestTrucksResponse struct {
+	Lat    float64                  `json:"lat"`
+	Lon    float64                  `json:"lon"`
+	Trucks []simulation.NearbyTruck `json:"trucks"`
+}
+
+// handleNearestTrucks serves GET /api/trucks/nearest?lat=..&lon=..&k=5, the k trucks closest to a point
+// with their great-circle distances, nearest first.
+func (s *Server) handleNearestTrucks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	lat, err := strconv.ParseFloat(query.Get("lat"), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		http.Error(w, "lat must be a latitude", http.StatusBadRequest)
+		return
+	}
+	lon, err := strconv.ParseFloat(query.Get("lon"), 64)
+	if err != nil || lon < -180 || lon > 180 {
+		http.Error(w, "lon must be a longitude", http.StatusBadRequest)
+		return
+	}
+	k := defaultNearestTrucks
+	if v := query.Get("k"); v != "" {
+		k, err = strconv.Atoi(v)
+		if err != nil || k <= 0 || k > maxNearestTrucks {
+			http.Error(w, "k must be between 1 and 100", http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(nearestTrucksResponse{
+		Lat:    lat,
+		Lon:    lon,
+		Trucks: s.sim.NearestTrucks(simulation.Point{Lat: lat, Lon: lon}, k),
+	})
+}
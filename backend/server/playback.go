@@ -0,0 +1,173 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"orbit/backend/history"
+)
+
+const (
+	// maxPlaybackSpeed bounds ?speed= so a request cannot turn into a tight send loop.
+	maxPlaybackSpeed = 100000
+	// playbackPage is how many frames are read from the position history at a time.
+	playbackPage = 256
+)
+
+type playbackPosition struct {
+	ID  string  `json:"id"`
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+type playbackFrame struct {
+	Time   time.Time          `json:"time"`
+	Trucks []playbackPosition `json:"trucks"`
+}
+
+// WithPositionHistory serves GET /api/playback, which streams frames from the given position history.
+func (s *Server) WithPositionHistory(store *history.Store) *Server {
+	s.positions = store
+	return s
+}
+
+// handlePlayback serves GET /api/playback?from=..&to=..&speed=.., which replays the recorded positions
+// between from and to at speed simulated seconds per second. It streams over a WebSocket when the request
+// asks to upgrade and as server-sent events otherwise, and ends once the last frame is sent.
+func (s *Server) handlePlayback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	from, to, speed, err := playbackParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if to.IsZero() {
+		// Stop at what was recorded when the request came in, so a slow playback does not chase the live fleet.
+		to = s.sim.SimulatedTime()
+	}
+
+	var send func(playbackFrame) error
+	var finish func()
+	if websocket.IsWebSocketUpgrade(r) {
+		conn, ok := s.acceptStream(w, r)
+		if !ok {
+			return
+		}
+		defer s.streams.release()
+		defer conn.Close()
+		send = func(frame playbackFrame) error {
+			return sendJSON(conn, "playback", frame)
+		}
+		finish = func() {
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "end of playback"))
+		}
+	} else {
+		if !s.streams.acquire() {
+			streamRejections.Inc()
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, int(s.streams.retryAfter.Round(time.Second).Seconds()))))
+			http.Error(w, "too many open streams", http.StatusServiceUnavailable)
+			return
+		}
+		defer s.streams.release()
+		rc := http.NewResponseController(w)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		send = func(frame playbackFrame) error {
+			msg, err := json.Marshal(frame)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "event: frame\ndata: %s\n\n", msg); err != nil {
+				return err
+			}
+			recordPayload(protocolREST, "playback", len(msg))
+			return rc.Flush()
+		}
+		finish = func() {
+			_, _ = fmt.Fprint(w, "event: end\ndata: {}\n\n")
+			_ = rc.Flush()
+		}
+	}
+
+	var (
+		began  time.Time
+		anchor time.Time
+		timer  *time.Timer
+	)
+	cursor := from
+	for {
+		frames := s.positions.Frames(cursor, to, playbackPage)
+		for _, frame := range frames {
+			if anchor.IsZero() {
+				began, anchor = time.Now(), frame.Time
+			}
+			if wait := time.Until(began.Add(time.Duration(float64(frame.Time.Sub(anchor)) / speed))); wait > 0 {
+				if timer == nil {
+					timer = time.NewTimer(wait)
+					defer timer.Stop()
+				} else {
+					timer.Reset(wait)
+				}
+				select {
+				case <-r.Context().Done():
+					return
+				case <-s.streams.closing:
+					return
+				case <-timer.C:
+				}
+			}
+			if err := send(playbackFrameOf(frame)); err != nil {
+				s.logger.Error("playback send failed", "err", err, "correlation_id", correlationIDFromContext(r.Context()))
+				return
+			}
+		}
+		if len(frames) < playbackPage {
+			break
+		}
+		// The history keeps milliseconds, so the next page starts just after the last frame sent.
+		cursor = frames[len(frames)-1].Time.Add(time.Millisecond)
+	}
+	finish()
+}
+
+func playbackParams(r *http.Request) (from, to time.Time, speed float64, err error) {
+	q := r.URL.Query()
+	for _, bound := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		if v := q.Get(bound.name); v != "" {
+			if *bound.dst, err = time.Parse(time.RFC3339, v); err != nil {
+				return from, to, 0, fmt.Errorf("%s must be an RFC 3339 time", bound.name)
+			}
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return from, to, 0, fmt.Errorf("to must not be before from")
+	}
+	speed = 1
+	if v := q.Get("speed"); v != "" {
+		speed, err = strconv.ParseFloat(v, 64)
+		if err != nil || !(speed > 0 && speed <= maxPlaybackSpeed) {
+			return from, to, 0, fmt.Errorf("speed must be greater than 0 and at most %d", maxPlaybackSpeed)
+		}
+	}
+	return from, to, speed, nil
+}
+
+func playbackFrameOf(frame history.Frame) playbackFrame {
+	resp := playbackFrame{Time: frame.Time, Trucks: make([]playbackPosition, len(frame.Positions))}
+	for i, p := range frame.Positions {
+		resp.Trucks[i] = playbackPosition{ID: p.TruckID, Lat: p.Lat, Lon: p.Lon}
+	}
+	return resp
+}
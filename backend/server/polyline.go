@@ -0,0 +1,73 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"strings"
+
+	"orbit/backend/simulation"
+)
+
+// encodePolyline encodes points in Google's encoded polyline format with five decimal places.
+func encodePolyline(points []simulation.Point) string {
+	var b strings.Builder
+	var lastLat, lastLon int64
+	for _, p := range points {
+		lat := int64(math.Round(p.Lat * 1e5))
+		lon := int64(math.Round(p.Lon * 1e5))
+		writePolylineValue(&b, lat-lastLat)
+		writePolylineValue(&b, lon-lastLon)
+		lastLat, lastLon = lat, lon
+	}
+	return b.String()
+}
+
+func writePolylineValue(b *strings.Builder, v int64) {
+	u := uint64(v) << 1
+	if v < 0 {
+		u = ^u
+	}
+	for u >= 0x20 {
+		b.WriteByte(byte(0x20|u&0x1f) + 63)
+		u >>= 5
+	}
+	b.WriteByte(byte(u) + 63)
+}
+
+// wantsPolylines reports whether a /ws/trucks client asked for route polylines with ?polyline=1.
+func wantsPolylines(r *http.Request) bool {
+	switch r.URL.Query().Get("polyline") {
+	case "1", "true":
+		return true
+	}
+	return false
+}
+
+// streamTruck adds what a /ws/trucks client opted into to a truck: its route as an encoded polyline and
+// its recent trail.
+type streamTruck struct {
+	simulation.Truck
+	Polyline string             `json:",omitempty"`
+	Trail    []simulation.Point `json:",omitempty"`
+}
+
+// polylineTracker remembers the polyline last sent for each truck on one stream, so a route is only sent
+// again after it changes.
+type polylineTracker map[string]string
+
+// changed returns the polylines of the trucks' routes, leaving empty those the client already has.
+func (t polylineTracker) changed(sim *simulation.Manager, trucks []simulation.Truck) []string {
+	ids := make([]string, len(trucks))
+	for i, truck := range trucks {
+		ids[i] = truck.ID
+	}
+	polylines := make([]string, len(trucks))
+	for i, route := range sim.RouteWaypoints(ids) {
+		encoded := encodePolyline(route)
+		if t[ids[i]] != encoded {
+			t[ids[i]] = encoded
+			polylines[i] = encoded
+		}
+	}
+	return polylines
+}
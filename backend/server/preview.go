@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"orbit/backend/simulation"
+)
+
+type intervalChangeResponse struct {
+	FromMs int64 `json:"fromMs"`
+	ToMs   int64 `json:"toMs"`
+}
+
+type configPreviewResponse struct {
+	// Reset is set when the change restarts the simulation and rebuilds every truck.
+	Reset         bool                     `json:"reset"`
+	Diff          []configDiffResponse     `json:"diff"`
+	TrucksAdded   []string                 `json:"trucksAdded"`
+	TrucksRemoved []string                 `json:"trucksRemoved"`
+	Interval      *intervalChangeResponse  `json:"updateInterval,omitempty"`
+	RoutesRebuilt []string                 `json:"routesRebuilt"`
+	LeavingBounds []string                 `json:"trucksLeavingBounds"`
+	Config        simulationConfigResponse `json:"config"`
+}
+
+// handleConfigPreview serves POST /api/simulation/config/preview. It takes the same body as
+// POST /api/simulation/config and reports what applying it would change, without applying it.
+func (s *Server) handleConfigPreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req simulationConfigRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var (
+		preview simulation.ConfigPreview
+		err     error
+	)
+	if req.RestoreDefaults {
+		preview, err = s.sim.PreviewConfig(s.sim.InitialConfig())
+	} else {
+		update, uerr := s.configUpdate(req)
+		if uerr != nil {
+			http.Error(w, uerr.Error(), http.StatusBadRequest)
+			return
+		}
+		if rejectOverLimit(w, s.checkConfigLimits(update)) {
+			return
+		}
+		preview, err = s.sim.PreviewUpdate(update)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	resp := configPreviewResponse{
+		Reset:         preview.Reset,
+		Diff:          []configDiffResponse{},
+		TrucksAdded:   nonNil(preview.TrucksAdded),
+		TrucksRemoved: nonNil(preview.TrucksRemoved),
+		RoutesRebuilt: nonNil(preview.RoutesRebuilt),
+		LeavingBounds: nonNil(preview.LeavingBounds),
+		Config:        simulationConfigToResponse(preview.Config),
+	}
+	for _, d := range preview.Diff {
+		resp.Diff = append(resp.Diff, configDiffResponse{Field: d.Field, From: d.From, To: d.To})
+	}
+	if preview.IntervalFrom != preview.IntervalTo {
+		resp.Interval = &intervalChangeResponse{FromMs: preview.IntervalFrom.Milliseconds(), ToMs: preview.IntervalTo.Milliseconds()}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// nonNil returns ids, or an empty slice in its place so that it encodes as [] rather than null.
+func nonNil(ids []string) []string {
+	if ids == nil {
+		return []string{}
+	}
+	return ids
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"orbit/backend/auth"
+	"orbit/backend/profiles"
+	"orbit/backend/simulation"
+)
+
+const (
+	profileParam = "profile"
+	// minStreamInterval is the shortest ?interval= a /ws/trucks client may ask for.
+	minStreamInterval = 100 * time.Millisecond
+)
+
+// streamFields are the names ?fields= accepts: the truck's own fields and the ones /ws/trucks adds.
+var streamFields = func() map[string]bool {
+	fields := map[string]bool{"X": true, "Y": true, "Polyline": true, "Trail": true}
+	truck := reflect.TypeOf(simulation.Truck{})
+	for i := 0; i < truck.NumField(); i++ {
+		fields[truck.Field(i).Name] = true
+	}
+	return fields
+}()
+
+// WithProfiles enables saved subscription profiles on /api/profiles and ?profile= on /ws/trucks.
+func (s *Server) WithProfiles(store *profiles.Store) *Server {
+	s.profiles = store
+	return s
+}
+
+// applyProfile fills in the query parameters of the profile a /ws/trucks client named with ?profile=.
+// Parameters in the URL win over the profile's.
+func (s *Server) applyProfile(r *http.Request) (*http.Request, error) {
+	name := r.URL.Query().Get(profileParam)
+	if name == "" {
+		return r, nil
+	}
+	if s.profiles == nil {
+		return nil, profiles.ErrNotFound
+	}
+	profile, ok := s.profiles.Get(name)
+	if !ok {
+		return nil, profiles.ErrNotFound
+	}
+	query := r.URL.Query()
+	for key, values := range profile.Query() {
+		if !query.Has(key) {
+			query[key] = values
+		}
+	}
+	r = r.Clone(r.Context())
+	r.URL.RawQuery = query.Encode()
+	return r, nil
+}
+
+// fieldsParam reads ?fields=ID,Lat,Lon, the truck fields a /ws/trucks client wants. ID is always sent. It
+// returns nil when the client wants every field.
+func fieldsParam(r *http.Request) (map[string]bool, error) {
+	v := r.URL.Query().Get("fields")
+	if v == "" {
+		return nil, nil
+	}
+	fields := map[string]bool{"ID": true}
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		if !streamFields[name] {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		fields[name] = true
+	}
+	return fields, nil
+}
+
+// selectFields keeps only the given fields of each truck in a snapshot.
+func selectFields(snapshot any, fields map[string]bool) (any, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	var trucks []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &trucks); err != nil {
+		return nil, err
+	}
+	for _, truck := range trucks {
+		for name := range truck {
+			if !fields[name] {
+				delete(truck, name)
+			}
+		}
+	}
+	return trucks, nil
+}
+
+// streamInterval reads ?interval=, how often a /ws/trucks client is sent a snapshot.
+func (s *Server) streamInterval(r *http.Request) (time.Duration, error) {
+	interval, err := durationParam(r.URL.Query().Get("interval"), s.wsInterval)
+	if err != nil || (r.URL.Query().Has("interval") && interval < minStreamInterval) {
+		return 0, fmt.Errorf("interval must be a duration of at least %s", minStreamInterval)
+	}
+	return interval, nil
+}
+
+// validateProfile checks a profile's parameters the way /ws/trucks would when a client connects with it.
+func (s *Server) validateProfile(p profiles.Profile) error {
+	if p.IntervalMs < 0 || p.Trail < 0 || p.TrailEvery < 0 {
+		return fmt.Errorf("intervalMs, trail, and trailEvery must not be negative")
+	}
+	r := &http.Request{URL: &url.URL{RawQuery: p.Query().Encode()}}
+	if p.Filters.BBox != "" {
+		if _, err := parseBBoxParam(p.Filters.BBox); err != nil {
+			return err
+		}
+	}
+	if p.Filters.Geohash != "" && !validGeohash(strings.ToLower(p.Filters.Geohash)) {
+		return fmt.Errorf("geohash must be up to %d geohash characters", simulation.GeohashPrecision)
+	}
+	if _, err := fieldsParam(r); err != nil {
+		return err
+	}
+	if _, err := s.streamInterval(r); err != nil {
+		return err
+	}
+	if _, err := wantsWebMercator(r); err != nil {
+		return err
+	}
+	_, err := trailParams(r, s.sim.TrackDepth())
+	return err
+}
+
+// handleProfiles serves GET /api/profiles, every saved subscription profile.
+func (s *Server) handleProfiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.profiles.List())
+}
+
+// handleProfile serves GET, PUT, and DELETE on /api/profiles/{name}. Only the caller who saved a profile,
+// or an admin, may replace or delete it.
+func (s *Server) handleProfile(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/profiles/")
+	if !profiles.ValidName(name) {
+		http.Error(w, profiles.ErrInvalidName.Error(), http.StatusNotFound)
+		return
+	}
+	existing, exists := s.profiles.Get(name)
+	switch r.Method {
+	case http.MethodGet:
+		if !exists {
+			http.Error(w, profiles.ErrNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(existing)
+		return
+	case http.MethodPut, http.MethodDelete:
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	caller, _ := principalFromContext(r.Context())
+	if exists && existing.Owner != "" && existing.Owner != caller.Subject && caller.Role < auth.RoleAdmin {
+		http.Error(w, "profile belongs to "+existing.Owner, http.StatusForbidden)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		if err := s.profiles.Delete(name); err != nil {
+			if errors.Is(err, profiles.ErrNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var profile profiles.Profile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if profile.Name != "" && profile.Name != name {
+		http.Error(w, "name does not match the URL", http.StatusBadRequest)
+		return
+	}
+	if err := s.validateProfile(profile); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	profile.Name = name
+	profile.Owner = caller.Subject
+	if exists && existing.Owner != "" {
+		profile.Owner = existing.Owner
+	}
+	profile.UpdatedAt = time.Now().UTC()
+	if err := s.profiles.Put(profile); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !exists {
+		w.WriteHeader(http.StatusCreated)
+	}
+	_ = json.NewEncoder(w).Encode(profile)
+}
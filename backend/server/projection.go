@@ -0,0 +1,46 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"orbit/backend/simulation"
+)
+
+// projectedTruck adds EPSG:3857 coordinates in metres to a truck for clients that draw on Mercator canvases.
+type projectedTruck struct {
+	simulation.Truck
+	X float64
+	Y float64
+	// Polyline and Trail are sent on /ws/trucks when asked for; see streamTruck.
+	Polyline string             `json:",omitempty"`
+	Trail    []simulation.Point `json:",omitempty"`
+}
+
+type projectedPage struct {
+	paginatedResponse
+	Trucks     []projectedTruck `json:"trucks"`
+	Projection string           `json:"projection"`
+}
+
+// wantsWebMercator reads the projection query parameter: EPSG:4326 (the default) or EPSG:3857.
+func wantsWebMercator(r *http.Request) (bool, error) {
+	switch strings.ToUpper(r.URL.Query().Get("projection")) {
+	case "", "EPSG:4326":
+		return false, nil
+	case "EPSG:3857":
+		return true, nil
+	default:
+		return false, fmt.Errorf("projection must be EPSG:4326 or EPSG:3857")
+	}
+}
+
+func projectTrucks(trucks []simulation.Truck) []projectedTruck {
+	projected := make([]projectedTruck, len(trucks))
+	for i, truck := range trucks {
+		x, y := simulation.ToWebMercator(simulation.Point{Lat: truck.Lat, Lon: truck.Lon})
+		projected[i] = projectedTruck{Truck: truck, X: x, Y: y}
+	}
+	return projected
+}
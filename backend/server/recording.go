@@ -0,0 +1,45 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"orbit/backend/zstdfile"
+)
+
+// handleRecording downloads the simulation recording for replay with orbitverify -replay. ?compress=zstd
+// streams it Zstandard-compressed.
+func (s *Server) handleRecording(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var compress bool
+	switch r.URL.Query().Get("compress") {
+	case "":
+	case "zstd":
+		compress = true
+	default:
+		http.Error(w, "compress must be zstd", http.StatusBadRequest)
+		return
+	}
+
+	if !compress {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="orbit-recording.json"`)
+		_ = json.NewEncoder(w).Encode(s.sim.Recording())
+		return
+	}
+	zw, err := zstdfile.NewWriter(w, true)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/zstd")
+	w.Header().Set("Content-Disposition", `attachment; filename="orbit-recording.json`+zstdfile.Ext+`"`)
+	if err := json.NewEncoder(zw).Encode(s.sim.Recording()); err != nil {
+		s.logger.Error("recording download failed", "err", err)
+	}
+	_ = zw.Close()
+}
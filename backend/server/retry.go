@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// defaultStreamRetryAfter is the base backoff hinted to streams turned away by the stream limit.
+	defaultStreamRetryAfter = 2 * time.Second
+	// closeWriteTimeout bounds how long a close frame may take to write to a slow client.
+	closeWriteTimeout = time.Second
+	// streamPingInterval is how often a quiet stream pings its client; a client that has not answered
+	// within streamPongWait is treated as gone.
+	streamPingInterval = 30 * time.Second
+	streamPongWait     = 2 * streamPingInterval
+)
+
+// retryHint is sent as the reason of a WebSocket close frame when the server asks a client to come back
+// later. Browsers cannot read the status of a rejected handshake, so streams are upgraded first and then
+// closed with the hint. The JSON stays well within the 123 bytes a close reason may carry.
+type retryHint struct {
+	Reason       string `json:"reason"`
+	RetryAfterMs int64  `json:"retryAfterMs"`
+}
+
+// jitter returns base plus a uniformly random share of spread, so that clients told to retry at the same
+// moment spread their reconnects instead of arriving together.
+func jitter(base, spread time.Duration) time.Duration {
+	if spread <= 0 {
+		return base
+	}
+	return base + time.Duration(rand.Int63n(int64(spread)))
+}
+
+// streamGate counts open WebSocket streams, turns away those beyond the limit, and tells the open ones to
+// reconnect when the server shuts down.
+type streamGate struct {
+	mu         sync.Mutex
+	limit      int
+	active     int
+	retryAfter time.Duration
+	closing    chan struct{}
+	window     time.Duration
+}
+
+func newStreamGate() *streamGate {
+	return &streamGate{retryAfter: defaultStreamRetryAfter, closing: make(chan struct{})}
+}
+
+func (g *streamGate) acquire() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.limit > 0 && g.active >= g.limit {
+		return false
+	}
+	g.active++
+	return true
+}
+
+func (g *streamGate) release() {
+	g.mu.Lock()
+	g.active--
+	g.mu.Unlock()
+}
+
+// WithStreamLimit caps the number of open WebSocket streams across /ws/trucks, /ws/follow, and /ws/events.
+// Streams beyond the limit are closed with code 1013 (try again later) and a jittered retry hint. A
+// non-positive limit removes the cap.
+func (s *Server) WithStreamLimit(limit int) *Server {
+	s.streams.mu.Lock()
+	s.streams.limit = limit
+	s.streams.mu.Unlock()
+	return s
+}
+
+// CloseStreams asks every open WebSocket stream to reconnect, closing each with code 1012 (service restart)
+// and a retry hint spread uniformly over window, and waits until they have closed or ctx is done. Call it
+// before shutting the HTTP server down so that a restart does not bring every dashboard back in the same
+// instant. Streams opened afterwards are closed the same way.
+func (s *Server) CloseStreams(ctx context.Context, window time.Duration) {
+	s.streams.mu.Lock()
+	select {
+	case <-s.streams.closing:
+	default:
+		s.streams.window = window
+		close(s.streams.closing)
+	}
+	s.streams.mu.Unlock()
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		s.streams.mu.Lock()
+		active := s.streams.active
+		s.streams.mu.Unlock()
+		if active == 0 {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// acceptStream upgrades the request to a WebSocket stream. When the stream limit is reached or the server
+// is closing streams, it closes the connection straight away with a retry hint and reports false. Callers
+// that get true must call s.streams.release when the stream ends.
+func (s *Server) acceptStream(w http.ResponseWriter, r *http.Request) (*websocket.Conn, bool) {
+	conn, err := s.wsUpgrader.Upgrade(w, r, s.runHeader())
+	if err != nil {
+		s.logger.Error("websocket upgrade failed", "err", err, "correlation_id", correlationIDFromContext(r.Context()))
+		return nil, false
+	}
+	select {
+	case <-s.streams.closing:
+		s.closeForRestart(conn)
+		return nil, false
+	default:
+	}
+	if !s.streams.acquire() {
+		streamRejections.Inc()
+		closeWithHint(conn, websocket.CloseTryAgainLater, "overloaded", jitter(s.streams.retryAfter, s.streams.retryAfter))
+		return nil, false
+	}
+	return conn, true
+}
+
+// watchClient reads from a stream whose client only listens, discarding anything it sends and answering
+// control frames. The returned channel is closed once the client disconnects or has not answered a ping
+// within streamPongWait. Streams that can stay quiet for long use it to notice clients that have gone,
+// and should ping the client every streamPingInterval.
+func watchClient(conn *websocket.Conn) <-chan struct{} {
+	gone := make(chan struct{})
+	_ = conn.SetReadDeadline(time.Now().Add(streamPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(streamPongWait))
+	})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+	return gone
+}
+
+// closeForRestart closes a stream because the server is going away, hinting a reconnect somewhere within
+// the restart window.
+func (s *Server) closeForRestart(conn *websocket.Conn) {
+	s.streams.mu.Lock()
+	window := s.streams.window
+	s.streams.mu.Unlock()
+	closeWithHint(conn, websocket.CloseServiceRestart, "restarting", jitter(0, window))
+}
+
+// closeWithHint sends a close frame carrying a retryHint and closes the connection.
+func closeWithHint(conn *websocket.Conn, code int, reason string, after time.Duration) {
+	payload, _ := json.Marshal(retryHint{Reason: reason, RetryAfterMs: after.Milliseconds()})
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, string(payload)), time.Now().Add(closeWriteTimeout))
+	_ = conn.Close()
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"orbit/backend/simulation"
+)
+
+type routeStatusResponse struct {
+	RouteID         string         `json:"routeId"`
+	Trucks          int            `json:"trucks"`
+	ByStatus        map[string]int `json:"byStatus"`
+	AverageProgress float64        `json:"averageProgress"`
+}
+
+// waypointAnnotationPayload annotates the waypoint at the same index in the waypoints it accompanies.
+type waypointAnnotationPayload struct {
+	StopType       string     `json:"stopType,omitempty"`
+	PlannedArrival *time.Time `json:"plannedArrival,omitempty"`
+	Notes          string     `json:"notes,omitempty"`
+}
+
+func annotationPayloads(annotations []simulation.WaypointAnnotation) []waypointAnnotationPayload {
+	if annotations == nil {
+		return nil
+	}
+	out := make([]waypointAnnotationPayload, len(annotations))
+	for i, a := range annotations {
+		out[i] = waypointAnnotationPayload{StopType: a.StopType, Notes: a.Notes}
+		if !a.PlannedArrival.IsZero() {
+			planned := a.PlannedArrival
+			out[i].PlannedArrival = &planned
+		}
+	}
+	return out
+}
+
+func waypointAnnotations(payloads []waypointAnnotationPayload) []simulation.WaypointAnnotation {
+	if payloads == nil {
+		return nil
+	}
+	out := make([]simulation.WaypointAnnotation, len(payloads))
+	for i, p := range payloads {
+		out[i] = simulation.WaypointAnnotation{StopType: p.StopType, Notes: p.Notes}
+		if p.PlannedArrival != nil {
+			out[i].PlannedArrival = *p.PlannedArrival
+		}
+	}
+	return out
+}
+
+// timetablePayload schedules a catalog route: stopTimesSeconds[i] is the running time from departure to
+// waypoint i.
+type timetablePayload struct {
+	Departures       []time.Time `json:"departures"`
+	StopTimesSeconds []float64   `json:"stopTimesSeconds,omitempty"`
+}
+
+func newTimetablePayload(t *simulation.Timetable) *timetablePayload {
+	if t == nil {
+		return nil
+	}
+	payload := &timetablePayload{Departures: t.Departures}
+	for _, d := range t.StopTimes {
+		payload.StopTimesSeconds = append(payload.StopTimesSeconds, d.Seconds())
+	}
+	return payload
+}
+
+func (p *timetablePayload) timetable() *simulation.Timetable {
+	if p == nil {
+		return nil
+	}
+	t := &simulation.Timetable{Departures: p.Departures}
+	for _, seconds := range p.StopTimesSeconds {
+		t.StopTimes = append(t.StopTimes, time.Duration(seconds*float64(time.Second)))
+	}
+	return t
+}
+
+type namedRoutePayload struct {
+	Name        string                      `json:"name"`
+	Waypoints   []simulation.Point          `json:"waypoints"`
+	Annotations []waypointAnnotationPayload `json:"annotations,omitempty"`
+	Loop        bool                        `json:"loop"`
+	Timetable   *timetablePayload           `json:"timetable,omitempty"`
+	// Trucks counts the trucks currently driving the route; it is ignored in requests.
+	Trucks int `json:"trucks"`
+}
+
+type namedRoutesResponse struct {
+	Routes []namedRoutePayload `json:"routes"`
+}
+
+func (s *Server) namedRoutePayload(route simulation.NamedRoute) namedRoutePayload {
+	payload := namedRoutePayload{
+		Name:        route.Name,
+		Waypoints:   route.Waypoints,
+		Annotations: annotationPayloads(route.Annotations),
+		Loop:        route.Loop,
+		Timetable:   newTimetablePayload(route.Timetable),
+	}
+	if status, ok := s.sim.RouteStatus(route.Name); ok {
+		payload.Trucks = status.Trucks
+	}
+	return payload
+}
+
+// handleRoutes serves the route catalog: GET lists it and POST adds or replaces a named route.
+func (s *Server) handleRoutes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		resp := namedRoutesResponse{Routes: []namedRoutePayload{}}
+		for _, route := range s.sim.NamedRoutes() {
+			resp.Routes = append(resp.Routes, s.namedRoutePayload(route))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	case http.MethodPost:
+		var req namedRoutePayload
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if rejectOverLimit(w, s.checkWaypointLimit(len(req.Waypoints))) {
+			return
+		}
+		route := simulation.NamedRoute{
+			Name:        req.Name,
+			Waypoints:   req.Waypoints,
+			Annotations: waypointAnnotations(req.Annotations),
+			Loop:        req.Loop,
+			Timetable:   req.Timetable.timetable(),
+		}
+		if err := s.sim.DefineRoute(route); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.logger.Info("route defined", "route", route.Name, "waypoints", len(route.Waypoints), "correlation_id", correlationIDFromContext(r.Context()))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(s.namedRoutePayload(route))
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleRoute dispatches /api/routes/completions, /api/routes/{name}, and /api/routes/{id}/... requests.
+func (s *Server) handleRoute(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/routes/")
+	id, action, _ := strings.Cut(rest, "/")
+	if id == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if id == "completions" && action == "" {
+		s.handleRouteCompletions(w, r)
+		return
+	}
+
+	switch action {
+	case "":
+		s.handleNamedRoute(w, r, id)
+	case "status":
+		s.handleRouteStatus(w, r, id)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) handleRouteStatus(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	status, ok := s.sim.RouteStatus(id)
+	if !ok {
+		http.Error(w, "route not found", http.StatusNotFound)
+		return
+	}
+
+	resp := routeStatusResponse{
+		RouteID:         status.RouteID,
+		Trucks:          status.Trucks,
+		ByStatus:        make(map[string]int, len(status.ByStatus)),
+		AverageProgress: status.AverageProgress,
+	}
+	for st, count := range status.ByStatus {
+		resp.ByStatus[string(st)] = count
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// handleNamedRoute serves GET and DELETE for a single catalog route.
+func (s *Server) handleNamedRoute(w http.ResponseWriter, r *http.Request, name string) {
+	switch r.Method {
+	case http.MethodGet:
+		route, ok := s.sim.NamedRoute(name)
+		if !ok {
+			http.Error(w, "route not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s.namedRoutePayload(route))
+	case http.MethodDelete:
+		if !s.sim.DeleteRoute(name) {
+			http.Error(w, "route not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,375 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"orbit/backend/simulation"
+)
+
+// sensorThingsPrefix is where the OGC SensorThings API v1.1 facade is served.
+const sensorThingsPrefix = "/sta/v1.1"
+
+const (
+	observationTypeMeasurement = "http://www.opengis.net/def/observationType/OGC-OM/2.0/OM_Measurement"
+	observationTypeCategory    = "http://www.opengis.net/def/observationType/OGC-OM/2.0/OM_CategoryObservation"
+)
+
+type staUnit struct {
+	Name       string `json:"name,omitempty"`
+	Symbol     string `json:"symbol,omitempty"`
+	Definition string `json:"definition,omitempty"`
+}
+
+// staStream describes one kind of Datastream each truck exposes. Datastream IDs are the truck ID and the
+// stream key joined by a colon.
+type staStream struct {
+	key             string
+	name            string
+	observationType string
+	unit            staUnit
+	// value returns the truck's current reading, or false when the truck has no such stream.
+	value func(simulation.Truck) (any, bool)
+}
+
+var staStreams = []staStream{
+	{
+		key:             "speed",
+		name:            "Speed",
+		observationType: observationTypeMeasurement,
+		unit:            staUnit{Name: "metre per second", Symbol: "m/s", Definition: "http://qudt.org/vocab/unit/M-PER-SEC"},
+		value:           func(t simulation.Truck) (any, bool) { return t.Speed, true },
+	},
+	{
+		key:             "fuel",
+		name:            "Fuel",
+		observationType: observationTypeMeasurement,
+		unit:            staUnit{Name: "litre", Symbol: "L", Definition: "http://qudt.org/vocab/unit/L"},
+		value:           func(t simulation.Truck) (any, bool) { return t.Fuel, !t.Electric },
+	},
+	{
+		key:             "battery",
+		name:            "Battery state of charge",
+		observationType: observationTypeMeasurement,
+		unit:            staUnit{Name: "ratio", Symbol: "1", Definition: "http://qudt.org/vocab/unit/UNITLESS"},
+		value:           func(t simulation.Truck) (any, bool) { return t.BatterySOC, t.Electric },
+	},
+	{
+		key:             "status",
+		name:            "Status",
+		observationType: observationTypeCategory,
+		value:           func(t simulation.Truck) (any, bool) { return string(t.Status), true },
+	},
+}
+
+type staThing struct {
+	ID              string         `json:"@iot.id"`
+	SelfLink        string         `json:"@iot.selfLink"`
+	Name            string         `json:"name"`
+	Description     string         `json:"description"`
+	Properties      map[string]any `json:"properties"`
+	LocationsLink   string         `json:"Locations@iot.navigationLink"`
+	DatastreamsLink string         `json:"Datastreams@iot.navigationLink"`
+}
+
+type staGeometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+type staLocation struct {
+	ID           string      `json:"@iot.id"`
+	SelfLink     string      `json:"@iot.selfLink"`
+	Name         string      `json:"name"`
+	Description  string      `json:"description"`
+	EncodingType string      `json:"encodingType"`
+	Location     staGeometry `json:"location"`
+	ThingsLink   string      `json:"Things@iot.navigationLink"`
+}
+
+type staDatastream struct {
+	ID                string  `json:"@iot.id"`
+	SelfLink          string  `json:"@iot.selfLink"`
+	Name              string  `json:"name"`
+	Description       string  `json:"description"`
+	ObservationType   string  `json:"observationType"`
+	UnitOfMeasurement staUnit `json:"unitOfMeasurement"`
+	PhenomenonTime    string  `json:"phenomenonTime"`
+	ThingLink         string  `json:"Thing@iot.navigationLink"`
+	ObservationsLink  string  `json:"Observations@iot.navigationLink"`
+}
+
+type staObservation struct {
+	ID             string `json:"@iot.id"`
+	SelfLink       string `json:"@iot.selfLink"`
+	PhenomenonTime string `json:"phenomenonTime"`
+	ResultTime     string `json:"resultTime"`
+	Result         any    `json:"result"`
+	DatastreamLink string `json:"Datastream@iot.navigationLink"`
+}
+
+type staCollection struct {
+	Count    *int   `json:"@iot.count,omitempty"`
+	NextLink string `json:"@iot.nextLink,omitempty"`
+	Value    []any  `json:"value"`
+}
+
+// staRef identifies the truck and, for Datastreams and Observations, the stream an entity belongs to.
+type staRef struct {
+	truckID string
+	stream  string
+}
+
+// parseSTARef splits an entity ID: Things and Locations use the truck ID, Datastreams add ":stream", and
+// Observations add ":stream:unixMillis".
+func parseSTARef(id string) staRef {
+	truckID, rest, _ := strings.Cut(id, ":")
+	stream, _, _ := strings.Cut(rest, ":")
+	return staRef{truckID: truckID, stream: stream}
+}
+
+// handleSensorThings serves a read-only OGC SensorThings API view of the fleet. Each truck is a Thing
+// with its current position as its Location. Speed, fuel or battery, and status are Datastreams whose
+// only Observation is the latest reading. Collections support $top, $skip, and $count.
+func (s *Server) handleSensorThings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	base := requestBaseURL(r) + sensorThingsPrefix
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, sensorThingsPrefix), "/")
+	if path == "" {
+		sets := []any{}
+		for _, name := range []string{"Things", "Locations", "Datastreams", "Observations"} {
+			sets = append(sets, map[string]string{"name": name, "url": base + "/" + name})
+		}
+		writeSTA(w, staCollection{Value: sets})
+		return
+	}
+
+	segments := strings.Split(path, "/")
+	set, id, hasID := parseSTASegment(segments[0])
+	if len(segments) > 2 || (len(segments) == 2 && !hasID) {
+		http.Error(w, "unsupported path", http.StatusNotFound)
+		return
+	}
+
+	trucks := visibleTrucks(r, s.sim.Trucks())
+	if !hasID {
+		entities, ok := staEntities(base, set, trucks, staRef{})
+		if !ok {
+			http.Error(w, "unknown entity set", http.StatusNotFound)
+			return
+		}
+		writeSTACollection(w, r, base+"/"+set, entities)
+		return
+	}
+
+	ref := parseSTARef(id)
+	entities, ok := staEntities(base, set, trucks, ref)
+	if !ok {
+		http.Error(w, "unknown entity set", http.StatusNotFound)
+		return
+	}
+	var entity any
+	for _, e := range entities {
+		if staID(e) == id {
+			entity = e
+		}
+	}
+	if entity == nil {
+		http.Error(w, "entity not found", http.StatusNotFound)
+		return
+	}
+	if len(segments) == 1 {
+		writeSTA(w, entity)
+		return
+	}
+
+	// Navigation to related entities; singular names lead to one entity.
+	nav := segments[1]
+	target, single := strings.TrimSuffix(nav, "s")+"s", !strings.HasSuffix(nav, "s")
+	if !staNavigable(set, nav) {
+		http.Error(w, "unsupported navigation", http.StatusNotFound)
+		return
+	}
+	if set == "Things" || set == "Locations" {
+		ref.stream = ""
+	}
+	related, _ := staEntities(base, target, trucks, ref)
+	if single {
+		if len(related) == 0 {
+			http.Error(w, "entity not found", http.StatusNotFound)
+			return
+		}
+		writeSTA(w, related[0])
+		return
+	}
+	writeSTACollection(w, r, base+"/"+segments[0]+"/"+nav, related)
+}
+
+func staNavigable(set, nav string) bool {
+	switch set {
+	case "Things":
+		return nav == "Locations" || nav == "Datastreams"
+	case "Locations":
+		return nav == "Things"
+	case "Datastreams":
+		return nav == "Thing" || nav == "Observations"
+	case "Observations":
+		return nav == "Datastream"
+	}
+	return false
+}
+
+// parseSTASegment splits "Things('truck-0001')" into the entity set and ID.
+func parseSTASegment(segment string) (set, id string, hasID bool) {
+	open := strings.Index(segment, "(")
+	if open < 0 || !strings.HasSuffix(segment, ")") {
+		return segment, "", false
+	}
+	return segment[:open], strings.Trim(segment[open+1:len(segment)-1], "'"), true
+}
+
+// staEntities builds the entities of a set, narrowed to ref's truck and stream when they are set. It
+// reports false for unknown sets.
+func staEntities(base, set string, trucks []simulation.Truck, ref staRef) ([]any, bool) {
+	entities := []any{}
+	for _, t := range trucks {
+		if ref.truckID != "" && t.ID != ref.truckID {
+			continue
+		}
+		at := t.UpdatedAt.UTC().Format(time.RFC3339Nano)
+		switch set {
+		case "Things":
+			self := fmt.Sprintf("%s/Things('%s')", base, t.ID)
+			entities = append(entities, staThing{
+				ID:              t.ID,
+				SelfLink:        self,
+				Name:            t.ID,
+				Description:     "Simulated truck " + t.ID,
+				Properties:      map[string]any{"type": t.Type, "routeId": t.RouteID, "electric": t.Electric},
+				LocationsLink:   self + "/Locations",
+				DatastreamsLink: self + "/Datastreams",
+			})
+		case "Locations":
+			self := fmt.Sprintf("%s/Locations('%s')", base, t.ID)
+			entities = append(entities, staLocation{
+				ID:           t.ID,
+				SelfLink:     self,
+				Name:         "Position of " + t.ID,
+				Description:  "Current position of truck " + t.ID,
+				EncodingType: "application/geo+json",
+				Location:     staGeometry{Type: "Point", Coordinates: []float64{t.Lon, t.Lat}},
+				ThingsLink:   self + "/Things",
+			})
+		case "Datastreams", "Observations":
+			for _, stream := range staStreams {
+				value, ok := stream.value(t)
+				if !ok || (ref.stream != "" && stream.key != ref.stream) {
+					continue
+				}
+				id := t.ID + ":" + stream.key
+				if set == "Datastreams" {
+					self := fmt.Sprintf("%s/Datastreams('%s')", base, id)
+					entities = append(entities, staDatastream{
+						ID:                id,
+						SelfLink:          self,
+						Name:              stream.name + " of " + t.ID,
+						Description:       stream.name + " reported by truck " + t.ID,
+						ObservationType:   stream.observationType,
+						UnitOfMeasurement: stream.unit,
+						PhenomenonTime:    at,
+						ThingLink:         self + "/Thing",
+						ObservationsLink:  self + "/Observations",
+					})
+					continue
+				}
+				id = fmt.Sprintf("%s:%d", id, t.UpdatedAt.UnixMilli())
+				self := fmt.Sprintf("%s/Observations('%s')", base, id)
+				entities = append(entities, staObservation{
+					ID:             id,
+					SelfLink:       self,
+					PhenomenonTime: at,
+					ResultTime:     at,
+					Result:         value,
+					DatastreamLink: self + "/Datastream",
+				})
+			}
+		default:
+			return nil, false
+		}
+	}
+	return entities, true
+}
+
+func staID(entity any) string {
+	switch e := entity.(type) {
+	case staThing:
+		return e.ID
+	case staLocation:
+		return e.ID
+	case staDatastream:
+		return e.ID
+	case staObservation:
+		return e.ID
+	}
+	return ""
+}
+
+// writeSTACollection pages entities by $top (default 100, at most 1000) and $skip, adding @iot.count
+// when $count=true and @iot.nextLink while more remain.
+func writeSTACollection(w http.ResponseWriter, r *http.Request, self string, entities []any) {
+	query := r.URL.Query()
+	top, skip := 100, 0
+	if v := query.Get("$top"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 0 {
+			http.Error(w, "$top must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		top = min(parsed, 1000)
+	}
+	if v := query.Get("$skip"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 0 {
+			http.Error(w, "$skip must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		skip = parsed
+	}
+
+	start := min(skip, len(entities))
+	end := min(start+top, len(entities))
+	resp := staCollection{Value: entities[start:end]}
+	if query.Get("$count") == "true" {
+		count := len(entities)
+		resp.Count = &count
+	}
+	if end < len(entities) {
+		resp.NextLink = fmt.Sprintf("%s?$top=%d&$skip=%d", self, top, end)
+	}
+	writeSTA(w, resp)
+}
+
+func writeSTA(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// requestBaseURL returns the scheme and host the request was addressed to, honouring X-Forwarded-Proto.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
@@ -3,29 +3,29 @@ package server
 import (
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"net/http"
 	"net/http/pprof"
+	"runtime"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/websocket"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"orbit/backend/analytics"
+	"orbit/backend/auth"
+	"orbit/backend/demo"
+	"orbit/backend/events"
+	"orbit/backend/history"
+	"orbit/backend/ids"
+	"orbit/backend/profiles"
+	"orbit/backend/redact"
 	"orbit/backend/simulation"
+	"orbit/backend/version"
 )
 
-var apiLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
-	Name:    "orbit_api_latency_seconds",
-	Help:    "Time spent serving HTTP handlers.",
-	Buckets: prometheus.DefBuckets,
-}, []string{"method", "path", "status"})
-
-func init() {
-	prometheus.MustRegister(apiLatency)
-}
-
 // Server exposes HTTP and WebSocket endpoints for the truck simulation.
 type Server struct {
 	sim               *simulation.Manager
@@ -37,10 +37,42 @@ type Server struct {
 	logger            *slog.Logger
 	correlationHeader string
 	adminEnabled      bool
+	configLimiter     *concurrencyLimiter
+	snapshotLimiter   *concurrencyLimiter
+	idGen             ids.Generator
+	follows           *followSessions
+	clusters          *analytics.BehaviorClusterer
+	proximity         *analytics.ProximityDetector
+	logHeaders        bool
+	demoSigner        *demo.Signer
+	demoRequired      bool
+	encoder           *snapshotEncoder
+	eventBus          *events.Bus
+	alerts            *events.Inbox
+	authenticator     auth.Authenticator
+	profiles          *profiles.Store
+	positions         *history.Store
+	streams           *streamGate
+	ui                fs.FS
+	extraRoutes       []extraRoute
+	limits            Limits
 }
 
+// extraRoute is an embedder's handler added with WithRoute.
+type extraRoute struct {
+	pattern string
+	handler http.HandlerFunc
+}
+
+const (
+	defaultConfigPostLimit   = 4
+	defaultSnapshotGetLimit  = 100
+	defaultLimiterRetryAfter = time.Second
+)
+
 // NewServer constructs a Server with sensible defaults for pagination and streaming.
 func NewServer(sim *simulation.Manager) *Server {
+	idGen := ids.NewULIDGenerator()
 	return &Server{
 		sim: sim,
 		wsUpgrader: websocket.Upgrader{
@@ -50,8 +82,14 @@ func NewServer(sim *simulation.Manager) *Server {
 		wsChunkSize:       200,
 		defaultPage:       1,
 		defaultLimit:      100,
-		logger:            slog.Default(),
+		logger:            redactingLogger(slog.Default()),
 		correlationHeader: "X-Correlation-ID",
+		configLimiter:     newConcurrencyLimiter(defaultConfigPostLimit, defaultLimiterRetryAfter),
+		snapshotLimiter:   newConcurrencyLimiter(defaultSnapshotGetLimit, defaultLimiterRetryAfter),
+		idGen:             idGen,
+		follows:           newFollowSessions(),
+		encoder:           newSnapshotEncoder(0),
+		streams:           newStreamGate(),
 	}
 }
 
@@ -61,14 +99,29 @@ func (s *Server) WithAdminEnabled() *Server {
 	return s
 }
 
-// WithLogger configures structured logging.
+// WithLogger configures structured logging. A logger that does not already redact is wrapped with the
+// default deny-lists, since request logs carry query strings and may carry headers.
 func (s *Server) WithLogger(logger *slog.Logger) *Server {
 	if logger != nil {
-		s.logger = logger
+		s.logger = redactingLogger(logger)
 	}
 	return s
 }
 
+func redactingLogger(logger *slog.Logger) *slog.Logger {
+	if _, ok := logger.Handler().(*redact.Handler); ok {
+		return logger
+	}
+	return slog.New(redact.NewHandler(logger.Handler(), redact.New(nil, nil)))
+}
+
+// WithRequestHeaderLogging adds request headers to the request log. Credential headers are redacted; pass
+// a logger built with redact.NewHandler to deny more.
+func (s *Server) WithRequestHeaderLogging() *Server {
+	s.logHeaders = true
+	return s
+}
+
 // WithCorrelationHeader configures the header used to propagate correlation IDs.
 func (s *Server) WithCorrelationHeader(header string) *Server {
 	if header != "" {
@@ -77,15 +130,98 @@ func (s *Server) WithCorrelationHeader(header string) *Server {
 	return s
 }
 
+// WithIDGenerator configures how event subscription identifiers are generated. Follow session IDs are
+// always random, since they grant access to a stream.
+func (s *Server) WithIDGenerator(gen ids.Generator) *Server {
+	if gen != nil {
+		s.idGen = gen
+	}
+	return s
+}
+
+// WithConcurrencyLimits bounds concurrent config POSTs and snapshot GETs. A non-positive limit disables that group.
+func (s *Server) WithConcurrencyLimits(configPosts, snapshotGets int) *Server {
+	s.configLimiter = newConcurrencyLimiter(configPosts, defaultLimiterRetryAfter)
+	s.snapshotLimiter = newConcurrencyLimiter(snapshotGets, defaultLimiterRetryAfter)
+	return s
+}
+
+// WithSnapshotEncoders bounds how many truck snapshots are encoded at once; the rest queue. A non-positive
+// count uses half of GOMAXPROCS.
+func (s *Server) WithSnapshotEncoders(workers int) *Server {
+	s.encoder = newSnapshotEncoder(workers)
+	return s
+}
+
+// WithRoute serves an embedder's handler at pattern next to the built-in endpoints, with the same request
+// logging and correlation IDs. Patterns follow http.ServeMux; registering a built-in pattern panics in
+// Routes.
+func (s *Server) WithRoute(pattern string, handler http.HandlerFunc) *Server {
+	s.extraRoutes = append(s.extraRoutes, extraRoute{pattern: pattern, handler: handler})
+	return s
+}
+
+// WithBehaviorClusters exposes behaviour cluster assignments from the given clusterer.
+func (s *Server) WithBehaviorClusters(c *analytics.BehaviorClusterer) *Server {
+	s.clusters = c
+	return s
+}
+
+// WithProximityDetector exposes the trucks currently too close to each other from the given detector.
+func (s *Server) WithProximityDetector(d *analytics.ProximityDetector) *Server {
+	s.proximity = d
+	return s
+}
+
 // Routes returns an http.Handler that serves all endpoints.
 func (s *Server) Routes() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthz", s.wrap(s.handleHealth))
 	mux.HandleFunc("/readyz", s.wrap(s.handleReadiness))
-	mux.HandleFunc("/api/trucks", s.wrap(s.handleTrucks))
-	mux.HandleFunc("/api/simulation/config", s.wrap(s.handleSimulationConfig))
+	mux.HandleFunc("/api/info", s.wrap(s.handleInfo))
+	mux.HandleFunc("/api/ui-config", s.wrap(s.handleUIConfig))
+	mux.HandleFunc("/api/trucks", s.wrap(s.snapshotLimiter.limit(s.handleTrucks, http.MethodGet)))
+	mux.HandleFunc("/api/trucks/", s.wrap(s.handleTruck))
+	mux.HandleFunc("/api/simulation/config", s.wrap(s.configLimiter.limit(s.handleSimulationConfig, http.MethodPost)))
+	mux.HandleFunc("/api/simulation/config/preview", s.wrap(s.handleConfigPreview))
+	mux.HandleFunc("/api/simulation/estimate", s.wrap(s.handleSimulationEstimate))
+	mux.HandleFunc("/api/simulation/config/history", s.wrap(s.handleConfigHistory))
+	mux.HandleFunc("/api/simulation/config/history/", s.wrap(s.configLimiter.limit(s.handleConfigHistoryItem, http.MethodPost)))
+	mux.HandleFunc("/api/simulation/pause", s.wrap(s.handleSimulationPause))
+	mux.HandleFunc("/api/simulation/resume", s.wrap(s.handleSimulationResume))
+	mux.HandleFunc("/api/routes", s.wrap(s.handleRoutes))
+	mux.HandleFunc("/api/routes/", s.wrap(s.handleRoute))
+	mux.HandleFunc("/api/trips", s.wrap(s.handleTrips))
+	mux.HandleFunc("/api/depots", s.wrap(s.handleDepots))
+	mux.HandleFunc("/api/shipments", s.wrap(s.handleShipments))
+	mux.HandleFunc("/api/fleet/commands", s.wrap(s.handleFleetCommands))
+	mux.HandleFunc("/api/fleet/trucks", s.wrap(s.handleFleetTrucks))
+	mux.HandleFunc("/api/analytics/clusters", s.wrap(s.handleClusters))
+	mux.HandleFunc("/api/analytics/leaderboard", s.wrap(s.handleLeaderboard))
+	mux.HandleFunc("/api/analytics/speed-compliance", s.wrap(s.handleSpeedCompliance))
+	mux.HandleFunc("/api/analytics/proximity", s.wrap(s.handleProximity))
+	mux.HandleFunc("/api/analytics/punctuality", s.wrap(s.handlePunctuality))
+	mux.HandleFunc("/api/sessions/follow", s.wrap(s.handleFollowSession))
+	mux.HandleFunc(sensorThingsPrefix, s.wrap(s.handleSensorThings))
+	mux.HandleFunc(sensorThingsPrefix+"/", s.wrap(s.handleSensorThings))
+	mux.HandleFunc("/wfs", s.wrap(s.handleWFS))
 	mux.HandleFunc("/ws/trucks", s.wrap(s.handleTrucksWebSocket))
-	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/ws/follow", s.wrap(s.handleFollowWebSocket))
+	if s.eventBus != nil {
+		mux.HandleFunc("/ws/events", s.wrap(s.handleEventsWebSocket))
+	}
+	if s.alerts != nil {
+		mux.HandleFunc("/api/alerts", s.wrap(s.handleAlerts))
+		mux.HandleFunc("/api/alerts/", s.wrap(s.handleAlert))
+	}
+	if s.positions != nil {
+		mux.HandleFunc("/api/playback", s.wrap(s.handlePlayback))
+	}
+	if s.profiles != nil {
+		mux.HandleFunc("/api/profiles", s.wrap(s.handleProfiles))
+		mux.HandleFunc("/api/profiles/", s.wrap(s.handleProfile))
+	}
+	mux.Handle("/metrics", metricsHandler())
 
 	if s.adminEnabled {
 		mux.HandleFunc("/admin/debug/pprof/", pprof.Index)
@@ -93,15 +229,36 @@ func (s *Server) Routes() http.Handler {
 		mux.HandleFunc("/admin/debug/pprof/profile", pprof.Profile)
 		mux.HandleFunc("/admin/debug/pprof/symbol", pprof.Symbol)
 		mux.HandleFunc("/admin/debug/pprof/trace", pprof.Trace)
+		mux.HandleFunc("/admin/simulation/recording", s.wrap(s.handleRecording))
+		mux.HandleFunc("/admin/simulation/step", s.wrap(s.handleSimulationStep))
+		mux.HandleFunc("/admin/trucks/", s.wrap(s.handleAdminTruck))
+		if s.demoSigner != nil {
+			mux.HandleFunc("/admin/demo-tokens", s.wrap(s.handleDemoTokens))
+		}
+	}
+	for _, route := range s.extraRoutes {
+		mux.HandleFunc(route.pattern, s.wrap(route.handler))
+	}
+	if s.ui != nil {
+		mux.HandleFunc("/", s.handleUI)
 	}
-	return mux
+	var handler http.Handler = mux
+	if s.demoSigner != nil {
+		handler = s.demoGate(handler)
+	}
+	if s.authenticator != nil {
+		handler = s.authGate(handler)
+	}
+	return handler
 }
 
 type paginatedResponse struct {
-	Trucks []simulation.Truck `json:"trucks"`
-	Page   int                `json:"page"`
-	Size   int                `json:"size"`
-	Total  int                `json:"total"`
+	Trucks        []simulation.Truck `json:"trucks"`
+	Page          int                `json:"page"`
+	Size          int                `json:"size"`
+	Total         int                `json:"total"`
+	SimulatedTime time.Time          `json:"simulatedTime"`
+	RunID         string             `json:"runId"`
 }
 
 type boundingBoxPayload struct {
@@ -111,17 +268,70 @@ type boundingBoxPayload struct {
 	MaxLon float64 `json:"maxLon"`
 }
 
+// speedZonePayload is a speed limit in m/s over either a bounding box or a polygon of at least three
+// vertices.
+type speedZonePayload struct {
+	Name        string              `json:"name"`
+	Limit       float64             `json:"limit"`
+	BoundingBox *boundingBoxPayload `json:"boundingBox,omitempty"`
+	Polygon     []simulation.Point  `json:"polygon,omitempty"`
+}
+
 type simulationConfigRequest struct {
-	NumTrucks        *int                `json:"numTrucks"`
-	UpdateIntervalMs *int                `json:"updateIntervalMs"`
-	BoundingBox      *boundingBoxPayload `json:"boundingBox"`
-	RestoreDefaults  bool                `json:"restoreDefaults"`
+	NumTrucks        *int                 `json:"numTrucks"`
+	UpdateIntervalMs *int                 `json:"updateIntervalMs"`
+	BoundingBox      *boundingBoxPayload  `json:"boundingBox"`
+	BoundingBoxes    []boundingBoxPayload `json:"boundingBoxes"`
+	TimeScale        *float64             `json:"timeScale"`
+	SpeedZones       []speedZonePayload   `json:"speedZones"`
+	RouteAssignments []string             `json:"routeAssignments"`
+	RestoreDefaults  bool                 `json:"restoreDefaults"`
+	Reset            bool                 `json:"reset"`
 }
 
 type simulationConfigResponse struct {
-	NumTrucks        int                 `json:"numTrucks"`
-	UpdateIntervalMs int                 `json:"updateIntervalMs"`
-	BoundingBox      *boundingBoxPayload `json:"boundingBox,omitempty"`
+	NumTrucks        int                  `json:"numTrucks"`
+	UpdateIntervalMs int                  `json:"updateIntervalMs"`
+	BoundingBox      *boundingBoxPayload  `json:"boundingBox,omitempty"`
+	BoundingBoxes    []boundingBoxPayload `json:"boundingBoxes,omitempty"`
+	TimeScale        float64              `json:"timeScale"`
+	SpeedZones       []speedZonePayload   `json:"speedZones,omitempty"`
+	RouteAssignments []string             `json:"routeAssignments,omitempty"`
+}
+
+type infoResponse struct {
+	Version           versionInfo `json:"version"`
+	GoMaxProcs        int         `json:"gomaxprocs"`
+	NumCPU            int         `json:"numCPU"`
+	SimulationWorkers int         `json:"simulationWorkers"`
+	RunID             string      `json:"runId"`
+	RunNumber         int         `json:"runNumber"`
+	Seed              int64       `json:"seed"`
+	// Regions lists update-interval regions, the default region last, with the trucks in each.
+	Regions []regionInfo `json:"regions"`
+	// Replay is the progress of the recording being played with -replay.
+	Replay *replayInfo `json:"replay,omitempty"`
+}
+
+type replayInfo struct {
+	Tick  int64   `json:"tick"`
+	Ticks int64   `json:"ticks"`
+	Speed float64 `json:"speed"`
+	Loops int     `json:"loops"`
+	Loop  bool    `json:"loop"`
+}
+
+type versionInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision"`
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
+}
+
+type regionInfo struct {
+	Name             string `json:"name"`
+	UpdateIntervalMs int    `json:"updateIntervalMs"`
+	Trucks           int    `json:"trucks"`
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -129,8 +339,18 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write([]byte("ok"))
 }
 
+// playing reports whether the simulation is playing a recording instead of running live.
+func (s *Server) playing() bool {
+	_, ok := s.sim.Playback()
+	return ok
+}
+
 func (s *Server) handleReadiness(w http.ResponseWriter, r *http.Request) {
-	if s.sim == nil || !s.sim.Started() {
+	if s.sim != nil && s.sim.Failure() != nil {
+		http.Error(w, "simulation failed", http.StatusServiceUnavailable)
+		return
+	}
+	if s.sim == nil || !s.sim.Started() && !s.playing() {
 		http.Error(w, "simulation not started", http.StatusServiceUnavailable)
 		return
 	}
@@ -138,10 +358,53 @@ func (s *Server) handleReadiness(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write([]byte("ready"))
 }
 
+func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	run := s.sim.Run()
+	build := version.Get()
+	resp := infoResponse{
+		Version:           versionInfo{Version: build.Version, Revision: build.Revision, GoVersion: build.GoVersion, Platform: build.Platform},
+		GoMaxProcs:        runtime.GOMAXPROCS(0),
+		NumCPU:            runtime.NumCPU(),
+		SimulationWorkers: s.sim.Config().Workers,
+		RunID:             run.ID,
+		RunNumber:         run.Number,
+		Seed:              run.Seed,
+	}
+	if p, ok := s.sim.Playback(); ok {
+		resp.Replay = &replayInfo{Tick: p.Tick, Ticks: p.Ticks, Speed: p.Speed, Loops: p.Loops, Loop: p.Loop}
+	}
+	for _, region := range s.sim.Regions() {
+		resp.Regions = append(resp.Regions, regionInfo{
+			Name:             region.Name,
+			UpdateIntervalMs: int(region.UpdateInterval / time.Millisecond),
+			Trucks:           region.Trucks,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
 func (s *Server) handleTrucks(w http.ResponseWriter, r *http.Request) {
 	page := s.defaultPage
 	size := s.defaultLimit
 
+	mercator, err := wantsWebMercator(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	geoJSON, err := wantsGeoJSON(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if geoJSON && mercator {
+		http.Error(w, "GeoJSON coordinates are always EPSG:4326", http.StatusBadRequest)
+		return
+	}
+	w.Header().Add("Vary", "Accept")
+
 	if v := r.URL.Query().Get("page"); v != "" {
 		if parsed, err := strconv.Atoi(v); err == nil && parsed > 0 {
 			page = parsed
@@ -153,7 +416,11 @@ func (s *Server) handleTrucks(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	snapshot := s.sim.Trucks()
+	snapshot, err := s.filteredTrucks(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	total := len(snapshot)
 
 	start := (page - 1) * size
@@ -166,14 +433,114 @@ func (s *Server) handleTrucks(w http.ResponseWriter, r *http.Request) {
 	}
 
 	resp := paginatedResponse{
-		Trucks: snapshot[start:end],
-		Page:   page,
-		Size:   size,
-		Total:  total,
+		Trucks:        snapshot[start:end],
+		Page:          page,
+		Size:          size,
+		Total:         total,
+		SimulatedTime: s.sim.SimulatedTime(),
+		RunID:         s.sim.RunID(),
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(resp)
+	if geoJSON {
+		s.writeSnapshotAs(w, r, geoJSONMimeType, truckCollection(resp))
+		return
+	}
+	if mercator {
+		s.writeSnapshot(w, r, projectedPage{paginatedResponse: resp, Trucks: projectTrucks(resp.Trucks), Projection: "EPSG:3857"})
+		return
+	}
+	s.writeSnapshot(w, r, resp)
+}
+
+// filteredTrucks returns the trucks the request may see, narrowed by the bbox, status, type, geohash, and
+// fleet query parameters.
+func (s *Server) filteredTrucks(r *http.Request) ([]simulation.Truck, error) {
+	var snapshot []simulation.Truck
+	if v := r.URL.Query().Get("bbox"); v != "" {
+		box, err := parseBBoxParam(v)
+		if err != nil {
+			return nil, err
+		}
+		snapshot = visibleTrucks(r, s.sim.TrucksInBox(box))
+	} else {
+		snapshot = visibleTrucks(r, s.sim.Trucks())
+	}
+	if status := r.URL.Query().Get("status"); status != "" {
+		filtered := snapshot[:0]
+		for _, truck := range snapshot {
+			if string(truck.Status) == status {
+				filtered = append(filtered, truck)
+			}
+		}
+		snapshot = filtered
+	}
+	if typ := r.URL.Query().Get("type"); typ != "" {
+		filtered := snapshot[:0]
+		for _, truck := range snapshot {
+			if string(truck.Type) == typ {
+				filtered = append(filtered, truck)
+			}
+		}
+		snapshot = filtered
+	}
+	if prefix := strings.ToLower(r.URL.Query().Get("geohash")); prefix != "" {
+		if !validGeohash(prefix) {
+			return nil, fmt.Errorf("geohash must be up to %d geohash characters", simulation.GeohashPrecision)
+		}
+		filtered := snapshot[:0]
+		for _, truck := range snapshot {
+			if strings.HasPrefix(truck.Geohash, prefix) {
+				filtered = append(filtered, truck)
+			}
+		}
+		snapshot = filtered
+	}
+	// An empty fleet parameter selects the home fleet.
+	if r.URL.Query().Has("fleet") {
+		fleet := r.URL.Query().Get("fleet")
+		filtered := snapshot[:0]
+		for _, truck := range snapshot {
+			if truck.Fleet == fleet {
+				filtered = append(filtered, truck)
+			}
+		}
+		snapshot = filtered
+	}
+	return snapshot, nil
+}
+
+// parseBBoxParam parses a bbox query parameter of minLat,minLon,maxLat,maxLon.
+func parseBBoxParam(v string) (simulation.BoundingBox, error) {
+	parts := strings.Split(v, ",")
+	if len(parts) != 4 {
+		return simulation.BoundingBox{}, fmt.Errorf("bbox must be minLat,minLon,maxLat,maxLon")
+	}
+	var values [4]float64
+	for i, part := range parts {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return simulation.BoundingBox{}, fmt.Errorf("bbox must be minLat,minLon,maxLat,maxLon")
+		}
+		values[i] = parsed
+	}
+	payload := boundingBoxPayload{MinLat: values[0], MinLon: values[1], MaxLat: values[2], MaxLon: values[3]}
+	if err := payload.validate(); err != nil {
+		return simulation.BoundingBox{}, err
+	}
+	return payload.toBoundingBox(), nil
+}
+
+// validGeohash reports whether s can prefix a truck's geohash.
+func validGeohash(s string) bool {
+	if len(s) > simulation.GeohashPrecision {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789bcdefghjkmnpqrstuvwxyz", c) {
+			return false
+		}
+	}
+	return true
 }
 
 func (s *Server) handleSimulationConfig(w http.ResponseWriter, r *http.Request) {
@@ -192,43 +559,18 @@ func (s *Server) handleSimulationConfig(w http.ResponseWriter, r *http.Request)
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
+			s.logConfigChange(r)
 			s.respondWithConfig(w, s.sim.Config())
 			return
 		}
 
-		if req.NumTrucks == nil && req.UpdateIntervalMs == nil && req.BoundingBox == nil {
-			http.Error(w, "no configuration provided", http.StatusBadRequest)
+		update, err := s.configUpdate(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-
-		update := simulation.ConfigUpdate{}
-		if req.NumTrucks != nil {
-			if *req.NumTrucks <= 0 {
-				http.Error(w, "numTrucks must be positive", http.StatusBadRequest)
-				return
-			}
-			update.NumTrucks = req.NumTrucks
-		}
-		if req.UpdateIntervalMs != nil {
-			if *req.UpdateIntervalMs <= 0 {
-				http.Error(w, "updateIntervalMs must be positive", http.StatusBadRequest)
-				return
-			}
-			interval := time.Duration(*req.UpdateIntervalMs) * time.Millisecond
-			update.UpdateInterval = &interval
-		}
-		if req.BoundingBox != nil {
-			if err := req.BoundingBox.validate(); err != nil {
-				http.Error(w, err.Error(), http.StatusBadRequest)
-				return
-			}
-			bbox := simulation.BoundingBox{
-				MinLat: req.BoundingBox.MinLat,
-				MaxLat: req.BoundingBox.MaxLat,
-				MinLon: req.BoundingBox.MinLon,
-				MaxLon: req.BoundingBox.MaxLon,
-			}
-			update.BoundingBox = &bbox
+		if rejectOverLimit(w, s.checkConfigLimits(update)) {
+			return
 		}
 
 		cfg, err := s.sim.ApplyUpdate(update)
@@ -236,12 +578,141 @@ func (s *Server) handleSimulationConfig(w http.ResponseWriter, r *http.Request)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		s.logConfigChange(r)
 		s.respondWithConfig(w, cfg)
 	default:
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	}
 }
 
+// configUpdate validates a config request and turns it into an update for the simulation.
+func (s *Server) configUpdate(req simulationConfigRequest) (simulation.ConfigUpdate, error) {
+	if req.NumTrucks == nil && req.UpdateIntervalMs == nil && req.BoundingBox == nil && req.BoundingBoxes == nil && req.TimeScale == nil && req.SpeedZones == nil && req.RouteAssignments == nil && !req.Reset {
+		return simulation.ConfigUpdate{}, fmt.Errorf("no configuration provided")
+	}
+
+	update := simulation.ConfigUpdate{Reset: req.Reset}
+	if req.NumTrucks != nil {
+		if *req.NumTrucks <= 0 {
+			return simulation.ConfigUpdate{}, fmt.Errorf("numTrucks must be positive")
+		}
+		update.NumTrucks = req.NumTrucks
+	}
+	if req.UpdateIntervalMs != nil {
+		if *req.UpdateIntervalMs <= 0 {
+			return simulation.ConfigUpdate{}, fmt.Errorf("updateIntervalMs must be positive")
+		}
+		interval := time.Duration(*req.UpdateIntervalMs) * time.Millisecond
+		update.UpdateInterval = &interval
+	}
+	if req.BoundingBox != nil && req.BoundingBoxes != nil {
+		return simulation.ConfigUpdate{}, fmt.Errorf("send either boundingBox or boundingBoxes, not both")
+	}
+	if req.BoundingBox != nil {
+		if err := req.BoundingBox.validate(); err != nil {
+			return simulation.ConfigUpdate{}, err
+		}
+		bbox := req.BoundingBox.toBoundingBox()
+		update.BoundingBox = &bbox
+	}
+	if req.BoundingBoxes != nil {
+		update.RouteBounds = make([]simulation.BoundingBox, 0, len(req.BoundingBoxes))
+		for i, payload := range req.BoundingBoxes {
+			if err := payload.validate(); err != nil {
+				return simulation.ConfigUpdate{}, fmt.Errorf("boundingBoxes[%d]: %v", i, err)
+			}
+			update.RouteBounds = append(update.RouteBounds, payload.toBoundingBox())
+		}
+	}
+	if req.TimeScale != nil {
+		if *req.TimeScale <= 0 {
+			return simulation.ConfigUpdate{}, fmt.Errorf("timeScale must be positive")
+		}
+		update.TimeScale = req.TimeScale
+	}
+	if req.SpeedZones != nil {
+		update.SpeedZones = make([]simulation.SpeedZone, 0, len(req.SpeedZones))
+		for i, payload := range req.SpeedZones {
+			zone, err := payload.toSpeedZone()
+			if err != nil {
+				return simulation.ConfigUpdate{}, fmt.Errorf("speedZones[%d]: %v", i, err)
+			}
+			update.SpeedZones = append(update.SpeedZones, zone)
+		}
+	}
+	if req.RouteAssignments != nil {
+		for _, name := range req.RouteAssignments {
+			if _, ok := s.sim.NamedRoute(name); !ok {
+				return simulation.ConfigUpdate{}, fmt.Errorf("route %q is not in the catalog", name)
+			}
+		}
+		update.RouteAssignments = req.RouteAssignments
+	}
+	return update, nil
+}
+
+type simulationStateResponse struct {
+	Running bool `json:"running"`
+	Paused  bool `json:"paused"`
+}
+
+func (s *Server) handleSimulationPause(w http.ResponseWriter, r *http.Request) {
+	s.changeSimulationState(w, r, s.sim.Pause)
+}
+
+func (s *Server) handleSimulationResume(w http.ResponseWriter, r *http.Request) {
+	s.changeSimulationState(w, r, s.sim.Resume)
+}
+
+func (s *Server) changeSimulationState(w http.ResponseWriter, r *http.Request, change func() error) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := change(); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(simulationStateResponse{Running: s.sim.Started(), Paused: s.sim.Paused()})
+}
+
+// maxStepTicks bounds a single step request. The step takes the simulation lock tick by tick, but Resume
+// is refused until it finishes, so an unbounded step could keep the simulation paused indefinitely.
+const maxStepTicks = 10000
+
+type stepResponse struct {
+	Ticks         int       `json:"ticks"`
+	SimulatedTime time.Time `json:"simulatedTime"`
+}
+
+// handleSimulationStep advances a paused simulation by ?ticks=N (default 1) ticks.
+func (s *Server) handleSimulationStep(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ticks := 1
+	if v := r.URL.Query().Get("ticks"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 || parsed > maxStepTicks {
+			http.Error(w, fmt.Sprintf("ticks must be between 1 and %d", maxStepTicks), http.StatusBadRequest)
+			return
+		}
+		ticks = parsed
+	}
+
+	if err := s.sim.StepOnce(ticks); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(stepResponse{Ticks: ticks, SimulatedTime: s.sim.SimulatedTime()})
+}
+
 func (s *Server) respondWithConfig(w http.ResponseWriter, cfg simulation.Config) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(simulationConfigToResponse(cfg))
@@ -249,22 +720,66 @@ func (s *Server) respondWithConfig(w http.ResponseWriter, cfg simulation.Config)
 
 func simulationConfigToResponse(cfg simulation.Config) simulationConfigResponse {
 	var bbox *boundingBoxPayload
-	if len(cfg.RouteBounds) > 0 {
-		bbox = &boundingBoxPayload{
-			MinLat: cfg.RouteBounds[0].MinLat,
-			MaxLat: cfg.RouteBounds[0].MaxLat,
-			MinLon: cfg.RouteBounds[0].MinLon,
-			MaxLon: cfg.RouteBounds[0].MaxLon,
+	var boxes []boundingBoxPayload
+	for _, b := range cfg.RouteBounds {
+		boxes = append(boxes, boundingBoxPayload{MinLat: b.MinLat, MaxLat: b.MaxLat, MinLon: b.MinLon, MaxLon: b.MaxLon})
+	}
+	if len(boxes) > 0 {
+		bbox = &boxes[0]
+	}
+
+	var zones []speedZonePayload
+	for _, z := range cfg.SpeedZones {
+		payload := speedZonePayload{Name: z.Name, Limit: z.Limit, Polygon: z.Polygon}
+		if len(z.Polygon) < 3 {
+			payload.BoundingBox = &boundingBoxPayload{MinLat: z.Bounds.MinLat, MaxLat: z.Bounds.MaxLat, MinLon: z.Bounds.MinLon, MaxLon: z.Bounds.MaxLon}
+			payload.Polygon = nil
 		}
+		zones = append(zones, payload)
 	}
 
 	return simulationConfigResponse{
 		NumTrucks:        cfg.NumTrucks,
 		UpdateIntervalMs: int(cfg.UpdateInterval.Milliseconds()),
 		BoundingBox:      bbox,
+		BoundingBoxes:    boxes,
+		TimeScale:        cfg.TimeScale,
+		SpeedZones:       zones,
+		RouteAssignments: cfg.RouteAssignments,
 	}
 }
 
+func (p speedZonePayload) toSpeedZone() (simulation.SpeedZone, error) {
+	if p.Limit <= 0 {
+		return simulation.SpeedZone{}, fmt.Errorf("limit must be positive")
+	}
+	zone := simulation.SpeedZone{Name: p.Name, Limit: p.Limit}
+	switch {
+	case p.BoundingBox != nil && p.Polygon != nil:
+		return simulation.SpeedZone{}, fmt.Errorf("send either boundingBox or polygon, not both")
+	case p.BoundingBox != nil:
+		if err := p.BoundingBox.validate(); err != nil {
+			return simulation.SpeedZone{}, err
+		}
+		zone.Bounds = p.BoundingBox.toBoundingBox()
+	case len(p.Polygon) >= 3:
+		for _, v := range p.Polygon {
+			if v.Lat < -90 || v.Lat > 90 || v.Lon < -180 || v.Lon > 180 {
+				return simulation.SpeedZone{}, fmt.Errorf("polygon vertex out of range")
+			}
+		}
+		zone.Polygon = p.Polygon
+		zone.Bounds = simulation.BoundingBoxFromPoints(p.Polygon)
+	default:
+		return simulation.SpeedZone{}, fmt.Errorf("a boundingBox or a polygon of at least three vertices is required")
+	}
+	return zone, nil
+}
+
+func (p boundingBoxPayload) toBoundingBox() simulation.BoundingBox {
+	return simulation.BoundingBox{MinLat: p.MinLat, MaxLat: p.MaxLat, MinLon: p.MinLon, MaxLon: p.MaxLon}
+}
+
 func (p boundingBoxPayload) validate() error {
 	if p.MinLat >= p.MaxLat || p.MinLon >= p.MaxLon {
 		return fmt.Errorf("invalid bounding box extents")
@@ -275,23 +790,101 @@ func (p boundingBoxPayload) validate() error {
 	return nil
 }
 
+// handleTrucksWebSocket streams truck snapshots. ?profile= fills in the parameters of a saved profile, and
+// the /api/trucks filters, ?fields=, and ?interval= shape what is sent and how often.
 func (s *Server) handleTrucksWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := s.wsUpgrader.Upgrade(w, r, nil)
+	r, err := s.applyProfile(r)
 	if err != nil {
-		s.logger.Error("websocket upgrade failed", "err", err, "correlation_id", correlationIDFromContext(r.Context()))
+		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	if _, err := s.filteredTrucks(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fields, err := fieldsParam(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	interval, err := s.streamInterval(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	mercator, err := wantsWebMercator(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var polylines polylineTracker
+	if wantsPolylines(r) {
+		polylines = polylineTracker{}
+	}
+	trail, err := trailParams(r, s.sim.TrackDepth())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conn, ok := s.acceptStream(w, r)
+	if !ok {
+		return
+	}
+	defer s.streams.release()
 	defer conn.Close()
 
-	ticker := time.NewTicker(s.wsInterval)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	delivery := &deliveryTracker{stream: "trucks"}
 	sendSnapshot := func() error {
-		trucks := s.sim.Trucks()
+		generated := s.sim.Generated()
+		trucks, _ := s.filteredTrucks(r)
 		if s.wsChunkSize > 0 && len(trucks) > s.wsChunkSize {
 			trucks = trucks[:s.wsChunkSize]
 		}
-		return conn.WriteJSON(trucks)
+		var routes []string
+		if polylines != nil {
+			routes = polylines.changed(s.sim, trucks)
+		}
+		trails := trail.trails(s.sim, trucks)
+		var payload any = trucks
+		switch {
+		case mercator:
+			projected := projectTrucks(trucks)
+			for i := range routes {
+				projected[i].Polyline = routes[i]
+			}
+			for i := range trails {
+				projected[i].Trail = trails[i]
+			}
+			payload = projected
+		case routes != nil || trails != nil:
+			streamed := make([]streamTruck, len(trucks))
+			for i, truck := range trucks {
+				streamed[i] = streamTruck{Truck: truck}
+				if routes != nil {
+					streamed[i].Polyline = routes[i]
+				}
+				if trails != nil {
+					streamed[i].Trail = trails[i]
+				}
+			}
+			payload = streamed
+		}
+		if fields != nil {
+			selected, err := selectFields(payload, fields)
+			if err != nil {
+				return err
+			}
+			payload = selected
+		}
+		err := s.sendSnapshot(r.Context(), conn, payload)
+		if err == nil {
+			delivery.delivered(generated)
+		}
+		return err
 	}
 
 	if err := sendSnapshot(); err != nil {
@@ -303,6 +896,9 @@ func (s *Server) handleTrucksWebSocket(w http.ResponseWriter, r *http.Request) {
 		select {
 		case <-r.Context().Done():
 			return
+		case <-s.streams.closing:
+			s.closeForRestart(conn)
+			return
 		case <-ticker.C:
 			if err := sendSnapshot(); err != nil {
 				s.logger.Error("websocket send failed", "err", err, "correlation_id", correlationIDFromContext(r.Context()))
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"orbit/backend/simulation"
+)
+
+type shipmentResponse struct {
+	ID          string           `json:"id"`
+	TruckID     string           `json:"truckId"`
+	Origin      simulation.Point `json:"origin"`
+	Destination simulation.Point `json:"destination"`
+	Status      string           `json:"status"`
+	PickedUpAt  *time.Time       `json:"pickedUpAt,omitempty"`
+	DeliveredAt *time.Time       `json:"deliveredAt,omitempty"`
+}
+
+type shipmentsResponse struct {
+	RunID     string             `json:"runId"`
+	Shipments []shipmentResponse `json:"shipments"`
+}
+
+// handleShipments lists shipments, optionally filtered by truckId and status.
+func (s *Server) handleShipments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	status := simulation.ShipmentStatus(query.Get("status"))
+	switch status {
+	case "", simulation.ShipmentStatusPickup, simulation.ShipmentStatusInTransit, simulation.ShipmentStatusDelivered:
+	default:
+		http.Error(w, "status must be pickup, inTransit, or delivered", http.StatusBadRequest)
+		return
+	}
+
+	resp := shipmentsResponse{RunID: s.sim.RunID(), Shipments: []shipmentResponse{}}
+	for _, shipment := range s.sim.Shipments(query.Get("truckId"), status) {
+		item := shipmentResponse{
+			ID:          shipment.ID,
+			TruckID:     shipment.TruckID,
+			Origin:      shipment.Origin,
+			Destination: shipment.Destination,
+			Status:      string(shipment.Status),
+		}
+		if !shipment.PickedUpAt.IsZero() {
+			item.PickedUpAt = &shipment.PickedUpAt
+		}
+		if !shipment.DeliveredAt.IsZero() {
+			item.DeliveredAt = &shipment.DeliveredAt
+		}
+		resp.Shipments = append(resp.Shipments, item)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"orbit/backend/simulation"
+)
+
+type trackPointPayload struct {
+	Time time.Time `json:"time"`
+	Lat  float64   `json:"lat"`
+	Lon  float64   `json:"lon"`
+}
+
+type truckHistoryResponse struct {
+	TruckID string              `json:"truckId"`
+	Depth   int                 `json:"depth"`
+	Points  []trackPointPayload `json:"points"`
+	// Polyline is Points in Google's encoded polyline format.
+	Polyline string `json:"polyline"`
+}
+
+// handleTruckHistory serves GET /api/trucks/{id}/history, the truck's recent track oldest first. ?limit=
+// keeps only the newest positions and ?since= drops positions reported before an RFC 3339 time.
+func (s *Server) handleTruckHistory(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	depth := s.sim.TrackDepth()
+	limit := depth
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > depth {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", depth), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	var since time.Time
+	if v := query.Get("since"); v != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "since must be an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+	}
+
+	track, ok := s.sim.Track(id, limit)
+	if !ok {
+		http.Error(w, "truck not found", http.StatusNotFound)
+		return
+	}
+	resp := truckHistoryResponse{TruckID: id, Depth: depth, Points: []trackPointPayload{}}
+	waypoints := make([]simulation.Point, 0, len(track))
+	for _, p := range track {
+		if p.Time.Before(since) {
+			continue
+		}
+		resp.Points = append(resp.Points, trackPointPayload{Time: p.Time, Lat: p.Lat, Lon: p.Lon})
+		waypoints = append(waypoints, simulation.Point{Lat: p.Lat, Lon: p.Lon})
+	}
+	resp.Polyline = encodePolyline(waypoints)
+	s.writeSnapshot(w, r, resp)
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"orbit/backend/simulation"
+)
+
+// trailOptions is a /ws/trucks client's choice of motion trail: the last Points positions, keeping every
+// Every-th update. Zero Points sends no trails.
+type trailOptions struct {
+	Points int
+	Every  int
+}
+
+// trailParams reads ?trail=K and ?trailEvery=N, each at most the simulation's track depth.
+func trailParams(r *http.Request, depth int) (trailOptions, error) {
+	opts := trailOptions{Every: 1}
+	query := r.URL.Query()
+	if v := query.Get("trail"); v != "" {
+		k, err := strconv.Atoi(v)
+		if err != nil || k < 0 || k > depth {
+			return trailOptions{}, fmt.Errorf("trail must be between 0 and %d", depth)
+		}
+		opts.Points = k
+	}
+	if v := query.Get("trailEvery"); v != "" {
+		every, err := strconv.Atoi(v)
+		if err != nil || every < 1 || every > depth {
+			return trailOptions{}, fmt.Errorf("trailEvery must be between 1 and %d", depth)
+		}
+		opts.Every = every
+	}
+	return opts, nil
+}
+
+// trails returns the trucks' trails, or nil when the client asked for none.
+func (o trailOptions) trails(sim *simulation.Manager, trucks []simulation.Truck) [][]simulation.Point {
+	if o.Points == 0 {
+		return nil
+	}
+	ids := make([]string, len(trucks))
+	for i, truck := range trucks {
+		ids[i] = truck.ID
+	}
+	return sim.Trails(ids, o.Points, o.Every)
+}
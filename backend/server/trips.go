@@ -0,0 +1,67 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"orbit/backend/simulation"
+)
+
+type tripResponse struct {
+	ID             string           `json:"id"`
+	TruckID        string           `json:"truckId"`
+	RouteID        string           `json:"routeId"`
+	Start          time.Time        `json:"start"`
+	End            time.Time        `json:"end"`
+	Origin         simulation.Point `json:"origin"`
+	Destination    simulation.Point `json:"destination"`
+	DistanceMeters float64          `json:"distanceMeters"`
+	AverageSpeed   float64          `json:"averageSpeed"`
+	Stops          int              `json:"stops"`
+}
+
+type tripsResponse struct {
+	RunID string         `json:"runId"`
+	Trips []tripResponse `json:"trips"`
+}
+
+// handleTrips lists completed trips, optionally filtered by truckId and a since timestamp on trip end.
+func (s *Server) handleTrips(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "since must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
+
+	resp := tripsResponse{RunID: s.sim.RunID(), Trips: []tripResponse{}}
+	for _, trip := range s.sim.Trips(r.URL.Query().Get("truckId")) {
+		if trip.End.Before(since) {
+			continue
+		}
+		resp.Trips = append(resp.Trips, tripResponse{
+			ID:             trip.ID,
+			TruckID:        trip.TruckID,
+			RouteID:        trip.RouteID,
+			Start:          trip.Start,
+			End:            trip.End,
+			Origin:         trip.Origin,
+			Destination:    trip.Destination,
+			DistanceMeters: trip.DistanceMeters,
+			AverageSpeed:   trip.AverageSpeed,
+			Stops:          trip.Stops,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"orbit/backend/simulation"
+)
+
+type truckPatchRequest struct {
+	Speed           *float64          `json:"speed"`
+	Status          *string           `json:"status"`
+	DurationSeconds float64           `json:"durationSeconds"`
+	Destination     *simulation.Point `json:"destination"`
+	SpeedLimit      *float64          `json:"speedLimit"`
+	Governed        *bool             `json:"governed"`
+}
+
+type truckRouteRequest struct {
+	// Name assigns a route from the catalog instead of explicit waypoints.
+	Name        string                      `json:"name"`
+	Waypoints   []simulation.Point          `json:"waypoints"`
+	Annotations []waypointAnnotationPayload `json:"annotations"`
+	Loop        bool                        `json:"loop"`
+}
+
+type truckRouteResponse struct {
+	TruckID     string                      `json:"truckId"`
+	RouteID     string                      `json:"routeId"`
+	Name        string                      `json:"name,omitempty"`
+	Waypoints   []simulation.Point          `json:"waypoints"`
+	Annotations []waypointAnnotationPayload `json:"annotations,omitempty"`
+	// Polyline is Waypoints in Google's encoded polyline format.
+	Polyline string `json:"polyline"`
+	Next     int    `json:"next"`
+	Loop     bool   `json:"loop"`
+}
+
+func (s *Server) handleTruck(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/trucks/")
+	if id, action, ok := strings.Cut(id, "/"); ok && id != "" {
+		switch action {
+		case "route":
+			s.handleTruckRoute(w, r, id)
+			return
+		case "handoff":
+			s.handleTruckHandoff(w, r, id)
+			return
+		case "history":
+			s.handleTruckHistory(w, r, id)
+			return
+		}
+	}
+	switch id {
+	case "nearest":
+		s.handleNearestTrucks(w, r)
+		return
+	case "clusters":
+		s.handleTruckClusters(w, r)
+		return
+	case "heatmap":
+		s.handleTruckHeatmap(w, r)
+		return
+	}
+	if id == "" || strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPatch {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req truckPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	update := simulation.TruckUpdate{
+		Speed:       req.Speed,
+		StatusFor:   time.Duration(req.DurationSeconds * float64(time.Second)),
+		Destination: req.Destination,
+		SpeedLimit:  req.SpeedLimit,
+		Governed:    req.Governed,
+	}
+	if req.Status != nil {
+		status := simulation.TruckStatus(*req.Status)
+		update.Status = &status
+	}
+	truck, ok, err := s.sim.UpdateTruck(id, update)
+	if !ok {
+		http.Error(w, "truck not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(truck)
+}
+
+// handleTruckRoute serves /api/trucks/{id}/route. GET returns the route the truck is driving and POST
+// replaces it with an ordered list of waypoints or a named route from the catalog.
+func (s *Server) handleTruckRoute(w http.ResponseWriter, r *http.Request, id string) {
+	switch r.Method {
+	case http.MethodGet:
+		route, ok := s.sim.TruckRoute(id)
+		if !ok {
+			http.Error(w, "truck not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(truckRouteResponse{
+			TruckID:     route.TruckID,
+			RouteID:     route.RouteID,
+			Name:        route.Name,
+			Waypoints:   route.Waypoints,
+			Annotations: annotationPayloads(route.Annotations),
+			Polyline:    encodePolyline(route.Waypoints),
+			Next:        route.Next,
+			Loop:        route.Loop,
+		})
+		return
+	case http.MethodPost:
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req truckRouteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var (
+		truck simulation.Truck
+		ok    bool
+		err   error
+	)
+	if req.Name != "" {
+		if len(req.Waypoints) > 0 || len(req.Annotations) > 0 {
+			http.Error(w, "send either name or waypoints, not both", http.StatusBadRequest)
+			return
+		}
+		truck, ok, err = s.sim.AssignNamedRoute(id, req.Name)
+	} else {
+		if rejectOverLimit(w, s.checkWaypointLimit(len(req.Waypoints))) {
+			return
+		}
+		truck, ok, err = s.sim.AssignAnnotatedRoute(id, req.Waypoints, waypointAnnotations(req.Annotations), req.Loop)
+	}
+	if !ok {
+		http.Error(w, "truck not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(truck)
+}
+
+type truckHandoffRequest struct {
+	Fleet    string `json:"fleet"`
+	IDPrefix string `json:"idPrefix"`
+	Exchange string `json:"exchange"`
+}
+
+// handleTruckHandoff serves POST /api/trucks/{id}/handoff, which sends a truck to a depot to be handed to
+// another fleet under a new ID.
+func (s *Server) handleTruckHandoff(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req truckHandoffRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	truck, ok, err := s.sim.HandoffTruck(id, simulation.Handoff{Fleet: req.Fleet, IDPrefix: req.IDPrefix, Exchange: req.Exchange})
+	if !ok {
+		http.Error(w, "truck not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(truck)
+}
+
+type truckPositionRequest struct {
+	Lat        *float64 `json:"lat"`
+	Lon        *float64 `json:"lon"`
+	ResetRoute bool     `json:"resetRoute"`
+}
+
+// handleAdminTruck serves POST /admin/trucks/{id}/position, which teleports a truck for demo setup and
+// for exercising geofence and alerting rules against arbitrary positions.
+func (s *Server) handleAdminTruck(w http.ResponseWriter, r *http.Request) {
+	id, action, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/admin/trucks/"), "/")
+	if !ok || id == "" || action != "position" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req truckPositionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Lat == nil || req.Lon == nil {
+		http.Error(w, "lat and lon are required", http.StatusBadRequest)
+		return
+	}
+
+	truck, ok, err := s.sim.TeleportTruck(id, simulation.Point{Lat: *req.Lat, Lon: *req.Lon}, req.ResetRoute)
+	if !ok {
+		http.Error(w, "truck not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(truck)
+}
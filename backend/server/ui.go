@@ -0,0 +1,37 @@
+package server
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// WithUI serves the dashboard from assets at the root path, so that one binary can stand in for the nginx
+// frontend container. Unknown paths fall back to index.html for client-side routing, as nginx's try_files
+// does; API, WebSocket, and admin paths are never answered with the dashboard.
+func (s *Server) WithUI(assets fs.FS) *Server {
+	s.ui = assets
+	return s
+}
+
+func (s *Server) handleUI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	for _, prefix := range []string{"/api/", "/ws/", "/admin/"} {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	files := http.FileServer(http.FS(s.ui))
+	name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+	if info, err := fs.Stat(s.ui, name); name != "" && (err != nil || info.IsDir()) {
+		r = r.Clone(r.Context())
+		r.URL.Path = "/"
+	}
+	files.ServeHTTP(w, r)
+}
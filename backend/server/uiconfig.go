@@ -0,0 +1,128 @@
+package server
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+
+	"orbit/backend/simulation"
+)
+
+// uiViewportWidth and uiViewportHeight are the map size, in pixels, the suggested zoom fits the bounds into.
+const (
+	uiViewportWidth  = 1024
+	uiViewportHeight = 768
+	uiMaxZoom        = 18
+)
+
+type uiCenter struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+type uiFleet struct {
+	Type   string `json:"type"`
+	Trucks int    `json:"trucks"`
+}
+
+type uiFeatures struct {
+	Events       bool `json:"events"`
+	Alerts       bool `json:"alerts"`
+	Profiles     bool `json:"profiles"`
+	Playback     bool `json:"playback"`
+	Clusters     bool `json:"clusters"`
+	Proximity    bool `json:"proximity"`
+	DemoTokens   bool `json:"demoTokens"`
+	Admin        bool `json:"admin"`
+	SensorThings bool `json:"sensorThings"`
+	WFS          bool `json:"wfs"`
+}
+
+type uiStreams struct {
+	Trucks string `json:"trucks"`
+	Follow string `json:"follow"`
+	Events string `json:"events,omitempty"`
+}
+
+type uiConfigResponse struct {
+	RunID       string             `json:"runId"`
+	Center      uiCenter           `json:"center"`
+	Zoom        int                `json:"zoom"`
+	BoundingBox boundingBoxPayload `json:"boundingBox"`
+	Fleets      []uiFleet          `json:"fleets"`
+	Features    uiFeatures         `json:"features"`
+	Streams     uiStreams          `json:"streams"`
+	// StreamIntervalMs is how often /ws/trucks pushes a snapshot.
+	StreamIntervalMs int `json:"streamIntervalMs"`
+}
+
+// handleUIConfig tells a frontend how to set itself up: where to centre the map and at what zoom, which
+// vehicle classes make up the fleet, which optional features are enabled, and where to stream from.
+func (s *Server) handleUIConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	bounds := s.sim.Bounds()
+	resp := uiConfigResponse{
+		RunID:       s.sim.RunID(),
+		Center:      uiCenter{Lat: (bounds.MinLat + bounds.MaxLat) / 2, Lon: (bounds.MinLon + bounds.MaxLon) / 2},
+		Zoom:        fitZoom(bounds),
+		BoundingBox: boundingBoxPayload{MinLat: bounds.MinLat, MaxLat: bounds.MaxLat, MinLon: bounds.MinLon, MaxLon: bounds.MaxLon},
+		Fleets:      []uiFleet{},
+		Features: uiFeatures{
+			Events:       s.eventBus != nil,
+			Alerts:       s.alerts != nil,
+			Profiles:     s.profiles != nil,
+			Playback:     s.positions != nil,
+			Clusters:     s.clusters != nil,
+			Proximity:    s.proximity != nil,
+			DemoTokens:   s.demoSigner != nil,
+			Admin:        s.adminEnabled,
+			SensorThings: true,
+			WFS:          true,
+		},
+		Streams:          uiStreams{Trucks: "/ws/trucks", Follow: "/ws/follow"},
+		StreamIntervalMs: int(s.wsInterval.Milliseconds()),
+	}
+	if s.eventBus != nil {
+		resp.Streams.Events = "/ws/events"
+	}
+
+	counts := make(map[string]int)
+	for _, truck := range visibleTrucks(r, s.sim.Trucks()) {
+		typ := string(truck.Type)
+		if typ == "" {
+			typ = "default"
+		}
+		counts[typ]++
+	}
+	for typ, n := range counts {
+		resp.Fleets = append(resp.Fleets, uiFleet{Type: typ, Trucks: n})
+	}
+	sort.Slice(resp.Fleets, func(i, j int) bool { return resp.Fleets[i].Type < resp.Fleets[j].Type })
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// fitZoom returns the largest web map zoom level at which bounds fits a 1024x768 viewport of 256-pixel
+// tiles.
+func fitZoom(bounds simulation.BoundingBox) int {
+	minX, minY := simulation.ToWebMercator(simulation.Point{Lat: bounds.MinLat, Lon: bounds.MinLon})
+	maxX, maxY := simulation.ToWebMercator(simulation.Point{Lat: bounds.MaxLat, Lon: bounds.MaxLon})
+	worldX, _ := simulation.ToWebMercator(simulation.Point{Lon: 180})
+	world := 2 * worldX
+
+	// At zoom z the world is 256 * 2^z pixels wide.
+	zoom := float64(uiMaxZoom)
+	if spanX := maxX - minX; spanX > 0 {
+		zoom = math.Min(zoom, math.Log2(uiViewportWidth*world/(256*spanX)))
+	}
+	if spanY := maxY - minY; spanY > 0 {
+		zoom = math.Min(zoom, math.Log2(uiViewportHeight*world/(256*spanY)))
+	}
+	return int(math.Max(0, math.Floor(zoom)))
+}
@@ -0,0 +1,303 @@
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"orbit/backend/simulation"
+)
+
+// wfsTypeName is the only feature type the WFS endpoint serves.
+const wfsTypeName = "orbit:trucks"
+
+const (
+	wfsNamespace    = "https://github.com/realmfikri/Orbit"
+	geoJSONMimeType = "application/geo+json"
+)
+
+type geoJSONGeometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+type geoJSONFeature struct {
+	Type       string          `json:"type"`
+	ID         string          `json:"id"`
+	Geometry   geoJSONGeometry `json:"geometry"`
+	Properties map[string]any  `json:"properties"`
+}
+
+type geoJSONFeatureCollection struct {
+	Type           string           `json:"type"`
+	NumberMatched  int              `json:"numberMatched"`
+	NumberReturned int              `json:"numberReturned"`
+	TimeStamp      time.Time        `json:"timeStamp"`
+	Features       []geoJSONFeature `json:"features"`
+}
+
+// truckFeature converts a truck to a GeoJSON Point feature. Coordinates are longitude, latitude.
+func truckFeature(truck simulation.Truck) geoJSONFeature {
+	props := map[string]any{
+		"truckId":       truck.ID,
+		"routeId":       truck.RouteID,
+		"status":        string(truck.Status),
+		"type":          string(truck.Type),
+		"speed":         truck.Speed,
+		"heading":       truck.Heading,
+		"odometer":      truck.Odometer,
+		"routeDistance": truck.RouteDistance,
+		"etaSeconds":    truck.ETASeconds,
+		"electric":      truck.Electric,
+		"updatedAt":     truck.UpdatedAt,
+	}
+	if truck.Geohash != "" {
+		props["geohash"] = truck.Geohash
+	}
+	if truck.Fleet != "" {
+		props["fleet"] = truck.Fleet
+	}
+	if truck.Electric {
+		props["batterySoc"] = truck.BatterySOC
+	} else {
+		props["fuel"] = truck.Fuel
+	}
+	return geoJSONFeature{
+		Type:       "Feature",
+		ID:         truck.ID,
+		Geometry:   geoJSONGeometry{Type: "Point", Coordinates: []float64{truck.Lon, truck.Lat}},
+		Properties: props,
+	}
+}
+
+// handleWFS serves a minimal WFS 2.0 view of the fleet for GIS tools. GetCapabilities and
+// DescribeFeatureType answer in XML as the standard requires; GetFeature always answers in GeoJSON. Features
+// can be narrowed with bbox and with time, an interval matched against each truck's last update.
+func (s *Server) handleWFS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := wfsQuery(r)
+	if service := query["service"]; service != "" && !strings.EqualFold(service, "WFS") {
+		http.Error(w, "service must be WFS", http.StatusBadRequest)
+		return
+	}
+	typeNames := query["typenames"]
+	if typeNames == "" {
+		// WFS 1.x spells it typeName.
+		typeNames = query["typename"]
+	}
+	if typeNames != "" && typeNames != wfsTypeName && typeNames != "trucks" {
+		http.Error(w, "unknown feature type "+typeNames, http.StatusBadRequest)
+		return
+	}
+
+	switch strings.ToLower(query["request"]) {
+	case "getcapabilities", "":
+		s.writeWFSCapabilities(w, r)
+	case "describefeaturetype":
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = fmt.Fprintf(w, wfsSchema, wfsNamespace)
+	case "getfeature":
+		s.handleWFSGetFeature(w, r, query)
+	default:
+		http.Error(w, "unsupported request "+query["request"], http.StatusBadRequest)
+	}
+}
+
+// wfsQuery returns the query parameters keyed by lower-case name, since WFS parameter names are
+// case-insensitive.
+func wfsQuery(r *http.Request) map[string]string {
+	query := make(map[string]string)
+	for key, values := range r.URL.Query() {
+		if len(values) > 0 {
+			query[strings.ToLower(key)] = values[0]
+		}
+	}
+	return query
+}
+
+func (s *Server) handleWFSGetFeature(w http.ResponseWriter, r *http.Request, query map[string]string) {
+	var bbox *simulation.BoundingBox
+	if v := query["bbox"]; v != "" {
+		parsed, err := parseWFSBBox(v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		bbox = &parsed
+	}
+	from, to, err := parseWFSTime(query["time"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	count, startIndex := -1, 0
+	for _, key := range []string{"count", "maxfeatures"} {
+		if v := query[key]; v != "" {
+			parsed, err := strconv.Atoi(v)
+			if err != nil || parsed < 0 {
+				http.Error(w, key+" must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			count = parsed
+		}
+	}
+	if v := query["startindex"]; v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 0 {
+			http.Error(w, "startIndex must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		startIndex = parsed
+	}
+
+	var matched []simulation.Truck
+	for _, truck := range visibleTrucks(r, s.sim.Trucks()) {
+		if bbox != nil && !bbox.Contains(simulation.Point{Lat: truck.Lat, Lon: truck.Lon}) {
+			continue
+		}
+		if (!from.IsZero() && truck.UpdatedAt.Before(from)) || (!to.IsZero() && truck.UpdatedAt.After(to)) {
+			continue
+		}
+		matched = append(matched, truck)
+	}
+
+	start := min(startIndex, len(matched))
+	end := len(matched)
+	if count >= 0 && count < end-start {
+		end = start + count
+	}
+	resp := geoJSONFeatureCollection{
+		Type:          "FeatureCollection",
+		NumberMatched: len(matched),
+		TimeStamp:     s.sim.SimulatedTime(),
+		Features:      []geoJSONFeature{},
+	}
+	for _, truck := range matched[start:end] {
+		resp.Features = append(resp.Features, truckFeature(truck))
+	}
+	resp.NumberReturned = len(resp.Features)
+
+	w.Header().Set("Content-Type", geoJSONMimeType)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// parseWFSBBox parses "a,b,c,d[,crs]". Corners are longitude, latitude unless the CRS is the EPSG:4326 URN,
+// whose axis order WFS 2.0 defines as latitude, longitude.
+func parseWFSBBox(v string) (simulation.BoundingBox, error) {
+	parts := strings.Split(v, ",")
+	if len(parts) != 4 && len(parts) != 5 {
+		return simulation.BoundingBox{}, fmt.Errorf("bbox must be minLon,minLat,maxLon,maxLat with an optional CRS")
+	}
+	var corners [4]float64
+	for i := range corners {
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(parts[i]), 64)
+		if err != nil {
+			return simulation.BoundingBox{}, fmt.Errorf("bbox must hold four numbers")
+		}
+		corners[i] = parsed
+	}
+	bbox := simulation.BoundingBox{MinLon: corners[0], MinLat: corners[1], MaxLon: corners[2], MaxLat: corners[3]}
+	if len(parts) == 5 {
+		crs := strings.TrimSpace(parts[4])
+		if strings.HasPrefix(crs, "urn:ogc:def:crs:EPSG:") && strings.HasSuffix(crs, ":4326") {
+			bbox = simulation.BoundingBox{MinLat: corners[0], MinLon: corners[1], MaxLat: corners[2], MaxLon: corners[3]}
+		}
+	}
+	if bbox.MinLat > bbox.MaxLat || bbox.MinLon > bbox.MaxLon {
+		return simulation.BoundingBox{}, fmt.Errorf("bbox minimum corner must not exceed the maximum")
+	}
+	return bbox, nil
+}
+
+// parseWFSTime parses an RFC 3339 interval "start/end", where either end may be empty or ".." for open, or
+// a single instant.
+func parseWFSTime(v string) (from, to time.Time, err error) {
+	if v == "" {
+		return time.Time{}, time.Time{}, nil
+	}
+	start, end, interval := strings.Cut(v, "/")
+	if !interval {
+		end = start
+	}
+	parse := func(s string) (time.Time, error) {
+		if s == "" || s == ".." {
+			return time.Time{}, nil
+		}
+		return time.Parse(time.RFC3339, s)
+	}
+	if from, err = parse(start); err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("time must be an RFC 3339 instant or start/end interval")
+	}
+	if to, err = parse(end); err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("time must be an RFC 3339 instant or start/end interval")
+	}
+	return from, to, nil
+}
+
+func (s *Server) writeWFSCapabilities(w http.ResponseWriter, r *http.Request) {
+	var href strings.Builder
+	_ = xml.EscapeText(&href, []byte(requestBaseURL(r)+"/wfs?"))
+	w.Header().Set("Content-Type", "application/xml")
+	_, _ = fmt.Fprintf(w, wfsCapabilities, wfsNamespace, href.String(), href.String(), href.String(), wfsTypeName, geoJSONMimeType)
+}
+
+const wfsCapabilities = `<?xml version="1.0" encoding="UTF-8"?>
+<wfs:WFS_Capabilities version="2.0.0" xmlns:wfs="http://www.opengis.net/wfs/2.0" xmlns:ows="http://www.opengis.net/ows/1.1" xmlns:xlink="http://www.w3.org/1999/xlink" xmlns:orbit="%s">
+  <ows:ServiceIdentification>
+    <ows:Title>Orbit fleet</ows:Title>
+    <ows:ServiceType>WFS</ows:ServiceType>
+    <ows:ServiceTypeVersion>2.0.0</ows:ServiceTypeVersion>
+  </ows:ServiceIdentification>
+  <ows:OperationsMetadata>
+    <ows:Operation name="GetCapabilities"><ows:DCP><ows:HTTP><ows:Get xlink:href="%s"/></ows:HTTP></ows:DCP></ows:Operation>
+    <ows:Operation name="DescribeFeatureType"><ows:DCP><ows:HTTP><ows:Get xlink:href="%s"/></ows:HTTP></ows:DCP></ows:Operation>
+    <ows:Operation name="GetFeature"><ows:DCP><ows:HTTP><ows:Get xlink:href="%s"/></ows:HTTP></ows:DCP></ows:Operation>
+  </ows:OperationsMetadata>
+  <wfs:FeatureTypeList>
+    <wfs:FeatureType>
+      <wfs:Name>%s</wfs:Name>
+      <wfs:Title>Trucks</wfs:Title>
+      <wfs:DefaultCRS>urn:ogc:def:crs:EPSG::4326</wfs:DefaultCRS>
+      <wfs:OutputFormats><wfs:Format>%s</wfs:Format></wfs:OutputFormats>
+      <ows:WGS84BoundingBox><ows:LowerCorner>-180 -90</ows:LowerCorner><ows:UpperCorner>180 90</ows:UpperCorner></ows:WGS84BoundingBox>
+    </wfs:FeatureType>
+  </wfs:FeatureTypeList>
+</wfs:WFS_Capabilities>
+`
+
+const wfsSchema = `<?xml version="1.0" encoding="UTF-8"?>
+<xsd:schema xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:gml="http://www.opengis.net/gml/3.2" xmlns:orbit="%[1]s" targetNamespace="%[1]s" elementFormDefault="qualified">
+  <xsd:import namespace="http://www.opengis.net/gml/3.2" schemaLocation="http://schemas.opengis.net/gml/3.2.1/gml.xsd"/>
+  <xsd:complexType name="trucksType">
+    <xsd:complexContent>
+      <xsd:extension base="gml:AbstractFeatureType">
+        <xsd:sequence>
+          <xsd:element name="geometry" type="gml:PointPropertyType"/>
+          <xsd:element name="truckId" type="xsd:string"/>
+          <xsd:element name="routeId" type="xsd:string"/>
+          <xsd:element name="status" type="xsd:string"/>
+          <xsd:element name="type" type="xsd:string"/>
+          <xsd:element name="speed" type="xsd:double"/>
+          <xsd:element name="heading" type="xsd:double"/>
+          <xsd:element name="odometer" type="xsd:double"/>
+          <xsd:element name="routeDistance" type="xsd:double"/>
+          <xsd:element name="etaSeconds" type="xsd:double"/>
+          <xsd:element name="electric" type="xsd:boolean"/>
+          <xsd:element name="fuel" type="xsd:double" minOccurs="0"/>
+          <xsd:element name="batterySoc" type="xsd:double" minOccurs="0"/>
+          <xsd:element name="updatedAt" type="xsd:dateTime"/>
+        </xsd:sequence>
+      </xsd:extension>
+    </xsd:complexContent>
+  </xsd:complexType>
+  <xsd:element name="trucks" type="orbit:trucksType" substitutionGroup="gml:AbstractFeature"/>
+</xsd:schema>
+`
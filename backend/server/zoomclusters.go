@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"orbit/backend/analytics"
+)
+
+const (
+	defaultClusterRadius = 40
+	maxClusterZoom       = 24
+)
+
+type zoomClusterPayload struct {
+	Count       int                `json:"count"`
+	Lat         float64            `json:"lat"`
+	Lon         float64            `json:"lon"`
+	BoundingBox boundingBoxPayload `json:"bbox"`
+	TruckID     string             `json:"truckId,omitempty"`
+}
+
+type zoomClustersResponse struct {
+	Zoom          int                  `json:"zoom"`
+	Radius        float64              `json:"radius"`
+	Total         int                  `json:"total"`
+	Clusters      []zoomClusterPayload `json:"clusters"`
+	SimulatedTime time.Time            `json:"simulatedTime"`
+}
+
+// handleTruckClusters serves GET /api/trucks/clusters?zoom=N, the trucks grouped into one marker per
+// cluster for drawing at that map zoom level. ?radius= sets the cluster radius in screen pixels, and the
+// /api/trucks filters narrow the trucks first.
+func (s *Server) handleTruckClusters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	zoom, err := strconv.Atoi(query.Get("zoom"))
+	if err != nil || zoom < 0 || zoom > maxClusterZoom {
+		http.Error(w, "zoom must be between 0 and 24", http.StatusBadRequest)
+		return
+	}
+	radius := float64(defaultClusterRadius)
+	if v := query.Get("radius"); v != "" {
+		radius, err = strconv.ParseFloat(v, 64)
+		if err != nil || radius < 1 || radius > 512 {
+			http.Error(w, "radius must be between 1 and 512 pixels", http.StatusBadRequest)
+			return
+		}
+	}
+	trucks, err := s.filteredTrucks(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	clusters := analytics.ClusterForZoom(trucks, zoom, radius)
+	resp := zoomClustersResponse{
+		Zoom:          zoom,
+		Radius:        radius,
+		Total:         len(trucks),
+		Clusters:      make([]zoomClusterPayload, len(clusters)),
+		SimulatedTime: s.sim.SimulatedTime(),
+	}
+	for i, c := range clusters {
+		resp.Clusters[i] = zoomClusterPayload{
+			Count:       c.Count,
+			Lat:         c.Lat,
+			Lon:         c.Lon,
+			BoundingBox: boundingBoxPayload{MinLat: c.Bounds.MinLat, MaxLat: c.Bounds.MaxLat, MinLon: c.Bounds.MinLon, MaxLon: c.Bounds.MaxLon},
+			TruckID:     c.TruckID,
+		}
+	}
+	s.writeSnapshot(w, r, resp)
+}
@@ -0,0 +1,91 @@
+package simulation
+
+import (
+	"fmt"
+	"time"
+
+	"orbit/backend/events"
+)
+
+const (
+	// maxStopTypeLength and maxAnnotationNotesLength bound the text carried by waypoint annotations.
+	maxStopTypeLength        = 64
+	maxAnnotationNotesLength = 1024
+)
+
+// WaypointAnnotation describes a stop on a planned route. Annotations are given alongside the waypoints of
+// an assigned or catalog route, index for index, and are reported in the waypoint reached and route
+// completed events when a truck arrives, with the arrival's delay against the plan.
+type WaypointAnnotation struct {
+	// StopType says what happens at the waypoint, such as "pickup" or "delivery".
+	StopType string
+	// PlannedArrival is when the truck is due, on the simulated clock. The zero time leaves the stop
+	// unscheduled. Looping routes compare every lap against the same time.
+	PlannedArrival time.Time
+	Notes          string
+}
+
+// IsZero reports whether the annotation carries nothing.
+func (a WaypointAnnotation) IsZero() bool {
+	return a.StopType == "" && a.PlannedArrival.IsZero() && a.Notes == ""
+}
+
+func validateAnnotations(annotations []WaypointAnnotation, waypoints int) error {
+	if len(annotations) > waypoints {
+		return fmt.Errorf("route has %d annotations for %d waypoints", len(annotations), waypoints)
+	}
+	for i, a := range annotations {
+		switch {
+		case len(a.StopType) > maxStopTypeLength:
+			return fmt.Errorf("annotation %d stop type is longer than %d characters", i, maxStopTypeLength)
+		case len(a.Notes) > maxAnnotationNotesLength:
+			return fmt.Errorf("annotation %d notes are longer than %d characters", i, maxAnnotationNotesLength)
+		}
+	}
+	return nil
+}
+
+// routeAnnotations lays annotations out against a route of n waypoints whose first given waypoint is at
+// index offset. It returns nil when there is nothing to carry.
+func routeAnnotations(annotations []WaypointAnnotation, n, offset int) []WaypointAnnotation {
+	empty := true
+	for _, a := range annotations {
+		if !a.IsZero() {
+			empty = false
+			break
+		}
+	}
+	if empty {
+		return nil
+	}
+	out := make([]WaypointAnnotation, n)
+	copy(out[offset:], annotations)
+	return out
+}
+
+// annotation returns the annotation of the waypoint at index i, if any.
+func (r *routeState) annotation(i int) (WaypointAnnotation, bool) {
+	if i < 0 || i >= len(r.annotations) || r.annotations[i].IsZero() {
+		return WaypointAnnotation{}, false
+	}
+	return r.annotations[i], true
+}
+
+// stopLocked describes the annotated or timetabled waypoint the truck just reached for its arrival events,
+// or returns nil when the waypoint is neither. A timetabled arrival takes precedence over the annotation's.
+func (m *Manager) stopLocked(state *routeState) *events.Stop {
+	a, ok := state.annotation(state.legIndex)
+	if scheduled, timetabled := state.scheduledArrival(state.legIndex); timetabled {
+		a.PlannedArrival, ok = scheduled, true
+	}
+	if !ok {
+		return nil
+	}
+	stop := &events.Stop{StopType: a.StopType, Notes: a.Notes}
+	if !a.PlannedArrival.IsZero() {
+		planned := a.PlannedArrival
+		delay := m.clock.Sub(planned).Seconds()
+		stop.PlannedArrival, stop.ArrivalDelaySeconds = &planned, &delay
+	}
+	return stop
+}
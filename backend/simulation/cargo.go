@@ -0,0 +1,92 @@
+package simulation
+
+import (
+	"fmt"
+	"time"
+)
+
+// ShipmentStatus tracks a shipment from pickup to delivery.
+type ShipmentStatus string
+
+const (
+	ShipmentStatusPickup    ShipmentStatus = "pickup"
+	ShipmentStatusInTransit ShipmentStatus = "inTransit"
+	ShipmentStatusDelivered ShipmentStatus = "delivered"
+)
+
+// maxShipmentRecords bounds how many shipments the manager keeps; the oldest are dropped first.
+const maxShipmentRecords = 10000
+
+// Shipment is cargo loaded at one route endpoint and delivered at the next.
+type Shipment struct {
+	ID          string
+	TruckID     string
+	Origin      Point
+	Destination Point
+	Status      ShipmentStatus
+	PickedUpAt  time.Time
+	DeliveredAt time.Time
+}
+
+// pickupLocked opens a shipment when a truck starts loading. Its destination is the end of the route the
+// truck is about to drive.
+func (m *Manager) pickupLocked(truck *Truck, state *routeState) {
+	if truck.ShipmentID != "" || len(state.waypoints) == 0 {
+		return
+	}
+	m.shipmentSeq++
+	shipment := &Shipment{
+		ID:          fmt.Sprintf("shp-%06d", m.shipmentSeq),
+		TruckID:     truck.ID,
+		Origin:      Point{Lat: truck.Lat, Lon: truck.Lon},
+		Destination: state.waypoints[len(state.waypoints)-1],
+		Status:      ShipmentStatusPickup,
+	}
+	m.shipments[shipment.ID] = shipment
+	m.shipmentOrder = append(m.shipmentOrder, shipment.ID)
+	if len(m.shipmentOrder) > maxShipmentRecords {
+		evicted := len(m.shipmentOrder) - maxShipmentRecords
+		for _, id := range m.shipmentOrder[:evicted] {
+			delete(m.shipments, id)
+		}
+		m.shipmentOrder = append(m.shipmentOrder[:0], m.shipmentOrder[evicted:]...)
+	}
+
+	truck.ShipmentID = shipment.ID
+	truck.CargoStatus = ShipmentStatusPickup
+}
+
+// departLocked marks the truck's shipment as in transit once loading completes.
+func (m *Manager) departLocked(truck *Truck) {
+	if shipment := m.shipments[truck.ShipmentID]; shipment != nil && shipment.Status == ShipmentStatusPickup {
+		shipment.Status = ShipmentStatusInTransit
+		shipment.PickedUpAt = m.clock
+		truck.CargoStatus = ShipmentStatusInTransit
+	}
+}
+
+// deliverLocked completes the truck's shipment once unloading finishes.
+func (m *Manager) deliverLocked(truck *Truck) {
+	if shipment := m.shipments[truck.ShipmentID]; shipment != nil && shipment.Status == ShipmentStatusInTransit {
+		shipment.Status = ShipmentStatusDelivered
+		shipment.DeliveredAt = m.clock
+	}
+	truck.ShipmentID = ""
+	truck.CargoStatus = ""
+}
+
+// Shipments returns shipments in creation order, optionally filtered by truck and status.
+func (m *Manager) Shipments(truckID string, status ShipmentStatus) []Shipment {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	shipments := make([]Shipment, 0, len(m.shipmentOrder))
+	for _, id := range m.shipmentOrder {
+		shipment := m.shipments[id]
+		if (truckID != "" && shipment.TruckID != truckID) || (status != "" && shipment.Status != status) {
+			continue
+		}
+		shipments = append(shipments, *shipment)
+	}
+	return shipments
+}
@@ -0,0 +1,192 @@
+package simulation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxRouteNameLength bounds the names in the route catalog.
+const maxRouteNameLength = 64
+
+// NamedRoute is an entry in the route catalog: an ordered list of waypoints that trucks can be given by
+// name. A truck on a named route reports the name as its RouteID and CurrentRoute. Like an assigned route,
+// a looping route starts over from its first waypoint and any other parks the truck at its last.
+// Annotations, if any, describe the waypoints index for index, and a Timetable schedules its trips.
+type NamedRoute struct {
+	Name        string
+	Waypoints   []Point
+	Annotations []WaypointAnnotation
+	Loop        bool
+	Timetable   *Timetable
+}
+
+// Validate reports whether the route can be added to the catalog.
+func (r NamedRoute) Validate() error {
+	switch {
+	case r.Name == "":
+		return fmt.Errorf("route name is required")
+	case len(r.Name) > maxRouteNameLength:
+		return fmt.Errorf("route name is longer than %d characters", maxRouteNameLength)
+	case strings.Contains(r.Name, "/"):
+		return fmt.Errorf("route name must not contain '/'")
+	case r.Name == "completions":
+		return fmt.Errorf("route name %q is reserved", r.Name)
+	}
+	if err := validateWaypoints(r.Waypoints, r.Loop); err != nil {
+		return err
+	}
+	if r.Timetable != nil {
+		if err := r.Timetable.validate(len(r.Waypoints)); err != nil {
+			return err
+		}
+	}
+	return validateAnnotations(r.Annotations, len(r.Waypoints))
+}
+
+func validateWaypoints(waypoints []Point, loop bool) error {
+	switch {
+	case len(waypoints) == 0:
+		return fmt.Errorf("route needs at least one waypoint")
+	case loop && len(waypoints) < 2:
+		return fmt.Errorf("looping route needs at least two waypoints")
+	case len(waypoints) > maxAssignedWaypoints:
+		return fmt.Errorf("route has more than %d waypoints", maxAssignedWaypoints)
+	}
+	for i, p := range waypoints {
+		if p.Lat < -90 || p.Lat > 90 || p.Lon < -180 || p.Lon > 180 {
+			return fmt.Errorf("waypoint %d is outside valid coordinates", i)
+		}
+	}
+	return nil
+}
+
+func cloneNamedRoutes(routes []NamedRoute) []NamedRoute {
+	if routes == nil {
+		return nil
+	}
+	out := make([]NamedRoute, len(routes))
+	for i, r := range routes {
+		out[i] = r
+		out[i].Waypoints = append([]Point(nil), r.Waypoints...)
+		if r.Annotations != nil {
+			out[i].Annotations = append([]WaypointAnnotation(nil), r.Annotations...)
+		}
+		if r.Timetable != nil {
+			out[i].Timetable = &Timetable{
+				Departures: append([]time.Time(nil), r.Timetable.Departures...),
+				StopTimes:  append([]time.Duration(nil), r.Timetable.StopTimes...),
+			}
+		}
+	}
+	return out
+}
+
+// NamedRoutes lists the route catalog in the order the routes were defined.
+func (m *Manager) NamedRoutes() []NamedRoute {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return cloneNamedRoutes(m.cfg.NamedRoutes)
+}
+
+// NamedRoute returns the catalog route with the given name.
+func (m *Manager) NamedRoute(name string) (NamedRoute, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	route, ok := m.namedRouteLocked(name)
+	if !ok {
+		return NamedRoute{}, false
+	}
+	return cloneNamedRoutes([]NamedRoute{route})[0], true
+}
+
+func (m *Manager) namedRouteLocked(name string) (NamedRoute, bool) {
+	for _, r := range m.cfg.NamedRoutes {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return NamedRoute{}, false
+}
+
+// DefineRoute adds the route to the catalog, replacing any route with the same name, and records the change
+// in the config history. Trucks already driving a replaced route keep its old waypoints until they are
+// assigned it again.
+func (m *Manager) DefineRoute(route NamedRoute) error {
+	if err := route.Validate(); err != nil {
+		return err
+	}
+	route = cloneNamedRoutes([]NamedRoute{route})[0]
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	routes := cloneNamedRoutes(m.cfg.NamedRoutes)
+	replaced := false
+	for i := range routes {
+		if routes[i].Name == route.Name {
+			routes[i], replaced = route, true
+		}
+	}
+	if !replaced {
+		routes = append(routes, route)
+	}
+	m.cfg.NamedRoutes = routes
+	m.recordConfigLocked(m.cfg, "routes", "")
+	return nil
+}
+
+// DeleteRoute removes the route from the catalog and reports whether it existed. Trucks driving it carry
+// on, and new trucks assigned it by Config.RouteAssignments get generated routes instead.
+func (m *Manager) DeleteRoute(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	routes := make([]NamedRoute, 0, len(m.cfg.NamedRoutes))
+	for _, r := range m.cfg.NamedRoutes {
+		if r.Name != name {
+			routes = append(routes, r)
+		}
+	}
+	if len(routes) == len(m.cfg.NamedRoutes) {
+		return false
+	}
+	m.cfg.NamedRoutes = cloneNamedRoutes(routes)
+	m.recordConfigLocked(m.cfg, "routes", "")
+	return true
+}
+
+// AssignNamedRoute puts the truck on the catalog route with the given name, as AssignRoute does with
+// explicit waypoints. It reports false when no such truck exists.
+func (m *Manager) AssignNamedRoute(id, name string) (Truck, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	truck, ok := m.trucks[id]
+	state := m.routes[id]
+	if !ok || state == nil {
+		return Truck{}, false, nil
+	}
+	route, ok := m.namedRouteLocked(name)
+	if !ok {
+		return Truck{}, true, fmt.Errorf("route %q is not in the catalog", name)
+	}
+	m.wakeLocked(id)
+	m.assignRouteLocked(truck, state, route.Waypoints, route.Annotations, route.Loop)
+	m.nameRouteLocked(truck, state, route.Name)
+	return *truck, true, nil
+}
+
+// nameRouteLocked labels the truck's current route with a catalog name.
+func (m *Manager) nameRouteLocked(truck *Truck, state *routeState, name string) {
+	state.routeName = name
+	truck.RouteID = name
+	truck.CurrentRoute = state.label()
+}
+
+// assignedRouteLocked returns the catalog route Config.RouteAssignments gives the truck built with the
+// given index, if any.
+func (m *Manager) assignedRouteLocked(index int) (NamedRoute, bool) {
+	if len(m.cfg.RouteAssignments) == 0 {
+		return NamedRoute{}, false
+	}
+	return m.namedRouteLocked(m.cfg.RouteAssignments[index%len(m.cfg.RouteAssignments)])
+}
@@ -0,0 +1,106 @@
+package simulation
+
+import (
+	"fmt"
+	"time"
+)
+
+// FleetCommandType identifies a bulk action applied to matching trucks.
+type FleetCommandType string
+
+const (
+	// FleetCommandSpeedCap lowers each truck's speed to at most SpeedCap.
+	FleetCommandSpeedCap FleetCommandType = "speedCap"
+	// FleetCommandReturnToDepot sends trucks to the nearest depot, or start point without depots, and parks
+	// them there.
+	FleetCommandReturnToDepot FleetCommandType = "returnToDepot"
+	// FleetCommandHold keeps trucks in place for HoldFor of simulated time.
+	FleetCommandHold FleetCommandType = "hold"
+)
+
+// FleetCommand describes a bulk action and the trucks it applies to. Empty filters match every truck.
+type FleetCommand struct {
+	Type     FleetCommandType
+	SpeedCap float64
+	HoldFor  time.Duration
+	TruckIDs []string
+	Status   TruckStatus
+}
+
+// ApplyFleetCommand applies the command to all matching trucks and returns how many were affected.
+func (m *Manager) ApplyFleetCommand(cmd FleetCommand) (int, error) {
+	switch cmd.Type {
+	case FleetCommandSpeedCap:
+		if cmd.SpeedCap <= 0 {
+			return 0, fmt.Errorf("speed cap must be positive")
+		}
+	case FleetCommandHold:
+		if cmd.HoldFor <= 0 {
+			return 0, fmt.Errorf("hold duration must be positive")
+		}
+	case FleetCommandReturnToDepot:
+	default:
+		return 0, fmt.Errorf("unknown fleet command %q", cmd.Type)
+	}
+
+	ids := make(map[string]struct{}, len(cmd.TruckIDs))
+	for _, id := range cmd.TruckIDs {
+		ids[id] = struct{}{}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	affected := 0
+	for _, truck := range m.sortedTrucksLocked() {
+		if len(ids) > 0 {
+			if _, ok := ids[truck.ID]; !ok {
+				continue
+			}
+		}
+		if cmd.Status != "" && truck.Status != cmd.Status {
+			continue
+		}
+		state := m.routes[truck.ID]
+		if state == nil {
+			continue
+		}
+
+		switch cmd.Type {
+		case FleetCommandSpeedCap:
+			if truck.Speed > cmd.SpeedCap {
+				truck.Speed = cmd.SpeedCap
+			}
+		case FleetCommandHold:
+			state.holdUntil = m.clock.Add(cmd.HoldFor)
+		case FleetCommandReturnToDepot:
+			current := Point{Lat: truck.Lat, Lon: truck.Lon}
+			depot := nearestPoint(current, m.depotLocationsLocked())
+			state.waypoints = []Point{current, depot}
+			state.annotations = nil
+			state.handoff = nil
+			state.routeName = ""
+			state.legIndex = 1
+			state.loop = false
+			state.terminal = true
+			state.parked = false
+			state.detouring = false
+		}
+		// A suspended truck only notices the command once it is back in per-tick processing; it is
+		// suspended again if the command leaves it idle.
+		m.wakeLocked(truck.ID)
+		affected++
+	}
+	return affected, nil
+}
+
+func nearestPoint(from Point, candidates []Point) Point {
+	best := candidates[0]
+	bestDistance := GreatCircleDistance(from, best)
+	for _, p := range candidates[1:] {
+		if d := GreatCircleDistance(from, p); d < bestDistance {
+			best, bestDistance = p, d
+		}
+	}
+	return best
+}
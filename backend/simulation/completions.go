@@ -0,0 +1,53 @@
+package simulation
+
+import "time"
+
+// maxCompletionRecords bounds how many route completions are kept in memory.
+const maxCompletionRecords = 10000
+
+// RouteCompletion records a truck reaching the end of a route. Depot return legs are not routes.
+type RouteCompletion struct {
+	TruckID string
+	RouteID string
+	// StartedAt is when the route began, including any loading before departure.
+	StartedAt   time.Time
+	CompletedAt time.Time
+	Elapsed     time.Duration
+	// DistanceMeters is the distance driven on the route, including detours.
+	DistanceMeters float64
+}
+
+// completeRouteLocked counts a finished route, records it, and publishes a route completed event.
+func (m *Manager) completeRouteLocked(truck *Truck, state *routeState, at Point) {
+	state.routesCompleted++
+	routesCompleted.Inc()
+	routeCompletionSeconds.Observe(m.clock.Sub(state.routeStarted).Seconds())
+
+	m.completions = append(m.completions, RouteCompletion{
+		TruckID:        truck.ID,
+		RouteID:        truck.RouteID,
+		StartedAt:      state.routeStarted,
+		CompletedAt:    m.clock,
+		Elapsed:        m.clock.Sub(state.routeStarted),
+		DistanceMeters: truck.RouteDistance,
+	})
+	if len(m.completions) > maxCompletionRecords {
+		m.completions = append(m.completions[:0], m.completions[len(m.completions)-maxCompletionRecords:]...)
+	}
+	m.emitRouteCompletedLocked(truck, state, at)
+}
+
+// RouteCompletions returns recorded route completions in completion order, optionally limited to one route
+// and one truck.
+func (m *Manager) RouteCompletions(routeID, truckID string) []RouteCompletion {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	completions := make([]RouteCompletion, 0, len(m.completions))
+	for _, c := range m.completions {
+		if (routeID == "" || c.RouteID == routeID) && (truckID == "" || c.TruckID == truckID) {
+			completions = append(completions, c)
+		}
+	}
+	return completions
+}
@@ -0,0 +1,27 @@
+package simulation
+
+// governLocked holds a governed truck at its speed limit before it moves.
+func (m *Manager) governLocked(truck *Truck) {
+	if !truck.Governed || truck.SpeedLimit <= 0 || truck.Speed <= truck.SpeedLimit {
+		return
+	}
+	truck.Speed = truck.SpeedLimit
+	truck.GovernorInterventions++
+	governorInterventions.Inc()
+}
+
+// recordSpeedLocked tracks driving over the speed limit once the truck's tick is done. Each unbroken stretch
+// of over-limit driving counts as one violation.
+func (m *Manager) recordSpeedLocked(truck *Truck, state *routeState) {
+	over := truck.Status == TruckStatusEnRoute && truck.SpeedLimit > 0 && truck.Speed > truck.SpeedLimit
+	if over {
+		if !state.overLimit {
+			truck.SpeedViolations++
+			speedViolations.Inc()
+		}
+		seconds := state.step.Seconds()
+		truck.OverLimitSeconds += seconds
+		overLimitSeconds.Add(seconds)
+	}
+	state.overLimit = over
+}
@@ -0,0 +1,117 @@
+package simulation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// CounterStore persists cumulative counters so dashboards do not drop to zero when the server restarts.
+type CounterStore interface {
+	// LoadCounters returns the saved values by metric name, or nil when nothing has been saved yet.
+	LoadCounters() (map[string]float64, error)
+	SaveCounters(values map[string]float64) error
+}
+
+// persistentCounters are the cumulative counters carried across restarts.
+var persistentCounters = map[string]prometheus.Counter{
+	"orbit_truck_updates_total":                truckUpdates,
+	"orbit_fleet_distance_meters_total":        fleetDistance,
+	"orbit_routes_completed_total":             routesCompleted,
+	"orbit_speed_violations_total":             speedViolations,
+	"orbit_over_speed_limit_seconds_total":     overLimitSeconds,
+	"orbit_speed_governor_interventions_total": governorInterventions,
+}
+
+// CounterValues returns the current value of every persistent counter.
+func CounterValues() map[string]float64 {
+	values := make(map[string]float64, len(persistentCounters))
+	for name, counter := range persistentCounters {
+		var m dto.Metric
+		if err := counter.Write(&m); err == nil && m.Counter != nil {
+			values[name] = m.Counter.GetValue()
+		}
+	}
+	return values
+}
+
+// RestoreCounters adds saved values onto the persistent counters. Call it once at startup, before the
+// simulation runs; unknown names and negative values are ignored.
+func RestoreCounters(values map[string]float64) {
+	for name, value := range values {
+		if counter, ok := persistentCounters[name]; ok && value > 0 {
+			counter.Add(value)
+		}
+	}
+}
+
+// PersistCounters saves the counters to store every interval and once more when ctx is done. Save errors
+// are passed to onError and do not stop the loop.
+func PersistCounters(ctx context.Context, store CounterStore, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	save := func() {
+		if err := store.SaveCounters(CounterValues()); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			save()
+			return
+		case <-ticker.C:
+			save()
+		}
+	}
+}
+
+// FileCounterStore keeps counters in a JSON file, replacing it atomically on each save.
+type FileCounterStore struct {
+	Path string
+}
+
+// LoadCounters reads the file, returning nil when it does not exist yet.
+func (s FileCounterStore) LoadCounters() (map[string]float64, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]float64
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// SaveCounters writes the values through a temporary file in the same directory.
+func (s FileCounterStore) SaveCounters(values map[string]float64) error {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
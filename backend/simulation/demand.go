@@ -0,0 +1,92 @@
+package simulation
+
+import "math/rand"
+
+// normalizeDemand drops EndWeights and ODMatrix when their shape does not match the configured points or
+// they hold no positive weight, so trucks fall back to uniform picks.
+func normalizeDemand(cfg Config) Config {
+	if len(cfg.EndWeights) != len(cfg.EndPoints) || !positiveWeights(cfg.EndWeights) {
+		cfg.EndWeights = nil
+	}
+	if len(cfg.ODMatrix) != len(cfg.StartPoints) {
+		cfg.ODMatrix = nil
+		return cfg
+	}
+	anyRow := false
+	for _, row := range cfg.ODMatrix {
+		if len(row) != len(cfg.EndPoints) {
+			cfg.ODMatrix = nil
+			return cfg
+		}
+		for _, w := range row {
+			if w < 0 {
+				cfg.ODMatrix = nil
+				return cfg
+			}
+		}
+		anyRow = anyRow || positiveWeights(row)
+	}
+	if !anyRow {
+		cfg.ODMatrix = nil
+	}
+	return cfg
+}
+
+func positiveWeights(weights []float64) bool {
+	total := 0.0
+	for _, w := range weights {
+		if w < 0 {
+			return false
+		}
+		total += w
+	}
+	return total > 0
+}
+
+// endWeightsLocked returns the weights for end points picked from the given origin: its ODMatrix row when
+// the origin is a start point with outbound demand, otherwise EndWeights. Nil means uniform.
+func (m *Manager) endWeightsLocked(from Point) []float64 {
+	for i, start := range m.cfg.StartPoints {
+		if i < len(m.cfg.ODMatrix) && start == from && positiveWeights(m.cfg.ODMatrix[i]) {
+			return m.cfg.ODMatrix[i]
+		}
+	}
+	return m.cfg.EndWeights
+}
+
+// startWeightsLocked weights start points by their total outbound demand in the ODMatrix.
+func (m *Manager) startWeightsLocked() []float64 {
+	if m.cfg.ODMatrix == nil {
+		return nil
+	}
+	weights := make([]float64, len(m.cfg.ODMatrix))
+	for i, row := range m.cfg.ODMatrix {
+		for _, w := range row {
+			weights[i] += w
+		}
+	}
+	return weights
+}
+
+// pickIndex chooses one of candidates, uniformly when weights is nil and otherwise in proportion to the
+// candidates' weights. Candidates with no weight are never chosen unless every candidate has none.
+func pickIndex(rng *rand.Rand, candidates []int, weights []float64) int {
+	if weights == nil {
+		return candidates[rng.Intn(len(candidates))]
+	}
+	total := 0.0
+	for _, i := range candidates {
+		total += weights[i]
+	}
+	if total <= 0 {
+		return candidates[rng.Intn(len(candidates))]
+	}
+	r := rng.Float64() * total
+	for _, i := range candidates {
+		if r < weights[i] {
+			return i
+		}
+		r -= weights[i]
+	}
+	return candidates[len(candidates)-1]
+}
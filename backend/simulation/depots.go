@@ -0,0 +1,212 @@
+package simulation
+
+import "fmt"
+
+// depotRadiusMeters is how close a truck must be to a configured depot to use its docks.
+const depotRadiusMeters = 100
+
+// Depot is a named hub where trucks start, load, and return between routes.
+type Depot struct {
+	Name     string
+	Location Point
+	// Capacity is the number of docks. Zero falls back to Config.DockCapacity.
+	Capacity int
+}
+
+// DepotStatus reports the trucks currently at a depot.
+type DepotStatus struct {
+	Depot
+	// Docked counts stationary trucks at the depot, including those waiting for a dock.
+	Docked int
+	Queued int
+}
+
+// depotDocks tracks dock occupancy at a loading location. Trucks that arrive while every dock is busy
+// wait in a FIFO queue.
+type depotDocks struct {
+	key      string
+	capacity int
+	busy     int
+	queue    []string
+}
+
+// dockStatus reports whether a stationary status occupies a depot dock.
+func dockStatus(status TruckStatus) bool {
+	return status == TruckStatusLoading || status == TruckStatusUnloading
+}
+
+// depotLocationsLocked returns where trucks return to: the configured depots, or the start points when none
+// are configured.
+func (m *Manager) depotLocationsLocked() []Point {
+	if len(m.cfg.Depots) == 0 {
+		return m.cfg.StartPoints
+	}
+	points := make([]Point, len(m.cfg.Depots))
+	for i, d := range m.cfg.Depots {
+		points[i] = d.Location
+	}
+	return points
+}
+
+// nearestDepot returns the configured depot closest to p.
+func (m *Manager) nearestDepot(p Point) Depot {
+	best := m.cfg.Depots[0]
+	bestDistance := GreatCircleDistance(p, best.Location)
+	for _, d := range m.cfg.Depots[1:] {
+		if distance := GreatCircleDistance(p, d.Location); distance < bestDistance {
+			best, bestDistance = d, distance
+		}
+	}
+	return best
+}
+
+// docksForLocked returns the docks serving p. With configured depots only trucks at a depot use docks;
+// otherwise every start and end point acts as a depot with Config.DockCapacity docks.
+func (m *Manager) docksForLocked(p Point) *depotDocks {
+	key, capacity := "", m.cfg.DockCapacity
+	if len(m.cfg.Depots) > 0 {
+		d := m.nearestDepot(p)
+		if GreatCircleDistance(p, d.Location) > depotRadiusMeters {
+			return nil
+		}
+		key = d.Name
+		if d.Capacity > 0 {
+			capacity = d.Capacity
+		}
+	} else {
+		candidates := append(append([]Point{}, m.cfg.StartPoints...), m.cfg.EndPoints...)
+		key = pointLabel(nearestPoint(p, candidates))
+	}
+	if capacity <= 0 {
+		return nil
+	}
+
+	docks, ok := m.depots[key]
+	if !ok {
+		docks = &depotDocks{key: key, capacity: capacity}
+		m.depots[key] = docks
+	}
+	return docks
+}
+
+// acquireDockLocked claims a dock for the truck or places it at the back of the depot queue. It reports
+// whether the truck may start service now.
+func (m *Manager) acquireDockLocked(truck *Truck, state *routeState) bool {
+	if state.docked != "" {
+		return true
+	}
+	d := m.docksForLocked(Point{Lat: truck.Lat, Lon: truck.Lon})
+	if d == nil {
+		return true
+	}
+
+	position := -1
+	for i, id := range d.queue {
+		if id == truck.ID {
+			position = i
+			break
+		}
+	}
+	// A free dock goes to the head of the queue, never to a truck that has just arrived.
+	free := d.busy < d.capacity
+	switch {
+	case position < 0 && (!free || len(d.queue) > 0):
+		d.queue = append(d.queue, truck.ID)
+		m.recordDepotLocked(d)
+		return false
+	case position > 0 || (position == 0 && !free):
+		return false
+	case position == 0:
+		d.queue = d.queue[1:]
+	}
+
+	d.busy++
+	state.docked = d.key
+	m.recordDepotLocked(d)
+	return true
+}
+
+// leaveDepotsLocked frees the truck's dock and takes it out of every depot queue, for a truck that is
+// removed or sent elsewhere. A truck left in a queue would hold it up for good, since a free dock only
+// goes to the head of the queue.
+func (m *Manager) leaveDepotsLocked(id string, state *routeState) {
+	if state != nil {
+		m.releaseDockLocked(state)
+	}
+	for _, d := range m.depots {
+		if queue := removeID(d.queue, id); len(queue) != len(d.queue) {
+			d.queue = queue
+			m.recordDepotLocked(d)
+		}
+	}
+}
+
+// releaseDockLocked frees the dock held by the truck, if any.
+func (m *Manager) releaseDockLocked(state *routeState) {
+	d := m.depots[state.docked]
+	state.docked = ""
+	if d == nil {
+		return
+	}
+	d.busy--
+	m.recordDepotLocked(d)
+}
+
+func (m *Manager) recordDepotLocked(d *depotDocks) {
+	depotQueueLength.WithLabelValues(d.key).Set(float64(len(d.queue)))
+	depotDocksBusy.WithLabelValues(d.key).Set(float64(d.busy))
+}
+
+// dispatchLocked gives a truck that finished its route the next leg: trucks back at a depot are sent to a
+// new destination and trucks that reached a destination return to the nearest depot.
+func (m *Manager) dispatchLocked(truck *Truck, state *routeState, current Point) {
+	state.planToken = 0
+	if state.returning {
+		from := m.nearestDepot(current)
+		end := m.pickEndpoint(truck.Type, current)
+		state.waypoints = m.buildRoute(truck.Type, current, end)
+		state.returning = false
+		truck.RouteID = fmt.Sprintf("%s_to_%s", from.Name, pointLabel(end))
+		m.requestPlanLocked(truck.ID, state)
+	} else {
+		to := m.nearestDepot(current)
+		state.waypoints = []Point{current, to.Location}
+		state.returning = true
+		truck.RouteID = fmt.Sprintf("%s_to_%s", pointLabel(current), to.Name)
+	}
+	state.annotations = nil
+	state.legIndex = 1
+}
+
+// Depots reports every configured depot with the trucks currently docked or queued there.
+func (m *Manager) Depots() []DepotStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	statuses := make([]DepotStatus, len(m.cfg.Depots))
+	index := make(map[string]int, len(m.cfg.Depots))
+	for i, d := range m.cfg.Depots {
+		statuses[i] = DepotStatus{Depot: d}
+		if statuses[i].Capacity <= 0 {
+			statuses[i].Capacity = m.cfg.DockCapacity
+		}
+		index[d.Name] = i
+		if docks := m.depots[d.Name]; docks != nil {
+			statuses[i].Queued = len(docks.queue)
+		}
+	}
+	if len(statuses) == 0 {
+		return statuses
+	}
+	for _, truck := range m.trucks {
+		if truck.Status == TruckStatusEnRoute {
+			continue
+		}
+		p := Point{Lat: truck.Lat, Lon: truck.Lon}
+		d := m.nearestDepot(p)
+		if GreatCircleDistance(p, d.Location) <= depotRadiusMeters {
+			statuses[index[d.Name]].Docked++
+		}
+	}
+	return statuses
+}
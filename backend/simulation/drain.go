@@ -0,0 +1,53 @@
+package simulation
+
+import (
+	"context"
+	"time"
+)
+
+// drainPollInterval is how often Drain checks whether every truck has stopped.
+const drainPollInterval = 10 * time.Millisecond
+
+// Drain lets every moving truck finish the leg it is on and stops each at the waypoint it reaches, so that
+// a following Stop and SaveState capture trucks at waypoints rather than mid-leg. Trucks that are not
+// moving stay where they are. The simulation keeps ticking until every truck has stopped or ctx is done,
+// and Drain returns ctx's error in that case. It returns straight away when the simulation is not
+// running. A drained fleet stays put until the manager is started again.
+func (m *Manager) Drain(ctx context.Context) error {
+	m.mu.Lock()
+	if !m.started || m.paused {
+		m.mu.Unlock()
+		return nil
+	}
+	m.draining = true
+	m.mu.Unlock()
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		if m.Drained() {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drained reports whether a drain is under way and every truck has stopped.
+func (m *Manager) Drained() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if !m.draining {
+		return false
+	}
+	for id, truck := range m.trucks {
+		state := m.routes[id]
+		if state != nil && !state.drained && !state.suspended && truck.Status == TruckStatusEnRoute {
+			return false
+		}
+	}
+	return true
+}
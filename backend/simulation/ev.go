@@ -0,0 +1,88 @@
+package simulation
+
+import "math"
+
+const (
+	// chargeTarget is the state of charge at which a charging truck resumes its route.
+	chargeTarget = 0.9
+	// chargeTaperStart is where the charging curve starts to taper off, as on real fast chargers.
+	chargeTaperStart = 0.8
+)
+
+// consumeBatteryLocked drains the battery for the distance travelled and inserts a detour to the nearest
+// charging station when the state of charge drops below the threshold.
+func (m *Manager) consumeBatteryLocked(truck *Truck, state *routeState, meters float64) {
+	kwh := meters / 1000 * m.cfg.EnergyPerKm
+	truck.BatterySOC = math.Max(0, truck.BatterySOC-kwh/m.cfg.BatteryCapacity)
+	m.recordEnergyLocked(state, kwh)
+
+	if truck.BatterySOC >= m.cfg.LowBatteryThreshold || state.detouring || state.charging {
+		return
+	}
+
+	stations := m.cfg.ChargingStations
+	if len(stations) == 0 {
+		stations = m.cfg.StartPoints
+	}
+	station := nearestPoint(Point{Lat: truck.Lat, Lon: truck.Lon}, stations)
+	state.insertWaypoint(state.legIndex, station)
+	state.detouring = true
+	state.chargeStop = state.legIndex
+}
+
+// arriveAtChargerLocked handles reaching the inserted charging stop. It removes the detour from the route
+// so the truck resumes toward its original waypoint once charged, and reports whether the stop was reached.
+func (m *Manager) arriveAtChargerLocked(truck *Truck, state *routeState) bool {
+	if !state.detouring || state.legIndex != state.chargeStop {
+		return false
+	}
+	state.removeWaypoint(state.chargeStop)
+	state.detouring = false
+	state.charging = true
+	truck.Status = TruckStatusCharging
+	return true
+}
+
+// chargeLocked adds energy for one tick while the truck is plugged in and reports whether it is still charging.
+func (m *Manager) chargeLocked(truck *Truck, state *routeState) bool {
+	if !state.charging {
+		return false
+	}
+
+	rate := m.cfg.ChargeRate
+	if truck.BatterySOC > chargeTaperStart {
+		rate *= math.Max(0.1, (1-truck.BatterySOC)/(1-chargeTaperStart))
+	}
+	truck.BatterySOC = math.Min(1, truck.BatterySOC+rate*state.step.Hours()/m.cfg.BatteryCapacity)
+
+	if truck.BatterySOC >= chargeTarget {
+		state.charging = false
+		return false
+	}
+	truck.Status = TruckStatusCharging
+	return true
+}
+
+func (r *routeState) insertWaypoint(index int, p Point) {
+	r.waypoints = append(r.waypoints, Point{})
+	copy(r.waypoints[index+1:], r.waypoints[index:])
+	r.waypoints[index] = p
+	if r.annotations != nil {
+		r.annotations = append(r.annotations, WaypointAnnotation{})
+		copy(r.annotations[index+1:], r.annotations[index:])
+		r.annotations[index] = WaypointAnnotation{}
+	}
+}
+
+func (r *routeState) removeWaypoint(index int) {
+	r.waypoints = append(r.waypoints[:index], r.waypoints[index+1:]...)
+	if r.annotations != nil {
+		r.annotations = append(r.annotations[:index], r.annotations[index+1:]...)
+	}
+	if r.legIndex > index {
+		r.legIndex--
+	}
+	if r.legIndex >= len(r.waypoints) {
+		r.legIndex = len(r.waypoints) - 1
+	}
+}
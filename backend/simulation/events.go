@@ -0,0 +1,89 @@
+package simulation
+
+import "orbit/backend/events"
+
+// WithEventBus publishes truck lifecycle events to bus: trucks joining the fleet, waypoints reached,
+// routes completed and status changes. Events are published after the manager releases its lock, so
+// subscribers may call back into the manager.
+func (m *Manager) WithEventBus(bus *events.Bus) *Manager {
+	m.mu.Lock()
+	m.eventBus = bus
+	m.mu.Unlock()
+	return m
+}
+
+// emitLocked queues an event for publishing once the lock is released. It is a no-op without a bus.
+func (m *Manager) emitLocked(typ string, payload any) {
+	if m.eventBus == nil {
+		return
+	}
+	m.pendingEvents = append(m.pendingEvents, events.Event{Type: typ, Payload: payload, RunID: m.run.ID})
+}
+
+// takeEventsLocked hands over the queued events to be published by publishEvents.
+func (m *Manager) takeEventsLocked() []events.Event {
+	pending := m.pendingEvents
+	m.pendingEvents = nil
+	return pending
+}
+
+func (m *Manager) publishEvents(pending []events.Event) {
+	for _, evt := range pending {
+		m.eventBus.Publish(evt)
+	}
+}
+
+// flushEvents publishes events queued by calls that do not publish themselves, such as building the fleet.
+func (m *Manager) flushEvents() {
+	m.mu.Lock()
+	pending := m.takeEventsLocked()
+	m.mu.Unlock()
+	m.publishEvents(pending)
+}
+
+func (m *Manager) emitTruckCreatedLocked(truck *Truck) {
+	m.emitLocked(events.TypeTruckCreated, events.TruckCreated{
+		TruckID:       truck.ID,
+		TruckType:     string(truck.Type),
+		RouteID:       truck.RouteID,
+		Lat:           truck.Lat,
+		Lon:           truck.Lon,
+		SimulatedTime: m.clock,
+	})
+}
+
+// emitArrivalLocked reports a truck reaching the waypoint it was heading for.
+func (m *Manager) emitArrivalLocked(truck *Truck, state *routeState, at Point) {
+	m.emitLocked(events.TypeWaypointReached, events.WaypointReached{
+		TruckID:       truck.ID,
+		RouteID:       truck.RouteID,
+		Waypoint:      state.legIndex,
+		Lat:           at.Lat,
+		Lon:           at.Lon,
+		SimulatedTime: m.clock,
+		Stop:          m.stopLocked(state),
+	})
+}
+
+func (m *Manager) emitRouteCompletedLocked(truck *Truck, state *routeState, at Point) {
+	m.emitLocked(events.TypeRouteCompleted, events.RouteCompleted{
+		TruckID:       truck.ID,
+		RouteID:       truck.RouteID,
+		Lat:           at.Lat,
+		Lon:           at.Lon,
+		SimulatedTime: m.clock,
+		Stop:          m.stopLocked(state),
+	})
+}
+
+func (m *Manager) emitStatusChangeLocked(truck *Truck, from TruckStatus) {
+	if truck.Status == from {
+		return
+	}
+	m.emitLocked(events.TypeStatusChanged, events.StatusChanged{
+		TruckID:       truck.ID,
+		From:          string(from),
+		To:            string(truck.Status),
+		SimulatedTime: m.clock,
+	})
+}
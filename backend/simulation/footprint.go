@@ -0,0 +1,92 @@
+package simulation
+
+import (
+	"reflect"
+	"time"
+	"unsafe"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// mapEntryBytes approximates what an entry costs in the manager's per-truck maps beyond its key and value:
+// bucket slots, tophash bytes, and spare capacity.
+const mapEntryBytes = 48
+
+// Footprint is the measured per-truck cost of the running fleet, for projecting the cost of other fleet
+// sizes.
+type Footprint struct {
+	Trucks int
+	// BytesPerTruck is the average memory a truck and its route state hold, not counting waypoints.
+	BytesPerTruck float64
+	// BytesPerWaypoint is the memory a route waypoint holds.
+	BytesPerWaypoint float64
+	// WaypointsPerTruck is the average length of the trucks' current routes.
+	WaypointsPerTruck float64
+	// UpdateSeconds is the mean time taken to advance one truck by one tick so far in this process. It is
+	// zero until the fleet has ticked.
+	UpdateSeconds float64
+	// TickInterval and TimeScale are the simulation's current tick interval and time scale.
+	TickInterval time.Duration
+	TimeScale    float64
+}
+
+// Footprint measures the memory the fleet's trucks and routes hold, from the sizes of their structures,
+// and the mean truck update time recorded in orbit_truck_update_duration_seconds. Memory held by the Go
+// runtime, caches, and connections is not included.
+func (m *Manager) Footprint() Footprint {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	fp := Footprint{
+		Trucks:           len(m.trucks),
+		BytesPerWaypoint: float64(unsafe.Sizeof(Point{})),
+		TickInterval:     m.baseInterval(),
+		TimeScale:        m.cfg.TimeScale,
+	}
+	var truckBytes, waypoints int
+	for id, truck := range m.trucks {
+		truckBytes += 2*(len(id)+mapEntryBytes) + int(unsafe.Sizeof(*truck)) + stringBytes(reflect.ValueOf(*truck))
+		// The spatial index holds the truck in its position map and in one cell.
+		truckBytes += 2*mapEntryBytes + int(unsafe.Sizeof(spatialEntry{}))
+		state := m.routes[id]
+		if state == nil {
+			continue
+		}
+		truckBytes += int(unsafe.Sizeof(*state)) + len(state.pendingDwell)*int(unsafe.Sizeof(TruckStatus("")))
+		truckBytes += len(state.annotations) * int(unsafe.Sizeof(WaypointAnnotation{}))
+		if state.trip != nil {
+			truckBytes += int(unsafe.Sizeof(*state.trip))
+		}
+		if state.timetable != nil {
+			truckBytes += int(unsafe.Sizeof(*state.timetable)) + len(state.timetable.Record.Arrivals)*int(unsafe.Sizeof(StopArrival{}))
+		}
+		if state.fix != nil {
+			truckBytes += int(unsafe.Sizeof(*state.fix))
+		}
+		truckBytes += cap(state.trail.points) * int(unsafe.Sizeof(trackEntry{}))
+		waypoints += len(state.waypoints)
+	}
+	if fp.Trucks > 0 {
+		fp.BytesPerTruck = float64(truckBytes) / float64(fp.Trucks)
+		fp.WaypointsPerTruck = float64(waypoints) / float64(fp.Trucks)
+	}
+
+	var metric dto.Metric
+	if err := updateDuration.Write(&metric); err == nil {
+		if h := metric.GetHistogram(); h.GetSampleCount() > 0 {
+			fp.UpdateSeconds = h.GetSampleSum() / float64(h.GetSampleCount())
+		}
+	}
+	return fp
+}
+
+// stringBytes sums the lengths of the string fields of a struct value.
+func stringBytes(v reflect.Value) int {
+	n := 0
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Field(i); f.Kind() == reflect.String {
+			n += f.Len()
+		}
+	}
+	return n
+}
@@ -0,0 +1,40 @@
+package simulation
+
+import "math"
+
+// cruisingSpeed is the speed in m/s (about 80 km/h) at which FuelPerKm applies; faster driving burns more.
+const cruisingSpeed = 22.0
+
+// consumeFuelLocked burns fuel for the distance travelled and schedules a refuelling stop when the tank
+// drops below the configured threshold.
+func (m *Manager) consumeFuelLocked(truck *Truck, state *routeState, meters float64) {
+	factor := 1 + math.Max(0, truck.Speed-cruisingSpeed)/cruisingSpeed
+	used := meters / 1000 * m.cfg.FuelPerKm * factor
+	truck.Fuel = math.Max(0, truck.Fuel-used)
+	m.recordEnergyLocked(state, used)
+
+	if truck.Fuel < m.cfg.TankCapacity*m.cfg.RefuelThreshold && !state.dwellQueued(TruckStatusRefueling) {
+		state.pendingDwell = append(state.pendingDwell, TruckStatusRefueling)
+	}
+}
+
+// recordFleetFuelLocked publishes the average fuel level and battery charge across the fleet.
+func (m *Manager) recordFleetFuelLocked() {
+	fuel, fuelled := 0.0, 0
+	charge, electric := 0.0, 0
+	for _, t := range m.trucks {
+		if t.Electric {
+			charge += t.BatterySOC
+			electric++
+		} else {
+			fuel += t.Fuel
+			fuelled++
+		}
+	}
+	if fuelled > 0 {
+		fleetAverageFuel.Set(fuel / float64(fuelled))
+	}
+	if electric > 0 {
+		fleetAverageCharge.Set(charge / float64(electric))
+	}
+}
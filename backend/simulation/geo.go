@@ -3,6 +3,7 @@ package simulation
 import (
 	"math"
 	"math/rand"
+	"time"
 )
 
 const earthRadiusMeters = 6371000.0
@@ -127,3 +128,51 @@ func RandomRouteWithinBounds(rng *rand.Rand, bounds BoundingBox, count int) []Po
 	}
 	return points
 }
+
+// civilHorizonDegrees is the elevation at which the sun's upper limb touches the horizon after refraction.
+const civilHorizonDegrees = -0.833
+
+// SolarElevation approximates the sun's elevation angle in degrees at the given time and location using
+// the NOAA fractional-year equations. It is accurate to within a degree, which is enough for day/night styling.
+func SolarElevation(t time.Time, p Point) float64 {
+	t = t.UTC()
+	hours := float64(t.Hour()) + float64(t.Minute())/60 + float64(t.Second())/3600
+	gamma := 2 * math.Pi / 365 * (float64(t.YearDay()-1) + (hours-12)/24)
+
+	declination := 0.006918 - 0.399912*math.Cos(gamma) + 0.070257*math.Sin(gamma) -
+		0.006758*math.Cos(2*gamma) + 0.000907*math.Sin(2*gamma) -
+		0.002697*math.Cos(3*gamma) + 0.00148*math.Sin(3*gamma)
+	eqTimeMinutes := 229.18 * (0.000075 + 0.001868*math.Cos(gamma) - 0.032077*math.Sin(gamma) -
+		0.014615*math.Cos(2*gamma) - 0.040849*math.Sin(2*gamma))
+
+	trueSolarMinutes := hours*60 + eqTimeMinutes + 4*p.Lon
+	hourAngle := degreesToRadians(trueSolarMinutes/4 - 180)
+	lat := degreesToRadians(p.Lat)
+
+	cosZenith := math.Sin(lat)*math.Sin(declination) + math.Cos(lat)*math.Cos(declination)*math.Cos(hourAngle)
+	cosZenith = math.Max(-1, math.Min(1, cosZenith))
+	return 90 - radiansToDegrees(math.Acos(cosZenith))
+}
+
+// IsDaylight reports whether the sun is above the horizon at the given time and location.
+func IsDaylight(t time.Time, p Point) bool {
+	return SolarElevation(t, p) > civilHorizonDegrees
+}
+
+// Contains reports whether p lies inside the box, edges included.
+func (b BoundingBox) Contains(p Point) bool {
+	return p.Lat >= b.MinLat && p.Lat <= b.MaxLat && p.Lon >= b.MinLon && p.Lon <= b.MaxLon
+}
+
+// PolygonContains reports whether p lies inside the polygon given by its vertices in order, using the
+// even-odd rule on latitude and longitude as planar coordinates. The polygon closes implicitly.
+func PolygonContains(polygon []Point, p Point) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) && p.Lon < (b.Lon-a.Lon)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
@@ -0,0 +1,55 @@
+package simulation
+
+// GeohashPrecision is the length of Truck.Geohash, a cell of about 5 m by 5 m.
+const GeohashPrecision = 9
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// EncodeGeohash returns the geohash of p with the given number of characters.
+func EncodeGeohash(p Point, precision int) string {
+	minLat, maxLat := -90.0, 90.0
+	minLon, maxLon := -180.0, 180.0
+	hash := make([]byte, 0, precision)
+	even := true
+	bits, ch := 0, 0
+	for len(hash) < precision {
+		ch <<= 1
+		if even {
+			mid := (minLon + maxLon) / 2
+			if p.Lon >= mid {
+				ch |= 1
+				minLon = mid
+			} else {
+				maxLon = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			if p.Lat >= mid {
+				ch |= 1
+				minLat = mid
+			} else {
+				maxLat = mid
+			}
+		}
+		even = !even
+		if bits++; bits == 5 {
+			hash = append(hash, geohashAlphabet[ch])
+			bits, ch = 0, 0
+		}
+	}
+	return string(hash)
+}
+
+// recordPositionLocked updates the truck's geohash, its place in the spatial index, and its trail from the
+// position it reports.
+func (m *Manager) recordPositionLocked(truck *Truck, state *routeState) {
+	p, at := Point{Lat: truck.Lat, Lon: truck.Lon}, truck.UpdatedAt
+	if state != nil && state.fix != nil {
+		p, at = Point{Lat: state.fix.Lat, Lon: state.fix.Lon}, state.fix.UpdatedAt
+	}
+	truck.Geohash = EncodeGeohash(p, GeohashPrecision)
+	m.index.move(truck, p)
+	if state != nil {
+		state.trail.add(p, at, m.cfg.TrackDepth)
+	}
+}
@@ -0,0 +1,150 @@
+package simulation
+
+import (
+	"fmt"
+	"strings"
+
+	"orbit/backend/events"
+)
+
+// Handoff transfers a truck to another fleet at an exchange point, as carriers do when they interline
+// freight.
+type Handoff struct {
+	// Fleet is the receiving fleet.
+	Fleet string
+	// IDPrefix replaces the prefix of the truck's ID, so truck-0007 handed to prefix acme becomes acme-0007.
+	// It defaults to Fleet.
+	IDPrefix string
+	// Exchange names the depot where the truck changes hands.
+	Exchange string
+}
+
+// pendingHandoff is a handoff waiting for the truck to reach the exchange; TruckID is the ID it will take.
+type pendingHandoff struct {
+	Handoff
+	TruckID string
+}
+
+// HandoffTruck sends the truck straight to the exchange depot and hands it to the receiving fleet when it
+// arrives. The truck then carries the new ID and fleet, and a truckHandedOff event links the two IDs. A
+// route change before the truck arrives cancels the handoff. It reports false when no such truck exists.
+func (m *Manager) HandoffTruck(id string, handoff Handoff) (Truck, bool, error) {
+	if handoff.Fleet == "" {
+		return Truck{}, true, fmt.Errorf("fleet is required")
+	}
+	if handoff.IDPrefix == "" {
+		handoff.IDPrefix = handoff.Fleet
+	}
+	if strings.ContainsAny(handoff.IDPrefix, "/ ") {
+		return Truck{}, true, fmt.Errorf("ID prefix must not contain slashes or spaces")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	truck, ok := m.trucks[id]
+	state := m.routes[id]
+	if !ok || state == nil {
+		return Truck{}, false, nil
+	}
+	if handoff.Fleet == truck.Fleet {
+		return Truck{}, true, fmt.Errorf("truck is already in fleet %s", handoff.Fleet)
+	}
+	exchange, ok := m.depotLocked(handoff.Exchange)
+	if !ok {
+		return Truck{}, true, fmt.Errorf("unknown exchange %q", handoff.Exchange)
+	}
+	newID := rebrandID(id, handoff.IDPrefix)
+	if err := m.checkHandoffIDLocked(id, newID); err != nil {
+		return Truck{}, true, err
+	}
+
+	m.wakeLocked(id)
+	current := Point{Lat: truck.Lat, Lon: truck.Lon}
+	m.replaceRouteLocked(truck, state, []Point{current, exchange.Location}, 1, false)
+	state.handoff = &pendingHandoff{Handoff: handoff, TruckID: newID}
+	truck.Heading = InitialBearing(current, exchange.Location)
+	truck.CurrentRoute = state.label()
+	updateETA(truck, state)
+	return m.reportedLocked(truck), true, nil
+}
+
+// depotLocked returns the configured depot with the given name.
+func (m *Manager) depotLocked(name string) (Depot, bool) {
+	for _, d := range m.cfg.Depots {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return Depot{}, false
+}
+
+// rebrandID swaps the prefix of a truck ID, the part before its last hyphen, for prefix.
+func rebrandID(id, prefix string) string {
+	if i := strings.LastIndexByte(id, '-'); i >= 0 {
+		id = id[i+1:]
+	}
+	return prefix + "-" + id
+}
+
+// checkHandoffIDLocked rejects a handoff whose new ID is already taken by a truck or another pending handoff.
+func (m *Manager) checkHandoffIDLocked(id, newID string) error {
+	if newID == id {
+		return fmt.Errorf("handoff must change the truck ID")
+	}
+	if _, ok := m.trucks[newID]; ok {
+		return fmt.Errorf("truck %s already exists", newID)
+	}
+	for other, state := range m.routes {
+		if other != id && state.handoff != nil && state.handoff.TruckID == newID {
+			return fmt.Errorf("truck %s is already being handed off as %s", other, newID)
+		}
+	}
+	return nil
+}
+
+// completeHandoffLocked rebrands a truck that reached the end of its route with a handoff pending. The
+// truck keeps its position, route state, and cargo under the new ID.
+func (m *Manager) completeHandoffLocked(truck *Truck, state *routeState, at Point) {
+	handoff := state.handoff
+	if handoff == nil {
+		return
+	}
+	state.handoff = nil
+	if _, taken := m.trucks[handoff.TruckID]; taken {
+		return
+	}
+
+	oldID, oldFleet := truck.ID, truck.Fleet
+	delete(m.trucks, oldID)
+	delete(m.routes, oldID)
+	truck.ID = handoff.TruckID
+	truck.Fleet = handoff.Fleet
+	m.trucks[truck.ID] = truck
+	m.routes[truck.ID] = state
+	if suspended, ok := m.suspended[oldID]; ok {
+		delete(m.suspended, oldID)
+		m.suspended[truck.ID] = suspended
+	}
+	for _, d := range m.depots {
+		for i, queued := range d.queue {
+			if queued == oldID {
+				d.queue[i] = truck.ID
+			}
+		}
+	}
+	if shipment := m.shipments[truck.ShipmentID]; shipment != nil {
+		shipment.TruckID = truck.ID
+	}
+
+	m.emitLocked(events.TypeTruckHandedOff, events.TruckHandedOff{
+		TruckID:         truck.ID,
+		PreviousTruckID: oldID,
+		Fleet:           truck.Fleet,
+		PreviousFleet:   oldFleet,
+		Exchange:        handoff.Exchange,
+		Lat:             at.Lat,
+		Lon:             at.Lon,
+		SimulatedTime:   m.clock,
+	})
+}
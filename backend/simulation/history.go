@@ -0,0 +1,105 @@
+package simulation
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// maxConfigHistory bounds how many applied configurations the manager keeps; the oldest are dropped first.
+const maxConfigHistory = 100
+
+// ConfigChange is one applied configuration and how it differs from the one before it.
+type ConfigChange struct {
+	ID string
+	// AppliedAt is the wall-clock time the configuration was applied.
+	AppliedAt time.Time
+	// Source says how the change was made: "initial", "apply", "update", "rollback", or
+	// "scale" when trucks are added or removed.
+	Source string
+	// RollbackOf is the ID of the change a rollback restored.
+	RollbackOf string
+	// RunID is the run the configuration started.
+	RunID  string
+	Diff   []ConfigDiff
+	Config Config
+}
+
+// ConfigDiff is a single Config field that changed, rendered as text.
+type ConfigDiff struct {
+	Field string
+	From  string
+	To    string
+}
+
+// ConfigHistory returns the applied configurations, oldest first.
+func (m *Manager) ConfigHistory() []ConfigChange {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	history := make([]ConfigChange, len(m.history))
+	for i, change := range m.history {
+		history[i] = change
+		history[i].Diff = append([]ConfigDiff(nil), change.Diff...)
+		history[i].Config = cloneConfig(change.Config)
+	}
+	return history
+}
+
+// ConfigChangeByID returns the recorded change with the given ID.
+func (m *Manager) ConfigChangeByID(id string) (ConfigChange, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, change := range m.history {
+		if change.ID == id {
+			change.Config = cloneConfig(change.Config)
+			return change, true
+		}
+	}
+	return ConfigChange{}, false
+}
+
+// RollbackConfig restarts the simulation with the configuration recorded under id.
+func (m *Manager) RollbackConfig(id string) (Config, error) {
+	change, ok := m.ConfigChangeByID(id)
+	if !ok {
+		return Config{}, fmt.Errorf("config %s not found", id)
+	}
+	if err := m.applyConfig(change.Config, "rollback", id); err != nil {
+		return Config{}, err
+	}
+	return m.Config(), nil
+}
+
+// recordConfigLocked appends cfg to the history with its diff against the previous entry.
+func (m *Manager) recordConfigLocked(cfg Config, source, rollbackOf string) {
+	m.historySeq++
+	change := ConfigChange{
+		ID:         fmt.Sprintf("cfg-%04d", m.historySeq),
+		AppliedAt:  time.Now(),
+		Source:     source,
+		RollbackOf: rollbackOf,
+		RunID:      m.run.ID,
+		Config:     cloneConfig(cfg),
+	}
+	if len(m.history) > 0 {
+		change.Diff = diffConfigs(m.history[len(m.history)-1].Config, cfg)
+	}
+	m.history = append(m.history, change)
+	if len(m.history) > maxConfigHistory {
+		m.history = append(m.history[:0], m.history[len(m.history)-maxConfigHistory:]...)
+	}
+}
+
+// diffConfigs lists the exported Config fields that differ between from and to.
+func diffConfigs(from, to Config) []ConfigDiff {
+	var diff []ConfigDiff
+	fv, tv := reflect.ValueOf(from), reflect.ValueOf(to)
+	for i := 0; i < fv.NumField(); i++ {
+		a, b := fv.Field(i).Interface(), tv.Field(i).Interface()
+		if reflect.DeepEqual(a, b) {
+			continue
+		}
+		diff = append(diff, ConfigDiff{Field: fv.Type().Field(i).Name, From: fmt.Sprint(a), To: fmt.Sprint(b)})
+	}
+	return diff
+}
@@ -0,0 +1,18 @@
+package simulation
+
+// accrueDriveTimeLocked adds one tick of driving to the driver's duty clock and schedules a rest break
+// once the drive-time limit is reached.
+func (m *Manager) accrueDriveTimeLocked(truck *Truck, state *routeState) {
+	if m.cfg.MaxDriveTime <= 0 {
+		return
+	}
+	state.driveTime += state.step
+	remaining := m.cfg.MaxDriveTime - state.driveTime
+	if remaining <= 0 {
+		remaining = 0
+		if !state.dwellQueued(TruckStatusResting) {
+			state.pendingDwell = append(state.pendingDwell, TruckStatusResting)
+		}
+	}
+	truck.RemainingDriveSeconds = remaining.Seconds()
+}
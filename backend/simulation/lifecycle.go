@@ -0,0 +1,113 @@
+package simulation
+
+// queueArrivalDwellLocked schedules the stationary statuses a truck passes through when it reaches the
+// waypoint it is currently heading to, at. It must be called before the route state advances.
+func (m *Manager) queueArrivalDwellLocked(truck *Truck, state *routeState, at Point) {
+	last := len(state.waypoints) - 1
+	switch {
+	case len(m.cfg.Depots) > 0 && state.legIndex == last:
+		if !state.returning {
+			m.completeRouteLocked(truck, state, at)
+			state.pendingDwell = append(state.pendingDwell, TruckStatusUnloading)
+			return
+		}
+		if m.cfg.MaintenanceEvery > 0 && state.routesCompleted%m.cfg.MaintenanceEvery == 0 {
+			state.pendingDwell = append(state.pendingDwell, TruckStatusMaintenance)
+		}
+		state.pendingDwell = append(state.pendingDwell, TruckStatusLoading)
+	case state.legIndex == last:
+		m.completeRouteLocked(truck, state, at)
+		state.pendingDwell = append(state.pendingDwell, TruckStatusUnloading)
+		if m.cfg.MaintenanceEvery > 0 && state.routesCompleted%m.cfg.MaintenanceEvery == 0 {
+			state.pendingDwell = append(state.pendingDwell, TruckStatusMaintenance)
+		}
+		if !state.loop {
+			// The next route starts where this one ended.
+			state.pendingDwell = append(state.pendingDwell, TruckStatusLoading)
+		}
+	case state.loop && state.legIndex == 0:
+		state.pendingDwell = append(state.pendingDwell, TruckStatusLoading)
+	}
+}
+
+// holdAtWaypointLocked pauses the truck as idle at an intermediate waypoint for the route's waypoint dwell.
+func (m *Manager) holdAtWaypointLocked(truck *Truck, state *routeState) {
+	d := m.cfg.WaypointDwell
+	if routeDwell, ok := m.cfg.RouteWaypointDwell[truck.RouteID]; ok {
+		d = routeDwell
+	}
+	if d <= 0 {
+		return
+	}
+	if until := m.clock.Add(d); until.After(state.holdUntil) {
+		state.holdUntil = until
+	}
+	truck.Status = TruckStatusIdle
+}
+
+// dwellLocked keeps the truck in its current stationary status until the simulated clock passes the dwell
+// deadline, then moves on to any queued statuses. It reports whether the truck is still dwelling.
+func (m *Manager) dwellLocked(truck *Truck, state *routeState) bool {
+	for {
+		if state.dwellStatus != "" && m.clock.Before(state.dwellUntil) {
+			truck.Status = state.dwellStatus
+			return true
+		}
+		if state.dwellStatus != "" {
+			m.finishDwellLocked(truck, state, state.dwellStatus)
+			state.dwellStatus = ""
+			// Trucks keep their dock between back-to-back unloading and loading.
+			if state.docked != "" && (len(state.pendingDwell) == 0 || !dockStatus(state.pendingDwell[0])) {
+				m.releaseDockLocked(state)
+			}
+		}
+
+		if len(state.pendingDwell) == 0 {
+			m.completeTripLocked(truck, state)
+			return false
+		}
+		next := state.pendingDwell[0]
+		d := m.cfg.Dwell[next]
+		if d > 0 && dockStatus(next) && !m.acquireDockLocked(truck, state) {
+			truck.Status = TruckStatusQueued
+			return true
+		}
+		state.pendingDwell = state.pendingDwell[1:]
+		if next == TruckStatusLoading {
+			m.pickupLocked(truck, state)
+		}
+		if d > 0 {
+			state.dwellStatus = next
+			state.dwellUntil = m.clock.Add(d)
+		} else {
+			m.finishDwellLocked(truck, state, next)
+		}
+	}
+}
+
+// finishDwellLocked applies the effect of completing a stationary status.
+func (m *Manager) finishDwellLocked(truck *Truck, state *routeState, status TruckStatus) {
+	switch status {
+	case TruckStatusLoading:
+		m.departLocked(truck)
+	case TruckStatusUnloading:
+		m.deliverLocked(truck)
+	case TruckStatusRefueling:
+		truck.Fuel = m.cfg.TankCapacity
+	case TruckStatusResting:
+		state.driveTime = 0
+		truck.RemainingDriveSeconds = m.cfg.MaxDriveTime.Seconds()
+	}
+}
+
+func (s *routeState) dwellQueued(status TruckStatus) bool {
+	if s.dwellStatus == status {
+		return true
+	}
+	for _, pending := range s.pendingDwell {
+		if pending == status {
+			return true
+		}
+	}
+	return false
+}
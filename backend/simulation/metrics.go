@@ -2,21 +2,135 @@ package simulation
 
 import (
 	"runtime"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// Latency histograms also publish sparse native buckets for scrapers that negotiate the protobuf format;
+// the classic buckets remain for text scrapes.
+const (
+	nativeHistogramBucketFactor = 1.1
+	nativeHistogramMaxBuckets   = 100
+)
+
 var (
 	tickLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
-		Name:    "orbit_tick_latency_seconds",
-		Help:    "Time between simulation ticks.",
-		Buckets: prometheus.DefBuckets,
+		Name:                            "orbit_tick_latency_seconds",
+		Help:                            "Time between simulation ticks.",
+		Buckets:                         prometheus.DefBuckets,
+		NativeHistogramBucketFactor:     nativeHistogramBucketFactor,
+		NativeHistogramMaxBucketNumber:  nativeHistogramMaxBuckets,
+		NativeHistogramMinResetDuration: time.Hour,
 	})
 
 	updateDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
-		Name:    "orbit_truck_update_duration_seconds",
-		Help:    "Duration spent updating an individual truck.",
-		Buckets: prometheus.DefBuckets,
+		Name:                            "orbit_truck_update_duration_seconds",
+		Help:                            "Duration spent updating an individual truck.",
+		Buckets:                         prometheus.DefBuckets,
+		NativeHistogramBucketFactor:     nativeHistogramBucketFactor,
+		NativeHistogramMaxBucketNumber:  nativeHistogramMaxBuckets,
+		NativeHistogramMinResetDuration: time.Hour,
+	})
+
+	truckUpdates = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "orbit_truck_updates_total",
+		Help: "Position updates produced across all trucks.",
+	})
+
+	fleetDistance = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "orbit_fleet_distance_meters_total",
+		Help: "Distance driven across all trucks.",
+	})
+
+	truckUpdateGap = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:                            "orbit_truck_update_gap_seconds",
+		Help:                            "Wall-clock time between consecutive updates of the same truck.",
+		Buckets:                         []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30, 60},
+		NativeHistogramBucketFactor:     nativeHistogramBucketFactor,
+		NativeHistogramMaxBucketNumber:  nativeHistogramMaxBuckets,
+		NativeHistogramMinResetDuration: time.Hour,
+	})
+
+	truckUpdateMaxGap = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "orbit_truck_update_max_gap_seconds",
+		Help: "Largest gap between consecutive updates of any truck during the last tick.",
+	})
+
+	routePlans = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "orbit_route_plans_total",
+		Help: "Routes requested from the route planner.",
+	})
+
+	routePlannerFallbacks = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "orbit_route_planner_fallbacks_total",
+		Help: "Routes requested from the route planner that kept random waypoints or a straight line because it failed or was too busy.",
+	})
+
+	routePlansPending = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "orbit_route_plans_pending",
+		Help: "Routes waiting in the plan queue for a planner worker.",
+	})
+
+	routesCompleted = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "orbit_routes_completed_total",
+		Help: "Routes driven to their final waypoint across all trucks.",
+	})
+
+	routeCompletionSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "orbit_route_completion_seconds",
+		Help:    "Simulated time from the start of a route to reaching its final waypoint.",
+		Buckets: prometheus.ExponentialBuckets(60, 2, 12),
+	})
+
+	simulationRestarts = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "orbit_simulation_restarts_total",
+		Help: "Restarts of the simulation loop after an internal error.",
+	})
+
+	fleetSize = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "orbit_trucks",
+		Help: "Number of trucks in the simulation.",
+	})
+
+	suspendedTrucks = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "orbit_suspended_trucks",
+		Help: "Idle trucks suspended from per-tick processing until they are due to move.",
+	})
+
+	fleetAverageFuel = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "orbit_fleet_average_fuel_liters",
+		Help: "Average remaining fuel across the fleet.",
+	})
+
+	fleetAverageCharge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "orbit_fleet_average_battery_soc",
+		Help: "Average battery state of charge across electric trucks, from 0 to 1.",
+	})
+
+	depotQueueLength = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "orbit_depot_queue_length",
+		Help: "Trucks waiting for a free dock at each depot.",
+	}, []string{"depot"})
+
+	depotDocksBusy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "orbit_depot_docks_busy",
+		Help: "Docks currently serving a truck at each depot.",
+	}, []string{"depot"})
+
+	speedViolations = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "orbit_speed_violations_total",
+		Help: "Stretches of driving over a truck's speed limit.",
+	})
+
+	overLimitSeconds = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "orbit_over_speed_limit_seconds_total",
+		Help: "Simulated time trucks spent driving over their speed limit.",
+	})
+
+	governorInterventions = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "orbit_speed_governor_interventions_total",
+		Help: "Times a speed governor pulled a truck back to its limit.",
 	})
 
 	goroutines = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
@@ -28,5 +142,5 @@ var (
 )
 
 func init() {
-	prometheus.MustRegister(tickLatency, updateDuration, goroutines)
+	prometheus.MustRegister(tickLatency, updateDuration, truckUpdates, fleetDistance, truckUpdateGap, truckUpdateMaxGap, routesCompleted, routePlans, routePlannerFallbacks, routePlansPending, routeCompletionSeconds, simulationRestarts, fleetSize, suspendedTrucks, fleetAverageFuel, fleetAverageCharge, depotQueueLength, depotDocksBusy, speedViolations, overLimitSeconds, governorInterventions, goroutines)
 }
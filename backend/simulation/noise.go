@@ -0,0 +1,70 @@
+package simulation
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// noiseSeedSalt separates the noise generator's seed from the run seed, so turning noise on or off leaves
+// the simulated fleet itself unchanged.
+const noiseSeedSalt = 0x6e6f697365
+
+// NoiseModel makes reported positions look like real GPS fixes. It only affects what Trucks and Truck
+// return; the simulation itself moves trucks along their true paths.
+type NoiseModel struct {
+	// SigmaMeters is the standard deviation of the Gaussian error added to each fix along both the
+	// north-south and east-west axes.
+	SigmaMeters float64
+	// DropoutProbability is the chance that an update yields no fix. The truck then keeps reporting its
+	// previous fix and UpdatedAt, like a receiver that lost the sky.
+	DropoutProbability float64
+}
+
+func (n NoiseModel) enabled() bool {
+	return n.SigmaMeters > 0 || n.DropoutProbability > 0
+}
+
+func normalizeNoise(n NoiseModel) NoiseModel {
+	n.SigmaMeters = math.Max(0, n.SigmaMeters)
+	n.DropoutProbability = math.Max(0, math.Min(1, n.DropoutProbability))
+	return n
+}
+
+// gpsFix is the position a truck last reported.
+type gpsFix struct {
+	Lat       float64
+	Lon       float64
+	UpdatedAt time.Time
+}
+
+// recordFixLocked draws the truck's reported fix for this update, or keeps the previous one on a dropout.
+func (m *Manager) recordFixLocked(truck *Truck, state *routeState) {
+	noise := m.cfg.Noise
+	if !noise.enabled() {
+		state.fix = nil
+		return
+	}
+	if m.noiseRand == nil {
+		m.noiseRand = rand.New(rand.NewSource(m.runSeed ^ noiseSeedSalt))
+	}
+	if state.fix != nil && m.noiseRand.Float64() < noise.DropoutProbability {
+		return
+	}
+	north := m.noiseRand.NormFloat64() * noise.SigmaMeters
+	east := m.noiseRand.NormFloat64() * noise.SigmaMeters
+	lat := truck.Lat + radiansToDegrees(north/earthRadiusMeters)
+	lon := truck.Lon + radiansToDegrees(east/(earthRadiusMeters*math.Max(math.Cos(degreesToRadians(truck.Lat)), 1e-6)))
+	state.fix = &gpsFix{Lat: math.Max(-90, math.Min(90, lat)), Lon: lon, UpdatedAt: truck.UpdatedAt}
+}
+
+// reportedLocked returns a copy of the truck as consumers see it: at its reported fix when noise is on, and
+// stamped by its own clock under clock skew.
+func (m *Manager) reportedLocked(truck *Truck) Truck {
+	reported := *truck
+	if state := m.routes[truck.ID]; state != nil && state.fix != nil {
+		reported.Lat, reported.Lon, reported.UpdatedAt = state.fix.Lat, state.fix.Lon, state.fix.UpdatedAt
+	}
+	reported.UpdatedAt = m.skewedLocked(truck.ID, reported.UpdatedAt)
+	return reported
+}
@@ -0,0 +1,230 @@
+package simulation
+
+import (
+	"fmt"
+	"time"
+)
+
+// TruckUpdate overrides parts of a single truck's state while the simulation runs. Nil fields are left
+// unchanged.
+type TruckUpdate struct {
+	Speed *float64
+	// Status forces the truck into a status. Idle holds the truck for StatusFor, or parks it until the next
+	// override when StatusFor is zero; stationary statuses last StatusFor or their configured dwell.
+	Status    *TruckStatus
+	StatusFor time.Duration
+	// Destination replaces the truck's route with a direct leg to the point.
+	Destination *Point
+	// SpeedLimit sets the truck's own limit, zero removing it, and Governed fits or removes its governor.
+	SpeedLimit *float64
+	Governed   *bool
+}
+
+// UpdateTruck applies the update to the truck with the given ID and returns the result. It reports false
+// when no such truck exists.
+func (m *Manager) UpdateTruck(id string, update TruckUpdate) (Truck, bool, error) {
+	if update.Speed != nil && *update.Speed <= 0 {
+		return Truck{}, true, fmt.Errorf("speed must be positive")
+	}
+	if update.SpeedLimit != nil && *update.SpeedLimit < 0 {
+		return Truck{}, true, fmt.Errorf("speed limit must not be negative")
+	}
+	if update.StatusFor < 0 {
+		return Truck{}, true, fmt.Errorf("status duration must not be negative")
+	}
+	if p := update.Destination; p != nil && (p.Lat < -90 || p.Lat > 90 || p.Lon < -180 || p.Lon > 180) {
+		return Truck{}, true, fmt.Errorf("destination is outside valid coordinates")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	truck, ok := m.trucks[id]
+	state := m.routes[id]
+	if !ok || state == nil {
+		return Truck{}, false, nil
+	}
+
+	if update.Status != nil {
+		if err := m.validateStatusOverrideLocked(*update.Status, update.StatusFor); err != nil {
+			return Truck{}, true, err
+		}
+	}
+	m.wakeLocked(id)
+
+	if update.Speed != nil {
+		truck.Speed = *update.Speed
+	}
+	if update.SpeedLimit != nil {
+		truck.SpeedLimit = *update.SpeedLimit
+	}
+	if update.Governed != nil {
+		truck.Governed = *update.Governed
+	}
+	if update.Destination != nil {
+		current := Point{Lat: truck.Lat, Lon: truck.Lon}
+		state.waypoints = []Point{current, *update.Destination}
+		state.annotations = nil
+		state.handoff = nil
+		state.routeName = ""
+		state.legIndex = 1
+		state.loop = false
+		state.parked = false
+		state.detouring = false
+		truck.CurrentRoute = state.label()
+	}
+	if update.Status != nil {
+		m.overrideStatusLocked(truck, state, *update.Status, update.StatusFor)
+	}
+	return *truck, true, nil
+}
+
+func (m *Manager) validateStatusOverrideLocked(status TruckStatus, d time.Duration) error {
+	switch status {
+	case TruckStatusEnRoute, TruckStatusIdle:
+		return nil
+	case TruckStatusLoading, TruckStatusUnloading, TruckStatusRefueling, TruckStatusMaintenance, TruckStatusResting:
+		if d <= 0 && m.cfg.Dwell[status] <= 0 {
+			return fmt.Errorf("status %q needs a duration", status)
+		}
+		return nil
+	default:
+		return fmt.Errorf("status %q cannot be set directly", status)
+	}
+}
+
+// overrideStatusLocked puts the truck into the requested status, dropping any hold or dwell in progress.
+func (m *Manager) overrideStatusLocked(truck *Truck, state *routeState, status TruckStatus, d time.Duration) {
+	state.holdUntil = time.Time{}
+	state.dwellStatus = ""
+	state.pendingDwell = nil
+	state.parked = status == TruckStatusIdle && d <= 0
+	m.leaveDepotsLocked(truck.ID, state)
+
+	switch status {
+	case TruckStatusEnRoute:
+	case TruckStatusIdle:
+		if d > 0 {
+			state.holdUntil = m.clock.Add(d)
+		}
+	default:
+		if d <= 0 {
+			d = m.cfg.Dwell[status]
+		}
+		state.dwellStatus = status
+		state.dwellUntil = m.clock.Add(d)
+	}
+	truck.Status = status
+}
+
+// TeleportTruck moves the truck to p without driving there. The truck carries on towards the waypoint it
+// was heading for unless resetRoute is set, in which case it drops its route, any hold or dwell, and its
+// trip in progress and sets off from p on a freshly generated route. It reports false when no such truck
+// exists.
+func (m *Manager) TeleportTruck(id string, p Point, resetRoute bool) (Truck, bool, error) {
+	if p.Lat < -90 || p.Lat > 90 || p.Lon < -180 || p.Lon > 180 {
+		return Truck{}, true, fmt.Errorf("position is outside valid coordinates")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	truck, ok := m.trucks[id]
+	state := m.routes[id]
+	if !ok || state == nil {
+		return Truck{}, false, nil
+	}
+	m.wakeLocked(id)
+
+	truck.Lat, truck.Lon = p.Lat, p.Lon
+	state.fix = nil
+	m.recordPositionLocked(truck, state)
+	if resetRoute {
+		m.replaceRouteLocked(truck, state, m.buildRoute(truck.Type, p, m.pickEndpoint(truck.Type, p)), 1, m.cfg.LoopRoutes)
+		m.requestPlanLocked(truck.ID, state)
+	}
+	if state.legIndex < len(state.waypoints) && state.waypoints[state.legIndex] != p {
+		truck.Heading = InitialBearing(p, state.waypoints[state.legIndex])
+	}
+	truck.CurrentRoute = state.label()
+	updateETA(truck, state)
+	return *truck, true, nil
+}
+
+// maxAssignedWaypoints bounds the routes accepted by AssignRoute.
+const maxAssignedWaypoints = 1000
+
+// AssignRoute replaces the truck's route with the given waypoints, dropping any hold, dwell, or trip in
+// progress. The truck drives from where it is to the first waypoint and on through the rest. A looping
+// route then starts over from the first waypoint and needs at least two; otherwise the truck parks at the
+// last waypoint. It reports false when no such truck exists.
+func (m *Manager) AssignRoute(id string, waypoints []Point, loop bool) (Truck, bool, error) {
+	return m.AssignAnnotatedRoute(id, waypoints, nil, loop)
+}
+
+// AssignAnnotatedRoute is AssignRoute with annotations for the waypoints, index for index. There may be
+// fewer annotations than waypoints.
+func (m *Manager) AssignAnnotatedRoute(id string, waypoints []Point, annotations []WaypointAnnotation, loop bool) (Truck, bool, error) {
+	if err := validateWaypoints(waypoints, loop); err != nil {
+		return Truck{}, true, err
+	}
+	if err := validateAnnotations(annotations, len(waypoints)); err != nil {
+		return Truck{}, true, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	truck, ok := m.trucks[id]
+	state := m.routes[id]
+	if !ok || state == nil {
+		return Truck{}, false, nil
+	}
+	m.wakeLocked(id)
+	m.assignRouteLocked(truck, state, waypoints, annotations, loop)
+	return *truck, true, nil
+}
+
+func (m *Manager) assignRouteLocked(truck *Truck, state *routeState, waypoints []Point, annotations []WaypointAnnotation, loop bool) {
+	current := Point{Lat: truck.Lat, Lon: truck.Lon}
+	if loop {
+		m.replaceRouteLocked(truck, state, append([]Point(nil), waypoints...), 0, true)
+		state.annotations = routeAnnotations(annotations, len(state.waypoints), 0)
+	} else {
+		m.replaceRouteLocked(truck, state, append([]Point{current}, waypoints...), 1, false)
+		state.annotations = routeAnnotations(annotations, len(state.waypoints), 1)
+		state.terminal = true
+	}
+	if target := state.waypoints[state.legIndex]; target != current {
+		truck.Heading = InitialBearing(current, target)
+	}
+	truck.CurrentRoute = state.label()
+	updateETA(truck, state)
+}
+
+// replaceRouteLocked starts the truck on a new route with the given waypoints, heading for
+// waypoints[legIndex]. Holds, dwells, detours, and the trip in progress are dropped.
+func (m *Manager) replaceRouteLocked(truck *Truck, state *routeState, waypoints []Point, legIndex int, loop bool) {
+	m.leaveDepotsLocked(truck.ID, state)
+	m.endTimetableLocked(state)
+	state.waypoints = waypoints
+	state.annotations = nil
+	state.handoff = nil
+	state.legIndex = legIndex
+	state.loop = loop
+	state.terminal = false
+	state.parked = false
+	state.returning = false
+	state.detouring = false
+	state.charging = false
+	state.holdUntil = time.Time{}
+	state.dwellStatus = ""
+	state.pendingDwell = nil
+	state.trip = nil
+	state.routeStarted = m.clock
+	state.routeName = ""
+	state.planToken = 0
+	truck.RouteID = fmt.Sprintf("%s_to_%s", pointLabel(waypoints[0]), pointLabel(waypoints[len(waypoints)-1]))
+	truck.RouteDistance = 0
+	truck.Status = TruckStatusEnRoute
+}
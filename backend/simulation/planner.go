@@ -0,0 +1,73 @@
+package simulation
+
+import "math/rand"
+
+// RoutePlanner plans the waypoints of a route from start to end, both included. Without one set by
+// WithRoutePlanner, routes come from RandomPlanner; plug in a planner to route with Valhalla, GraphHopper,
+// or a road network of your own. The manager calls Plan with its lock held whenever it generates a route,
+// so only fast, in-process planners belong here; put planners backed by a service behind a PlanQueue.
+// When Plan fails, the manager falls back to RandomPlanner and counts the fallback in
+// orbit_route_planner_fallbacks_total.
+type RoutePlanner interface {
+	Plan(start, end Point) ([]Point, error)
+}
+
+// RandomPlanner is the built-in route planner. It places random waypoints between start and end, drawing
+// from the simulation's generator so that routes follow from the seed.
+type RandomPlanner struct {
+	Rand *rand.Rand
+	// Waypoints is the number of waypoints per route, counting start and end.
+	Waypoints int
+	// Bounds are the boxes waypoints are drawn from, one chosen at random for each route. Without bounds
+	// they fall anywhere within Area.
+	Bounds []BoundingBox
+	Area   BoundingBox
+}
+
+// Plan returns start, Waypoints-2 random points, and end. It never fails.
+func (p RandomPlanner) Plan(start, end Point) ([]Point, error) {
+	waypoints := []Point{start}
+	if p.Waypoints > 2 {
+		bounds := p.Area
+		if len(p.Bounds) > 0 {
+			bounds = p.Bounds[p.Rand.Intn(len(p.Bounds))]
+		}
+		waypoints = append(waypoints, RandomRouteWithinBounds(p.Rand, bounds, p.Waypoints-2)...)
+	}
+	return append(waypoints, end), nil
+}
+
+// randomPlannerLocked returns the built-in planner for trucks of the given type.
+func (m *Manager) randomPlannerLocked(typ TruckType) RandomPlanner {
+	return RandomPlanner{
+		Rand:      m.rand,
+		Waypoints: m.profileLocked(typ).WaypointsPerRoute,
+		Bounds:    m.cfg.RouteBounds,
+		Area:      m.defaultBounds(),
+	}
+}
+
+// WithRoutePlanner generates routes with p instead of random waypoints within the route bounds. Trucks on
+// assigned, catalog, and depot return routes are unaffected. A nil planner restores the built-in
+// generator.
+func (m *Manager) WithRoutePlanner(p RoutePlanner) *Manager {
+	m.mu.Lock()
+	m.planner = p
+	m.mu.Unlock()
+	return m
+}
+
+// plannedRouteLocked asks the route planner for a route, reporting false when there is no planner or it
+// failed.
+func (m *Manager) plannedRouteLocked(start, end Point) ([]Point, bool) {
+	if m.planner == nil {
+		return nil, false
+	}
+	routePlans.Inc()
+	waypoints, err := m.planner.Plan(start, end)
+	if err != nil || len(waypoints) < 2 {
+		routePlannerFallbacks.Inc()
+		return nil, false
+	}
+	return waypoints, true
+}
@@ -0,0 +1,177 @@
+package simulation
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	// DefaultPlanWorkers is how many routes a PlanQueue plans at once unless PlanQueueOptions says otherwise.
+	DefaultPlanWorkers = 4
+	// DefaultPlanBacklog is how many routes may wait for a worker before new ones fall back at once.
+	DefaultPlanBacklog = 1024
+)
+
+// PlanQueueOptions configures a PlanQueue.
+type PlanQueueOptions struct {
+	// Workers bounds the number of concurrent Plan calls.
+	Workers int
+	// Backlog bounds the routes waiting for a worker. Routes beyond it keep their straight line.
+	Backlog int
+}
+
+// planJob asks for the road route of the provisional straight line from start to end. token ties the
+// answer to the route it was asked for, so a truck rerouted in the meantime keeps its new route.
+type planJob struct {
+	truckID    string
+	token      uint64
+	start, end Point
+}
+
+// PlanQueue plans routes with a slow planner, such as a routing service, off the simulation's lock. A
+// manager using one gives each new route a provisional straight line from start to end and swaps in the
+// planned route when it arrives, so a slow or unreachable planner never holds up a tick.
+type PlanQueue struct {
+	planner RoutePlanner
+	workers int
+	jobs    chan planJob
+
+	mu  sync.Mutex
+	sim *Manager
+}
+
+// NewPlanQueue creates a queue for planner with defaults for unset options. Call Run to start planning
+// and Manager.WithPlanQueue to route through it.
+func NewPlanQueue(planner RoutePlanner, opts PlanQueueOptions) *PlanQueue {
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultPlanWorkers
+	}
+	if opts.Backlog <= 0 {
+		opts.Backlog = DefaultPlanBacklog
+	}
+	return &PlanQueue{planner: planner, workers: opts.Workers, jobs: make(chan planJob, opts.Backlog)}
+}
+
+// Run plans queued routes on Workers goroutines until ctx is cancelled.
+func (q *PlanQueue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < q.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-q.jobs:
+					waypoints, err := q.planner.Plan(job.start, job.end)
+					q.mu.Lock()
+					sim := q.sim
+					q.mu.Unlock()
+					if sim != nil {
+						sim.applyPlannedRoute(job, waypoints, err)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Pending returns the number of routes waiting for a worker.
+func (q *PlanQueue) Pending() int {
+	return len(q.jobs)
+}
+
+// offer queues a job, reporting false when the backlog is full.
+func (q *PlanQueue) offer(job planJob) bool {
+	select {
+	case q.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// WithPlanQueue plans generated routes through q instead of calling a planner under the lock. It takes
+// precedence over WithRoutePlanner. Routes then depend on how quickly the planner answers, so runs that
+// use a queue do not replay identically. A nil queue plans synchronously again.
+func (m *Manager) WithPlanQueue(q *PlanQueue) *Manager {
+	if q != nil {
+		q.mu.Lock()
+		q.sim = m
+		q.mu.Unlock()
+	}
+	m.mu.Lock()
+	m.planQueue = q
+	m.mu.Unlock()
+	return m
+}
+
+// requestPlanLocked queues the road route for the provisional straight line the truck was just given.
+func (m *Manager) requestPlanLocked(truckID string, state *routeState) {
+	if m.planQueue == nil || len(state.waypoints) != 2 {
+		return
+	}
+	routePlans.Inc()
+	m.planSeq++
+	job := planJob{truckID: truckID, token: m.planSeq, start: state.waypoints[0], end: state.waypoints[1]}
+	if !m.planQueue.offer(job) {
+		routePlannerFallbacks.Inc()
+		return
+	}
+	state.planToken = job.token
+	routePlansPending.Set(float64(m.planQueue.Pending()))
+}
+
+// applyPlannedRoute swaps a planned route in for the truck's provisional straight line. The truck carries
+// on from where it is towards the first planned waypoint past its position. Answers for trucks that have
+// been rerouted, removed, or already arrived are dropped; failures keep the straight line.
+func (m *Manager) applyPlannedRoute(job planJob, waypoints []Point, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.planQueue != nil {
+		routePlansPending.Set(float64(m.planQueue.Pending()))
+	}
+
+	truck, ok := m.trucks[job.truckID]
+	state := m.routes[job.truckID]
+	if !ok || state == nil || state.planToken != job.token {
+		return
+	}
+	state.planToken = 0
+	if err != nil || len(waypoints) < 2 {
+		routePlannerFallbacks.Inc()
+		return
+	}
+	if len(state.waypoints) != 2 || state.waypoints[0] != job.start || state.waypoints[1] != job.end || state.detouring {
+		return
+	}
+	current := Point{Lat: truck.Lat, Lon: truck.Lon}
+	if !state.loop && state.legIndex == 1 && current == job.end {
+		return
+	}
+
+	nearest := 0
+	for i, p := range waypoints {
+		if GreatCircleDistance(current, p) < GreatCircleDistance(current, waypoints[nearest]) {
+			nearest = i
+		}
+	}
+	next := nearest + 1
+	switch {
+	case state.legIndex == 0:
+		// A looping truck on its way back to the start keeps heading there.
+		next = 0
+	case next >= len(waypoints):
+		next = len(waypoints) - 1
+	}
+	state.waypoints = append([]Point(nil), waypoints...)
+	state.annotations = nil
+	state.legIndex = next
+	if target := state.waypoints[next]; target != current {
+		truck.Heading = InitialBearing(current, target)
+	}
+	truck.CurrentRoute = state.label()
+	updateETA(truck, state)
+}
@@ -0,0 +1,123 @@
+package simulation
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// ConfigPreview describes what applying a configuration would do to the running simulation, without
+// applying it.
+type ConfigPreview struct {
+	// Reset is set when applying restarts the simulation and rebuilds every truck from the seed.
+	Reset bool
+	// Config is the configuration that would be applied.
+	Config Config
+	Diff   []ConfigDiff
+	// TrucksAdded and TrucksRemoved list the truck IDs that would join and leave the fleet.
+	TrucksAdded   []string
+	TrucksRemoved []string
+	// IntervalFrom and IntervalTo are the update interval before and after.
+	IntervalFrom time.Duration
+	IntervalTo   time.Duration
+	// RoutesRebuilt lists the route IDs that would be dropped straight away and replaced with new routes.
+	RoutesRebuilt []string
+	// LeavingBounds lists the kept trucks with waypoints still to reach outside new route bounds. They
+	// finish the route and then plan their next one within the bounds.
+	LeavingBounds []string
+}
+
+// PreviewUpdate reports what ApplyUpdate would do with the update, without changing anything.
+func (m *Manager) PreviewUpdate(update ConfigUpdate) (ConfigPreview, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if !m.started {
+		return ConfigPreview{}, fmt.Errorf("simulation not started")
+	}
+	cfg := cloneConfig(normalizeConfig(mergeUpdate(cloneConfig(m.cfg), update)))
+	return m.previewLocked(cfg, update.Reset), nil
+}
+
+// PreviewConfig reports what ApplyConfig would do with cfg, without changing anything. Applying a whole
+// configuration always restarts the simulation.
+func (m *Manager) PreviewConfig(cfg Config) (ConfigPreview, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if !m.started {
+		return ConfigPreview{}, fmt.Errorf("simulation not started")
+	}
+	return m.previewLocked(cloneConfig(normalizeConfig(cfg)), true), nil
+}
+
+func (m *Manager) previewLocked(cfg Config, reset bool) ConfigPreview {
+	preview := ConfigPreview{
+		Reset:        reset,
+		Config:       cfg,
+		Diff:         diffConfigs(cloneConfig(m.cfg), cfg),
+		IntervalFrom: m.cfg.UpdateInterval,
+		IntervalTo:   cfg.UpdateInterval,
+	}
+	trucks := m.sortedTrucksLocked()
+
+	if reset {
+		// The rebuilt fleet is numbered from one again.
+		kept := make(map[string]bool, cfg.NumTrucks)
+		for i := 0; i < cfg.NumTrucks; i++ {
+			kept[fmt.Sprintf("truck-%04d", i+1)] = true
+		}
+		routes := make(map[string]bool)
+		for _, truck := range trucks {
+			routes[truck.RouteID] = true
+			if kept[truck.ID] {
+				delete(kept, truck.ID)
+			} else {
+				preview.TrucksRemoved = append(preview.TrucksRemoved, truck.ID)
+			}
+		}
+		for i := 0; i < cfg.NumTrucks; i++ {
+			if id := fmt.Sprintf("truck-%04d", i+1); kept[id] {
+				preview.TrucksAdded = append(preview.TrucksAdded, id)
+			}
+		}
+		for route := range routes {
+			preview.RoutesRebuilt = append(preview.RoutesRebuilt, route)
+		}
+		sort.Strings(preview.RoutesRebuilt)
+		return preview
+	}
+
+	if extra := cfg.NumTrucks - len(trucks); extra > 0 {
+		for i := 0; i < extra; i++ {
+			preview.TrucksAdded = append(preview.TrucksAdded, fmt.Sprintf("truck-%04d", m.truckSeq+i+1))
+		}
+	} else if extra < 0 {
+		preview.TrucksRemoved = m.newestTruckIDsLocked(-extra)
+		trucks = trucks[:len(trucks)+extra]
+	}
+	if !reflect.DeepEqual(m.cfg.RouteBounds, cfg.RouteBounds) && len(cfg.RouteBounds) > 0 {
+		for _, truck := range trucks {
+			if state := m.routes[truck.ID]; state != nil && state.routeName == "" && state.legIndex < len(state.waypoints) && !withinBounds(state.waypoints[state.legIndex:], cfg.RouteBounds) {
+				preview.LeavingBounds = append(preview.LeavingBounds, truck.ID)
+			}
+		}
+	}
+	return preview
+}
+
+// withinBounds reports whether every waypoint lies in one of the bounding boxes.
+func withinBounds(waypoints []Point, bounds []BoundingBox) bool {
+	for _, p := range waypoints {
+		inside := false
+		for _, b := range bounds {
+			if b.Contains(p) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,26 @@
+package simulation
+
+import "math"
+
+// webMercatorRadius is the WGS 84 semi-major axis used by EPSG:3857, not the mean radius used for distances.
+const webMercatorRadius = 6378137.0
+
+// MaxMercatorLatitude is the latitude at which EPSG:3857 maps to a square world; positions beyond it are
+// clamped.
+const MaxMercatorLatitude = 85.05112878
+
+// ToWebMercator projects a WGS 84 position to EPSG:3857 (spherical Web Mercator) x/y in metres.
+func ToWebMercator(p Point) (x, y float64) {
+	lat := math.Max(-MaxMercatorLatitude, math.Min(MaxMercatorLatitude, p.Lat))
+	x = webMercatorRadius * degreesToRadians(p.Lon)
+	y = webMercatorRadius * math.Log(math.Tan(math.Pi/4+degreesToRadians(lat)/2))
+	return x, y
+}
+
+// FromWebMercator converts EPSG:3857 x/y metres back to a WGS 84 position.
+func FromWebMercator(x, y float64) Point {
+	return Point{
+		Lat: radiansToDegrees(2*math.Atan(math.Exp(y/webMercatorRadius)) - math.Pi/2),
+		Lon: radiansToDegrees(x / webMercatorRadius),
+	}
+}
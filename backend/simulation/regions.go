@@ -0,0 +1,157 @@
+package simulation
+
+import (
+	"fmt"
+	"time"
+)
+
+// Region gives the trucks inside Bounds their own update interval, so a dense urban area can update
+// every 500ms while long-haul corridors update every few seconds. Trucks outside every region use
+// Config.UpdateInterval. When regions overlap the first one listed wins.
+type Region struct {
+	Name           string
+	Bounds         BoundingBox
+	UpdateInterval time.Duration
+}
+
+// RegionStatus describes one region and the trucks currently inside it.
+type RegionStatus struct {
+	Name           string
+	UpdateInterval time.Duration
+	Trucks         int
+}
+
+// defaultRegionName labels trucks outside every configured region.
+const defaultRegionName = "default"
+
+// baseInterval is how often the manager ticks: the shortest interval of the default and every region.
+// Slower regions tick every few manager ticks, and their trucks sit out the ticks in between.
+func (m *Manager) baseInterval() time.Duration {
+	return baseIntervalOf(m.cfg)
+}
+
+func baseIntervalOf(cfg Config) time.Duration {
+	interval := cfg.UpdateInterval
+	for _, r := range cfg.Regions {
+		if r.UpdateInterval > 0 && r.UpdateInterval < interval {
+			interval = r.UpdateInterval
+		}
+	}
+	return interval
+}
+
+// regionLocked returns the index of the region the truck is in, or -1 for the default region.
+func (m *Manager) regionLocked(truck *Truck) int {
+	p := Point{Lat: truck.Lat, Lon: truck.Lon}
+	for i, r := range m.cfg.Regions {
+		if r.UpdateInterval > 0 && r.Bounds.Contains(p) {
+			return i
+		}
+	}
+	return -1
+}
+
+// regionTicksLocked returns how many manager ticks apart a region ticks: its interval rounded up to
+// whole ticks. region is an index into Regions plus one, with zero for the default region.
+func (m *Manager) regionTicksLocked(region int) int64 {
+	interval := m.cfg.UpdateInterval
+	if region > 0 && region <= len(m.cfg.Regions) {
+		interval = m.cfg.Regions[region-1].UpdateInterval
+	}
+	base := m.baseInterval()
+	return max(1, int64((interval+base-1)/base))
+}
+
+// dueLocked reports whether the truck's region has ticked since the truck last advanced. All regions
+// tick on the manager's tick count, so the trucks of a region advance together. When the truck is due,
+// state.step is set to the simulated time it advances by.
+func (m *Manager) dueLocked(state *routeState) bool {
+	since := m.ticks % m.regionTicksLocked(state.region)
+	ticked := m.clock.Add(-time.Duration(since) * m.tickDuration())
+	if !state.steppedAt.Before(ticked) {
+		return false
+	}
+	state.step = m.clock.Sub(state.steppedAt)
+	state.steppedAt = m.clock
+	return true
+}
+
+// rezoneLocked files the truck under the region it is in now, so that the workers visit it on that
+// region's ticks from now on.
+func (m *Manager) rezoneLocked(truck *Truck, state *routeState) {
+	if region := m.regionLocked(truck) + 1; region != state.region {
+		state.region = region
+		m.shardEpoch++
+	}
+}
+
+// shardSchedule is a worker's copy of its shard split by region. Each tick the worker only visits the
+// trucks of regions that have ticked since it last ran, so trucks in slow regions cost nothing on the
+// ticks they sit out.
+type shardSchedule struct {
+	epoch    uint64
+	built    bool
+	byRegion [][]*Truck
+	// lastTick is the manager tick the worker last ran on, or -1 before its first run.
+	lastTick int64
+}
+
+// dueLocked returns the trucks of shard index whose regions have ticked since the worker last ran,
+// splitting the shard by region again whenever shardEpoch has moved on.
+func (s *shardSchedule) dueLocked(m *Manager, index int) []*Truck {
+	if !s.built || s.epoch != m.shardEpoch {
+		s.byRegion = make([][]*Truck, len(m.cfg.Regions)+1)
+		for _, truck := range m.shards[index] {
+			region := 0
+			if state := m.routes[truck.ID]; state != nil && state.region < len(s.byRegion) {
+				region = state.region
+			}
+			s.byRegion[region] = append(s.byRegion[region], truck)
+		}
+		s.epoch, s.built = m.shardEpoch, true
+	}
+	var due []*Truck
+	for region, trucks := range s.byRegion {
+		every := m.regionTicksLocked(region)
+		if s.lastTick < 0 || m.ticks/every != s.lastTick/every {
+			due = append(due, trucks...)
+		}
+	}
+	s.lastTick = m.ticks
+	return due
+}
+
+// Regions lists the configured regions followed by the default region, with the number of trucks in each.
+func (m *Manager) Regions() []RegionStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	statuses := make([]RegionStatus, 0, len(m.cfg.Regions)+1)
+	for _, r := range m.cfg.Regions {
+		statuses = append(statuses, RegionStatus{Name: r.Name, UpdateInterval: r.UpdateInterval})
+	}
+	statuses = append(statuses, RegionStatus{Name: defaultRegionName, UpdateInterval: m.cfg.UpdateInterval})
+	for _, truck := range m.trucks {
+		if i := m.regionLocked(truck); i >= 0 {
+			statuses[i].Trucks++
+		} else {
+			statuses[len(statuses)-1].Trucks++
+		}
+	}
+	return statuses
+}
+
+// normalizeRegions drops regions without an interval and names unnamed ones by position.
+func normalizeRegions(regions []Region) []Region {
+	var kept []Region
+	for _, r := range regions {
+		if r.UpdateInterval <= 0 {
+			continue
+		}
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("region-%d", len(kept)+1)
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
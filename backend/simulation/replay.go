@@ -0,0 +1,259 @@
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Recording captures everything needed to reproduce a simulation in step mode: the configuration and seed
+// of every run and the tick at which it started. Attach it to bug reports and load it with Replay.
+type Recording struct {
+	Runs []RecordedRun
+	// Ticks is the number of ticks simulated when the recording was taken.
+	Ticks int64
+}
+
+// RecordedRun is one run in a recording. Its Config has the run's actual seed and start time filled in.
+type RecordedRun struct {
+	Tick   int64
+	Config Config
+}
+
+// Recording returns the runs simulated so far.
+func (m *Manager) Recording() Recording {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rec := Recording{Runs: make([]RecordedRun, len(m.recording)), Ticks: m.ticks}
+	for i, run := range m.recording {
+		rec.Runs[i] = RecordedRun{Tick: run.Tick, Config: cloneConfig(run.Config)}
+	}
+	return rec
+}
+
+// Replay puts a manager that has not been started into replay mode. StepOnce then reproduces the recorded
+// runs, switching to each recorded configuration at the tick it was applied, so stepping rec.Ticks ticks
+// yields the same trajectories every time.
+//
+// Runs driven by the ticker share the random source between worker goroutines, so only runs recorded in
+// step mode replay identically to the original.
+func (m *Manager) Replay(rec Recording) error {
+	if len(rec.Runs) == 0 {
+		return fmt.Errorf("recording has no runs")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.started {
+		return fmt.Errorf("simulation is running")
+	}
+	m.recording = nil
+	m.ticks = 0
+	m.replay = nil
+	for _, run := range rec.Runs[1:] {
+		m.replay = append(m.replay, RecordedRun{Tick: run.Tick, Config: cloneConfig(normalizeConfig(run.Config))})
+	}
+	m.resetLocked(cloneConfig(normalizeConfig(rec.Runs[0].Config)))
+	return nil
+}
+
+// recordRunLocked appends the run that is starting to the recording.
+func (m *Manager) recordRunLocked() {
+	cfg := cloneConfig(m.cfg)
+	cfg.Seed = m.runSeed
+	cfg.StartTime = m.clock
+	cfg.RotateSeed = false
+	m.recording = append(m.recording, RecordedRun{Tick: m.ticks, Config: cfg})
+}
+
+// replayDueLocked switches to the next recorded run once its tick is reached. It reports whether the fleet
+// was rebuilt.
+func (m *Manager) replayDueLocked() bool {
+	if len(m.replay) == 0 || m.replay[0].Tick > m.ticks {
+		return false
+	}
+	next := m.replay[0]
+	m.replay = m.replay[1:]
+	m.resetLocked(next.Config)
+	m.ensureTrucksLocked()
+	return true
+}
+
+// ResumeFromRecording replays rec up to its last recorded tick on a manager that has not been started, so
+// Start continues the recorded world live from where the recording ended.
+func (m *Manager) ResumeFromRecording(rec Recording) error {
+	if err := m.Replay(rec); err != nil {
+		return err
+	}
+	if rec.Ticks <= 0 {
+		return nil
+	}
+	return m.StepOnce(int(rec.Ticks))
+}
+
+// DefaultReplayFrame is how often Play steps the fleet unless ReplayTiming.Frame says otherwise.
+const DefaultReplayFrame = 100 * time.Millisecond
+
+// ReplayTiming maps a recording's simulated timeline onto the wall clock for Play. Duration fits the whole
+// recording into that much wall-clock time, so 8 recorded hours can play in 20 minutes. Otherwise Speed
+// plays that many simulated seconds per second, and with neither the recording plays at the time scale of
+// its first run.
+type ReplayTiming struct {
+	Duration time.Duration
+	Speed    float64
+	// Loop starts the recording over from its first tick when it ends.
+	Loop bool
+	// Frame is how often the fleet is stepped to catch up with the wall clock.
+	Frame time.Duration
+}
+
+// Playback is the progress of a recording being played by Play.
+type Playback struct {
+	Tick  int64
+	Ticks int64
+	// Speed is the simulated seconds played per wall-clock second.
+	Speed float64
+	Loops int
+	Loop  bool
+}
+
+// replaySegment is the ticks of one recorded run and the simulated time each takes.
+type replaySegment struct {
+	start, end int64
+	tick       time.Duration
+}
+
+// replayTimeline converts offsets into a recording's simulated timeline to ticks.
+type replayTimeline []replaySegment
+
+func newReplayTimeline(rec Recording) replayTimeline {
+	timeline := make(replayTimeline, 0, len(rec.Runs))
+	for i, run := range rec.Runs {
+		end := rec.Ticks
+		if i+1 < len(rec.Runs) {
+			end = min(rec.Runs[i+1].Tick, rec.Ticks)
+		}
+		cfg := normalizeConfig(run.Config)
+		tick := time.Duration(float64(baseIntervalOf(cfg)) * cfg.TimeScale)
+		if end > run.Tick && tick > 0 {
+			timeline = append(timeline, replaySegment{start: run.Tick, end: end, tick: tick})
+		}
+	}
+	return timeline
+}
+
+// span is the simulated time the recording covers.
+func (t replayTimeline) span() time.Duration {
+	var span time.Duration
+	for _, seg := range t {
+		span += time.Duration(seg.end-seg.start) * seg.tick
+	}
+	return span
+}
+
+// tickAt returns how many ticks into the recording offset falls, walking the runs so that a run with a
+// longer tick advances proportionally fewer ticks.
+func (t replayTimeline) tickAt(offset time.Duration) int64 {
+	for _, seg := range t {
+		length := time.Duration(seg.end-seg.start) * seg.tick
+		if offset < length {
+			return seg.start + int64(offset/seg.tick)
+		}
+		offset -= length
+	}
+	if len(t) == 0 {
+		return 0
+	}
+	return t[len(t)-1].end
+}
+
+// Play replays rec on a manager that has not been started, stepping it in step mode to follow the wall
+// clock as timing maps it, so every truck moves exactly as recorded but on a compressed or stretched
+// timeline. Several frames' worth of ticks are stepped at once when playback runs faster than the tick
+// rate. Play returns when ctx is done or, unless timing.Loop is set, when the recording has played out,
+// leaving the fleet where the recording ended.
+func (m *Manager) Play(ctx context.Context, rec Recording, timing ReplayTiming) error {
+	if rec.Ticks <= 0 {
+		return fmt.Errorf("recording has no ticks to play")
+	}
+	if timing.Duration < 0 || timing.Speed < 0 || math.IsNaN(timing.Speed) || math.IsInf(timing.Speed, 0) {
+		return fmt.Errorf("replay duration and speed must not be negative")
+	}
+	timeline := newReplayTimeline(rec)
+	span := timeline.span()
+	if span <= 0 {
+		return fmt.Errorf("recording covers no simulated time")
+	}
+	speed := timing.Speed
+	switch {
+	case timing.Duration > 0:
+		speed = span.Seconds() / timing.Duration.Seconds()
+	case speed == 0:
+		speed = normalizeConfig(rec.Runs[0].Config).TimeScale
+	}
+	frame := timing.Frame
+	if frame <= 0 {
+		frame = DefaultReplayFrame
+	}
+
+	if err := m.Replay(rec); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.playback = &Playback{Ticks: rec.Ticks, Speed: speed, Loop: timing.Loop}
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.playback = nil
+		m.mu.Unlock()
+	}()
+
+	ticker := time.NewTicker(frame)
+	defer ticker.Stop()
+	began := time.Now()
+	var played int64
+	for {
+		elapsed := time.Duration(float64(time.Since(began)) * speed)
+		if due := timeline.tickAt(elapsed) - played; due > 0 {
+			if err := m.step(int(due)); err != nil {
+				return err
+			}
+			played += due
+			m.mu.Lock()
+			m.playback.Tick = played
+			m.mu.Unlock()
+		}
+		if played >= rec.Ticks {
+			if !timing.Loop {
+				return nil
+			}
+			// Carry the overshoot into the next loop so the pace does not stutter at the seam.
+			began = began.Add(time.Duration(float64(span) / speed))
+			played = 0
+			if err := m.Replay(rec); err != nil {
+				return err
+			}
+			m.mu.Lock()
+			m.playback.Tick = 0
+			m.playback.Loops++
+			m.mu.Unlock()
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Playback returns the progress of the recording Play is playing, and false when none is.
+func (m *Manager) Playback() (Playback, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.playback == nil {
+		return Playback{}, false
+	}
+	return *m.playback, true
+}
@@ -0,0 +1,42 @@
+package simulation
+
+import "math/rand"
+
+// countingSource wraps the standard generator and counts draws so the random state can be saved as a seed
+// and a draw count, then restored by replaying the draws.
+type countingSource struct {
+	src   rand.Source64
+	seed  int64
+	draws uint64
+}
+
+// newRand returns a generator seeded with seed together with its counting source.
+func newRand(seed int64) (*rand.Rand, *countingSource) {
+	src := &countingSource{src: rand.NewSource(seed).(rand.Source64), seed: seed}
+	return rand.New(src), src
+}
+
+// restoreRand returns a generator in the state reached after draws draws from seed.
+func restoreRand(seed int64, draws uint64) (*rand.Rand, *countingSource) {
+	rng, src := newRand(seed)
+	for src.draws < draws {
+		src.Uint64()
+	}
+	return rng, src
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.seed = seed
+	s.draws = 0
+}
@@ -0,0 +1,125 @@
+package simulation
+
+// RouteStatus summarises every truck dispatched on a shared route.
+type RouteStatus struct {
+	RouteID         string
+	Trucks          int
+	ByStatus        map[TruckStatus]int
+	AverageProgress float64
+}
+
+// RouteStatus aggregates trucks sharing the route ID. It returns false when no truck is on the route.
+func (m *Manager) RouteStatus(routeID string) (RouteStatus, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := RouteStatus{RouteID: routeID, ByStatus: make(map[TruckStatus]int)}
+	progress := 0.0
+	for _, truck := range m.trucks {
+		if truck.RouteID != routeID {
+			continue
+		}
+		status.Trucks++
+		status.ByStatus[truck.Status]++
+		progress += routeProgress(truck, m.routes[truck.ID])
+	}
+	if status.Trucks == 0 {
+		return RouteStatus{}, false
+	}
+	status.AverageProgress = progress / float64(status.Trucks)
+	return status, true
+}
+
+// routeProgress returns the fraction of the current waypoint sequence already covered, from 0 to 1.
+func routeProgress(truck *Truck, state *routeState) float64 {
+	if state == nil || len(state.waypoints) < 2 {
+		return 0
+	}
+
+	total := 0.0
+	for i := 1; i < len(state.waypoints); i++ {
+		total += GreatCircleDistance(state.waypoints[i-1], state.waypoints[i])
+	}
+	if total == 0 {
+		return 1
+	}
+
+	remaining := remainingDistance(truck, state)
+	if remaining > total {
+		remaining = total
+	}
+	return 1 - remaining/total
+}
+
+// remainingDistance is the distance from the truck's position through every waypoint it has yet to reach.
+func remainingDistance(truck *Truck, state *routeState) float64 {
+	if state == nil || state.legIndex >= len(state.waypoints) {
+		return 0
+	}
+	remaining := GreatCircleDistance(Point{Lat: truck.Lat, Lon: truck.Lon}, state.waypoints[state.legIndex])
+	for i := state.legIndex + 1; i < len(state.waypoints); i++ {
+		remaining += GreatCircleDistance(state.waypoints[i-1], state.waypoints[i])
+	}
+	return remaining
+}
+
+// updateETA refreshes the truck's estimated time to reach its final waypoint.
+func updateETA(truck *Truck, state *routeState) {
+	truck.ETASeconds = 0
+	if truck.Speed > 0 {
+		truck.ETASeconds = remainingDistance(truck, state) / truck.Speed
+	}
+}
+
+// TruckRoute is the route a truck is driving.
+type TruckRoute struct {
+	TruckID string
+	RouteID string
+	// Name is the catalog route the truck was given, if any.
+	Name      string
+	Waypoints []Point
+	// Annotations runs alongside Waypoints, or is nil when the route carries none.
+	Annotations []WaypointAnnotation
+	// Next is the index of the waypoint the truck is heading for.
+	Next int
+	Loop bool
+}
+
+// TruckRoute returns the route the truck is driving. It returns false when no such truck exists.
+func (m *Manager) TruckRoute(id string) (TruckRoute, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	truck, ok := m.trucks[id]
+	state := m.routes[id]
+	if !ok || state == nil {
+		return TruckRoute{}, false
+	}
+	route := TruckRoute{
+		TruckID:   id,
+		RouteID:   truck.RouteID,
+		Name:      state.routeName,
+		Waypoints: append([]Point(nil), state.waypoints...),
+		Next:      state.legIndex,
+		Loop:      state.loop,
+	}
+	if state.annotations != nil {
+		route.Annotations = append([]WaypointAnnotation(nil), state.annotations...)
+	}
+	return route, true
+}
+
+// RouteWaypoints returns each truck's route waypoints in the order of ids, with nil for unknown trucks. It
+// takes the lock once, for streams that need every visible truck's route on each push.
+func (m *Manager) RouteWaypoints(ids []string) [][]Point {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	routes := make([][]Point, len(ids))
+	for i, id := range ids {
+		if state := m.routes[id]; state != nil {
+			routes[i] = append([]Point(nil), state.waypoints...)
+		}
+	}
+	return routes
+}
@@ -0,0 +1,66 @@
+package simulation
+
+import (
+	"math/rand"
+	"time"
+
+	"orbit/backend/ids"
+)
+
+// RunInfo identifies one simulation run. A run starts when the fleet is built and ends when a config
+// change resets it, so records from different runs can be told apart downstream.
+type RunInfo struct {
+	ID string
+	// Number counts runs started by this manager, starting at 1.
+	Number int
+	// Seed is the seed the run was generated from. It differs from Config.Seed when seed rotation is on.
+	Seed      int64
+	StartedAt time.Time
+}
+
+// WithRunIDGenerator configures how run IDs are generated. Defaults to ULIDs.
+func (m *Manager) WithRunIDGenerator(gen ids.Generator) *Manager {
+	if gen != nil {
+		m.mu.Lock()
+		m.runIDs = gen
+		m.mu.Unlock()
+	}
+	return m
+}
+
+// Run returns the current run. It is the zero RunInfo until the fleet is first built.
+func (m *Manager) Run() RunInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.run
+}
+
+// RunID returns the current run ID, or "" before the fleet is first built.
+func (m *Manager) RunID() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.run.ID
+}
+
+// startRunLocked assigns a new run ID as the fleet is built from the run's seed.
+func (m *Manager) startRunLocked() {
+	m.run = RunInfo{
+		ID:        m.runIDs.NewID(),
+		Number:    m.run.Number + 1,
+		Seed:      m.runSeed,
+		StartedAt: m.clock,
+	}
+}
+
+// nextSeedLocked picks the seed for the run that follows a reset. With RotateSeed each run derives a fresh
+// seed from the previous one, so the sequence of runs is still reproducible from Config.Seed.
+func (m *Manager) nextSeedLocked(cfg Config) int64 {
+	if !cfg.RotateSeed || m.run.Number == 0 {
+		return cfg.Seed
+	}
+	seed := rand.New(rand.NewSource(m.runSeed)).Int63()
+	if seed == 0 {
+		seed = defaultSeed
+	}
+	return seed
+}
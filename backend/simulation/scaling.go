@@ -0,0 +1,172 @@
+package simulation
+
+import (
+	"fmt"
+	"sort"
+)
+
+// AddTrucks grows the fleet by n trucks without disturbing the trucks already on the road. New trucks get
+// fresh IDs, start at a start point or depot like the initial fleet, and draw their type from TypeMix. It
+// returns the new trucks.
+func (m *Manager) AddTrucks(n int) ([]Truck, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("truck count must be positive")
+	}
+
+	m.mu.Lock()
+	m.ensureTrucksLocked()
+	added := m.addTrucksLocked(n)
+	m.resizedLocked()
+	m.mu.Unlock()
+
+	m.flushEvents()
+	return added, nil
+}
+
+func (m *Manager) addTrucksLocked(n int) []Truck {
+	added := make([]Truck, 0, n)
+	for i := 0; i < n; i++ {
+		truck := m.buildTruck(m.truckSeq, m.pickTruckTypeLocked())
+		m.truckSeq++
+		m.trucks[truck.ID] = truck
+		m.assignShardLocked(truck)
+		m.emitTruckCreatedLocked(truck)
+		added = append(added, *truck)
+	}
+	fleetSize.Set(float64(len(m.trucks)))
+	return added
+}
+
+// RemoveTrucks takes trucks off the road. It removes the trucks with the given IDs, or when ids is empty
+// the n most recently added trucks, and returns the IDs it removed. Unknown IDs are ignored. At least one
+// truck must remain.
+func (m *Manager) RemoveTrucks(ids []string, n int) ([]string, error) {
+	if len(ids) == 0 && n <= 0 {
+		return nil, fmt.Errorf("truck IDs or a positive count are required")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(ids) == 0 {
+		ids = m.newestTruckIDsLocked(n)
+	}
+
+	unique := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := m.trucks[id]; ok {
+			unique[id] = struct{}{}
+		}
+	}
+	if len(unique) > 0 && len(unique) >= len(m.trucks) {
+		return nil, fmt.Errorf("cannot remove every truck")
+	}
+
+	removed := m.removeTrucksLocked(ids)
+	if len(removed) > 0 {
+		m.resizedLocked()
+	}
+	return removed, nil
+}
+
+// newestTruckIDsLocked returns the IDs of the n most recently added trucks.
+func (m *Manager) newestTruckIDsLocked(n int) []string {
+	trucks := m.sortedTrucksLocked()
+	if n > len(trucks) {
+		n = len(trucks)
+	}
+	ids := make([]string, 0, n)
+	for _, truck := range trucks[len(trucks)-n:] {
+		ids = append(ids, truck.ID)
+	}
+	return ids
+}
+
+func (m *Manager) removeTrucksLocked(ids []string) []string {
+	removed := make([]string, 0, len(ids))
+	gone := make(map[*Truck]struct{}, len(ids))
+	for _, id := range ids {
+		truck, ok := m.trucks[id]
+		if !ok {
+			continue
+		}
+		m.leaveDepotsLocked(id, m.routes[id])
+		delete(m.suspended, id)
+		m.endTimetableLocked(m.routes[id])
+		m.index.remove(truck)
+		delete(m.trucks, id)
+		delete(m.routes, id)
+		gone[truck] = struct{}{}
+		removed = append(removed, id)
+	}
+	if len(removed) == 0 {
+		return removed
+	}
+
+	for i, shard := range m.shards {
+		kept := make([]*Truck, 0, len(shard))
+		for _, truck := range shard {
+			if _, ok := gone[truck]; !ok {
+				kept = append(kept, truck)
+			}
+		}
+		m.shards[i] = kept
+	}
+	m.shardEpoch++
+	sort.Strings(removed)
+	fleetSize.Set(float64(len(m.trucks)))
+	suspendedTrucks.Set(float64(len(m.suspended)))
+	return removed
+}
+
+// pickTruckTypeLocked draws a type for a single new truck in proportion to TypeMix.
+func (m *Manager) pickTruckTypeLocked() TruckType {
+	var names []TruckType
+	for typ, weight := range m.cfg.TypeMix {
+		if weight > 0 {
+			names = append(names, typ)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	weights := make([]float64, len(names))
+	for i, typ := range names {
+		weights[i] = float64(m.cfg.TypeMix[typ])
+	}
+	return names[pickIndex(m.rand, indices(len(names)), weights)]
+}
+
+// assignShardLocked hands a new truck to the worker with the fewest trucks. Before Start the fleet is
+// sharded when the workers are created.
+func (m *Manager) assignShardLocked(truck *Truck) {
+	if len(m.shards) == 0 {
+		return
+	}
+	smallest := 0
+	for i, shard := range m.shards {
+		if len(shard) < len(m.shards[smallest]) {
+			smallest = i
+		}
+	}
+	shard := make([]*Truck, len(m.shards[smallest]), len(m.shards[smallest])+1)
+	copy(shard, m.shards[smallest])
+	m.shards[smallest] = append(shard, truck)
+	m.shardEpoch++
+}
+
+// resizedLocked keeps NumTrucks in line with the fleet after it is scaled and records the change.
+func (m *Manager) resizedLocked() {
+	m.cfg.NumTrucks = len(m.trucks)
+	m.recordConfigLocked(m.cfg, "scale", "")
+}
+
+func removeID(ids []string, id string) []string {
+	for i, existing := range ids {
+		if existing == id {
+			return append(ids[:i:i], ids[i+1:]...)
+		}
+	}
+	return ids
+}
@@ -4,17 +4,28 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"runtime"
 	"sort"
 	"sync"
 	"time"
+
+	"orbit/backend/events"
+	"orbit/backend/ids"
 )
 
 // TruckStatus represents the current lifecycle state of a truck in the simulation.
 type TruckStatus string
 
 const (
-	TruckStatusEnRoute TruckStatus = "enroute"
-	TruckStatusIdle    TruckStatus = "idle"
+	TruckStatusEnRoute     TruckStatus = "enroute"
+	TruckStatusIdle        TruckStatus = "idle"
+	TruckStatusLoading     TruckStatus = "loading"
+	TruckStatusUnloading   TruckStatus = "unloading"
+	TruckStatusRefueling   TruckStatus = "refueling"
+	TruckStatusMaintenance TruckStatus = "maintenance"
+	TruckStatusCharging    TruckStatus = "charging"
+	TruckStatusQueued      TruckStatus = "queued"
+	TruckStatusResting     TruckStatus = "resting"
 )
 
 // Truck describes the simulated vehicle state.
@@ -24,7 +35,53 @@ type Truck struct {
 	Lon          float64
 	Speed        float64
 	CurrentRoute string
+	// RouteID identifies the origin/destination route the truck was dispatched on and is shared by trucks
+	// assigned the same pair.
+	RouteID      string
 	Status       TruckStatus
+	SunElevation float64
+	Daylight     bool
+	// Heading is the direction of travel in degrees clockwise from north, kept while the truck is stopped.
+	Heading float64
+	// Odometer is the distance driven in metres since the simulation started and RouteDistance the distance
+	// driven on the current route.
+	Odometer      float64
+	RouteDistance float64
+	// Fuel is the remaining fuel in litres.
+	Fuel float64
+	// Electric trucks use BatterySOC, the battery state of charge from 0 to 1, instead of fuel.
+	Electric   bool
+	BatterySOC float64
+	// RemainingDriveSeconds is the driving time left before the driver must take a rest break. It is zero
+	// when hours of service are not simulated.
+	RemainingDriveSeconds float64
+	// ETASeconds estimates the time left to reach the route's final waypoint: the remaining great-circle
+	// distance through the outstanding waypoints at the truck's current speed, ignoring dwells and stops. It
+	// is recomputed on every update and is zero once the truck has arrived or when it has no speed.
+	ETASeconds float64
+	// UpdatedAt is the simulated time of the truck's most recent update. Compare it with the simulated clock
+	// to spot stale trucks or to interpolate between updates.
+	UpdatedAt time.Time
+	// ShipmentID is the cargo on board and CargoStatus its progress; both are empty when the truck is empty.
+	ShipmentID  string
+	CargoStatus ShipmentStatus
+	// Type is the truck's vehicle class; it is empty unless Config.TypeMix is set.
+	Type TruckType
+	// SpeedLimit is the truck's limit in metres per second, zero when unlimited. Governed trucks are held at
+	// the limit; the rest can exceed it, and each stretch over the limit counts as a violation.
+	SpeedLimit            float64
+	Governed              bool
+	SpeedViolations       int
+	OverLimitSeconds      float64
+	GovernorInterventions int
+	// SpeedZone names the speed zone the truck is in and ZoneSpeedLimit is that zone's limit in metres per
+	// second; both are empty outside every zone. Inside a zone Speed is capped at the limit.
+	SpeedZone      string
+	ZoneSpeedLimit float64
+	// Fleet is the carrier operating the truck. It is empty for the home fleet and set by HandoffTruck.
+	Fleet string
+	// Geohash is the GeohashPrecision-character geohash of the reported position, updated with it.
+	Geohash string
 }
 
 // Point represents a coordinate used for routing.
@@ -44,7 +101,88 @@ type Config struct {
 	WaypointsPerRoute int
 	RouteBounds       []BoundingBox
 	LoopRoutes        bool
-	UpdateInterval    time.Duration
+	// NamedRoutes is the route catalog, and RouteAssignments names the catalog routes new trucks drive:
+	// truck i gets RouteAssignments[i % len(RouteAssignments)], starting at its first waypoint. Names
+	// missing from the catalog fall back to generated routes.
+	NamedRoutes      []NamedRoute
+	RouteAssignments []string
+	UpdateInterval   time.Duration
+	// Regions update the trucks inside them at their own interval instead of UpdateInterval.
+	Regions []Region
+	// SpeedZones cap the speed of trucks inside them.
+	SpeedZones []SpeedZone
+	// Noise adds GPS-like error and dropouts to reported positions.
+	Noise NoiseModel
+	// ClockSkew offsets and drifts each truck's reported UpdatedAt.
+	ClockSkew ClockSkew
+	// EndWeights makes end point picks weighted: EndWeights[j] is the relative likelihood of EndPoints[j].
+	EndWeights []float64
+	// ODMatrix weights start-to-end pairs: ODMatrix[i][j] is the relative share of trucks starting at
+	// StartPoints[i] that head to EndPoints[j]. Start points are picked in proportion to their row totals.
+	// It is ignored unless it has a row per start point and a column per end point.
+	ODMatrix [][]float64
+	// TypeMix generates a mixed fleet: each type gets a share of NumTrucks proportional to its weight, so
+	// weights that sum to NumTrucks are exact counts. When NumTrucks is unset it defaults to the sum.
+	TypeMix map[TruckType]int
+	// TruckProfiles overrides DefaultTruckProfiles per type.
+	TruckProfiles map[TruckType]TruckProfile
+	// RotateSeed gives every run after the first a new seed derived from the previous run's seed, instead of
+	// replaying Config.Seed after each reset.
+	RotateSeed bool
+	// Workers is the number of goroutines that share truck updates. Defaults to GOMAXPROCS.
+	Workers int
+	// StartTime is the simulated clock at the start of a run. Defaults to the wall clock when the fleet is built.
+	StartTime time.Time
+	// TimeScale multiplies simulated time per tick, e.g. 10 runs the fleet at ten times real time.
+	TimeScale float64
+	// Dwell is how long trucks remain in each stationary status, in simulated time. Statuses without a
+	// positive duration are skipped. Refuelling lasts 15 minutes, and resting 10 hours when MaxDriveTime is
+	// set, unless Dwell says otherwise.
+	Dwell map[TruckStatus]time.Duration
+	// WaypointDwell is how long trucks pause as idle at each intermediate waypoint, in simulated time.
+	WaypointDwell time.Duration
+	// RouteWaypointDwell overrides WaypointDwell for trucks on the given RouteIDs.
+	RouteWaypointDwell map[string]time.Duration
+	// DockCapacity is the number of docks at each depot. Without configured Depots the start and end points
+	// act as depots. Loading and unloading occupy a dock for their dwell time and trucks that find every
+	// dock busy queue in arrival order. Zero means unlimited docks.
+	DockCapacity int
+	// Depots are named hubs. When set, trucks start at a depot, drive to an end point, and return to the
+	// nearest depot before their next dispatch instead of reshuffling waypoints.
+	Depots []Depot
+	// MaxDriveTime is the simulated driving time after which a driver must rest for the resting dwell.
+	// Zero disables hours-of-service rules.
+	MaxDriveTime time.Duration
+	// MaintenanceEvery sends a truck to maintenance after this many completed routes. Zero disables it.
+	MaintenanceEvery int
+	// TankCapacity is the fuel tank size in litres.
+	TankCapacity float64
+	// FuelPerKm is the consumption in litres per kilometre at cruising speed.
+	FuelPerKm float64
+	// RefuelThreshold is the tank fraction below which a truck stops to refuel.
+	RefuelThreshold float64
+	// ElectricShare is the fraction of the fleet generated as electric trucks.
+	ElectricShare float64
+	// ChargingStations are where electric trucks detour to recharge. Defaults to the start points.
+	ChargingStations []Point
+	// BatteryCapacity is the usable battery size in kWh.
+	BatteryCapacity float64
+	// EnergyPerKm is the consumption in kWh per kilometre.
+	EnergyPerKm float64
+	// ChargeRate is the peak charging power in kW.
+	ChargeRate float64
+	// LowBatteryThreshold is the state of charge below which a truck detours to a charging station.
+	LowBatteryThreshold float64
+	// SpeedLimit is the limit given to every truck in metres per second. Zero disables speed compliance.
+	SpeedLimit float64
+	// GovernedShare is the fraction of trucks fitted with a governor that holds them at SpeedLimit.
+	GovernedShare float64
+	// SuspendAfter stops processing trucks that will stay idle, parked, or in a stationary status for at
+	// least this long until they are due to move again. Zero disables suspension.
+	SuspendAfter time.Duration
+	// TrackDepth is how many recent reported positions each truck keeps for Track and Trails. Zero means
+	// DefaultTrackDepth.
+	TrackDepth int
 }
 
 const (
@@ -53,12 +191,90 @@ const (
 	defaultSpeedMin  = 10
 	defaultSpeedMax  = 25
 	defaultInterval  = time.Second
+	defaultTimeScale = 1
+
+	defaultRestPeriod   = 10 * time.Hour
+	defaultRefuelPeriod = 15 * time.Minute
+
+	defaultTankCapacity    = 400
+	defaultFuelPerKm       = 0.35
+	defaultRefuelThreshold = 0.15
+
+	defaultBatteryCapacity     = 300
+	defaultEnergyPerKm         = 1.2
+	defaultChargeRate          = 150
+	defaultLowBatteryThreshold = 0.2
 )
 
 type routeState struct {
 	waypoints []Point
-	legIndex  int
-	loop      bool
+	// annotations runs alongside waypoints when the route carries waypoint annotations, and is nil otherwise.
+	annotations []WaypointAnnotation
+	legIndex    int
+	loop        bool
+	// routeName is the catalog route the truck is driving, shown instead of a coordinate label.
+	routeName string
+	// terminal routes park the truck at the final waypoint instead of generating a new leg.
+	terminal  bool
+	parked    bool
+	holdUntil time.Time
+	// lastUpdate is the wall-clock time of the most recent update, used for data-quality metrics.
+	lastUpdate time.Time
+
+	dwellStatus     TruckStatus
+	dwellUntil      time.Time
+	pendingDwell    []TruckStatus
+	routesCompleted int
+	// routeStarted is when the current route began.
+	routeStarted time.Time
+	// zoneCapped is set while a speed zone holds the truck below cruiseSpeed, its speed outside the zone.
+	zoneCapped  bool
+	cruiseSpeed float64
+	// fix is the position last reported under Config.Noise; nil reports the true position.
+	fix *gpsFix
+	// driveTime is the simulated driving time since the driver's last rest break.
+	driveTime time.Duration
+	// trip is the journey in progress; tripsCompleted numbers the truck's trip records.
+	trip           *tripProgress
+	tripsCompleted int
+	// returning is set while a depot-based truck drives back to a depot.
+	returning bool
+	// docked is the key of the depot whose dock the truck holds.
+	docked string
+
+	// detouring is set while an electric truck heads to the charging stop inserted at chargeStop.
+	detouring  bool
+	chargeStop int
+	charging   bool
+
+	// drained is set once a draining truck has stopped at a waypoint; it is not saved.
+	drained bool
+
+	// handoff is the transfer to another fleet due when the truck reaches the end of its route.
+	handoff *pendingHandoff
+
+	// timetable is the timetabled departure the truck has taken, if any.
+	timetable *timetabledTrip
+
+	// trail holds the truck's recent reported positions; see Trails and Track.
+	trail trail
+
+	// overLimit is set while the truck is driving over its speed limit.
+	overLimit bool
+
+	// steppedAt is the simulated time the truck last advanced; step is how far its current advance spans.
+	steppedAt time.Time
+	step      time.Duration
+	// region is the index of the region the truck was in when it last advanced plus one, so the zero
+	// value is the default region.
+	region int
+
+	// suspended trucks are skipped every tick until wakeAt, or until an override when wakeAt is zero.
+	suspended bool
+	wakeAt    time.Time
+
+	// planToken identifies the planned route awaited from the plan queue, zero when none is.
+	planToken uint64
 }
 
 // ConfigUpdate captures partial updates that can be applied to a running simulation.
@@ -66,9 +282,27 @@ type ConfigUpdate struct {
 	NumTrucks      *int
 	UpdateInterval *time.Duration
 	BoundingBox    *BoundingBox
+	TimeScale      *float64
+	// RouteBounds replaces every route bounding box, taking precedence over BoundingBox. An empty, non-nil
+	// slice clears them so routes span the start and end points.
+	RouteBounds []BoundingBox
+	// SpeedZones replaces every speed zone; an empty, non-nil slice clears them.
+	SpeedZones []SpeedZone
+	// RouteAssignments replaces the catalog routes given to trucks built from then on; an empty, non-nil
+	// slice clears them.
+	RouteAssignments []string
+	// Reset restarts the simulation with the merged configuration instead of applying it in place.
+	Reset bool
 }
 
 func normalizeConfig(cfg Config) Config {
+	if cfg.NumTrucks <= 0 {
+		for _, weight := range cfg.TypeMix {
+			if weight > 0 {
+				cfg.NumTrucks += weight
+			}
+		}
+	}
 	if cfg.NumTrucks <= 0 {
 		cfg.NumTrucks = defaultNumTrucks
 	}
@@ -90,9 +324,59 @@ func normalizeConfig(cfg Config) Config {
 	if len(cfg.EndPoints) == 0 {
 		cfg.EndPoints = []Point{{Lat: 37.7749, Lon: -122.4194}}
 	}
+	cfg = normalizeDemand(cfg)
 	if cfg.UpdateInterval == 0 {
 		cfg.UpdateInterval = defaultInterval
 	}
+	if cfg.TrackDepth <= 0 {
+		cfg.TrackDepth = DefaultTrackDepth
+	}
+	cfg.Regions = normalizeRegions(cfg.Regions)
+	cfg.SpeedZones = normalizeSpeedZones(cfg.SpeedZones)
+	cfg.Noise = normalizeNoise(cfg.Noise)
+	cfg.ClockSkew = normalizeClockSkew(cfg.ClockSkew)
+	if cfg.TimeScale <= 0 {
+		cfg.TimeScale = defaultTimeScale
+	}
+	if cfg.TankCapacity <= 0 {
+		cfg.TankCapacity = defaultTankCapacity
+	}
+	if cfg.FuelPerKm <= 0 {
+		cfg.FuelPerKm = defaultFuelPerKm
+	}
+	if cfg.RefuelThreshold <= 0 || cfg.RefuelThreshold >= 1 {
+		cfg.RefuelThreshold = defaultRefuelThreshold
+	}
+	if cfg.BatteryCapacity <= 0 {
+		cfg.BatteryCapacity = defaultBatteryCapacity
+	}
+	if cfg.EnergyPerKm <= 0 {
+		cfg.EnergyPerKm = defaultEnergyPerKm
+	}
+	if cfg.ChargeRate <= 0 {
+		cfg.ChargeRate = defaultChargeRate
+	}
+	if cfg.LowBatteryThreshold <= 0 || cfg.LowBatteryThreshold >= chargeTarget {
+		cfg.LowBatteryThreshold = defaultLowBatteryThreshold
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.GOMAXPROCS(0)
+	}
+	defaults := map[TruckStatus]time.Duration{TruckStatusRefueling: defaultRefuelPeriod}
+	if cfg.MaxDriveTime > 0 {
+		defaults[TruckStatusResting] = defaultRestPeriod
+	}
+	for status, d := range defaults {
+		if _, ok := cfg.Dwell[status]; ok {
+			continue
+		}
+		dwell := make(map[TruckStatus]time.Duration, len(cfg.Dwell)+1)
+		for status, d := range cfg.Dwell {
+			dwell[status] = d
+		}
+		dwell[status] = d
+		cfg.Dwell = dwell
+	}
 
 	return cfg
 }
@@ -101,6 +385,49 @@ func cloneConfig(cfg Config) Config {
 	cfg.StartPoints = append([]Point{}, cfg.StartPoints...)
 	cfg.EndPoints = append([]Point{}, cfg.EndPoints...)
 	cfg.RouteBounds = append([]BoundingBox{}, cfg.RouteBounds...)
+	cfg.Regions = append([]Region(nil), cfg.Regions...)
+	cfg.NamedRoutes = cloneNamedRoutes(cfg.NamedRoutes)
+	cfg.RouteAssignments = append([]string(nil), cfg.RouteAssignments...)
+	cfg.SpeedZones = append([]SpeedZone(nil), cfg.SpeedZones...)
+	for i := range cfg.SpeedZones {
+		cfg.SpeedZones[i].Polygon = append([]Point(nil), cfg.SpeedZones[i].Polygon...)
+	}
+	cfg.ChargingStations = append([]Point{}, cfg.ChargingStations...)
+	cfg.Depots = append([]Depot{}, cfg.Depots...)
+	cfg.EndWeights = append([]float64(nil), cfg.EndWeights...)
+	if cfg.ODMatrix != nil {
+		matrix := make([][]float64, len(cfg.ODMatrix))
+		for i, row := range cfg.ODMatrix {
+			matrix[i] = append([]float64(nil), row...)
+		}
+		cfg.ODMatrix = matrix
+	}
+	dwell := make(map[TruckStatus]time.Duration, len(cfg.Dwell))
+	for status, d := range cfg.Dwell {
+		dwell[status] = d
+	}
+	cfg.Dwell = dwell
+	if cfg.TypeMix != nil {
+		mix := make(map[TruckType]int, len(cfg.TypeMix))
+		for typ, weight := range cfg.TypeMix {
+			mix[typ] = weight
+		}
+		cfg.TypeMix = mix
+	}
+	if cfg.TruckProfiles != nil {
+		profiles := make(map[TruckType]TruckProfile, len(cfg.TruckProfiles))
+		for typ, profile := range cfg.TruckProfiles {
+			profiles[typ] = profile
+		}
+		cfg.TruckProfiles = profiles
+	}
+	if cfg.RouteWaypointDwell != nil {
+		routeDwell := make(map[string]time.Duration, len(cfg.RouteWaypointDwell))
+		for routeID, d := range cfg.RouteWaypointDwell {
+			routeDwell[routeID] = d
+		}
+		cfg.RouteWaypointDwell = routeDwell
+	}
 	return cfg
 }
 
@@ -109,72 +436,192 @@ type Manager struct {
 	mu     sync.RWMutex
 	trucks map[string]*Truck
 	routes map[string]*routeState
+	depots map[string]*depotDocks
+	// index locates trucks by reported position for bounding-box and nearest queries.
+	index *spatialIndex
+	trips []Trip
+	// completions records routes driven to their final waypoint.
+	completions []RouteCompletion
+	// timetable records departures on timetabled routes; timetableNext is the next departure on each.
+	timetable     []TimetableRecord
+	timetableNext map[string]int
+	// truckSeq numbers truck IDs; IDs of removed trucks are not reused.
+	truckSeq int
+
+	shipments     map[string]*Shipment
+	shipmentOrder []string
+	shipmentSeq   int
+
+	history    []ConfigChange
+	historySeq int
+
+	// ticks counts ticks since the manager was created; recording and replay are keyed by it.
+	ticks     int64
+	recording []RecordedRun
+	replay    []RecordedRun
+	// playback describes the recording Play is stepping through, nil when none is playing.
+	playback *Playback
+	// stepping is set while step advances the fleet, which it does without holding the lock across ticks,
+	// so that nothing starts the ticker alongside it.
+	stepping bool
 
-	cfg      Config
-	initial  Config
-	rand     *rand.Rand
-	ticker   *time.Ticker
-	lastTick time.Time
+	cfg     Config
+	initial Config
+	rand    *rand.Rand
+	randSrc *countingSource
+	// noiseRand draws GPS noise. It is separate from rand so that noise does not change the simulation.
+	noiseRand *rand.Rand
+	runIDs    ids.Generator
+	run       RunInfo
+	runSeed   int64
+	ticker    *time.Ticker
+	lastTick  time.Time
+	clock     time.Time
+	// tickGenerated is the wall-clock time at which the current tick started, and generated the tick time
+	// of the most recent truck update. Streams compare generated with the time a write completes to
+	// measure how stale the data they deliver is.
+	tickGenerated time.Time
+	generated     time.Time
+	// maxGap is the largest per-truck update gap observed since the last tick.
+	maxGap time.Duration
 
 	ctx      context.Context
 	cancel   context.CancelFunc
 	baseCtx  context.Context
 	wg       sync.WaitGroup
 	tickSubs []chan time.Time
+	// shards holds the trucks each worker advances. Slices are replaced, never modified in place, so
+	// workers can range over a snapshot without holding the lock.
+	shards [][]*Truck
+	// shardEpoch moves on whenever a shard gains or loses a truck or a truck changes region, telling the
+	// workers to split their shards by region again.
+	shardEpoch uint64
+	// suspended holds trucks taken out of the shards by suspendIdleLocked.
+	suspended map[string]*Truck
+
+	// planner generates routes in place of random waypoints when set. planQueue plans them off the lock
+	// instead; planSeq numbers its requests.
+	planner   RoutePlanner
+	planQueue *PlanQueue
+	planSeq   uint64
+
+	// eventBus receives truck lifecycle events; pendingEvents holds those raised under the lock.
+	eventBus      *events.Bus
+	pendingEvents []events.Event
+
+	// supervision restarts the loop after a panic from snapshot, the latest saved state. restartCancel is
+	// set while a restart is pending and failure once restarts are used up.
+	supervision   SupervisionPolicy
+	snapshot      []byte
+	restarts      int
+	restartCancel context.CancelFunc
+	failure       error
 
 	started bool
+	paused  bool
+	// draining stops trucks from starting new legs; see Drain.
+	draining bool
 }
 
 // NewManager creates a manager with deterministic seeding and defaults.
 func NewManager(cfg Config) *Manager {
 	cfg = normalizeConfig(cfg)
 
+	rng, src := newRand(cfg.Seed)
 	return &Manager{
-		trucks:  make(map[string]*Truck, cfg.NumTrucks),
-		routes:  make(map[string]*routeState, cfg.NumTrucks),
-		cfg:     cfg,
-		initial: cfg,
-		rand:    rand.New(rand.NewSource(cfg.Seed)),
+		trucks: make(map[string]*Truck, cfg.NumTrucks),
+		index:  newSpatialIndex(),
+		routes: make(map[string]*routeState, cfg.NumTrucks),
+		depots: make(map[string]*depotDocks),
+
+		suspended: make(map[string]*Truck),
+
+		shipments:     make(map[string]*Shipment),
+		timetableNext: make(map[string]int),
+		cfg:           cfg,
+		initial:       cfg,
+		rand:          rng,
+		randSrc:       src,
+		runIDs:        ids.NewULIDGenerator(),
+		runSeed:       cfg.Seed,
 	}
 }
 
-// Start spins up goroutines per truck and begins ticking.
+// Start spreads trucks across worker shards and begins ticking.
 func (m *Manager) Start(ctx context.Context) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if m.started {
 		return fmt.Errorf("simulation already started")
 	}
-	m.started = true
+	if m.playback != nil {
+		return fmt.Errorf("a recording is playing")
+	}
+	if m.stepping {
+		return fmt.Errorf("a step is in progress")
+	}
 	if m.baseCtx == nil {
 		m.baseCtx = ctx
 	}
-	m.ctx, m.cancel = context.WithCancel(m.baseCtx)
-	m.ticker = time.NewTicker(m.cfg.UpdateInterval)
+	if m.draining {
+		m.draining = false
+		for _, state := range m.routes {
+			state.drained = false
+		}
+	}
+	m.startLocked(m.baseCtx)
+	return nil
+}
+
+func (m *Manager) startLocked(ctx context.Context) {
+	m.started = true
+	m.failure = nil
+	m.ctx, m.cancel = context.WithCancel(ctx)
+	m.ticker = time.NewTicker(m.baseInterval())
 	m.lastTick = time.Now()
-	m.tickSubs = make([]chan time.Time, 0, m.cfg.NumTrucks)
 
-	for i := 0; i < m.cfg.NumTrucks; i++ {
-		truck := m.buildTruck(i)
-		m.trucks[truck.ID] = truck
+	m.ensureTrucksLocked()
+	trucks := m.sortedTrucksLocked()
+
+	workers := m.cfg.Workers
+	if workers > len(trucks) {
+		workers = len(trucks)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	m.shards = make([][]*Truck, workers)
+	m.shardEpoch++
+	for i, truck := range trucks {
+		if _, ok := m.suspended[truck.ID]; ok {
+			continue
+		}
+		m.shards[i%workers] = append(m.shards[i%workers], truck)
 	}
 
-	for _, truck := range m.trucks {
+	m.tickSubs = make([]chan time.Time, 0, workers)
+	for i := range m.shards {
 		tickCh := make(chan time.Time, 1)
 		m.tickSubs = append(m.tickSubs, tickCh)
 		m.wg.Add(1)
-		go m.runTruck(truck, tickCh)
+		go m.runShard(i, tickCh)
 	}
 
 	m.wg.Add(1)
 	go m.runTicker()
 
-	return nil
+	if m.supervision.MaxRestarts > 0 {
+		m.wg.Add(1)
+		go m.runSnapshots(m.supervision.SnapshotInterval)
+	}
 }
 
 // Stop cancels the simulation and waits for goroutines to finish.
 func (m *Manager) Stop() {
 	m.mu.Lock()
+	if m.restartCancel != nil {
+		m.restartCancel()
+	}
 	if !m.started {
 		m.mu.Unlock()
 		return
@@ -193,6 +640,39 @@ func (m *Manager) Stop() {
 	m.wg.Wait()
 }
 
+// Pause freezes truck movement without tearing down routes, truck state, or the ticker.
+func (m *Manager) Pause() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.started {
+		return fmt.Errorf("simulation not started")
+	}
+	m.paused = true
+	return nil
+}
+
+// Resume continues a paused simulation from where it left off. It is refused while StepOnce is stepping
+// the fleet, since the ticker would advance the trucks alongside it.
+func (m *Manager) Resume() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.started {
+		return fmt.Errorf("simulation not started")
+	}
+	if m.stepping {
+		return fmt.Errorf("a step is in progress")
+	}
+	m.paused = false
+	return nil
+}
+
+// Paused returns whether truck movement is currently frozen.
+func (m *Manager) Paused() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.paused
+}
+
 // Config returns a copy of the current simulation configuration.
 func (m *Manager) Config() Config {
 	m.mu.RLock()
@@ -207,6 +687,10 @@ func (m *Manager) InitialConfig() Config {
 
 // ApplyConfig restarts the simulation using the provided configuration.
 func (m *Manager) ApplyConfig(cfg Config) error {
+	return m.applyConfig(cfg, "apply", "")
+}
+
+func (m *Manager) applyConfig(cfg Config, source, rollbackOf string) error {
 	m.mu.RLock()
 	baseCtx := m.baseCtx
 	started := m.started
@@ -226,13 +710,49 @@ func (m *Manager) ApplyConfig(cfg Config) error {
 	m.resetLocked(cfg)
 	m.mu.Unlock()
 
-	return m.Start(baseCtx)
+	if err := m.Start(baseCtx); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.recordConfigLocked(cfg, source, rollbackOf)
+	m.mu.Unlock()
+	return nil
 }
 
-// ApplyUpdate merges the provided updates into the current configuration and restarts the simulation.
+// ApplyUpdate merges the provided updates into the current configuration. Unless update.Reset is set, the
+// running fleet is kept: the ticker is reset in place, new bounds apply to routes generated from now on,
+// and NumTrucks adds or removes the newest trucks. Reset restarts the simulation from the seed.
 func (m *Manager) ApplyUpdate(update ConfigUpdate) (Config, error) {
-	cfg := m.Config()
+	if update.Reset {
+		cfg := mergeUpdate(m.Config(), update)
+		if err := m.applyConfig(cfg, "update", ""); err != nil {
+			return Config{}, err
+		}
+		return m.Config(), nil
+	}
 
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.started {
+		return Config{}, fmt.Errorf("simulation not started")
+	}
+
+	cfg := cloneConfig(normalizeConfig(mergeUpdate(cloneConfig(m.cfg), update)))
+	previous := m.baseInterval()
+	m.cfg = cfg
+	if interval := m.baseInterval(); interval != previous && m.ticker != nil {
+		m.ticker.Reset(interval)
+	}
+	if extra := cfg.NumTrucks - len(m.trucks); extra > 0 {
+		m.addTrucksLocked(extra)
+	} else if extra < 0 {
+		m.removeTrucksLocked(m.newestTruckIDsLocked(-extra))
+	}
+	m.recordConfigLocked(m.cfg, "update", "")
+	return cloneConfig(m.cfg), nil
+}
+
+func mergeUpdate(cfg Config, update ConfigUpdate) Config {
 	if update.NumTrucks != nil {
 		cfg.NumTrucks = *update.NumTrucks
 	}
@@ -242,21 +762,50 @@ func (m *Manager) ApplyUpdate(update ConfigUpdate) (Config, error) {
 	if update.BoundingBox != nil {
 		cfg.RouteBounds = []BoundingBox{*update.BoundingBox}
 	}
-
-	if err := m.ApplyConfig(cfg); err != nil {
-		return Config{}, err
+	if update.RouteBounds != nil {
+		cfg.RouteBounds = append([]BoundingBox{}, update.RouteBounds...)
+	}
+	if update.TimeScale != nil {
+		cfg.TimeScale = *update.TimeScale
+	}
+	if update.SpeedZones != nil {
+		cfg.SpeedZones = append([]SpeedZone{}, update.SpeedZones...)
+	}
+	if update.RouteAssignments != nil {
+		cfg.RouteAssignments = append([]string{}, update.RouteAssignments...)
 	}
-	return m.Config(), nil
+	return cfg
 }
 
 func (m *Manager) resetLocked(cfg Config) {
 	m.cfg = cfg
 	m.trucks = make(map[string]*Truck, cfg.NumTrucks)
 	m.routes = make(map[string]*routeState, cfg.NumTrucks)
-	m.rand = rand.New(rand.NewSource(cfg.Seed))
+	m.index = newSpatialIndex()
+	m.depots = make(map[string]*depotDocks)
+	m.suspended = make(map[string]*Truck)
+	suspendedTrucks.Set(0)
+	m.trips = nil
+	m.completions = nil
+	m.timetable = nil
+	m.timetableNext = make(map[string]int)
+	m.shipments = make(map[string]*Shipment)
+	m.shipmentOrder = nil
+	m.shipmentSeq = 0
+	depotQueueLength.Reset()
+	depotDocksBusy.Reset()
+	m.runSeed = m.nextSeedLocked(cfg)
+	m.rand, m.randSrc = newRand(m.runSeed)
+	m.noiseRand = nil
 	m.tickSubs = nil
+	m.shards = nil
 	m.ticker = nil
 	m.lastTick = time.Time{}
+	m.tickGenerated = time.Time{}
+	m.generated = time.Time{}
+	m.clock = time.Time{}
+	m.paused = false
+	m.draining = false
 }
 
 // Started returns whether the simulation is currently running.
@@ -272,8 +821,104 @@ func (m *Manager) Trucks() []Truck {
 	defer m.mu.RUnlock()
 	trucks := make([]Truck, 0, len(m.trucks))
 	for _, t := range m.trucks {
-		copy := *t
-		trucks = append(trucks, copy)
+		trucks = append(trucks, m.reportedLocked(t))
+	}
+	sort.Slice(trucks, func(i, j int) bool {
+		return trucks[i].ID < trucks[j].ID
+	})
+	return trucks
+}
+
+// StepOnce synchronously advances every truck by n ticks in ID order. Stepping is deterministic for a
+// given configuration and is only allowed while the ticker is not driving the simulation: before Start or
+// while paused. Start and Resume are refused until the step finishes. It is refused while Play drives the
+// fleet, and while paused when it would cross into the next run of a replayed recording, which needs the
+// simulation stopped to rebuild the fleet.
+func (m *Manager) StepOnce(n int) error {
+	m.mu.Lock()
+	playing := m.playback != nil
+	m.mu.Unlock()
+	if playing {
+		return fmt.Errorf("a recording is playing")
+	}
+	return m.step(n)
+}
+
+// step is StepOnce without the playback check, for Play itself.
+func (m *Manager) step(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("step count must be positive")
+	}
+
+	m.mu.Lock()
+	if m.started && !m.paused {
+		m.mu.Unlock()
+		return fmt.Errorf("simulation is running; pause it before stepping")
+	}
+	if m.stepping {
+		m.mu.Unlock()
+		return fmt.Errorf("a step is in progress")
+	}
+	if m.started && len(m.replay) > 0 && m.replay[0].Tick < m.ticks+int64(n) {
+		m.mu.Unlock()
+		return fmt.Errorf("stepping %d ticks would start the next recorded run at tick %d; stop the simulation first", n, m.replay[0].Tick)
+	}
+	m.stepping = true
+	m.ensureTrucksLocked()
+	trucks := m.sortedTrucksLocked()
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.stepping = false
+		m.mu.Unlock()
+	}()
+
+	for i := 0; i < n; i++ {
+		m.mu.Lock()
+		if m.replayDueLocked() {
+			trucks = m.sortedTrucksLocked()
+		}
+		m.mu.Unlock()
+		m.advanceClock()
+		for _, truck := range trucks {
+			m.advanceTruck(truck)
+		}
+	}
+	return nil
+}
+
+// ensureTrucksLocked builds the fleet if it has not been generated yet.
+func (m *Manager) ensureTrucksLocked() {
+	if len(m.trucks) > 0 {
+		return
+	}
+	m.clock = m.cfg.StartTime
+	if m.clock.IsZero() {
+		m.clock = time.Now()
+	}
+	m.startRunLocked()
+	m.recordRunLocked()
+	if m.historySeq == 0 {
+		m.recordConfigLocked(m.cfg, "initial", "")
+	}
+	types := m.truckTypesLocked()
+	for i := 0; i < m.cfg.NumTrucks; i++ {
+		var typ TruckType
+		if types != nil {
+			typ = types[i]
+		}
+		truck := m.buildTruck(i, typ)
+		m.trucks[truck.ID] = truck
+		m.emitTruckCreatedLocked(truck)
+	}
+	m.truckSeq = m.cfg.NumTrucks
+	fleetSize.Set(float64(len(m.trucks)))
+}
+
+func (m *Manager) sortedTrucksLocked() []*Truck {
+	trucks := make([]*Truck, 0, len(m.trucks))
+	for _, t := range m.trucks {
+		trucks = append(trucks, t)
 	}
 	sort.Slice(trucks, func(i, j int) bool {
 		return trucks[i].ID < trucks[j].ID
@@ -281,28 +926,66 @@ func (m *Manager) Trucks() []Truck {
 	return trucks
 }
 
-func (m *Manager) runTruck(truck *Truck, tickCh <-chan time.Time) {
+// Truck returns a snapshot copy of a single truck.
+func (m *Manager) Truck(id string) (Truck, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	t, ok := m.trucks[id]
+	if !ok {
+		return Truck{}, false
+	}
+	return m.reportedLocked(t), true
+}
+
+// RemainingRoute returns the truck's current position followed by the waypoints it has yet to reach.
+func (m *Manager) RemainingRoute(id string) ([]Point, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	t, ok := m.trucks[id]
+	if !ok {
+		return nil, false
+	}
+	route := []Point{{Lat: t.Lat, Lon: t.Lon}}
+	if state := m.routes[id]; state != nil && state.legIndex < len(state.waypoints) {
+		route = append(route, state.waypoints[state.legIndex:]...)
+	}
+	return route, true
+}
+
+func (m *Manager) runShard(index int, tickCh <-chan time.Time) {
 	defer m.wg.Done()
+	defer m.recoverLoop()
+	schedule := shardSchedule{lastTick: -1}
 	for {
 		select {
 		case <-m.ctx.Done():
 			return
 		case <-tickCh:
-			start := time.Now()
-			m.advanceTruck(truck)
-			updateDuration.Observe(time.Since(start).Seconds())
+			m.mu.RLock()
+			trucks := schedule.dueLocked(m, index)
+			m.mu.RUnlock()
+			for _, truck := range trucks {
+				start := time.Now()
+				m.advanceTruck(truck)
+				updateDuration.Observe(time.Since(start).Seconds())
+			}
 		}
 	}
 }
 
 func (m *Manager) runTicker() {
 	defer m.wg.Done()
+	defer m.recoverLoop()
 	for {
 		select {
 		case <-m.ctx.Done():
 			return
 		case t := <-m.ticker.C:
 			m.recordTickLatency(t)
+			if m.Paused() {
+				continue
+			}
+			m.advanceClock()
 			for _, ch := range m.tickSubs {
 				select {
 				case ch <- t:
@@ -314,16 +997,53 @@ func (m *Manager) runTicker() {
 }
 
 func (m *Manager) advanceTruck(truck *Truck) {
+	m.publishEvents(m.advanceTruckEvents(truck))
+}
+
+// advanceTruckEvents advances the truck under the lock and returns the events it raised. The deferred
+// unlock keeps the lock from being held forever if the update panics.
+func (m *Manager) advanceTruckEvents(truck *Truck) []events.Event {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	status := truck.Status
+	m.advanceTruckLocked(truck)
+	if state := m.routes[truck.ID]; state != nil {
+		m.rezoneLocked(truck, state)
+	}
+	m.emitStatusChangeLocked(truck, status)
+	return m.takeEventsLocked()
+}
 
+func (m *Manager) advanceTruckLocked(truck *Truck) {
 	state := m.routes[truck.ID]
-	if state == nil {
+	if state == nil || state.suspended {
+		return
+	}
+	if m.draining && (state.drained || truck.Status != TruckStatusEnRoute) {
+		state.drained = true
 		return
 	}
+	if !m.dueLocked(state) {
+		return
+	}
+	m.recordUpdateLocked(state, time.Now())
+	truck.UpdatedAt = m.clock
+	m.generated = m.tickGenerated
+	wasMoving := truck.Status == TruckStatusEnRoute
+	defer m.recordStopLocked(truck, state, wasMoving)
+	defer m.recordSpeedLocked(truck, state)
+	defer updateETA(truck, state)
+	defer m.recordPositionLocked(truck, state)
+	defer m.recordFixLocked(truck, state)
 
-	if len(state.waypoints) < 2 {
+	if len(state.waypoints) < 2 || state.parked || m.clock.Before(state.holdUntil) {
 		truck.Status = TruckStatusIdle
+		m.suspendIdleLocked(truck, state)
+		return
+	}
+
+	if m.dwellLocked(truck, state) || m.chargeLocked(truck, state) {
+		m.suspendIdleLocked(truck, state)
 		return
 	}
 
@@ -331,18 +1051,123 @@ func (m *Manager) advanceTruck(truck *Truck) {
 		state.legIndex = len(state.waypoints) - 1
 	}
 
+	m.governLocked(truck)
+	m.applySpeedZoneLocked(truck, state)
 	target := state.waypoints[state.legIndex]
 	current := Point{Lat: truck.Lat, Lon: truck.Lon}
-	next, reached := StepTowards(current, target, truck.Speed, m.cfg.UpdateInterval.Seconds())
+	next, reached := StepTowards(current, target, truck.Speed, state.step.Seconds())
 
 	truck.Lat = next.Lat
 	truck.Lon = next.Lon
+	if current != target {
+		truck.Heading = InitialBearing(current, target)
+	}
 	truck.CurrentRoute = state.label()
 	truck.Status = TruckStatusEnRoute
+	m.departTimetableLocked(state)
+	truck.SunElevation = SolarElevation(m.clock, next)
+	truck.Daylight = truck.SunElevation > civilHorizonDegrees
+	moved := GreatCircleDistance(current, next)
+	m.recordDistanceLocked(truck, moved)
+	m.recordMovementLocked(truck, state, current, moved)
+	if truck.Electric {
+		m.consumeBatteryLocked(truck, state, moved)
+	} else {
+		m.consumeFuelLocked(truck, state, moved)
+	}
+	m.accrueDriveTimeLocked(truck, state)
 
 	if reached {
-		state.advance(next, m.rand)
+		if m.draining {
+			state.drained = true
+		}
+		if m.arriveAtChargerLocked(truck, state) {
+			return
+		}
+		last := state.legIndex == len(state.waypoints)-1
+		first := state.loop && state.legIndex == 0
+		m.arriveTimetableLocked(state, last)
+		m.emitArrivalLocked(truck, state, next)
+		if last {
+			m.markArrivalLocked(state, next)
+			m.completeHandoffLocked(truck, state, next)
+		}
+		if state.terminal && last {
+			m.completeRouteLocked(truck, state, next)
+			m.endTimetableLocked(state)
+			state.parked = true
+			m.completeTripLocked(truck, state)
+			return
+		}
+		if !last && !(state.loop && state.legIndex == 0) {
+			m.holdAtWaypointLocked(truck, state)
+		}
+		m.queueArrivalDwellLocked(truck, state, next)
+		if len(m.cfg.Depots) > 0 && last && state.routeName == "" {
+			m.dispatchLocked(truck, state, next)
+		} else {
+			state.advance(next, m.rand)
+		}
+		if last {
+			truck.RouteDistance = 0
+			state.routeStarted = m.clock
+			m.endTimetableLocked(state)
+		}
+		if first {
+			m.takeDepartureLocked(truck, state)
+		}
 	}
+	m.dwellLocked(truck, state)
+}
+
+func (m *Manager) recordUpdateLocked(state *routeState, now time.Time) {
+	truckUpdates.Inc()
+	if !state.lastUpdate.IsZero() {
+		gap := now.Sub(state.lastUpdate)
+		truckUpdateGap.Observe(gap.Seconds())
+		if gap > m.maxGap {
+			m.maxGap = gap
+		}
+	}
+	state.lastUpdate = now
+}
+
+// recordDistanceLocked adds the distance driven this tick to the truck's odometers and the fleet total.
+func (m *Manager) recordDistanceLocked(truck *Truck, meters float64) {
+	truck.Odometer += meters
+	truck.RouteDistance += meters
+	fleetDistance.Add(meters)
+}
+
+// advanceClock moves the simulated clock forward by one tick.
+func (m *Manager) advanceClock() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clock = m.clock.Add(m.tickDuration())
+	m.ticks++
+	m.tickGenerated = time.Now()
+	m.wakeDueLocked()
+}
+
+// tickDuration is the simulated time that elapses per tick after applying the time scale.
+func (m *Manager) tickDuration() time.Duration {
+	return time.Duration(float64(m.baseInterval()) * m.cfg.TimeScale)
+}
+
+// Generated returns the wall-clock time at which the tick behind the most recent truck update started,
+// or the zero time before the first update. Read it before the trucks it describes so that a tick landing
+// in between does not make the snapshot look fresher than it is.
+func (m *Manager) Generated() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.generated
+}
+
+// SimulatedTime returns the current simulated clock.
+func (m *Manager) SimulatedTime() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.clock
 }
 
 func (m *Manager) recordTickLatency(now time.Time) {
@@ -357,68 +1182,163 @@ func (m *Manager) recordTickLatency(now time.Time) {
 	delta := now.Sub(m.lastTick)
 	m.lastTick = now
 	tickLatency.Observe(delta.Seconds())
+
+	truckUpdateMaxGap.Set(m.maxGap.Seconds())
+	m.maxGap = 0
+	m.recordFleetFuelLocked()
 }
 
-func (m *Manager) buildTruck(index int) *Truck {
+func (m *Manager) buildTruck(index int, typ TruckType) *Truck {
 	start := m.pickStartpoint()
-	end := m.pickEndpoint()
-	waypoints := m.buildRoute(start, end)
+	startLabel := pointLabel(start)
+	if len(m.cfg.Depots) > 0 {
+		home := m.cfg.Depots[m.rand.Intn(len(m.cfg.Depots))]
+		start, startLabel = home.Location, home.Name
+	}
+	end := m.pickEndpoint(typ, start)
+	waypoints := m.buildRoute(typ, start, end)
+	routeID := fmt.Sprintf("%s_to_%s", startLabel, pointLabel(end))
 	truck := &Truck{
 		ID:           fmt.Sprintf("truck-%04d", index+1),
 		Lat:          start.Lat,
 		Lon:          start.Lon,
-		Speed:        m.pickSpeed(),
-		CurrentRoute: fmt.Sprintf("%s_to_%s", pointLabel(start), pointLabel(end)),
+		Speed:        m.pickSpeed(typ),
+		Heading:      InitialBearing(start, waypoints[1]),
+		CurrentRoute: routeID,
+		RouteID:      routeID,
 		Status:       TruckStatusEnRoute,
+		SunElevation: SolarElevation(m.clock, start),
+		UpdatedAt:    m.clock,
 	}
+	if m.cfg.ElectricShare > 0 && m.rand.Float64() < m.cfg.ElectricShare {
+		truck.Electric = true
+		truck.BatterySOC = 1
+	} else {
+		truck.Fuel = m.cfg.TankCapacity
+	}
+	truck.Daylight = truck.SunElevation > civilHorizonDegrees
+	truck.Type = typ
+	truck.SpeedLimit = m.cfg.SpeedLimit
+	truck.Governed = m.cfg.SpeedLimit > 0 && m.cfg.GovernedShare > 0 && m.rand.Float64() < m.cfg.GovernedShare
+	truck.RemainingDriveSeconds = m.cfg.MaxDriveTime.Seconds()
 	m.routes[truck.ID] = &routeState{
-		waypoints: waypoints,
-		legIndex:  1,
-		loop:      m.cfg.LoopRoutes,
+		waypoints:    waypoints,
+		legIndex:     1,
+		loop:         m.cfg.LoopRoutes,
+		pendingDwell: []TruckStatus{TruckStatusLoading},
+		routeStarted: m.clock,
+		steppedAt:    m.clock,
+	}
+	if route, ok := m.assignedRouteLocked(index); ok {
+		state := m.routes[truck.ID]
+		state.waypoints = append([]Point(nil), route.Waypoints...)
+		state.annotations = routeAnnotations(route.Annotations, len(state.waypoints), 0)
+		state.legIndex = min(1, len(state.waypoints)-1)
+		state.loop = route.Loop
+		state.terminal = !route.Loop
+		start := state.waypoints[0]
+		truck.Lat, truck.Lon = start.Lat, start.Lon
+		if target := state.waypoints[state.legIndex]; target != start {
+			truck.Heading = InitialBearing(start, target)
+		}
+		truck.SunElevation = SolarElevation(m.clock, start)
+		truck.Daylight = truck.SunElevation > civilHorizonDegrees
+		m.nameRouteLocked(truck, state, route.Name)
+		m.takeDepartureLocked(truck, state)
+	} else {
+		m.requestPlanLocked(truck.ID, m.routes[truck.ID])
 	}
+	updateETA(truck, m.routes[truck.ID])
+	m.recordPositionLocked(truck, nil)
+	m.routes[truck.ID].region = m.regionLocked(truck) + 1
 	return truck
 }
 
-func (m *Manager) pickSpeed() float64 {
-	delta := m.cfg.SpeedMax - m.cfg.SpeedMin
-	return m.cfg.SpeedMin + m.rand.Float64()*delta
+func (m *Manager) pickSpeed(typ TruckType) float64 {
+	profile := m.profileLocked(typ)
+	delta := profile.SpeedMax - profile.SpeedMin
+	return profile.SpeedMin + m.rand.Float64()*delta
 }
 
 func (m *Manager) pickStartpoint() Point {
-	return m.cfg.StartPoints[m.rand.Intn(len(m.cfg.StartPoints))]
+	return m.cfg.StartPoints[pickIndex(m.rand, indices(len(m.cfg.StartPoints)), m.startWeightsLocked())]
 }
 
-func (m *Manager) pickEndpoint() Point {
-	return m.cfg.EndPoints[m.rand.Intn(len(m.cfg.EndPoints))]
+// pickEndpoint picks a random end point, weighted by the origin's demand, and limited to those within the
+// type's MaxRouteDistance of from when any are in reach.
+func (m *Manager) pickEndpoint(typ TruckType, from Point) Point {
+	weights := m.endWeightsLocked(from)
+	if limit := m.profileLocked(typ).MaxRouteDistance; limit > 0 {
+		var reachable []int
+		for i, p := range m.cfg.EndPoints {
+			if GreatCircleDistance(from, p) <= limit && (weights == nil || weights[i] > 0) {
+				reachable = append(reachable, i)
+			}
+		}
+		if len(reachable) > 0 {
+			return m.cfg.EndPoints[pickIndex(m.rand, reachable, weights)]
+		}
+	}
+	return m.cfg.EndPoints[pickIndex(m.rand, indices(len(m.cfg.EndPoints)), weights)]
+}
+
+func indices(n int) []int {
+	out := make([]int, n)
+	for i := range out {
+		out[i] = i
+	}
+	return out
 }
 
 func pointLabel(p Point) string {
 	return fmt.Sprintf("%.3f,%.3f", p.Lat, p.Lon)
 }
 
-func (m *Manager) buildRoute(start, end Point) []Point {
-	waypoints := []Point{start}
-	if m.cfg.WaypointsPerRoute > 2 {
-		bounds := m.defaultBounds()
-		if len(m.cfg.RouteBounds) > 0 {
-			bounds = m.cfg.RouteBounds[m.rand.Intn(len(m.cfg.RouteBounds))]
-		}
-		intermediate := RandomRouteWithinBounds(m.rand, bounds, m.cfg.WaypointsPerRoute-2)
-		waypoints = append(waypoints, intermediate...)
+// buildRoute generates a route from start to end. With a plan queue it returns a provisional straight line,
+// for which the caller requests the planned route with requestPlanLocked once the truck is on it.
+func (m *Manager) buildRoute(typ TruckType, start, end Point) []Point {
+	if m.planQueue != nil {
+		return []Point{start, end}
+	}
+	if planned, ok := m.plannedRouteLocked(start, end); ok {
+		return planned
 	}
-	return append(waypoints, end)
+	waypoints, _ := m.randomPlannerLocked(typ).Plan(start, end)
+	return waypoints
 }
 
 func (m *Manager) defaultBounds() BoundingBox {
 	allPoints := append([]Point{}, m.cfg.StartPoints...)
 	allPoints = append(allPoints, m.cfg.EndPoints...)
+	for _, d := range m.cfg.Depots {
+		allPoints = append(allPoints, d.Location)
+	}
 	if len(allPoints) == 0 {
 		return BoundingBox{MinLat: -90, MaxLat: 90, MinLon: -180, MaxLon: 180}
 	}
 	return BoundingBoxFromPoints(allPoints)
 }
 
+// Bounds returns the area the simulation operates in: the extent of the start and end points and depots,
+// widened to cover every route bounding box.
+func (m *Manager) Bounds() BoundingBox {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	bounds := m.defaultBounds()
+	for _, b := range m.cfg.RouteBounds {
+		bounds.MinLat = min(bounds.MinLat, b.MinLat)
+		bounds.MaxLat = max(bounds.MaxLat, b.MaxLat)
+		bounds.MinLon = min(bounds.MinLon, b.MinLon)
+		bounds.MaxLon = max(bounds.MaxLon, b.MaxLon)
+	}
+	return bounds
+}
+
 func (r *routeState) label() string {
+	if r.routeName != "" {
+		return r.routeName
+	}
 	if len(r.waypoints) == 0 {
 		return ""
 	}
@@ -452,6 +1372,7 @@ func (r *routeState) advance(current Point, rng *rand.Rand) {
 		rest[i], rest[j] = rest[j], rest[i]
 	})
 	r.waypoints = append([]Point{current}, rest...)
+	r.annotations = nil
 	if len(r.waypoints) > 1 {
 		r.legIndex = 1
 	} else {
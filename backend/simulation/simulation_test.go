@@ -1,10 +1,23 @@
 package simulation
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"math"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"orbit/backend/events"
+	"orbit/backend/ids"
 )
 
 func TestGreatCircleDistanceAndBearing(t *testing.T) {
@@ -21,6 +34,27 @@ func TestGreatCircleDistanceAndBearing(t *testing.T) {
 	}
 }
 
+func TestSolarElevationDayNight(t *testing.T) {
+	equator := Point{Lat: 0, Lon: 0}
+	noon := time.Date(2024, time.March, 20, 12, 0, 0, 0, time.UTC)
+	midnight := time.Date(2024, time.March, 20, 0, 0, 0, 0, time.UTC)
+
+	if elevation := SolarElevation(noon, equator); elevation < 80 {
+		t.Fatalf("expected sun near zenith at equinox noon, got %.2f", elevation)
+	}
+	if elevation := SolarElevation(midnight, equator); elevation > -80 {
+		t.Fatalf("expected sun near nadir at midnight, got %.2f", elevation)
+	}
+	if !IsDaylight(noon, equator) || IsDaylight(midnight, equator) {
+		t.Fatalf("unexpected daylight flags")
+	}
+
+	// 12:00 UTC is early morning darkness on the US west coast.
+	if IsDaylight(noon, Point{Lat: 37.77, Lon: -122.42}) {
+		t.Fatalf("expected night in San Francisco at 12:00 UTC")
+	}
+}
+
 func TestStepTowardsConverges(t *testing.T) {
 	start := Point{Lat: 47.0, Lon: -122.0}
 	end := Point{Lat: 47.0, Lon: -122.001}
@@ -195,3 +229,2616 @@ func TestRouteLoopsAndAdvancesWaypoints(t *testing.T) {
 		time.Sleep(50 * time.Millisecond)
 	}
 }
+
+func BenchmarkTrucksSnapshot(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("trucks=%d", n), func(b *testing.B) {
+			manager := NewManager(Config{NumTrucks: n, Seed: 1, UpdateInterval: time.Hour})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if err := manager.Start(ctx); err != nil {
+				b.Fatalf("start failed: %v", err)
+			}
+			defer manager.Stop()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = manager.Trucks()
+			}
+		})
+	}
+}
+
+func TestStepOnceIsDeterministic(t *testing.T) {
+	cfg := Config{
+		NumTrucks:         10,
+		Seed:              11,
+		WaypointsPerRoute: 4,
+		UpdateInterval:    time.Minute,
+		StartTime:         time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	manager1 := NewManager(cfg)
+	manager2 := NewManager(cfg)
+	if err := manager1.StepOnce(50); err != nil {
+		t.Fatalf("step manager1: %v", err)
+	}
+	if err := manager2.StepOnce(50); err != nil {
+		t.Fatalf("step manager2: %v", err)
+	}
+
+	snap1 := manager1.Trucks()
+	snap2 := manager2.Trucks()
+	if len(snap1) != cfg.NumTrucks {
+		t.Fatalf("expected %d trucks, got %d", cfg.NumTrucks, len(snap1))
+	}
+	for i := range snap1 {
+		if snap1[i] != snap2[i] {
+			t.Fatalf("step mode diverged for %s: %+v vs %+v", snap1[i].ID, snap1[i], snap2[i])
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := manager1.Start(ctx); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	defer manager1.Stop()
+	if err := manager1.StepOnce(1); err == nil {
+		t.Fatalf("expected stepping a running simulation to fail")
+	}
+}
+
+func TestPauseFreezesMovementUntilResume(t *testing.T) {
+	cfg := Config{
+		NumTrucks:      2,
+		Seed:           3,
+		SpeedMin:       5,
+		SpeedMax:       5,
+		UpdateInterval: 10 * time.Millisecond,
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 1, Lon: 1}},
+	}
+
+	manager := NewManager(cfg)
+	if err := manager.Pause(); err == nil {
+		t.Fatalf("expected pausing a stopped simulation to fail")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := manager.Start(ctx); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	defer manager.Stop()
+
+	if err := manager.Pause(); err != nil {
+		t.Fatalf("pause failed: %v", err)
+	}
+	// Allow any tick already in flight to drain before sampling.
+	time.Sleep(3 * cfg.UpdateInterval)
+	frozen := manager.Trucks()
+	time.Sleep(5 * cfg.UpdateInterval)
+	for i, truck := range manager.Trucks() {
+		if truck.Lat != frozen[i].Lat || truck.Lon != frozen[i].Lon {
+			t.Fatalf("expected trucks to stay put while paused")
+		}
+	}
+
+	if err := manager.Resume(); err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	time.Sleep(5 * cfg.UpdateInterval)
+	moved := false
+	for i, truck := range manager.Trucks() {
+		if truck.Lat != frozen[i].Lat || truck.Lon != frozen[i].Lon {
+			moved = true
+		}
+	}
+	if !moved {
+		t.Fatalf("expected trucks to move after resume")
+	}
+}
+
+func TestFleetCommands(t *testing.T) {
+	cfg := Config{
+		NumTrucks:      4,
+		Seed:           8,
+		SpeedMin:       20,
+		SpeedMax:       30,
+		UpdateInterval: time.Second,
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 0, Lon: 1}},
+	}
+
+	t.Run("speed cap with filter", func(t *testing.T) {
+		manager := NewManager(cfg)
+		if err := manager.StepOnce(1); err != nil {
+			t.Fatalf("step failed: %v", err)
+		}
+		affected, err := manager.ApplyFleetCommand(FleetCommand{Type: FleetCommandSpeedCap, SpeedCap: 5, TruckIDs: []string{"truck-0001", "truck-0002"}})
+		if err != nil || affected != 2 {
+			t.Fatalf("unexpected result: affected %d err %v", affected, err)
+		}
+		for _, truck := range manager.Trucks() {
+			capped := truck.ID == "truck-0001" || truck.ID == "truck-0002"
+			if capped != (truck.Speed == 5) {
+				t.Fatalf("unexpected speed for %s: %.2f", truck.ID, truck.Speed)
+			}
+		}
+	})
+
+	t.Run("hold then release", func(t *testing.T) {
+		manager := NewManager(cfg)
+		if err := manager.StepOnce(1); err != nil {
+			t.Fatalf("step failed: %v", err)
+		}
+		if _, err := manager.ApplyFleetCommand(FleetCommand{Type: FleetCommandHold, HoldFor: 3 * time.Second}); err != nil {
+			t.Fatalf("hold failed: %v", err)
+		}
+		before := manager.Trucks()
+		_ = manager.StepOnce(2)
+		held := manager.Trucks()
+		for i := range held {
+			if held[i].Lat != before[i].Lat || held[i].Lon != before[i].Lon || held[i].Status != TruckStatusIdle {
+				t.Fatalf("expected %s to hold position", held[i].ID)
+			}
+		}
+		_ = manager.StepOnce(2)
+		if manager.Trucks()[0].Lon == before[0].Lon {
+			t.Fatalf("expected trucks to move after hold expires")
+		}
+	})
+
+	t.Run("return to depot parks trucks", func(t *testing.T) {
+		manager := NewManager(cfg)
+		if err := manager.StepOnce(100); err != nil {
+			t.Fatalf("step failed: %v", err)
+		}
+		if _, err := manager.ApplyFleetCommand(FleetCommand{Type: FleetCommandReturnToDepot}); err != nil {
+			t.Fatalf("return failed: %v", err)
+		}
+		_ = manager.StepOnce(500)
+		for _, truck := range manager.Trucks() {
+			if GreatCircleDistance(Point{Lat: truck.Lat, Lon: truck.Lon}, cfg.StartPoints[0]) > 1 || truck.Status != TruckStatusIdle {
+				t.Fatalf("expected %s parked at depot, got %+v", truck.ID, truck)
+			}
+		}
+	})
+
+	t.Run("rejects unknown command", func(t *testing.T) {
+		manager := NewManager(cfg)
+		if _, err := manager.ApplyFleetCommand(FleetCommand{Type: "teleport"}); err == nil {
+			t.Fatalf("expected error for unknown command")
+		}
+	})
+}
+
+func TestTimeScaleMultipliesDistancePerTick(t *testing.T) {
+	base := Config{
+		NumTrucks:      1,
+		Seed:           4,
+		SpeedMin:       10,
+		SpeedMax:       10,
+		UpdateInterval: time.Second,
+		StartTime:      time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 10, Lon: 0}},
+	}
+	scaled := base
+	scaled.TimeScale = 10
+
+	realTime := NewManager(base)
+	accelerated := NewManager(scaled)
+	_ = realTime.StepOnce(1)
+	_ = accelerated.StepOnce(1)
+
+	origin := base.StartPoints[0]
+	slow := realTime.Trucks()[0]
+	fast := accelerated.Trucks()[0]
+	slowDistance := GreatCircleDistance(origin, Point{Lat: slow.Lat, Lon: slow.Lon})
+	fastDistance := GreatCircleDistance(origin, Point{Lat: fast.Lat, Lon: fast.Lon})
+	if math.Abs(fastDistance-10*slowDistance) > 0.5 {
+		t.Fatalf("expected 10x distance, got %.2f vs %.2f", fastDistance, slowDistance)
+	}
+	if got := accelerated.SimulatedTime().Sub(scaled.StartTime); got != 10*time.Second {
+		t.Fatalf("expected simulated clock to advance 10s, got %s", got)
+	}
+}
+
+func TestLifecycleDwellAtRouteEndpoints(t *testing.T) {
+	cfg := Config{
+		NumTrucks:      1,
+		Seed:           2,
+		SpeedMin:       100,
+		SpeedMax:       100,
+		UpdateInterval: time.Second,
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 0, Lon: 0.005}},
+		Dwell: map[TruckStatus]time.Duration{
+			TruckStatusLoading:     3 * time.Second,
+			TruckStatusUnloading:   2 * time.Second,
+			TruckStatusMaintenance: 4 * time.Second,
+		},
+		MaintenanceEvery: 1,
+	}
+
+	manager := NewManager(cfg)
+	var statuses []TruckStatus
+	for i := 0; i < 20; i++ {
+		if err := manager.StepOnce(1); err != nil {
+			t.Fatalf("step failed: %v", err)
+		}
+		status := manager.Trucks()[0].Status
+		if len(statuses) == 0 || statuses[len(statuses)-1] != status {
+			statuses = append(statuses, status)
+		}
+	}
+
+	want := []TruckStatus{TruckStatusLoading, TruckStatusEnRoute, TruckStatusUnloading, TruckStatusMaintenance, TruckStatusLoading, TruckStatusEnRoute}
+	if len(statuses) < len(want) {
+		t.Fatalf("expected transitions %v, got %v", want, statuses)
+	}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Fatalf("expected transitions %v, got %v", want, statuses)
+		}
+	}
+}
+
+func TestFuelDepletesAndTriggersRefuel(t *testing.T) {
+	cfg := Config{
+		NumTrucks:       1,
+		Seed:            6,
+		SpeedMin:        20,
+		SpeedMax:        20,
+		UpdateInterval:  10 * time.Second,
+		StartPoints:     []Point{{Lat: 0, Lon: 0}},
+		EndPoints:       []Point{{Lat: 0, Lon: 5}},
+		TankCapacity:    1,
+		FuelPerKm:       1,
+		RefuelThreshold: 0.5,
+		Dwell:           map[TruckStatus]time.Duration{TruckStatusRefueling: 30 * time.Second},
+	}
+
+	manager := NewManager(cfg)
+	_ = manager.StepOnce(1)
+	if fuel := manager.Trucks()[0].Fuel; fuel >= cfg.TankCapacity || fuel <= 0 {
+		t.Fatalf("expected fuel to deplete after driving, got %.3f", fuel)
+	}
+
+	refueled := false
+	for i := 0; i < 10; i++ {
+		_ = manager.StepOnce(1)
+		truck := manager.Trucks()[0]
+		if truck.Status == TruckStatusRefueling {
+			refueled = true
+		}
+		if refueled && truck.Status == TruckStatusEnRoute {
+			if truck.Fuel < cfg.TankCapacity*cfg.RefuelThreshold {
+				t.Fatalf("expected a refilled tank after refuelling, got %.3f", truck.Fuel)
+			}
+			return
+		}
+	}
+	t.Fatalf("expected truck to stop for fuel and continue")
+}
+
+func TestRefuellingTakesTimeByDefault(t *testing.T) {
+	cfg := Config{
+		NumTrucks:       1,
+		Seed:            6,
+		SpeedMin:        20,
+		SpeedMax:        20,
+		UpdateInterval:  time.Minute,
+		StartPoints:     []Point{{Lat: 0, Lon: 0}},
+		EndPoints:       []Point{{Lat: 0, Lon: 5}},
+		TankCapacity:    10,
+		FuelPerKm:       1,
+		RefuelThreshold: 0.5,
+	}
+
+	manager := NewManager(cfg)
+	if d := manager.Config().Dwell[TruckStatusRefueling]; d != defaultRefuelPeriod {
+		t.Fatalf("expected refuelling to default to %s, got %s", defaultRefuelPeriod, d)
+	}
+	refuelling := 0
+	for i := 0; i < 40; i++ {
+		_ = manager.StepOnce(1)
+		truck := manager.Trucks()[0]
+		if truck.Status == TruckStatusRefueling {
+			refuelling++
+		} else if refuelling > 0 {
+			if truck.Fuel < cfg.TankCapacity*cfg.RefuelThreshold {
+				t.Fatalf("expected a refilled tank after refuelling, got %.3f", truck.Fuel)
+			}
+			break
+		}
+	}
+	// Fifteen minutes at a minute a tick.
+	if refuelling != 15 {
+		t.Fatalf("expected the truck to be seen refuelling for 15 ticks, got %d", refuelling)
+	}
+}
+
+func TestElectricTruckDetoursToChargingStation(t *testing.T) {
+	station := Point{Lat: 0.01, Lon: 0.02}
+	cfg := Config{
+		NumTrucks:           1,
+		Seed:                9,
+		SpeedMin:            20,
+		SpeedMax:            20,
+		UpdateInterval:      10 * time.Second,
+		StartPoints:         []Point{{Lat: 0, Lon: 0}},
+		EndPoints:           []Point{{Lat: 0, Lon: 1}},
+		ElectricShare:       1,
+		ChargingStations:    []Point{station},
+		BatteryCapacity:     2,
+		EnergyPerKm:         1,
+		ChargeRate:          100,
+		LowBatteryThreshold: 0.5,
+	}
+
+	manager := NewManager(cfg)
+	_ = manager.StepOnce(1)
+	if truck := manager.Trucks()[0]; !truck.Electric || truck.Fuel != 0 || truck.BatterySOC >= 1 {
+		t.Fatalf("expected a discharging electric truck, got %+v", truck)
+	}
+
+	charged := false
+	for i := 0; i < 200; i++ {
+		_ = manager.StepOnce(1)
+		truck := manager.Trucks()[0]
+		if truck.Status == TruckStatusCharging {
+			if d := GreatCircleDistance(Point{Lat: truck.Lat, Lon: truck.Lon}, station); d > 1 {
+				t.Fatalf("expected to charge at the station, %.1fm away", d)
+			}
+			charged = true
+		}
+		if charged && truck.Status == TruckStatusEnRoute {
+			if truck.BatterySOC < cfg.LowBatteryThreshold {
+				t.Fatalf("expected battery recharged, got %.2f", truck.BatterySOC)
+			}
+			route, _ := manager.RemainingRoute(truck.ID)
+			if route[len(route)-1] != cfg.EndPoints[0] {
+				t.Fatalf("expected original destination after charging, got %v", route)
+			}
+			return
+		}
+	}
+	t.Fatalf("expected truck to detour, charge, and resume")
+}
+
+func TestDepotDocksServeQueuedTrucksInOrder(t *testing.T) {
+	cfg := Config{
+		NumTrucks:      3,
+		Seed:           3,
+		SpeedMin:       1,
+		SpeedMax:       2,
+		UpdateInterval: time.Second,
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 0, Lon: 1}},
+		Dwell:          map[TruckStatus]time.Duration{TruckStatusLoading: 3 * time.Second},
+		DockCapacity:   1,
+	}
+
+	manager := NewManager(cfg)
+	served := map[string]int{}
+	for tick := 1; tick <= 9; tick++ {
+		if err := manager.StepOnce(1); err != nil {
+			t.Fatalf("step failed: %v", err)
+		}
+		loading := 0
+		for _, truck := range manager.Trucks() {
+			if truck.Status == TruckStatusLoading {
+				loading++
+				if _, ok := served[truck.ID]; !ok {
+					served[truck.ID] = tick
+				}
+			}
+		}
+		if loading > 1 {
+			t.Fatalf("tick %d: %d trucks loading with a single dock", tick, loading)
+		}
+	}
+
+	if served["truck-0001"] >= served["truck-0002"] || served["truck-0002"] >= served["truck-0003"] {
+		t.Fatalf("expected trucks served in queue order, got %v", served)
+	}
+	if served["truck-0003"] == 0 {
+		t.Fatalf("expected every truck to reach the dock, got %v", served)
+	}
+}
+
+func TestReroutingAQueuedTruckFreesItsPlaceInTheQueue(t *testing.T) {
+	cfg := Config{
+		NumTrucks:      3,
+		Seed:           3,
+		SpeedMin:       1,
+		SpeedMax:       2,
+		UpdateInterval: time.Second,
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 0, Lon: 1}},
+		Dwell:          map[TruckStatus]time.Duration{TruckStatusLoading: 3 * time.Second},
+		DockCapacity:   1,
+	}
+
+	manager := NewManager(cfg)
+	if err := manager.StepOnce(1); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	if _, ok, err := manager.AssignRoute("truck-0002", []Point{{Lat: 0.5, Lon: 0.5}}, false); !ok || err != nil {
+		t.Fatalf("assign route: %v, %v", ok, err)
+	}
+
+	for tick := 0; tick < 10; tick++ {
+		if err := manager.StepOnce(1); err != nil {
+			t.Fatalf("step failed: %v", err)
+		}
+		if truck, _ := manager.Truck("truck-0003"); truck.Status == TruckStatusLoading {
+			return
+		}
+	}
+	t.Fatalf("expected truck-0003 to reach the dock once truck-0002 left the queue")
+}
+
+func TestDriversRestAfterMaxDriveTime(t *testing.T) {
+	cfg := Config{
+		NumTrucks:      1,
+		Seed:           4,
+		SpeedMin:       1,
+		SpeedMax:       2,
+		UpdateInterval: time.Minute,
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 0, Lon: 1}},
+		MaxDriveTime:   5 * time.Minute,
+		Dwell:          map[TruckStatus]time.Duration{TruckStatusResting: 3 * time.Minute},
+	}
+
+	manager := NewManager(cfg)
+	var statuses []TruckStatus
+	for i := 0; i < 12; i++ {
+		if err := manager.StepOnce(1); err != nil {
+			t.Fatalf("step failed: %v", err)
+		}
+		truck := manager.Trucks()[0]
+		if i == 0 && truck.RemainingDriveSeconds != 240 {
+			t.Fatalf("expected 4 minutes of driving left after the first tick, got %.0fs", truck.RemainingDriveSeconds)
+		}
+		statuses = append(statuses, truck.Status)
+	}
+
+	driving, resting := 0, 0
+	for _, status := range statuses[:8] {
+		switch status {
+		case TruckStatusEnRoute:
+			driving++
+		case TruckStatusResting:
+			resting++
+		}
+	}
+	if driving != 5 || resting != 3 {
+		t.Fatalf("expected 5 ticks driving then 3 resting, got %v", statuses)
+	}
+	if statuses[8] != TruckStatusEnRoute {
+		t.Fatalf("expected driving to resume after the rest break, got %v", statuses)
+	}
+}
+
+func TestTripsRecordedFromDepartureToEndOfDwell(t *testing.T) {
+	cfg := Config{
+		NumTrucks:      1,
+		Seed:           8,
+		SpeedMin:       100,
+		SpeedMax:       101,
+		UpdateInterval: time.Second,
+		StartTime:      time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 0, Lon: 0.005}},
+		Dwell:          map[TruckStatus]time.Duration{TruckStatusUnloading: 2 * time.Second},
+	}
+
+	manager := NewManager(cfg)
+	if err := manager.StepOnce(12); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+
+	trips := manager.Trips("truck-0001")
+	if len(trips) == 0 {
+		t.Fatalf("expected a completed trip")
+	}
+	trip := trips[0]
+	leg := GreatCircleDistance(cfg.StartPoints[0], cfg.EndPoints[0])
+	if math.Abs(trip.DistanceMeters-leg) > 1 {
+		t.Fatalf("expected trip distance %.1fm, got %.1fm", leg, trip.DistanceMeters)
+	}
+	if trip.Stops != 0 || trip.AverageSpeed <= 0 {
+		t.Fatalf("unexpected trip summary: %+v", trip)
+	}
+	// Six seconds of driving plus two seconds unloading.
+	if got := trip.End.Sub(trip.Start); got != 8*time.Second {
+		t.Fatalf("expected an 8s trip, got %s (%+v)", got, trip)
+	}
+	if len(manager.Trips("truck-9999")) != 0 {
+		t.Fatalf("expected no trips for an unknown truck")
+	}
+}
+
+func TestRouteCompletionsRecorded(t *testing.T) {
+	cfg := Config{
+		NumTrucks:      1,
+		Seed:           8,
+		SpeedMin:       100,
+		SpeedMax:       101,
+		UpdateInterval: time.Second,
+		StartTime:      time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 0, Lon: 0.005}},
+		Dwell:          map[TruckStatus]time.Duration{TruckStatusUnloading: 2 * time.Second},
+	}
+
+	manager := NewManager(cfg)
+	if err := manager.StepOnce(12); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+
+	completions := manager.RouteCompletions("", "truck-0001")
+	if len(completions) != 1 {
+		t.Fatalf("expected one completion, got %+v", completions)
+	}
+	c := completions[0]
+	leg := GreatCircleDistance(cfg.StartPoints[0], cfg.EndPoints[0])
+	if math.Abs(c.DistanceMeters-leg) > 1 {
+		t.Fatalf("expected completion distance %.1fm, got %.1fm", leg, c.DistanceMeters)
+	}
+	if !c.StartedAt.Equal(cfg.StartTime) || c.Elapsed != c.CompletedAt.Sub(c.StartedAt) || c.Elapsed <= 0 {
+		t.Fatalf("unexpected completion timing: %+v", c)
+	}
+	if len(manager.RouteCompletions("no-such-route", "")) != 0 {
+		t.Fatalf("expected no completions for an unknown route")
+	}
+}
+
+func TestETACountsDownOnEachTick(t *testing.T) {
+	cfg := Config{
+		NumTrucks:      1,
+		Seed:           8,
+		SpeedMin:       100,
+		SpeedMax:       101,
+		UpdateInterval: time.Second,
+		StartTime:      time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 0, Lon: 0.005}},
+		Dwell:          map[TruckStatus]time.Duration{TruckStatusLoading: time.Nanosecond},
+	}
+
+	manager := NewManager(cfg)
+	if err := manager.StepOnce(2); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	truck := manager.Trucks()[0]
+	leg := GreatCircleDistance(cfg.StartPoints[0], cfg.EndPoints[0])
+	want := (leg - GreatCircleDistance(cfg.StartPoints[0], Point{Lat: truck.Lat, Lon: truck.Lon})) / truck.Speed
+	if truck.ETASeconds <= 0 || math.Abs(truck.ETASeconds-want) > 0.01 {
+		t.Fatalf("expected an ETA of %.2fs, got %.2fs", want, truck.ETASeconds)
+	}
+
+	if err := manager.StepOnce(1); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	if got := manager.Trucks()[0].ETASeconds; math.Abs(truck.ETASeconds-got-1) > 0.01 {
+		t.Fatalf("expected the ETA to drop by a second, from %.2fs to %.2fs", truck.ETASeconds, got)
+	}
+}
+
+func TestSpeedZoneCapsAndRestoresSpeed(t *testing.T) {
+	cfg := Config{
+		NumTrucks:      1,
+		Seed:           8,
+		SpeedMin:       100,
+		SpeedMax:       101,
+		UpdateInterval: time.Second,
+		StartTime:      time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 0, Lon: 0.05}},
+		Dwell:          map[TruckStatus]time.Duration{TruckStatusLoading: time.Nanosecond},
+		SpeedZones: []SpeedZone{{
+			Name:    "yard",
+			Polygon: []Point{{Lat: -0.001, Lon: -0.001}, {Lat: 0.001, Lon: -0.001}, {Lat: 0.001, Lon: 0.00002}, {Lat: -0.001, Lon: 0.00002}},
+			Limit:   1,
+		}},
+	}
+
+	manager := NewManager(cfg)
+	if err := manager.StepOnce(2); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	truck := manager.Trucks()[0]
+	if truck.Speed != 1 || truck.SpeedZone != "yard" || truck.ZoneSpeedLimit != 1 {
+		t.Fatalf("expected the truck capped at 1 m/s in the yard, got %+v", truck)
+	}
+	if moved := GreatCircleDistance(cfg.StartPoints[0], Point{Lat: truck.Lat, Lon: truck.Lon}); math.Abs(moved-1) > 0.01 {
+		t.Fatalf("expected the truck to move 1m, moved %.2fm", moved)
+	}
+
+	if err := manager.StepOnce(5); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	truck = manager.Trucks()[0]
+	if truck.Speed < 100 || truck.SpeedZone != "" || truck.ZoneSpeedLimit != 0 {
+		t.Fatalf("expected the cruising speed back outside the yard, got %+v", truck)
+	}
+}
+
+func TestDepotTrucksReturnBetweenDispatches(t *testing.T) {
+	cfg := Config{
+		NumTrucks:      4,
+		Seed:           9,
+		SpeedMin:       100,
+		SpeedMax:       101,
+		UpdateInterval: time.Second,
+		EndPoints:      []Point{{Lat: 0, Lon: 0.005}},
+		Depots: []Depot{
+			{Name: "north", Location: Point{Lat: 0.002, Lon: 0}, Capacity: 1},
+			{Name: "south", Location: Point{Lat: -0.002, Lon: 0}, Capacity: 1},
+		},
+		Dwell: map[TruckStatus]time.Duration{TruckStatusLoading: 5 * time.Second},
+	}
+
+	manager := NewManager(cfg)
+	if err := manager.StepOnce(1); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+
+	docked := 0
+	for _, depot := range manager.Depots() {
+		docked += depot.Docked
+		if depot.Capacity != 1 {
+			t.Fatalf("unexpected capacity for %s: %d", depot.Name, depot.Capacity)
+		}
+	}
+	if docked != cfg.NumTrucks {
+		t.Fatalf("expected every truck docked at a depot at start, got %d", docked)
+	}
+
+	returned := map[string]bool{}
+	for i := 0; i < 120; i++ {
+		if err := manager.StepOnce(1); err != nil {
+			t.Fatalf("step failed: %v", err)
+		}
+		for _, truck := range manager.Trucks() {
+			if strings.HasSuffix(truck.RouteID, "_to_north") || strings.HasSuffix(truck.RouteID, "_to_south") {
+				returned[truck.ID] = true
+			}
+		}
+	}
+	if len(returned) != cfg.NumTrucks {
+		t.Fatalf("expected every truck to head back to a depot, got %v", returned)
+	}
+}
+
+func TestHandoffRebrandsTruckAtExchange(t *testing.T) {
+	bus := events.NewBus()
+	sub := bus.Subscribe("test", events.SubscribeOptions{QueueSize: 1000})
+	defer sub.Close()
+
+	manager := NewManager(Config{
+		NumTrucks:      2,
+		Seed:           4,
+		SpeedMin:       100,
+		SpeedMax:       101,
+		UpdateInterval: time.Second,
+		EndPoints:      []Point{{Lat: 0.01, Lon: 0.01}},
+		Depots:         []Depot{{Name: "yard", Location: Point{Lat: 0, Lon: 0}}, {Name: "interline", Location: Point{Lat: 0, Lon: 0.005}}},
+	}).WithEventBus(bus)
+	if err := manager.StepOnce(1); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+
+	if _, _, err := manager.HandoffTruck("truck-0001", Handoff{Fleet: "acme", Exchange: "nowhere"}); err == nil {
+		t.Fatal("expected an error for an unknown exchange")
+	}
+	if _, _, err := manager.HandoffTruck("truck-0001", Handoff{Fleet: "acme", IDPrefix: "truck", Exchange: "interline"}); err == nil {
+		t.Fatal("expected an error for a handoff that keeps the ID")
+	}
+	if _, ok, _ := manager.HandoffTruck("truck-9999", Handoff{Fleet: "acme", Exchange: "interline"}); ok {
+		t.Fatal("expected an unknown truck to be reported")
+	}
+	if _, _, err := manager.HandoffTruck("truck-0001", Handoff{Fleet: "acme", Exchange: "interline"}); err != nil {
+		t.Fatalf("handoff failed: %v", err)
+	}
+	if _, _, err := manager.HandoffTruck("truck-0002", Handoff{Fleet: "acme", IDPrefix: "acme", Exchange: "interline"}); err != nil {
+		t.Fatalf("handoff of a second truck failed: %v", err)
+	}
+	if err := manager.StepOnce(30); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+
+	if _, ok := manager.Truck("truck-0001"); ok {
+		t.Fatal("expected the old ID to be gone after the handoff")
+	}
+	truck, ok := manager.Truck("acme-0001")
+	if !ok || truck.Fleet != "acme" {
+		t.Fatalf("expected acme-0001 in fleet acme, got %+v (%v)", truck, ok)
+	}
+	if _, ok := manager.TruckRoute("acme-0001"); !ok {
+		t.Fatal("expected the route state to follow the new ID")
+	}
+
+	handoffs := make(map[string]events.TruckHandedOff)
+	for len(sub.C()) > 0 {
+		if p, ok := (<-sub.C()).Payload.(events.TruckHandedOff); ok {
+			handoffs[p.PreviousTruckID] = p
+		}
+	}
+	if len(handoffs) != 2 {
+		t.Fatalf("expected two handoff events, got %+v", handoffs)
+	}
+	h := handoffs["truck-0001"]
+	if h.TruckID != "acme-0001" || h.Fleet != "acme" || h.Exchange != "interline" || h.PreviousFleet != "" {
+		t.Fatalf("unexpected handoff event: %+v", h)
+	}
+	if GreatCircleDistance(Point{Lat: h.Lat, Lon: h.Lon}, Point{Lat: 0, Lon: 0.005}) > 1 {
+		t.Fatalf("expected the handoff at the exchange, got %+v", h)
+	}
+	if _, _, err := manager.HandoffTruck("acme-0001", Handoff{Fleet: "acme", Exchange: "interline"}); err == nil {
+		t.Fatal("expected an error for a handoff to the truck's own fleet")
+	}
+}
+
+func TestGeohashFollowsReportedPosition(t *testing.T) {
+	if got := EncodeGeohash(Point{Lat: 57.64911, Lon: 10.40744}, 11); got != "u4pruydqqvj" {
+		t.Fatalf("expected u4pruydqqvj, got %s", got)
+	}
+	if got := EncodeGeohash(Point{Lat: 42.6, Lon: -5.6}, 5); got != "ezs42" {
+		t.Fatalf("expected ezs42, got %s", got)
+	}
+
+	manager := NewManager(Config{NumTrucks: 3, Seed: 5, UpdateInterval: time.Second, Noise: NoiseModel{SigmaMeters: 20}})
+	if err := manager.StepOnce(5); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	for _, truck := range manager.Trucks() {
+		if want := EncodeGeohash(Point{Lat: truck.Lat, Lon: truck.Lon}, GeohashPrecision); truck.Geohash != want {
+			t.Fatalf("expected %s to report geohash %s, got %s", truck.ID, want, truck.Geohash)
+		}
+	}
+}
+
+func TestTrucksInBoxMatchesScan(t *testing.T) {
+	manager := NewManager(Config{
+		NumTrucks:      200,
+		Seed:           6,
+		SpeedMin:       200,
+		SpeedMax:       300,
+		UpdateInterval: time.Second,
+		RouteBounds:    []BoundingBox{{MinLat: 40, MaxLat: 41, MinLon: -75, MaxLon: -73}},
+		Noise:          NoiseModel{SigmaMeters: 50},
+	})
+	if err := manager.StepOnce(20); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	if _, err := manager.RemoveTrucks([]string{"truck-0001", "truck-0002"}, 0); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	if _, _, err := manager.TeleportTruck("truck-0003", Point{Lat: 40.5, Lon: -74}, false); err != nil {
+		t.Fatalf("teleport failed: %v", err)
+	}
+
+	for _, box := range []BoundingBox{
+		{MinLat: 40.2, MaxLat: 40.6, MinLon: -74.5, MaxLon: -73.9},
+		{MinLat: 40.49, MaxLat: 40.51, MinLon: -74.01, MaxLon: -73.99},
+		{MinLat: -90, MaxLat: 90, MinLon: -180, MaxLon: 180},
+	} {
+		var want []string
+		for _, truck := range manager.Trucks() {
+			if box.Contains(Point{Lat: truck.Lat, Lon: truck.Lon}) {
+				want = append(want, truck.ID)
+			}
+		}
+		var got []string
+		for _, truck := range manager.TrucksInBox(box) {
+			got = append(got, truck.ID)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("box %+v: expected %v, got %v", box, want, got)
+		}
+		if len(got) == 0 {
+			t.Fatalf("box %+v: expected some trucks", box)
+		}
+	}
+}
+
+func TestNearestTrucksMatchesScan(t *testing.T) {
+	manager := NewManager(Config{
+		NumTrucks:      300,
+		Seed:           7,
+		SpeedMin:       200,
+		SpeedMax:       300,
+		UpdateInterval: time.Second,
+		RouteBounds:    []BoundingBox{{MinLat: 40, MaxLat: 42, MinLon: -75, MaxLon: -72}},
+	})
+	if err := manager.StepOnce(10); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+
+	for _, p := range []Point{{Lat: 41, Lon: -73.5}, {Lat: 40.01, Lon: -74.99}, {Lat: 10, Lon: 10}} {
+		trucks := manager.Trucks()
+		sort.Slice(trucks, func(i, j int) bool {
+			di := GreatCircleDistance(p, Point{Lat: trucks[i].Lat, Lon: trucks[i].Lon})
+			dj := GreatCircleDistance(p, Point{Lat: trucks[j].Lat, Lon: trucks[j].Lon})
+			return di < dj
+		})
+		nearest := manager.NearestTrucks(p, 7)
+		if len(nearest) != 7 {
+			t.Fatalf("expected 7 trucks near %v, got %d", p, len(nearest))
+		}
+		for i, got := range nearest {
+			if got.ID != trucks[i].ID {
+				t.Fatalf("near %v: expected %s at rank %d, got %s", p, trucks[i].ID, i, got.ID)
+			}
+			if want := GreatCircleDistance(p, Point{Lat: got.Lat, Lon: got.Lon}); math.Abs(got.DistanceMeters-want) > 1e-6 {
+				t.Fatalf("expected distance %f, got %f", want, got.DistanceMeters)
+			}
+		}
+	}
+	if got := manager.NearestTrucks(Point{}, 1000); len(got) != 300 {
+		t.Fatalf("expected k beyond the fleet to return every truck, got %d", len(got))
+	}
+}
+
+func TestShipmentsMoveFromPickupToDelivered(t *testing.T) {
+	cfg := Config{
+		NumTrucks:      1,
+		Seed:           10,
+		SpeedMin:       100,
+		SpeedMax:       101,
+		UpdateInterval: time.Second,
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 0, Lon: 0.005}},
+		Dwell: map[TruckStatus]time.Duration{
+			TruckStatusLoading:   2 * time.Second,
+			TruckStatusUnloading: 2 * time.Second,
+		},
+	}
+
+	manager := NewManager(cfg)
+	var cargo []ShipmentStatus
+	for i := 0; i < 14; i++ {
+		if err := manager.StepOnce(1); err != nil {
+			t.Fatalf("step failed: %v", err)
+		}
+		status := manager.Trucks()[0].CargoStatus
+		if len(cargo) == 0 || cargo[len(cargo)-1] != status {
+			cargo = append(cargo, status)
+		}
+	}
+	want := []ShipmentStatus{ShipmentStatusPickup, ShipmentStatusInTransit, ShipmentStatusPickup}
+	if len(cargo) < len(want) {
+		t.Fatalf("expected cargo transitions %v, got %v", want, cargo)
+	}
+	for i := range want {
+		if cargo[i] != want[i] {
+			t.Fatalf("expected cargo transitions %v, got %v", want, cargo)
+		}
+	}
+
+	delivered := manager.Shipments("", ShipmentStatusDelivered)
+	if len(delivered) != 1 {
+		t.Fatalf("expected one delivered shipment, got %+v", manager.Shipments("", ""))
+	}
+	shipment := delivered[0]
+	if shipment.Destination != cfg.EndPoints[0] || !shipment.DeliveredAt.After(shipment.PickedUpAt) {
+		t.Fatalf("unexpected delivered shipment: %+v", shipment)
+	}
+}
+
+func TestHeadingFollowsTravelDirection(t *testing.T) {
+	cfg := Config{
+		NumTrucks:      1,
+		Seed:           12,
+		SpeedMin:       10,
+		SpeedMax:       11,
+		UpdateInterval: time.Second,
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 0, Lon: 1}},
+	}
+
+	manager := NewManager(cfg)
+	if err := manager.StepOnce(3); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	if heading := manager.Trucks()[0].Heading; math.Abs(heading-90) > 0.5 {
+		t.Fatalf("expected an eastbound heading near 90, got %.2f", heading)
+	}
+}
+
+func TestOdometerAccumulatesAndRouteDistanceResets(t *testing.T) {
+	cfg := Config{
+		NumTrucks:      1,
+		Seed:           13,
+		SpeedMin:       100,
+		SpeedMax:       101,
+		UpdateInterval: time.Second,
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 0, Lon: 0.005}},
+		LoopRoutes:     true,
+	}
+
+	manager := NewManager(cfg)
+	leg := GreatCircleDistance(cfg.StartPoints[0], cfg.EndPoints[0])
+	if err := manager.StepOnce(3); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	truck := manager.Trucks()[0]
+	if truck.Odometer <= 0 || truck.Odometer != truck.RouteDistance {
+		t.Fatalf("expected odometers to match on the first route, got %+v", truck)
+	}
+
+	if err := manager.StepOnce(6); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	truck = manager.Trucks()[0]
+	if truck.Odometer <= leg {
+		t.Fatalf("expected odometer past the first leg (%.0fm), got %.0fm", leg, truck.Odometer)
+	}
+	if truck.RouteDistance >= truck.Odometer {
+		t.Fatalf("expected route distance to reset after completing a route, got %+v", truck)
+	}
+}
+
+func TestUpdatedAtTracksSimulatedClock(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	manager := NewManager(Config{NumTrucks: 2, Seed: 14, UpdateInterval: time.Second, StartTime: start})
+	if err := manager.StepOnce(5); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	for _, truck := range manager.Trucks() {
+		if !truck.UpdatedAt.Equal(manager.SimulatedTime()) || !truck.UpdatedAt.Equal(start.Add(5*time.Second)) {
+			t.Fatalf("expected %s updated at the simulated clock, got %s", truck.ID, truck.UpdatedAt)
+		}
+	}
+}
+
+func TestTrucksPauseAtIntermediateWaypoints(t *testing.T) {
+	cfg := Config{
+		NumTrucks:         1,
+		Seed:              3,
+		SpeedMin:          100,
+		SpeedMax:          101,
+		WaypointsPerRoute: 3,
+		RouteBounds:       []BoundingBox{{MinLat: 0, MaxLat: 0.001, MinLon: 0, MaxLon: 0.01}},
+		UpdateInterval:    time.Second,
+		StartTime:         time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		StartPoints:       []Point{{Lat: 0, Lon: 0}},
+		EndPoints:         []Point{{Lat: 0, Lon: 0.01}},
+		WaypointDwell:     30 * time.Second,
+	}
+
+	idleTicks := func(cfg Config) int {
+		manager := NewManager(cfg)
+		idle := 0
+		for i := 0; i < 120; i++ {
+			if err := manager.StepOnce(1); err != nil {
+				t.Fatalf("step failed: %v", err)
+			}
+			truck := manager.Trucks()[0]
+			if truck.Status == TruckStatusIdle {
+				idle++
+			} else if idle > 0 {
+				if truck.Status != TruckStatusEnRoute {
+					t.Fatalf("expected the truck to continue its route after the pause, got %s", truck.Status)
+				}
+				break
+			}
+		}
+		return idle
+	}
+
+	if idle := idleTicks(cfg); idle != 30 {
+		t.Fatalf("expected a 30 tick pause at the waypoint, got %d", idle)
+	}
+
+	probe := NewManager(cfg)
+	if err := probe.StepOnce(1); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	cfg.RouteWaypointDwell = map[string]time.Duration{probe.Trucks()[0].RouteID: 0}
+	if idle := idleTicks(cfg); idle != 0 {
+		t.Fatalf("expected the route override to skip the pause, got %d idle ticks", idle)
+	}
+}
+
+func TestRunsGetNewIDsAndRotatedSeeds(t *testing.T) {
+	cfg := Config{
+		NumTrucks:      2,
+		Seed:           7,
+		UpdateInterval: time.Hour,
+		RotateSeed:     true,
+	}
+	manager := NewManager(cfg).WithRunIDGenerator(ids.NewSequenceGenerator("run-"))
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer manager.Stop()
+
+	first := manager.Run()
+	if first.ID == "" || first.Number != 1 || first.Seed != 7 {
+		t.Fatalf("unexpected first run %+v", first)
+	}
+
+	if err := manager.ApplyConfig(cfg); err != nil {
+		t.Fatalf("apply config: %v", err)
+	}
+	second := manager.Run()
+	if second.ID == first.ID || second.Number != 2 {
+		t.Fatalf("expected a new run, got %+v after %+v", second, first)
+	}
+	if want := rand.New(rand.NewSource(7)).Int63(); second.Seed != want {
+		t.Fatalf("expected rotated seed %d, got %d", want, second.Seed)
+	}
+
+	cfg.RotateSeed = false
+	if err := manager.ApplyConfig(cfg); err != nil {
+		t.Fatalf("apply config: %v", err)
+	}
+	if third := manager.Run(); third.Seed != 7 {
+		t.Fatalf("expected the configured seed without rotation, got %d", third.Seed)
+	}
+}
+
+func TestPlayRemapsTheRecordingOntoTheWallClock(t *testing.T) {
+	cfg := Config{
+		NumTrucks:      10,
+		Seed:           5,
+		UpdateInterval: time.Second,
+		StartTime:      time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+	original := NewManager(cfg)
+	if err := original.StepOnce(60); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	rec := original.Recording()
+
+	played := NewManager(Config{})
+	began := time.Now()
+	if err := played.Play(context.Background(), rec, ReplayTiming{Duration: 150 * time.Millisecond, Frame: 10 * time.Millisecond}); err != nil {
+		t.Fatalf("play: %v", err)
+	}
+	if took := time.Since(began); took < 140*time.Millisecond || took > 2*time.Second {
+		t.Fatalf("expected a minute of ticks to play in about 150ms, took %s", took)
+	}
+	if !reflect.DeepEqual(original.Trucks(), played.Trucks()) {
+		t.Fatalf("played trucks differ from the original run")
+	}
+	if _, ok := played.Playback(); ok {
+		t.Fatalf("expected no playback once the recording ended")
+	}
+
+	looping := NewManager(Config{})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- looping.Play(ctx, rec, ReplayTiming{Speed: 1200, Loop: true, Frame: 5 * time.Millisecond})
+	}()
+	for {
+		if p, ok := looping.Playback(); ok && p.Loops >= 2 {
+			if p.Ticks != 60 || p.Speed != 1200 || !p.Loop {
+				t.Fatalf("unexpected playback %+v", p)
+			}
+			break
+		}
+		select {
+		case err := <-done:
+			t.Fatalf("expected playback to loop until cancelled, got %v", err)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("play: %v", err)
+	}
+
+	// A second run with ticks twice as long takes half as many ticks for the same simulated time.
+	slow := cfg
+	slow.UpdateInterval = 2 * time.Second
+	timeline := newReplayTimeline(Recording{Runs: []RecordedRun{{Config: cfg}, {Tick: 10, Config: slow}}, Ticks: 20})
+	if span := timeline.span(); span != 30*time.Second {
+		t.Fatalf("expected 30s of simulated time, got %s", span)
+	}
+	for offset, want := range map[time.Duration]int64{5 * time.Second: 5, 14 * time.Second: 12, 30 * time.Second: 20, time.Hour: 20} {
+		if got := timeline.tickAt(offset); got != want {
+			t.Fatalf("expected tick %d at %s, got %d", want, offset, got)
+		}
+	}
+	if err := NewManager(Config{}).Play(context.Background(), Recording{Runs: rec.Runs}, ReplayTiming{}); err == nil {
+		t.Fatalf("expected a recording without ticks to be refused")
+	}
+}
+
+func TestPausedStepsStayClearOfTheRunningTicker(t *testing.T) {
+	cfg := Config{NumTrucks: 3, Seed: 11, UpdateInterval: time.Hour, StartTime: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	second := cfg
+	second.Seed = 12
+	manager := NewManager(Config{})
+	if err := manager.Replay(Recording{Runs: []RecordedRun{{Config: cfg}, {Tick: 2, Config: second}}}); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer manager.Stop()
+	if err := manager.Pause(); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+
+	if err := manager.StepOnce(4); err == nil {
+		t.Fatalf("expected a paused step across the next recorded run to be refused")
+	}
+	if err := manager.StepOnce(2); err != nil {
+		t.Fatalf("expected steps up to the next recorded run to be allowed: %v", err)
+	}
+	if !manager.Paused() || manager.Run().Number != 1 {
+		t.Fatalf("expected the simulation to stay paused in the first run, got paused=%v run %+v", manager.Paused(), manager.Run())
+	}
+
+	manager.mu.Lock()
+	manager.stepping = true
+	manager.mu.Unlock()
+	if err := manager.Resume(); err == nil {
+		t.Fatalf("expected resume to be refused while a step is in progress")
+	}
+	manager.mu.Lock()
+	manager.stepping = false
+	manager.mu.Unlock()
+	if err := manager.Resume(); err != nil {
+		t.Fatalf("resume after the step: %v", err)
+	}
+}
+
+func TestReplayReproducesRecordedRuns(t *testing.T) {
+	cfg := Config{
+		NumTrucks:         20,
+		Seed:              11,
+		WaypointsPerRoute: 4,
+		UpdateInterval:    time.Second,
+		StartTime:         time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Dwell:             map[TruckStatus]time.Duration{TruckStatusLoading: 5 * time.Second},
+	}
+
+	original := NewManager(cfg)
+	if err := original.StepOnce(40); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	rec := original.Recording()
+	if len(rec.Runs) != 1 || rec.Ticks != 40 || rec.Runs[0].Config.Seed != 11 {
+		t.Fatalf("unexpected recording %+v", rec)
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		t.Fatalf("encode recording: %v", err)
+	}
+	var decoded Recording
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("decode recording: %v", err)
+	}
+
+	replayed := NewManager(Config{})
+	if err := replayed.ResumeFromRecording(decoded); err != nil {
+		t.Fatalf("resume from recording: %v", err)
+	}
+	if !reflect.DeepEqual(original.Trucks(), replayed.Trucks()) {
+		t.Fatalf("replayed trucks differ from the original run")
+	}
+
+	second := cfg
+	second.NumTrucks = 5
+	second.Seed = 12
+	rec.Runs = append(rec.Runs, RecordedRun{Tick: 10, Config: second})
+	rec.Ticks = 25
+	snapshots := make([][]Truck, 2)
+	for i := range snapshots {
+		manager := NewManager(Config{})
+		if err := manager.Replay(rec); err != nil {
+			t.Fatalf("replay: %v", err)
+		}
+		if err := manager.StepOnce(int(rec.Ticks)); err != nil {
+			t.Fatalf("step failed: %v", err)
+		}
+		snapshots[i] = manager.Trucks()
+		if got := manager.Run(); got.Number != 2 || got.Seed != 12 {
+			t.Fatalf("expected replay to switch to the second run, got %+v", got)
+		}
+	}
+	if len(snapshots[0]) != 5 || !reflect.DeepEqual(snapshots[0], snapshots[1]) {
+		t.Fatalf("expected identical replays of the second run")
+	}
+}
+
+func TestLoadStateResumesWhereSaveLeftOff(t *testing.T) {
+	cfg := Config{
+		NumTrucks:         20,
+		Seed:              5,
+		WaypointsPerRoute: 4,
+		StartPoints:       []Point{{Lat: 0, Lon: 0}, {Lat: 0.05, Lon: 0}},
+		EndPoints:         []Point{{Lat: 0, Lon: 0.1}, {Lat: 0.1, Lon: 0.1}},
+		RouteBounds:       []BoundingBox{{MinLat: 0, MaxLat: 0.1, MinLon: 0, MaxLon: 0.1}},
+		UpdateInterval:    time.Minute,
+		StartTime:         time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		DockCapacity:      1,
+		MaxDriveTime:      time.Hour,
+		ElectricShare:     0.5,
+		Dwell: map[TruckStatus]time.Duration{
+			TruckStatusLoading:   10 * time.Minute,
+			TruckStatusUnloading: 10 * time.Minute,
+			TruckStatusResting:   30 * time.Minute,
+		},
+	}
+
+	original := NewManager(cfg)
+	if err := original.StepOnce(50); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := original.SaveState(&buf); err != nil {
+		t.Fatalf("save state: %v", err)
+	}
+
+	restored := NewManager(Config{})
+	if err := restored.LoadState(&buf); err != nil {
+		t.Fatalf("load state: %v", err)
+	}
+	if !reflect.DeepEqual(original.Trucks(), restored.Trucks()) {
+		t.Fatalf("restored trucks differ from the saved ones")
+	}
+
+	for _, m := range []*Manager{original, restored} {
+		if err := m.StepOnce(200); err != nil {
+			t.Fatalf("step failed: %v", err)
+		}
+	}
+	if !reflect.DeepEqual(original.Trucks(), restored.Trucks()) {
+		t.Fatalf("restored simulation diverged from the original")
+	}
+	if !reflect.DeepEqual(original.Trips(""), restored.Trips("")) || original.Run() != restored.Run() {
+		t.Fatalf("restored trips or run differ from the original")
+	}
+}
+
+func TestWebMercatorProjection(t *testing.T) {
+	x, y := ToWebMercator(Point{Lat: 0, Lon: 180})
+	if math.Abs(x-20037508.34) > 0.01 || math.Abs(y) > 1e-6 {
+		t.Fatalf("unexpected projection of the antimeridian: %.2f, %.2f", x, y)
+	}
+	if _, y := ToWebMercator(Point{Lat: 90, Lon: 0}); math.Abs(y-20037508.34) > 0.01 {
+		t.Fatalf("expected the pole to clamp to the edge of the square world, got %.2f", y)
+	}
+
+	seattle := Point{Lat: 47.6062, Lon: -122.3321}
+	back := FromWebMercator(ToWebMercator(seattle))
+	if math.Abs(back.Lat-seattle.Lat) > 1e-9 || math.Abs(back.Lon-seattle.Lon) > 1e-9 {
+		t.Fatalf("round trip drifted: %+v", back)
+	}
+}
+
+func TestTypeMixAssignsProfiles(t *testing.T) {
+	near, far := Point{Lat: 0, Lon: 0.1}, Point{Lat: 0, Lon: 5}
+	cfg := Config{
+		Seed:           9,
+		UpdateInterval: time.Second,
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{near, far},
+		TypeMix:        map[TruckType]int{TruckTypeVan: 30, TruckTypeSemi: 20},
+	}
+
+	manager := NewManager(cfg)
+	if err := manager.StepOnce(1); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	counts := make(map[TruckType]int)
+	for _, truck := range manager.Trucks() {
+		counts[truck.Type]++
+		profile := DefaultTruckProfiles[truck.Type]
+		if truck.Speed < profile.SpeedMin || truck.Speed > profile.SpeedMax {
+			t.Fatalf("%s %s drives at %.1f m/s outside its profile", truck.Type, truck.ID, truck.Speed)
+		}
+		if truck.Type == TruckTypeVan && !strings.HasSuffix(truck.RouteID, pointLabel(near)) {
+			t.Fatalf("expected vans to stay within reach, got route %s", truck.RouteID)
+		}
+	}
+	if counts[TruckTypeVan] != 30 || counts[TruckTypeSemi] != 20 {
+		t.Fatalf("expected 30 vans and 20 semis, got %v", counts)
+	}
+
+	cfg.NumTrucks = 7
+	scaled := NewManager(cfg)
+	if err := scaled.StepOnce(1); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	counts = make(map[TruckType]int)
+	for _, truck := range scaled.Trucks() {
+		counts[truck.Type]++
+	}
+	if counts[TruckTypeVan] != 4 || counts[TruckTypeSemi] != 3 {
+		t.Fatalf("expected the mix to scale to 4 vans and 3 semis, got %v", counts)
+	}
+}
+
+func TestGeneratedTracksTickOfLatestUpdate(t *testing.T) {
+	manager := NewManager(Config{
+		NumTrucks:      2,
+		Seed:           3,
+		SpeedMin:       10,
+		SpeedMax:       11,
+		UpdateInterval: time.Second,
+		StartTime:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if got := manager.Generated(); !got.IsZero() {
+		t.Fatalf("expected no generation time before the first tick, got %v", got)
+	}
+
+	before := time.Now()
+	if err := manager.StepOnce(1); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	first := manager.Generated()
+	if first.Before(before) || first.After(time.Now()) {
+		t.Fatalf("expected the generation time of the step, got %v", first)
+	}
+	_ = manager.StepOnce(1)
+	if second := manager.Generated(); !second.After(first) {
+		t.Fatalf("expected each tick to advance the generation time, got %v then %v", first, second)
+	}
+}
+
+func TestRouteCatalogAssignsNamedRoutes(t *testing.T) {
+	depotLoop := NamedRoute{Name: "depot-loop", Waypoints: []Point{{Lat: 0, Lon: 0}, {Lat: 0, Lon: 0.01}, {Lat: 0.01, Lon: 0.01}}, Loop: true}
+	manager := NewManager(Config{
+		NumTrucks:        2,
+		Seed:             4,
+		SpeedMin:         10,
+		SpeedMax:         11,
+		UpdateInterval:   time.Second,
+		StartTime:        time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		StartPoints:      []Point{{Lat: 1, Lon: 1}},
+		EndPoints:        []Point{{Lat: 1, Lon: 2}},
+		NamedRoutes:      []NamedRoute{depotLoop},
+		RouteAssignments: []string{"depot-loop", "missing"},
+	})
+	if err := manager.StepOnce(1); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+
+	first, _ := manager.Truck("truck-0001")
+	if first.RouteID != "depot-loop" || first.CurrentRoute != "depot-loop" || first.Lat > 0.01 || first.Lon > 0.011 {
+		t.Fatalf("expected truck-0001 on the named loop, got %+v", first)
+	}
+	if second, _ := manager.Truck("truck-0002"); second.RouteID == "missing" || second.CurrentRoute == "missing" {
+		t.Fatalf("expected an unknown assignment to fall back to a generated route, got %+v", second)
+	}
+
+	if err := manager.DefineRoute(NamedRoute{Name: "a/b", Waypoints: []Point{{}}}); err == nil {
+		t.Fatalf("expected a name with a slash to be rejected")
+	}
+	if err := manager.DefineRoute(NamedRoute{Name: "completions", Waypoints: []Point{{}}}); err == nil {
+		t.Fatalf("expected a reserved name to be rejected")
+	}
+	spur := NamedRoute{Name: "spur", Waypoints: []Point{{Lat: 1, Lon: 1.001}}}
+	if err := manager.DefineRoute(spur); err != nil {
+		t.Fatalf("define route failed: %v", err)
+	}
+	if routes := manager.NamedRoutes(); len(routes) != 2 || routes[1].Name != "spur" {
+		t.Fatalf("expected the catalog to list both routes, got %+v", routes)
+	}
+	if history := manager.ConfigHistory(); history[len(history)-1].Source != "routes" {
+		t.Fatalf("expected the catalog change in the config history, got %+v", history[len(history)-1])
+	}
+
+	if _, _, err := manager.AssignNamedRoute("truck-0002", "nope"); err == nil {
+		t.Fatalf("expected an unknown route to be rejected")
+	}
+	assigned, ok, err := manager.AssignNamedRoute("truck-0002", "spur")
+	if err != nil || !ok {
+		t.Fatalf("assign failed: ok %v err %v", ok, err)
+	}
+	if assigned.RouteID != "spur" || assigned.CurrentRoute != "spur" {
+		t.Fatalf("expected the truck to report the route name, got %+v", assigned)
+	}
+	if status, ok := manager.RouteStatus("spur"); !ok || status.Trucks != 1 {
+		t.Fatalf("expected route status by name, got %+v", status)
+	}
+
+	if !manager.DeleteRoute("spur") || manager.DeleteRoute("spur") {
+		t.Fatalf("expected the route to be deleted once")
+	}
+	if _, ok := manager.NamedRoute("spur"); ok {
+		t.Fatalf("expected the deleted route to be gone")
+	}
+}
+
+func TestAssignRouteReplacesTruckRoute(t *testing.T) {
+	manager := NewManager(Config{
+		NumTrucks:      1,
+		Seed:           4,
+		SpeedMin:       100,
+		SpeedMax:       101,
+		UpdateInterval: time.Second,
+		StartTime:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 1, Lon: 1}},
+		Dwell:          map[TruckStatus]time.Duration{TruckStatusLoading: time.Nanosecond},
+	})
+	if err := manager.StepOnce(1); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+
+	if _, ok, _ := manager.AssignRoute("truck-9999", []Point{{Lat: 0, Lon: 0.001}}, false); ok {
+		t.Fatalf("expected unknown truck to be reported")
+	}
+	if _, _, err := manager.AssignRoute("truck-0001", nil, false); err == nil {
+		t.Fatalf("expected an empty route to be rejected")
+	}
+	if _, _, err := manager.AssignRoute("truck-0001", []Point{{Lat: 0, Lon: 0.001}}, true); err == nil {
+		t.Fatalf("expected a single-waypoint loop to be rejected")
+	}
+	if _, _, err := manager.AssignRoute("truck-0001", []Point{{Lat: 0, Lon: 200}}, false); err == nil {
+		t.Fatalf("expected invalid coordinates to be rejected")
+	}
+
+	loop := []Point{{Lat: 0, Lon: 0.001}, {Lat: 0.001, Lon: 0.001}}
+	if _, _, err := manager.AssignRoute("truck-0001", loop, true); err != nil {
+		t.Fatalf("assign loop failed: %v", err)
+	}
+	route, _ := manager.RemainingRoute("truck-0001")
+	if len(route) != 3 || route[1] != loop[0] || route[2] != loop[1] {
+		t.Fatalf("expected the assigned loop, got %v", route)
+	}
+
+	last := Point{Lat: 0, Lon: 0.002}
+	assigned, _, err := manager.AssignRoute("truck-0001", []Point{{Lat: 0, Lon: 0.001}, last}, false)
+	if err != nil {
+		t.Fatalf("assign route failed: %v", err)
+	}
+	if assigned.Status != TruckStatusEnRoute || assigned.RouteDistance != 0 {
+		t.Fatalf("expected the truck to set off on the new route, got %+v", assigned)
+	}
+	_ = manager.StepOnce(20)
+	parked, _ := manager.Truck("truck-0001")
+	if parked.Lat != last.Lat || parked.Lon != last.Lon || parked.Status != TruckStatusIdle {
+		t.Fatalf("expected the truck to park at the last waypoint, got %+v", parked)
+	}
+}
+
+func TestTeleportTruckMovesAndOptionallyResetsRoute(t *testing.T) {
+	manager := NewManager(Config{
+		NumTrucks:      1,
+		Seed:           4,
+		SpeedMin:       10,
+		SpeedMax:       11,
+		UpdateInterval: time.Second,
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 0, Lon: 1}},
+	})
+	if err := manager.StepOnce(1); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+
+	if _, ok, _ := manager.TeleportTruck("truck-9999", Point{}, false); ok {
+		t.Fatalf("expected unknown truck to be reported")
+	}
+	if _, _, err := manager.TeleportTruck("truck-0001", Point{Lat: 91}, false); err == nil {
+		t.Fatalf("expected invalid coordinates to be rejected")
+	}
+
+	target := Point{Lat: 0, Lon: 1}
+	p := Point{Lat: 0.5, Lon: 0.5}
+	moved, ok, err := manager.TeleportTruck("truck-0001", p, false)
+	if err != nil || !ok {
+		t.Fatalf("teleport failed: ok %v err %v", ok, err)
+	}
+	if moved.Lat != p.Lat || moved.Lon != p.Lon {
+		t.Fatalf("expected truck at %v, got %.4f,%.4f", p, moved.Lat, moved.Lon)
+	}
+	if route, _ := manager.RemainingRoute("truck-0001"); route[len(route)-1] != target {
+		t.Fatalf("expected the truck to keep heading for %v, got %v", target, route)
+	}
+
+	from := Point{Lat: 0.2, Lon: 0.2}
+	reset, _, err := manager.TeleportTruck("truck-0001", from, true)
+	if err != nil {
+		t.Fatalf("teleport with reset failed: %v", err)
+	}
+	if reset.Status != TruckStatusEnRoute || reset.RouteDistance != 0 || !strings.HasPrefix(reset.RouteID, "0.200,0.200_to_") {
+		t.Fatalf("expected a fresh route from the new position, got %+v", reset)
+	}
+	_ = manager.StepOnce(1)
+	if after, _ := manager.Truck("truck-0001"); after.Lat == from.Lat && after.Lon == from.Lon {
+		t.Fatalf("expected the truck to drive on from its new position")
+	}
+}
+
+func TestUpdateTruckOverridesSingleTruck(t *testing.T) {
+	manager := NewManager(Config{
+		NumTrucks:      2,
+		Seed:           4,
+		SpeedMin:       100,
+		SpeedMax:       101,
+		UpdateInterval: time.Second,
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 0, Lon: 1}},
+	})
+	if err := manager.StepOnce(1); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+
+	if _, ok, _ := manager.UpdateTruck("truck-9999", TruckUpdate{}); ok {
+		t.Fatalf("expected unknown truck to be reported")
+	}
+	zero := 0.0
+	if _, _, err := manager.UpdateTruck("truck-0001", TruckUpdate{Speed: &zero}); err == nil {
+		t.Fatalf("expected non-positive speed to be rejected")
+	}
+	queued := TruckStatusQueued
+	if _, _, err := manager.UpdateTruck("truck-0001", TruckUpdate{Status: &queued}); err == nil {
+		t.Fatalf("expected queued status to be rejected")
+	}
+
+	speed := 50.0
+	dest := Point{Lat: 0.001, Lon: 0}
+	updated, ok, err := manager.UpdateTruck("truck-0001", TruckUpdate{Speed: &speed, Destination: &dest})
+	if err != nil || !ok {
+		t.Fatalf("update failed: ok %v err %v", ok, err)
+	}
+	if updated.Speed != speed {
+		t.Fatalf("expected speed %.0f, got %.2f", speed, updated.Speed)
+	}
+	route, _ := manager.RemainingRoute("truck-0001")
+	if len(route) != 2 || route[1] != dest {
+		t.Fatalf("expected route to end at the new destination, got %v", route)
+	}
+
+	idle := TruckStatusIdle
+	if _, _, err := manager.UpdateTruck("truck-0002", TruckUpdate{Status: &idle, StatusFor: 3 * time.Second}); err != nil {
+		t.Fatalf("idle override failed: %v", err)
+	}
+	before, _ := manager.Truck("truck-0002")
+	_ = manager.StepOnce(2)
+	held, _ := manager.Truck("truck-0002")
+	if held.Lat != before.Lat || held.Lon != before.Lon || held.Status != TruckStatusIdle {
+		t.Fatalf("expected truck-0002 to hold, got %+v", held)
+	}
+	_ = manager.StepOnce(2)
+	if moved, _ := manager.Truck("truck-0002"); moved.Status != TruckStatusEnRoute {
+		t.Fatalf("expected truck-0002 to resume after the hold, got %s", moved.Status)
+	}
+	other, _ := manager.Truck("truck-0001")
+	if other.Speed != speed {
+		t.Fatalf("expected truck-0001 to keep its override, got speed %.2f", other.Speed)
+	}
+}
+
+func TestODMatrixWeightsStartEndPairs(t *testing.T) {
+	seattle, tacoma := Point{Lat: 47.606, Lon: -122.332}, Point{Lat: 47.253, Lon: -122.444}
+	portland, spokane, sf := Point{Lat: 45.515, Lon: -122.679}, Point{Lat: 47.659, Lon: -117.426}, Point{Lat: 37.775, Lon: -122.419}
+	manager := NewManager(Config{
+		NumTrucks:   2000,
+		Seed:        11,
+		StartPoints: []Point{seattle, tacoma},
+		EndPoints:   []Point{portland, spokane, sf},
+		ODMatrix: [][]float64{
+			{80, 20, 0},
+			{0, 0, 25},
+		},
+	})
+	if err := manager.StepOnce(1); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+
+	counts := make(map[string]int)
+	for _, truck := range manager.Trucks() {
+		counts[truck.RouteID]++
+	}
+	pair := func(from, to Point) int { return counts[pointLabel(from)+"_to_"+pointLabel(to)] }
+	if n := pair(seattle, sf) + pair(tacoma, portland) + pair(tacoma, spokane); n != 0 {
+		t.Fatalf("expected no trucks on zero-weight pairs, got %d (%v)", n, counts)
+	}
+	// Seattle carries 100 of 125 units of demand, 80 of them to Portland.
+	if n := pair(seattle, portland); n < 1200 || n > 1360 {
+		t.Fatalf("expected about 1280 Seattle to Portland trucks, got %d (%v)", n, counts)
+	}
+	if n := pair(tacoma, sf); n < 340 || n > 460 {
+		t.Fatalf("expected about 400 Tacoma to San Francisco trucks, got %d (%v)", n, counts)
+	}
+
+	malformed := NewManager(Config{StartPoints: []Point{seattle}, EndPoints: []Point{portland}, ODMatrix: [][]float64{{1, 2}}})
+	if cfg := malformed.Config(); cfg.ODMatrix != nil {
+		t.Fatalf("expected mismatched matrix to be dropped, got %v", cfg.ODMatrix)
+	}
+}
+
+func TestAddAndRemoveTrucksKeepsExistingFleet(t *testing.T) {
+	manager := NewManager(Config{
+		NumTrucks:      4,
+		Seed:           6,
+		Workers:        2,
+		UpdateInterval: 10 * time.Millisecond,
+		StartTime:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 0, Lon: 1}},
+	})
+	if err := manager.StepOnce(5); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	before := manager.Trucks()
+
+	added, err := manager.AddTrucks(2)
+	if err != nil || len(added) != 2 || added[0].ID != "truck-0005" || added[1].ID != "truck-0006" {
+		t.Fatalf("unexpected added trucks %+v: %v", added, err)
+	}
+	for i, truck := range manager.Trucks()[:4] {
+		if truck != before[i] {
+			t.Fatalf("expected %s to keep its state, got %+v want %+v", truck.ID, truck, before[i])
+		}
+	}
+
+	removed, err := manager.RemoveTrucks([]string{"truck-0002"}, 0)
+	if err != nil || len(removed) != 1 {
+		t.Fatalf("unexpected removal %v: %v", removed, err)
+	}
+	if removed, _ := manager.RemoveTrucks(nil, 1); len(removed) != 1 || removed[0] != "truck-0006" {
+		t.Fatalf("expected the newest truck to be removed, got %v", removed)
+	}
+	if _, err := manager.RemoveTrucks(nil, 10); err == nil {
+		t.Fatalf("expected removing every truck to fail")
+	}
+	if n := manager.Config().NumTrucks; n != 4 {
+		t.Fatalf("expected NumTrucks to follow the fleet, got %d", n)
+	}
+	if history := manager.ConfigHistory(); history[len(history)-1].Source != "scale" {
+		t.Fatalf("expected scaling to be recorded, got %+v", history[len(history)-1])
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := manager.Start(ctx); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	defer manager.Stop()
+	late, err := manager.AddTrucks(1)
+	if err != nil {
+		t.Fatalf("add while running failed: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		truck, _ := manager.Truck(late[0].ID)
+		if truck.UpdatedAt.After(late[0].UpdatedAt) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %s to be advanced by a worker", late[0].ID)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSpeedGovernorAndViolations(t *testing.T) {
+	manager := NewManager(Config{
+		NumTrucks:      40,
+		Seed:           9,
+		SpeedMin:       20,
+		SpeedMax:       30,
+		SpeedLimit:     25,
+		GovernedShare:  0.5,
+		UpdateInterval: time.Second,
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 0, Lon: 1}},
+	})
+	if err := manager.StepOnce(10); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+
+	governed, violators := 0, 0
+	for _, truck := range manager.Trucks() {
+		if truck.SpeedLimit != 25 {
+			t.Fatalf("expected %s to get the fleet limit, got %.1f", truck.ID, truck.SpeedLimit)
+		}
+		if truck.Governed {
+			governed++
+			if truck.Speed > 25 || truck.SpeedViolations != 0 {
+				t.Fatalf("expected governed %s to stay at the limit, got %+v", truck.ID, truck)
+			}
+			continue
+		}
+		if truck.Speed > 25 {
+			violators++
+			// Without dwell times the truck drives every tick, so all ten are one stretch over the limit.
+			if truck.SpeedViolations != 1 || truck.OverLimitSeconds != 10 {
+				t.Fatalf("expected %s to record one 10s violation, got %d over %.0fs", truck.ID, truck.SpeedViolations, truck.OverLimitSeconds)
+			}
+		}
+	}
+	if governed == 0 || violators == 0 {
+		t.Fatalf("expected a mix of governed trucks and violators, got %d and %d", governed, violators)
+	}
+
+	var target Truck
+	for _, truck := range manager.Trucks() {
+		if truck.Governed {
+			target = truck
+			break
+		}
+	}
+	fast := 40.0
+	if _, _, err := manager.UpdateTruck(target.ID, TruckUpdate{Speed: &fast}); err != nil {
+		t.Fatalf("override failed: %v", err)
+	}
+	_ = manager.StepOnce(1)
+	if truck, _ := manager.Truck(target.ID); truck.Speed != 25 || truck.GovernorInterventions == 0 {
+		t.Fatalf("expected the governor to pull %s back to the limit, got %+v", target.ID, truck)
+	}
+}
+
+func TestCountersSurviveRestartThroughStore(t *testing.T) {
+	store := FileCounterStore{Path: filepath.Join(t.TempDir(), "counters.json")}
+	if values, err := store.LoadCounters(); err != nil || values != nil {
+		t.Fatalf("expected no counters before the first save, got %v %v", values, err)
+	}
+
+	saved := CounterValues()
+	saved["orbit_fleet_distance_meters_total"] += 1500
+	if err := store.SaveCounters(saved); err != nil {
+		t.Fatalf("save counters: %v", err)
+	}
+	loaded, err := store.LoadCounters()
+	if err != nil || loaded["orbit_fleet_distance_meters_total"] != saved["orbit_fleet_distance_meters_total"] {
+		t.Fatalf("unexpected counters after load: %v %v", loaded, err)
+	}
+
+	before := CounterValues()["orbit_routes_completed_total"]
+	RestoreCounters(map[string]float64{"orbit_routes_completed_total": 7, "unknown_total": 3})
+	if after := CounterValues()["orbit_routes_completed_total"]; after != before+7 {
+		t.Fatalf("expected restored routes completed to add 7, got %.0f from %.0f", after, before)
+	}
+}
+
+func TestManagerPublishesTruckEvents(t *testing.T) {
+	bus := events.NewBus()
+	sub := bus.Subscribe("test", events.SubscribeOptions{QueueSize: 1000})
+	defer sub.Close()
+
+	manager := NewManager(Config{
+		NumTrucks:         1,
+		Seed:              3,
+		SpeedMin:          100,
+		SpeedMax:          101,
+		WaypointsPerRoute: 3,
+		RouteBounds:       []BoundingBox{{MinLat: 0, MaxLat: 0.001, MinLon: 0, MaxLon: 0.01}},
+		UpdateInterval:    time.Second,
+		StartTime:         time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		StartPoints:       []Point{{Lat: 0, Lon: 0}},
+		EndPoints:         []Point{{Lat: 0, Lon: 0.01}},
+		WaypointDwell:     5 * time.Second,
+	}).WithEventBus(bus)
+	if err := manager.StepOnce(30); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+
+	counts := make(map[string]int)
+	var waypoints []int
+	var statuses []string
+	for len(sub.C()) > 0 {
+		evt := <-sub.C()
+		counts[evt.Type]++
+		if evt.RunID != manager.RunID() {
+			t.Fatalf("expected events stamped with run %s, got %+v", manager.RunID(), evt)
+		}
+		switch p := evt.Payload.(type) {
+		case events.WaypointReached:
+			waypoints = append(waypoints, p.Waypoint)
+		case events.StatusChanged:
+			statuses = append(statuses, p.From+">"+p.To)
+		}
+	}
+
+	if counts[events.TypeTruckCreated] != 1 || counts[events.TypeRouteCompleted] != 1 {
+		t.Fatalf("expected one truck created and one route completed, got %v", counts)
+	}
+	if len(waypoints) < 2 || !reflect.DeepEqual(waypoints[:2], []int{1, 2}) {
+		t.Fatalf("expected both waypoints reached in order, got %v", waypoints)
+	}
+	if len(statuses) < 2 || statuses[0] != "enroute>idle" || statuses[1] != "idle>enroute" {
+		t.Fatalf("expected the waypoint pause to change status, got %v", statuses)
+	}
+}
+
+func TestWaypointAnnotationsFlowIntoArrivalEvents(t *testing.T) {
+	bus := events.NewBus()
+	sub := bus.Subscribe("test", events.SubscribeOptions{QueueSize: 1000})
+	defer sub.Close()
+
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	manager := NewManager(Config{
+		NumTrucks:      1,
+		Seed:           4,
+		SpeedMin:       100,
+		SpeedMax:       101,
+		UpdateInterval: time.Second,
+		StartTime:      start,
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 1, Lon: 1}},
+		Dwell:          map[TruckStatus]time.Duration{TruckStatusLoading: time.Nanosecond},
+	}).WithEventBus(bus)
+	if err := manager.StepOnce(1); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+
+	waypoints := []Point{{Lat: 0, Lon: 0.001}, {Lat: 0, Lon: 0.002}}
+	if _, _, err := manager.AssignAnnotatedRoute("truck-0001", waypoints, make([]WaypointAnnotation, 3), false); err == nil {
+		t.Fatalf("expected more annotations than waypoints to be rejected")
+	}
+	annotations := []WaypointAnnotation{
+		{StopType: "pickup"},
+		{StopType: "delivery", PlannedArrival: start.Add(5 * time.Second), Notes: "rear entrance"},
+	}
+	if _, _, err := manager.AssignAnnotatedRoute("truck-0001", waypoints, annotations, false); err != nil {
+		t.Fatalf("assign route failed: %v", err)
+	}
+	route, _ := manager.TruckRoute("truck-0001")
+	if len(route.Annotations) != 3 || !route.Annotations[0].IsZero() || route.Annotations[2].Notes != "rear entrance" {
+		t.Fatalf("expected annotations to line up behind the truck's position, got %+v", route.Annotations)
+	}
+	for len(sub.C()) > 0 {
+		<-sub.C()
+	}
+	if err := manager.StepOnce(20); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+
+	var stops []string
+	var completed *events.Stop
+	for len(sub.C()) > 0 {
+		switch p := (<-sub.C()).Payload.(type) {
+		case events.WaypointReached:
+			if p.Stop == nil {
+				t.Fatalf("expected every assigned waypoint to carry its annotation, got %+v", p)
+			}
+			stops = append(stops, p.StopType)
+		case events.RouteCompleted:
+			completed = p.Stop
+		}
+	}
+	if !reflect.DeepEqual(stops, []string{"pickup", "delivery"}) {
+		t.Fatalf("expected both stops reached in order, got %v", stops)
+	}
+	if completed == nil || completed.Notes != "rear entrance" || completed.PlannedArrival == nil || completed.ArrivalDelaySeconds == nil {
+		t.Fatalf("expected the route completion to carry the final stop, got %+v", completed)
+	}
+	parked, _ := manager.Truck("truck-0001")
+	if want := parked.UpdatedAt.Sub(start.Add(5 * time.Second)).Seconds(); *completed.ArrivalDelaySeconds > want {
+		t.Fatalf("expected the delay to compare arrival with the plan, got %.0fs", *completed.ArrivalDelaySeconds)
+	}
+}
+
+func TestRegionsUpdateAtTheirOwnInterval(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	manager := NewManager(Config{
+		NumTrucks:      1,
+		Seed:           5,
+		SpeedMin:       10,
+		SpeedMax:       11,
+		UpdateInterval: time.Second,
+		StartTime:      start,
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 0, Lon: 1}},
+		Regions: []Region{
+			{Name: "corridor", Bounds: BoundingBox{MinLat: -1, MaxLat: 1, MinLon: -1, MaxLon: 2}, UpdateInterval: 5 * time.Second},
+			{Name: "urban", Bounds: BoundingBox{MinLat: 10, MaxLat: 11, MinLon: 10, MaxLon: 11}, UpdateInterval: 500 * time.Millisecond},
+		},
+	})
+
+	if err := manager.StepOnce(9); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	truck := manager.Trucks()[0]
+	if truck.Lon != 0 || !truck.UpdatedAt.Equal(start) {
+		t.Fatalf("expected the corridor truck to wait for its interval, got %+v", truck)
+	}
+	if got := manager.SimulatedTime(); !got.Equal(start.Add(4500 * time.Millisecond)) {
+		t.Fatalf("expected the clock to tick at the fastest region's interval, got %s", got)
+	}
+
+	if err := manager.StepOnce(1); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	truck = manager.Trucks()[0]
+	moved := GreatCircleDistance(Point{Lat: 0, Lon: 0}, Point{Lat: truck.Lat, Lon: truck.Lon})
+	if math.Abs(moved-truck.Speed*5) > 0.01 || !truck.UpdatedAt.Equal(start.Add(5*time.Second)) {
+		t.Fatalf("expected one five-second step, moved %.2fm at %s", moved, truck.UpdatedAt)
+	}
+
+	want := []RegionStatus{
+		{Name: "corridor", UpdateInterval: 5 * time.Second, Trucks: 1},
+		{Name: "urban", UpdateInterval: 500 * time.Millisecond},
+		{Name: "default", UpdateInterval: time.Second},
+	}
+	if got := manager.Regions(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected regions %+v", got)
+	}
+}
+
+func TestWorkersOnlyVisitTrucksOfRegionsThatTicked(t *testing.T) {
+	manager := NewManager(Config{
+		NumTrucks:      3,
+		Seed:           5,
+		UpdateInterval: time.Second,
+		StartTime:      time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 0, Lon: 1}},
+		Regions: []Region{
+			{Name: "corridor", Bounds: BoundingBox{MinLat: -1, MaxLat: 1, MinLon: -1, MaxLon: 2}, UpdateInterval: 5 * time.Second},
+			{Name: "urban", Bounds: BoundingBox{MinLat: 10, MaxLat: 11, MinLon: 10, MaxLon: 11}, UpdateInterval: 500 * time.Millisecond},
+		},
+	})
+	manager.mu.Lock()
+	manager.ensureTrucksLocked()
+	manager.shards = [][]*Truck{manager.sortedTrucksLocked()}
+	manager.mu.Unlock()
+
+	schedule := shardSchedule{lastTick: -1}
+	var visited []int64
+	for tick := 1; tick <= 30; tick++ {
+		manager.advanceClock()
+		manager.mu.RLock()
+		due := schedule.dueLocked(manager, 0)
+		ticks := manager.ticks
+		manager.mu.RUnlock()
+		// A worker visits its whole shard on its first tick.
+		if tick > 1 && len(due) > 0 {
+			visited = append(visited, ticks)
+		}
+		for _, truck := range due {
+			manager.advanceTruck(truck)
+		}
+	}
+	// The corridor ticks every ten half-second ticks, and its trucks are left alone in between.
+	if want := []int64{10, 20, 30}; !reflect.DeepEqual(visited, want) {
+		t.Fatalf("expected the corridor trucks to be visited on ticks %v, got %v", want, visited)
+	}
+	for _, truck := range manager.Trucks() {
+		if !truck.UpdatedAt.Equal(manager.SimulatedTime()) {
+			t.Fatalf("expected %s to have advanced on the last corridor tick, updated at %s", truck.ID, truck.UpdatedAt)
+		}
+	}
+}
+
+func TestIdleTrucksSuspendAndWake(t *testing.T) {
+	run := func(suspendAfter time.Duration) *Manager {
+		manager := NewManager(Config{
+			NumTrucks:      2,
+			Seed:           9,
+			UpdateInterval: time.Second,
+			StartTime:      time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+			SuspendAfter:   suspendAfter,
+		})
+		if err := manager.StepOnce(1); err != nil {
+			t.Fatalf("step failed: %v", err)
+		}
+		idle := TruckStatusIdle
+		if _, _, err := manager.UpdateTruck("truck-0001", TruckUpdate{Status: &idle, StatusFor: 10 * time.Minute}); err != nil {
+			t.Fatalf("hold truck: %v", err)
+		}
+		if _, _, err := manager.UpdateTruck("truck-0002", TruckUpdate{Status: &idle}); err != nil {
+			t.Fatalf("park truck: %v", err)
+		}
+		if err := manager.StepOnce(2); err != nil {
+			t.Fatalf("step failed: %v", err)
+		}
+		return manager
+	}
+
+	manager := run(time.Minute)
+	if got := manager.SuspendedTrucks(); got != 2 {
+		t.Fatalf("expected both idle trucks suspended, got %d", got)
+	}
+	baseline := run(0)
+	if got := baseline.SuspendedTrucks(); got != 0 {
+		t.Fatalf("expected no suspension when disabled, got %d", got)
+	}
+
+	for _, m := range []*Manager{manager, baseline} {
+		if err := m.StepOnce(600); err != nil {
+			t.Fatalf("step failed: %v", err)
+		}
+	}
+	if got := manager.SuspendedTrucks(); got != 1 {
+		t.Fatalf("expected the held truck to wake on schedule and the parked one to sleep, got %d suspended", got)
+	}
+	held, _ := manager.Truck("truck-0001")
+	want, _ := baseline.Truck("truck-0001")
+	if held.Lat != want.Lat || held.Lon != want.Lon || held.Status != TruckStatusEnRoute {
+		t.Fatalf("expected the woken truck to match an unsuspended run, got %+v want %+v", held, want)
+	}
+
+	enRoute := TruckStatusEnRoute
+	if _, _, err := manager.UpdateTruck("truck-0002", TruckUpdate{Status: &enRoute}); err != nil {
+		t.Fatalf("release truck: %v", err)
+	}
+	if got := manager.SuspendedTrucks(); got != 0 {
+		t.Fatalf("expected an override to wake the parked truck, got %d suspended", got)
+	}
+	parked, _ := manager.Truck("truck-0002")
+	if err := manager.StepOnce(1); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	if moved, _ := manager.Truck("truck-0002"); moved.Lat == parked.Lat && moved.Lon == parked.Lon {
+		t.Fatalf("expected the woken truck to move again")
+	}
+}
+
+func TestFleetCommandsWakeSuspendedTrucks(t *testing.T) {
+	manager := NewManager(Config{
+		NumTrucks:      1,
+		Seed:           9,
+		UpdateInterval: time.Second,
+		SuspendAfter:   time.Second,
+	})
+	if err := manager.StepOnce(1); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	idle := TruckStatusIdle
+	if _, _, err := manager.UpdateTruck("truck-0001", TruckUpdate{Status: &idle}); err != nil {
+		t.Fatalf("park truck: %v", err)
+	}
+	if err := manager.StepOnce(2); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	if got := manager.SuspendedTrucks(); got != 1 {
+		t.Fatalf("expected the parked truck to be suspended, got %d", got)
+	}
+
+	parked, _ := manager.Truck("truck-0001")
+	if n, err := manager.ApplyFleetCommand(FleetCommand{Type: FleetCommandReturnToDepot}); n != 1 || err != nil {
+		t.Fatalf("return to depot: %d, %v", n, err)
+	}
+	if got := manager.SuspendedTrucks(); got != 0 {
+		t.Fatalf("expected the command to wake the truck, got %d suspended", got)
+	}
+	if err := manager.StepOnce(50); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	if moved, _ := manager.Truck("truck-0001"); moved.Lat == parked.Lat && moved.Lon == parked.Lon {
+		t.Fatalf("expected the truck to drive back to the depot, still at %+v", moved)
+	}
+}
+
+func TestSupervisionRestartsFromSnapshot(t *testing.T) {
+	cfg := Config{
+		NumTrucks:      2,
+		Seed:           5,
+		SpeedMin:       1,
+		SpeedMax:       2,
+		UpdateInterval: 5 * time.Millisecond,
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 1, Lon: 1}},
+		Dwell:          map[TruckStatus]time.Duration{TruckStatusLoading: time.Nanosecond},
+	}
+
+	failures := make(chan bool, 2)
+	manager := NewManager(cfg).WithSupervision(SupervisionPolicy{
+		MaxRestarts:      1,
+		Backoff:          time.Millisecond,
+		SnapshotInterval: time.Hour,
+		OnFailure:        func(err error, restarting bool) { failures <- restarting },
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := manager.Start(ctx); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	defer manager.Stop()
+
+	// A negative leg index makes the next update of the truck index out of range and panic.
+	corrupt := func() {
+		manager.mu.Lock()
+		manager.routes["truck-0001"].legIndex = -1
+		manager.mu.Unlock()
+	}
+	waitFor := func(what string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// With an hour between snapshots only the one taken at start exists, so it predates the corruption.
+	waitFor("a snapshot", func() bool { manager.mu.RLock(); defer manager.mu.RUnlock(); return manager.snapshot != nil })
+	corrupt()
+	if restarting := <-failures; !restarting {
+		t.Fatalf("expected the first failure to be restarted")
+	}
+	waitFor("the restart", func() bool { return manager.Restarts() == 1 && manager.Started() })
+	manager.mu.RLock()
+	legIndex := manager.routes["truck-0001"].legIndex
+	manager.mu.RUnlock()
+	if legIndex < 0 {
+		t.Fatalf("expected the restart to restore the route from the snapshot")
+	}
+
+	corrupt()
+	if restarting := <-failures; restarting {
+		t.Fatalf("expected no restart once restarts are used up")
+	}
+	waitFor("the simulation to stop", func() bool { return !manager.Started() })
+	if manager.Failure() == nil {
+		t.Fatalf("expected the failure to be reported")
+	}
+}
+
+func TestNoiseModelPerturbsReportedPositionsOnly(t *testing.T) {
+	cfg := Config{
+		NumTrucks:      5,
+		Seed:           11,
+		UpdateInterval: time.Second,
+		StartTime:      time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Dwell:          map[TruckStatus]time.Duration{TruckStatusLoading: time.Nanosecond},
+	}
+	clean := NewManager(cfg)
+	noisyCfg := cfg
+	noisyCfg.Noise = NoiseModel{SigmaMeters: 10}
+	noisy := NewManager(noisyCfg)
+	for _, m := range []*Manager{clean, noisy} {
+		if err := m.StepOnce(5); err != nil {
+			t.Fatalf("step failed: %v", err)
+		}
+	}
+
+	reported := noisy.Trucks()
+	for i, truth := range clean.Trucks() {
+		noisy.mu.RLock()
+		actual := *noisy.trucks[truth.ID]
+		noisy.mu.RUnlock()
+		if actual.Lat != truth.Lat || actual.Lon != truth.Lon {
+			t.Fatalf("expected noise to leave the true path of %s unchanged", truth.ID)
+		}
+		offset := GreatCircleDistance(Point{Lat: truth.Lat, Lon: truth.Lon}, Point{Lat: reported[i].Lat, Lon: reported[i].Lon})
+		if offset == 0 || offset > 60 {
+			t.Fatalf("expected %s to be reported within a few sigma of its position, off by %.1fm", truth.ID, offset)
+		}
+	}
+
+	dropCfg := cfg
+	dropCfg.Noise = NoiseModel{DropoutProbability: 1}
+	dropped := NewManager(dropCfg)
+	if err := dropped.StepOnce(2); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	before, _ := dropped.Truck("truck-0001")
+	if err := dropped.StepOnce(3); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	after, _ := dropped.Truck("truck-0001")
+	if after.Lat != before.Lat || after.Lon != before.Lon || !after.UpdatedAt.Equal(before.UpdatedAt) {
+		t.Fatalf("expected dropouts to keep the previous fix, got %+v then %+v", before, after)
+	}
+}
+
+func TestClockSkewOffsetsReportedTimestampsPerTruck(t *testing.T) {
+	cfg := Config{
+		NumTrucks:      5,
+		Seed:           11,
+		UpdateInterval: time.Second,
+		StartTime:      time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Dwell:          map[TruckStatus]time.Duration{TruckStatusLoading: time.Nanosecond},
+	}
+	clean := NewManager(cfg)
+	skewedCfg := cfg
+	skewedCfg.ClockSkew = ClockSkew{MaxOffset: time.Minute}
+	skewed := NewManager(skewedCfg)
+
+	offsets := map[time.Duration]bool{}
+	var first []time.Duration
+	for step := 0; step < 2; step++ {
+		for _, m := range []*Manager{clean, skewed} {
+			if err := m.StepOnce(3); err != nil {
+				t.Fatalf("step failed: %v", err)
+			}
+		}
+		reported := skewed.Trucks()
+		for i, truth := range clean.Trucks() {
+			if reported[i].Lat != truth.Lat || reported[i].Lon != truth.Lon {
+				t.Fatalf("expected clock skew to leave the position of %s unchanged", truth.ID)
+			}
+			offset := reported[i].UpdatedAt.Sub(truth.UpdatedAt)
+			if offset == 0 || offset < -time.Minute || offset > time.Minute {
+				t.Fatalf("expected %s to be skewed within a minute, off by %s", truth.ID, offset)
+			}
+			if step == 0 {
+				first = append(first, offset)
+				offsets[offset] = true
+			} else if offset != first[i] {
+				t.Fatalf("expected a stable offset for %s, got %s then %s", truth.ID, first[i], offset)
+			}
+		}
+	}
+	if len(offsets) < 2 {
+		t.Fatalf("expected trucks to get different offsets, got %v", offsets)
+	}
+
+	driftCfg := cfg
+	driftCfg.ClockSkew = ClockSkew{MaxDriftPPM: 1000}
+	drifting := NewManager(driftCfg)
+	var previous time.Duration
+	for step := 0; step < 2; step++ {
+		if err := drifting.StepOnce(50); err != nil {
+			t.Fatalf("step failed: %v", err)
+		}
+		truck, _ := drifting.Truck("truck-0001")
+		drifting.mu.RLock()
+		offset := truck.UpdatedAt.Sub(drifting.trucks["truck-0001"].UpdatedAt)
+		drifting.mu.RUnlock()
+		if offset == 0 || (step > 0 && (offset > 0) != (previous > 0)) || (step > 0 && absDuration(offset) <= absDuration(previous)) {
+			t.Fatalf("expected drift to grow steadily, got %s then %s", previous, offset)
+		}
+		previous = offset
+	}
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+type stubPlanner struct {
+	route []Point
+	err   error
+}
+
+func (p stubPlanner) Plan(start, end Point) ([]Point, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return append(append([]Point{start}, p.route...), end), nil
+}
+
+func TestRoutePlannerGeneratesRoutesWithFallback(t *testing.T) {
+	cfg := Config{
+		NumTrucks:         1,
+		Seed:              3,
+		WaypointsPerRoute: 4,
+		UpdateInterval:    time.Second,
+		StartTime:         time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		StartPoints:       []Point{{Lat: 0, Lon: 0}},
+		EndPoints:         []Point{{Lat: 0, Lon: 1}},
+	}
+	road := []Point{{Lat: 0.1, Lon: 0.5}}
+	planned := NewManager(cfg).WithRoutePlanner(stubPlanner{route: road})
+	if err := planned.StepOnce(1); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	route, _ := planned.TruckRoute("truck-0001")
+	if len(route.Waypoints) != 3 || route.Waypoints[1] != road[0] {
+		t.Fatalf("expected the planner's route, got %v", route.Waypoints)
+	}
+
+	before := fallbackCount()
+	failing := NewManager(cfg).WithRoutePlanner(stubPlanner{err: fmt.Errorf("unreachable")})
+	if err := failing.StepOnce(1); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	route, _ = failing.TruckRoute("truck-0001")
+	if len(route.Waypoints) != 4 {
+		t.Fatalf("expected random waypoints when the planner fails, got %v", route.Waypoints)
+	}
+	if after := fallbackCount(); after != before+1 {
+		t.Fatalf("expected one fallback to be counted, got %.0f from %.0f", after, before)
+	}
+}
+
+func TestUpdateMetricsCountUpdatesAndTheLargestGap(t *testing.T) {
+	manager := NewManager(Config{
+		NumTrucks:      3,
+		Seed:           4,
+		UpdateInterval: time.Second,
+		StartTime:      time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+	})
+	var before dto.Metric
+	_ = truckUpdates.Write(&before)
+
+	for i := 0; i < 4; i++ {
+		if err := manager.StepOnce(1); err != nil {
+			t.Fatalf("step failed: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	var after dto.Metric
+	_ = truckUpdates.Write(&after)
+	if got := after.GetCounter().GetValue() - before.GetCounter().GetValue(); got != 12 {
+		t.Fatalf("expected 3 trucks over 4 ticks to make 12 updates, got %v", got)
+	}
+
+	// The gauge is published, and the running maximum restarted, once per tick of the ticker.
+	manager.recordTickLatency(time.Now())
+	manager.recordTickLatency(time.Now())
+	var gap dto.Metric
+	_ = truckUpdateMaxGap.Write(&gap)
+	if got := gap.GetGauge().GetValue(); got < 0.02 || got > 1 {
+		t.Fatalf("expected the largest gap to cover the 20ms pause between steps, got %vs", got)
+	}
+	manager.recordTickLatency(time.Now())
+	_ = truckUpdateMaxGap.Write(&gap)
+	if got := gap.GetGauge().GetValue(); got != 0 {
+		t.Fatalf("expected no gap for a tick without updates, got %vs", got)
+	}
+}
+
+func fallbackCount() float64 {
+	var m dto.Metric
+	_ = routePlannerFallbacks.Write(&m)
+	return m.GetCounter().GetValue()
+}
+
+func TestRandomPlannerDrawsWaypointsWithinBounds(t *testing.T) {
+	box := BoundingBox{MinLat: 10, MaxLat: 11, MinLon: 20, MaxLon: 21}
+	planner := RandomPlanner{Rand: rand.New(rand.NewSource(1)), Waypoints: 5, Bounds: []BoundingBox{box}}
+	start, end := Point{Lat: 0, Lon: 0}, Point{Lat: 1, Lon: 1}
+	route, err := planner.Plan(start, end)
+	if err != nil {
+		t.Fatalf("plan failed: %v", err)
+	}
+	if len(route) != 5 || route[0] != start || route[4] != end {
+		t.Fatalf("expected start, three waypoints, and end, got %v", route)
+	}
+	for _, p := range route[1:4] {
+		if !box.Contains(p) {
+			t.Fatalf("expected %v within %+v", p, box)
+		}
+	}
+
+	direct, _ := RandomPlanner{Rand: rand.New(rand.NewSource(1)), Waypoints: 2, Area: box}.Plan(start, end)
+	if len(direct) != 2 {
+		t.Fatalf("expected a direct route, got %v", direct)
+	}
+}
+
+func TestDrainStopsTrucksAtTheirNextWaypoint(t *testing.T) {
+	manager := NewManager(Config{
+		NumTrucks:      3,
+		Seed:           2,
+		SpeedMin:       200,
+		SpeedMax:       201,
+		UpdateInterval: time.Millisecond,
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 0, Lon: 0.01}},
+		Dwell:          map[TruckStatus]time.Duration{TruckStatusLoading: time.Nanosecond},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if manager.Drained() {
+		t.Fatalf("expected no drain before Drain is called")
+	}
+	if err := manager.Start(ctx); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	defer manager.Stop()
+
+	drainCtx, drainCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer drainCancel()
+	if err := manager.Drain(drainCtx); err != nil {
+		t.Fatalf("drain failed: %v", err)
+	}
+	stopped := manager.Trucks()
+	for _, truck := range stopped {
+		route, _ := manager.TruckRoute(truck.ID)
+		atWaypoint := false
+		for _, p := range route.Waypoints {
+			if p.Lat == truck.Lat && p.Lon == truck.Lon {
+				atWaypoint = true
+			}
+		}
+		if !atWaypoint {
+			t.Fatalf("expected %s to stop at a waypoint, got %+v", truck.ID, truck)
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	for i, truck := range manager.Trucks() {
+		if truck.Lat != stopped[i].Lat || truck.Lon != stopped[i].Lon {
+			t.Fatalf("expected %s to stay put once drained", truck.ID)
+		}
+	}
+}
+
+func TestTrailsDecimateRecentPositionsOldestFirst(t *testing.T) {
+	var tr trail
+	for i := 0; i < DefaultTrackDepth+6; i++ {
+		tr.add(Point{Lat: float64(i)}, time.Time{}, DefaultTrackDepth)
+		tr.add(Point{Lat: float64(i)}, time.Time{}, DefaultTrackDepth)
+	}
+	last := float64(DefaultTrackDepth + 5)
+	want := []Point{{Lat: last - 4}, {Lat: last - 2}, {Lat: last}}
+	if got := tr.recent(3, 2); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if got := tr.recent(DefaultTrackDepth*2, 1); len(got) != DefaultTrackDepth || got[0].Lat != last-DefaultTrackDepth+1 {
+		t.Fatalf("expected the last %d positions, got %d starting at %v", DefaultTrackDepth, len(got), got[0])
+	}
+
+	m := NewManager(Config{NumTrucks: 2, UpdateInterval: time.Second, Seed: 4})
+	if err := m.StepOnce(5); err != nil {
+		t.Fatalf("step: %v", err)
+	}
+	trails := m.Trails([]string{m.Trucks()[0].ID, "missing"}, 3, 1)
+	if len(trails[0]) == 0 || trails[1] != nil {
+		t.Fatalf("expected a trail for the known truck only, got %v", trails)
+	}
+	if got := trails[0][len(trails[0])-1]; got != (Point{Lat: m.Trucks()[0].Lat, Lon: m.Trucks()[0].Lon}) {
+		t.Fatalf("expected the trail to end at the current position, got %v", got)
+	}
+}
+
+func TestTrackKeepsTimestampedPositionsUpToTheConfiguredDepth(t *testing.T) {
+	m := NewManager(Config{NumTrucks: 1, UpdateInterval: time.Second, Seed: 4, TrackDepth: 4})
+	if err := m.StepOnce(10); err != nil {
+		t.Fatalf("step: %v", err)
+	}
+	truck := m.Trucks()[0]
+	track, ok := m.Track(truck.ID, 100)
+	if !ok || len(track) != 4 {
+		t.Fatalf("expected the last 4 positions, got %v", track)
+	}
+	for i := 1; i < len(track); i++ {
+		if !track[i].Time.After(track[i-1].Time) {
+			t.Fatalf("expected times to increase, got %v", track)
+		}
+	}
+	if last := track[len(track)-1]; last.Lat != truck.Lat || last.Lon != truck.Lon || !last.Time.Equal(truck.UpdatedAt) {
+		t.Fatalf("expected the track to end at the current position, got %+v for %+v", last, truck)
+	}
+	if newest, _ := m.Track(truck.ID, 1); len(newest) != 1 || newest[0] != track[3] {
+		t.Fatalf("expected the newest position alone, got %v", newest)
+	}
+	if _, ok := m.Track("missing", 4); ok {
+		t.Fatalf("expected an unknown truck to have no track")
+	}
+}
+
+func TestTimetabledRouteHoldsTrucksUntilTheirDepartures(t *testing.T) {
+	start := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	m := NewManager(Config{
+		NumTrucks:      2,
+		Seed:           3,
+		UpdateInterval: time.Second,
+		StartTime:      start,
+		NamedRoutes: []NamedRoute{{
+			Name:      "line-1",
+			Waypoints: []Point{{Lat: 0, Lon: 0}, {Lat: 0, Lon: 0.001}},
+			Loop:      true,
+			Timetable: &Timetable{
+				Departures: []time.Time{start.Add(-time.Minute), start.Add(30 * time.Second), start.Add(90 * time.Second), start.Add(150 * time.Second)},
+				StopTimes:  []time.Duration{0, 10 * time.Second},
+			},
+		}},
+		RouteAssignments: []string{"line-1"},
+	})
+	if err := m.StepOnce(20); err != nil {
+		t.Fatalf("step: %v", err)
+	}
+	for _, truck := range m.Trucks() {
+		if truck.Status != TruckStatusIdle || truck.Lon != 0 {
+			t.Fatalf("expected %s to wait at the first stop before its departure, got %s at %v", truck.ID, truck.Status, truck.Lon)
+		}
+	}
+	if err := m.StepOnce(300); err != nil {
+		t.Fatalf("step: %v", err)
+	}
+
+	records := m.TimetableRecords("line-1")
+	if len(records) != 4 || !records[0].Missed || records[0].Departure != 0 {
+		t.Fatalf("expected the departure before the start to be missed and three trips run, got %+v", records)
+	}
+	trucks := map[int]string{}
+	for _, r := range records[1:] {
+		trucks[r.Departure] = r.TruckID
+		if r.Missed || !r.Completed || r.ActualDeparture.Before(r.ScheduledDeparture) || len(r.Arrivals) != 1 {
+			t.Fatalf("unexpected trip %+v", r)
+		}
+		if a := r.Arrivals[0]; a.Waypoint != 1 || !a.Scheduled.Equal(r.ScheduledDeparture.Add(10*time.Second)) {
+			t.Fatalf("unexpected arrival %+v", a)
+		}
+	}
+	if trucks[1] != "truck-0001" || trucks[2] != "truck-0002" || trucks[3] != "truck-0001" {
+		t.Fatalf("expected the trucks to take departures in turn, got %v", trucks)
+	}
+	for _, truck := range m.Trucks() {
+		if truck.Status != TruckStatusIdle {
+			t.Fatalf("expected %s to park once the timetable ran out, got %s", truck.ID, truck.Status)
+		}
+	}
+
+	bad := NamedRoute{Name: "bad", Waypoints: []Point{{Lat: 0, Lon: 0}, {Lat: 0, Lon: 1}}, Timetable: &Timetable{Departures: []time.Time{start, start.Add(-time.Second)}}}
+	if err := bad.Validate(); err == nil {
+		t.Fatal("expected out-of-order departures to be rejected")
+	}
+}
+
+// gatedPlanner answers once release is closed, like a slow routing service.
+type gatedPlanner struct {
+	release chan struct{}
+	route   []Point
+	err     error
+}
+
+func (p gatedPlanner) Plan(start, end Point) ([]Point, error) {
+	<-p.release
+	if p.err != nil {
+		return nil, p.err
+	}
+	return append(append([]Point{start}, p.route...), end), nil
+}
+
+func TestPlanQueueSwapsInPlannedRoutesWithoutBlockingTicks(t *testing.T) {
+	cfg := Config{
+		NumTrucks:      2,
+		Seed:           3,
+		UpdateInterval: time.Second,
+		StartPoints:    []Point{{Lat: 0, Lon: 0}},
+		EndPoints:      []Point{{Lat: 0, Lon: 1}},
+	}
+	road := []Point{{Lat: 0.1, Lon: 0.5}}
+	planner := gatedPlanner{release: make(chan struct{}), route: road}
+	queue := NewPlanQueue(planner, PlanQueueOptions{Workers: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go queue.Run(ctx)
+	m := NewManager(cfg).WithPlanQueue(queue)
+
+	// The planner has not answered, yet the fleet ticks on straight lines.
+	if err := m.StepOnce(5); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	route, _ := m.TruckRoute("truck-0001")
+	if len(route.Waypoints) != 2 || route.Next != 1 {
+		t.Fatalf("expected a provisional straight line, got %v", route.Waypoints)
+	}
+
+	close(planner.release)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		route, _ = m.TruckRoute("truck-0002")
+		if len(route.Waypoints) == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the planned route to be swapped in, got %v", route.Waypoints)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if route.Waypoints[1] != road[0] || route.Next != 1 {
+		t.Fatalf("expected the truck to head for the planned waypoint, got %+v", route)
+	}
+
+	before := fallbackCount()
+	failing := gatedPlanner{release: make(chan struct{}), err: fmt.Errorf("unreachable")}
+	close(failing.release)
+	// A backlog of one with no workers running: the first truck's plan is queued, the second falls back.
+	stalled := NewManager(cfg).WithPlanQueue(NewPlanQueue(failing, PlanQueueOptions{Backlog: 1}))
+	if err := stalled.StepOnce(1); err != nil {
+		t.Fatalf("step failed: %v", err)
+	}
+	if after := fallbackCount(); after != before+1 {
+		t.Fatalf("expected the overflowing plan to fall back, got %.0f from %.0f", after, before)
+	}
+	for _, id := range []string{"truck-0001", "truck-0002"} {
+		if route, _ := stalled.TruckRoute(id); len(route.Waypoints) != 2 {
+			t.Fatalf("%s: expected a straight line, got %v", id, route.Waypoints)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package simulation
+
+import (
+	"hash/fnv"
+	"math"
+	"time"
+)
+
+// ClockSkew gives every truck a device clock that disagrees with the simulated clock, for exercising
+// downstream time alignment. Each truck's UpdatedAt is reported off by a stable per-truck offset plus a
+// drift that grows with the simulated time since the run started. Like NoiseModel it only affects what
+// Trucks and Truck return.
+type ClockSkew struct {
+	// MaxOffset bounds each truck's offset, drawn uniformly from [-MaxOffset, MaxOffset].
+	MaxOffset time.Duration
+	// MaxDriftPPM bounds each truck's drift rate in parts per million, drawn uniformly from
+	// [-MaxDriftPPM, MaxDriftPPM]. A clock drifting at 100 ppm gains 0.36 seconds an hour.
+	MaxDriftPPM float64
+}
+
+func (c ClockSkew) enabled() bool {
+	return c.MaxOffset > 0 || c.MaxDriftPPM > 0
+}
+
+func normalizeClockSkew(c ClockSkew) ClockSkew {
+	if c.MaxOffset < 0 {
+		c.MaxOffset = -c.MaxOffset
+	}
+	c.MaxDriftPPM = math.Abs(c.MaxDriftPPM)
+	return c
+}
+
+// truckClock returns the truck's clock offset and drift rate. Both derive from the run seed and the truck
+// ID alone, so they are stable for the life of the truck and draw nothing from the simulation's generator.
+func (m *Manager) truckClock(id string) (time.Duration, float64) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(id))
+	state := h.Sum64() ^ uint64(m.runSeed)
+	offset := (2*unitFloat(splitmix64(&state)) - 1) * float64(m.cfg.ClockSkew.MaxOffset)
+	drift := (2*unitFloat(splitmix64(&state)) - 1) * m.cfg.ClockSkew.MaxDriftPPM
+	return time.Duration(offset), drift
+}
+
+// skewedLocked returns the time the truck's own clock shows at the simulated time at.
+func (m *Manager) skewedLocked(id string, at time.Time) time.Time {
+	if !m.cfg.ClockSkew.enabled() || at.IsZero() {
+		return at
+	}
+	offset, drift := m.truckClock(id)
+	elapsed := at.Sub(m.run.StartedAt)
+	return at.Add(offset + time.Duration(float64(elapsed)*drift/1e6))
+}
+
+// splitmix64 advances state and returns the next output of the SplitMix64 generator.
+func splitmix64(state *uint64) uint64 {
+	*state += 0x9e3779b97f4a7c15
+	z := *state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// unitFloat maps a uniformly random uint64 onto [0, 1).
+func unitFloat(v uint64) float64 {
+	return float64(v>>11) / (1 << 53)
+}
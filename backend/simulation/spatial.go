@@ -0,0 +1,201 @@
+package simulation
+
+import (
+	"math"
+	"sort"
+)
+
+// spatialCellDegrees is the side of a spatial index cell, about 5.5 km of latitude.
+const spatialCellDegrees = 0.05
+
+type spatialCell struct {
+	lat, lon int32
+}
+
+func cellOf(p Point) spatialCell {
+	return spatialCell{lat: int32(math.Floor(p.Lat / spatialCellDegrees)), lon: int32(math.Floor(p.Lon / spatialCellDegrees))}
+}
+
+type spatialEntry struct {
+	cell spatialCell
+	at   Point
+}
+
+// spatialIndex is a uniform grid of the trucks' reported positions. It is keyed by truck pointer, so
+// entries survive the ID change of a handoff.
+type spatialIndex struct {
+	cells  map[spatialCell]map[*Truck]struct{}
+	trucks map[*Truck]spatialEntry
+}
+
+func newSpatialIndex() *spatialIndex {
+	return &spatialIndex{cells: make(map[spatialCell]map[*Truck]struct{}), trucks: make(map[*Truck]spatialEntry)}
+}
+
+// move records the truck at p.
+func (ix *spatialIndex) move(truck *Truck, p Point) {
+	cell := cellOf(p)
+	if old, ok := ix.trucks[truck]; ok && old.cell != cell {
+		ix.leave(truck, old.cell)
+	}
+	if ix.cells[cell] == nil {
+		ix.cells[cell] = make(map[*Truck]struct{})
+	}
+	ix.cells[cell][truck] = struct{}{}
+	ix.trucks[truck] = spatialEntry{cell: cell, at: p}
+}
+
+func (ix *spatialIndex) remove(truck *Truck) {
+	if old, ok := ix.trucks[truck]; ok {
+		ix.leave(truck, old.cell)
+		delete(ix.trucks, truck)
+	}
+}
+
+func (ix *spatialIndex) leave(truck *Truck, cell spatialCell) {
+	delete(ix.cells[cell], truck)
+	if len(ix.cells[cell]) == 0 {
+		delete(ix.cells, cell)
+	}
+}
+
+// within calls visit for every truck whose indexed position is inside box. It walks the cells the box
+// covers, or every occupied cell when that is fewer, so a world-sized box costs no more than a scan.
+func (ix *spatialIndex) within(box BoundingBox, visit func(*Truck)) {
+	lo, hi := cellOf(Point{Lat: box.MinLat, Lon: box.MinLon}), cellOf(Point{Lat: box.MaxLat, Lon: box.MaxLon})
+	check := func(trucks map[*Truck]struct{}) {
+		for truck := range trucks {
+			if box.Contains(ix.trucks[truck].at) {
+				visit(truck)
+			}
+		}
+	}
+	covered := (int64(hi.lat) - int64(lo.lat) + 1) * (int64(hi.lon) - int64(lo.lon) + 1)
+	if covered > int64(len(ix.cells)) {
+		for cell, trucks := range ix.cells {
+			if cell.lat >= lo.lat && cell.lat <= hi.lat && cell.lon >= lo.lon && cell.lon <= hi.lon {
+				check(trucks)
+			}
+		}
+		return
+	}
+	for lat := lo.lat; lat <= hi.lat; lat++ {
+		for lon := lo.lon; lon <= hi.lon; lon++ {
+			check(ix.cells[spatialCell{lat: lat, lon: lon}])
+		}
+	}
+}
+
+// TrucksInBox returns the trucks whose reported position lies inside box, sorted by ID. It queries the
+// spatial index rather than scanning the fleet.
+func (m *Manager) TrucksInBox(box BoundingBox) []Truck {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	trucks := []Truck{}
+	m.index.within(box, func(truck *Truck) {
+		trucks = append(trucks, m.reportedLocked(truck))
+	})
+	sort.Slice(trucks, func(i, j int) bool {
+		return trucks[i].ID < trucks[j].ID
+	})
+	return trucks
+}
+
+// NearbyTruck is a truck found by NearestTrucks with its distance from the query point.
+type NearbyTruck struct {
+	Truck
+	DistanceMeters float64
+}
+
+type spatialCandidate struct {
+	truck    *Truck
+	distance float64
+}
+
+// nearest returns the k trucks closest to p by great-circle distance, nearest first. It searches rings of
+// cells outwards from p's cell until no unsearched cell can hold a closer truck, and scans every truck
+// instead once the rings grow larger than the occupied grid.
+func (ix *spatialIndex) nearest(p Point, k int) []spatialCandidate {
+	if k <= 0 || len(ix.trucks) == 0 {
+		return nil
+	}
+	var found []spatialCandidate
+	collect := func(trucks map[*Truck]struct{}) {
+		for truck := range trucks {
+			found = append(found, spatialCandidate{truck: truck, distance: GreatCircleDistance(p, ix.trucks[truck].at)})
+		}
+	}
+	done := func() []spatialCandidate {
+		sort.Slice(found, func(i, j int) bool {
+			if found[i].distance != found[j].distance {
+				return found[i].distance < found[j].distance
+			}
+			return found[i].truck.ID < found[j].truck.ID
+		})
+		return found[:min(k, len(found))]
+	}
+
+	center := cellOf(p)
+	for r := int32(0); ; r++ {
+		if side := int(2*r + 1); side*side > 4*len(ix.cells)+64 {
+			found = found[:0]
+			for truck, entry := range ix.trucks {
+				found = append(found, spatialCandidate{truck: truck, distance: GreatCircleDistance(p, entry.at)})
+			}
+			return done()
+		}
+		for lat := center.lat - r; lat <= center.lat+r; lat++ {
+			for lon := center.lon - r; lon <= center.lon+r; lon++ {
+				if lat == center.lat-r || lat == center.lat+r || lon == center.lon-r || lon == center.lon+r {
+					collect(ix.cells[spatialCell{lat: lat, lon: lon}])
+				}
+			}
+		}
+		if len(found) < k {
+			continue
+		}
+		best := done()
+		if best[len(best)-1].distance <= ringClearance(p, center, r) {
+			return best
+		}
+	}
+}
+
+// ringClearance is a lower bound on the distance from p to any point outside the cells within r rings of
+// center.
+func ringClearance(p Point, center spatialCell, r int32) float64 {
+	minLat := float64(center.lat-r) * spatialCellDegrees
+	maxLat := float64(center.lat+r+1) * spatialCellDegrees
+	minLon := float64(center.lon-r) * spatialCellDegrees
+	maxLon := float64(center.lon+r+1) * spatialCellDegrees
+
+	clearance := math.Inf(1)
+	if minLat > -90 {
+		clearance = math.Min(clearance, degreesToRadians(p.Lat-minLat)*earthRadiusMeters)
+	}
+	if maxLat < 90 {
+		clearance = math.Min(clearance, degreesToRadians(maxLat-p.Lat)*earthRadiusMeters)
+	}
+	// The distance to a meridian dλ away is asin(sin dλ · cos φ), which only bounds points within 90°.
+	dLon := math.Min(p.Lon-minLon, maxLon-p.Lon)
+	if dLon >= 90 {
+		return 0
+	}
+	meridian := math.Asin(math.Sin(degreesToRadians(dLon))*math.Cos(degreesToRadians(p.Lat))) * earthRadiusMeters
+	return math.Min(clearance, meridian)
+}
+
+// NearestTrucks returns the k trucks whose reported positions are closest to p, nearest first, using the
+// spatial index.
+func (m *Manager) NearestTrucks(p Point, k int) []NearbyTruck {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	candidates := m.index.nearest(p, k)
+	nearby := make([]NearbyTruck, len(candidates))
+	for i, c := range candidates {
+		nearby[i] = NearbyTruck{Truck: m.reportedLocked(c.truck), DistanceMeters: c.distance}
+	}
+	return nearby
+}
@@ -0,0 +1,72 @@
+package simulation
+
+import "fmt"
+
+// SpeedZone caps the speed of every truck inside it, whatever speed the truck was assigned. The zone is
+// Polygon when it has at least three vertices and Bounds otherwise.
+type SpeedZone struct {
+	Name    string
+	Bounds  BoundingBox
+	Polygon []Point
+	// Limit is the maximum speed inside the zone in metres per second.
+	Limit float64
+}
+
+// Contains reports whether p lies inside the zone.
+func (z SpeedZone) Contains(p Point) bool {
+	if len(z.Polygon) >= 3 {
+		return PolygonContains(z.Polygon, p)
+	}
+	return z.Bounds.Contains(p)
+}
+
+// speedZoneLocked returns the most restrictive speed zone containing p.
+func (m *Manager) speedZoneLocked(p Point) (SpeedZone, bool) {
+	var found SpeedZone
+	ok := false
+	for _, z := range m.cfg.SpeedZones {
+		if z.Contains(p) && (!ok || z.Limit < found.Limit) {
+			found, ok = z, true
+		}
+	}
+	return found, ok
+}
+
+// applySpeedZoneLocked caps the truck's speed while it is inside a speed zone and restores its cruising
+// speed once it leaves. A speed set while capped, by an override or fleet command, becomes the new
+// cruising speed.
+func (m *Manager) applySpeedZoneLocked(truck *Truck, state *routeState) {
+	if state.zoneCapped {
+		if truck.Speed == truck.ZoneSpeedLimit {
+			truck.Speed = state.cruiseSpeed
+		}
+		state.zoneCapped = false
+	}
+	truck.SpeedZone, truck.ZoneSpeedLimit = "", 0
+
+	zone, ok := m.speedZoneLocked(Point{Lat: truck.Lat, Lon: truck.Lon})
+	if !ok {
+		return
+	}
+	truck.SpeedZone, truck.ZoneSpeedLimit = zone.Name, zone.Limit
+	if truck.Speed > zone.Limit {
+		state.cruiseSpeed = truck.Speed
+		state.zoneCapped = true
+		truck.Speed = zone.Limit
+	}
+}
+
+// normalizeSpeedZones drops zones without a positive limit and names unnamed ones.
+func normalizeSpeedZones(zones []SpeedZone) []SpeedZone {
+	var kept []SpeedZone
+	for _, z := range zones {
+		if z.Limit <= 0 {
+			continue
+		}
+		if z.Name == "" {
+			z.Name = fmt.Sprintf("zone-%d", len(kept)+1)
+		}
+		kept = append(kept, z)
+	}
+	return kept
+}
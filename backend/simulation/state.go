@@ -0,0 +1,282 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// stateVersion is bumped whenever the saved state layout changes incompatibly.
+const stateVersion = 1
+
+// savedState is the serialized form of a manager, written by SaveState and read by LoadState.
+type savedState struct {
+	Version  int
+	Config   Config
+	Initial  Config
+	Run      RunInfo
+	RunSeed  int64
+	RNGDraws uint64
+	Clock    time.Time
+	Ticks    int64
+
+	Trucks      []Truck
+	TruckSeq    int
+	Routes      map[string]savedRoute
+	Depots      []savedDepot
+	Trips       []Trip
+	Completions []RouteCompletion
+	// Timetable and TimetableNext are the recorded and next departures on timetabled routes.
+	Timetable     []TimetableRecord
+	TimetableNext map[string]int
+	Shipments     []Shipment
+	ShipmentSeq   int
+
+	History    []ConfigChange
+	HistorySeq int
+	Recording  []RecordedRun
+}
+
+type savedRoute struct {
+	Waypoints       []Point
+	Annotations     []WaypointAnnotation
+	RouteName       string
+	LegIndex        int
+	Loop            bool
+	Terminal        bool
+	Parked          bool
+	HoldUntil       time.Time
+	DwellStatus     TruckStatus
+	DwellUntil      time.Time
+	PendingDwell    []TruckStatus
+	RoutesCompleted int
+	DriveTime       time.Duration
+	Trip            *savedTrip
+	TripsCompleted  int
+	Returning       bool
+	Docked          string
+	Detouring       bool
+	ChargeStop      int
+	Charging        bool
+	OverLimit       bool
+	Owed            time.Duration
+	RouteStarted    time.Time
+	ZoneCapped      bool
+	CruiseSpeed     float64
+	Handoff         *pendingHandoff
+	Timetable       *timetabledTrip
+}
+
+type savedTrip struct {
+	Start       time.Time
+	Origin      Point
+	Destination Point
+	Distance    float64
+	Moving      time.Duration
+	Planned     time.Duration
+	Energy      float64
+	Stops       int
+	Arrived     bool
+}
+
+type savedDepot struct {
+	Key      string
+	Capacity int
+	Busy     int
+	Queue    []string
+}
+
+// SaveState writes the full simulation state, including route progress and the random generator, as JSON.
+// A manager restored with LoadState continues exactly where this one left off.
+func (m *Manager) SaveState(w io.Writer) error {
+	m.mu.RLock()
+	state := savedState{
+		Version:       stateVersion,
+		Config:        m.cfg,
+		Initial:       m.initial,
+		Run:           m.run,
+		RunSeed:       m.runSeed,
+		RNGDraws:      m.randSrc.draws,
+		Clock:         m.clock,
+		Ticks:         m.ticks,
+		Trucks:        make([]Truck, 0, len(m.trucks)),
+		TruckSeq:      m.truckSeq,
+		Routes:        make(map[string]savedRoute, len(m.routes)),
+		Trips:         m.trips,
+		Completions:   m.completions,
+		Timetable:     m.timetable,
+		TimetableNext: m.timetableNext,
+		ShipmentSeq:   m.shipmentSeq,
+		History:       m.history,
+		HistorySeq:    m.historySeq,
+		Recording:     m.recording,
+	}
+	for _, truck := range m.sortedTrucksLocked() {
+		state.Trucks = append(state.Trucks, *truck)
+	}
+	for id, r := range m.routes {
+		saved := saveRoute(r)
+		saved.Owed = m.clock.Sub(r.steppedAt)
+		state.Routes[id] = saved
+	}
+	for _, d := range m.depots {
+		state.Depots = append(state.Depots, savedDepot{Key: d.key, Capacity: d.capacity, Busy: d.busy, Queue: d.queue})
+	}
+	for _, id := range m.shipmentOrder {
+		state.Shipments = append(state.Shipments, *m.shipments[id])
+	}
+	// Encode under the lock: the saved slices and maps share memory with the live state.
+	err := json.NewEncoder(w).Encode(state)
+	m.mu.RUnlock()
+	return err
+}
+
+// LoadState replaces the state of a manager that has not been started with one written by SaveState.
+func (m *Manager) LoadState(r io.Reader) error {
+	var state savedState
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return fmt.Errorf("decode state: %w", err)
+	}
+	if state.Version != stateVersion {
+		return fmt.Errorf("unsupported state version %d", state.Version)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.started {
+		return fmt.Errorf("simulation is running")
+	}
+
+	m.resetLocked(cloneConfig(normalizeConfig(state.Config)))
+	m.initial = cloneConfig(normalizeConfig(state.Initial))
+	m.run = state.Run
+	m.runSeed = state.RunSeed
+	m.rand, m.randSrc = restoreRand(state.RunSeed, state.RNGDraws)
+	m.clock = state.Clock
+	m.ticks = state.Ticks
+	for i := range state.Trucks {
+		truck := state.Trucks[i]
+		m.trucks[truck.ID] = &truck
+	}
+	m.truckSeq = state.TruckSeq
+	for id, r := range state.Routes {
+		state := loadRoute(r)
+		state.steppedAt = m.clock.Add(-r.Owed)
+		if truck, ok := m.trucks[id]; ok {
+			state.region = m.regionLocked(truck) + 1
+		}
+		m.routes[id] = state
+	}
+	for id, truck := range m.trucks {
+		m.recordPositionLocked(truck, m.routes[id])
+	}
+	for _, d := range state.Depots {
+		m.depots[d.Key] = &depotDocks{key: d.Key, capacity: d.Capacity, busy: d.Busy, queue: d.Queue}
+	}
+	m.trips = state.Trips
+	m.completions = state.Completions
+	m.timetable = state.Timetable
+	for route, next := range state.TimetableNext {
+		m.timetableNext[route] = next
+	}
+	for i := range state.Shipments {
+		shipment := state.Shipments[i]
+		m.shipments[shipment.ID] = &shipment
+		m.shipmentOrder = append(m.shipmentOrder, shipment.ID)
+	}
+	m.shipmentSeq = state.ShipmentSeq
+	m.history = state.History
+	m.historySeq = state.HistorySeq
+	m.recording = state.Recording
+	fleetSize.Set(float64(len(m.trucks)))
+	return nil
+}
+
+func saveRoute(r *routeState) savedRoute {
+	saved := savedRoute{
+		Waypoints:       r.waypoints,
+		Annotations:     r.annotations,
+		RouteName:       r.routeName,
+		LegIndex:        r.legIndex,
+		Loop:            r.loop,
+		Terminal:        r.terminal,
+		Parked:          r.parked,
+		HoldUntil:       r.holdUntil,
+		DwellStatus:     r.dwellStatus,
+		DwellUntil:      r.dwellUntil,
+		PendingDwell:    r.pendingDwell,
+		RoutesCompleted: r.routesCompleted,
+		DriveTime:       r.driveTime,
+		TripsCompleted:  r.tripsCompleted,
+		Returning:       r.returning,
+		Docked:          r.docked,
+		Detouring:       r.detouring,
+		ChargeStop:      r.chargeStop,
+		Charging:        r.charging,
+		OverLimit:       r.overLimit,
+		RouteStarted:    r.routeStarted,
+		ZoneCapped:      r.zoneCapped,
+		CruiseSpeed:     r.cruiseSpeed,
+		Handoff:         r.handoff,
+		Timetable:       r.timetable,
+	}
+	if t := r.trip; t != nil {
+		saved.Trip = &savedTrip{
+			Start:       t.start,
+			Origin:      t.origin,
+			Destination: t.destination,
+			Distance:    t.distance,
+			Moving:      t.moving,
+			Planned:     t.planned,
+			Energy:      t.energy,
+			Stops:       t.stops,
+			Arrived:     t.arrived,
+		}
+	}
+	return saved
+}
+
+func loadRoute(saved savedRoute) *routeState {
+	r := &routeState{
+		waypoints:       saved.Waypoints,
+		annotations:     saved.Annotations,
+		routeName:       saved.RouteName,
+		legIndex:        saved.LegIndex,
+		loop:            saved.Loop,
+		terminal:        saved.Terminal,
+		parked:          saved.Parked,
+		holdUntil:       saved.HoldUntil,
+		dwellStatus:     saved.DwellStatus,
+		dwellUntil:      saved.DwellUntil,
+		pendingDwell:    saved.PendingDwell,
+		routesCompleted: saved.RoutesCompleted,
+		driveTime:       saved.DriveTime,
+		tripsCompleted:  saved.TripsCompleted,
+		returning:       saved.Returning,
+		docked:          saved.Docked,
+		detouring:       saved.Detouring,
+		chargeStop:      saved.ChargeStop,
+		charging:        saved.Charging,
+		overLimit:       saved.OverLimit,
+		handoff:         saved.Handoff,
+		timetable:       saved.Timetable,
+		routeStarted:    saved.RouteStarted,
+		zoneCapped:      saved.ZoneCapped,
+		cruiseSpeed:     saved.CruiseSpeed,
+	}
+	if t := saved.Trip; t != nil {
+		r.trip = &tripProgress{
+			start:       t.Start,
+			origin:      t.Origin,
+			destination: t.Destination,
+			distance:    t.Distance,
+			moving:      t.Moving,
+			planned:     t.Planned,
+			energy:      t.Energy,
+			stops:       t.Stops,
+			arrived:     t.Arrived,
+		}
+	}
+	return r
+}
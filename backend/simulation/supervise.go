@@ -0,0 +1,175 @@
+package simulation
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+const (
+	defaultRestartBackoff    = time.Second
+	defaultMaxRestartBackoff = time.Minute
+	defaultSnapshotInterval  = 10 * time.Second
+)
+
+// SupervisionPolicy restarts the simulation loop when it dies from an internal error, i.e. a panic in a
+// simulation goroutine. The manager keeps an in-memory snapshot of its state and restarts from the latest
+// one. Without supervision a panic crashes the process as usual.
+type SupervisionPolicy struct {
+	// MaxRestarts is how many restarts are attempted over the manager's lifetime; 0 disables supervision.
+	// Once they are used up the simulation stays stopped and reports the failure.
+	MaxRestarts int
+	// Backoff is the wait before the first restart (default 1s). It doubles for each further restart, up
+	// to MaxBackoff (default 1m).
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// SnapshotInterval is how often, in wall time, the state is snapshotted for restarts (default 10s).
+	SnapshotInterval time.Duration
+	// OnFailure, if set, is called when the loop dies; restarting reports whether a restart will follow.
+	OnFailure func(err error, restarting bool)
+}
+
+// WithSupervision restarts the simulation loop according to policy when it panics.
+func (m *Manager) WithSupervision(policy SupervisionPolicy) *Manager {
+	if policy.Backoff <= 0 {
+		policy.Backoff = defaultRestartBackoff
+	}
+	if policy.MaxBackoff < policy.Backoff {
+		policy.MaxBackoff = max(defaultMaxRestartBackoff, policy.Backoff)
+	}
+	if policy.SnapshotInterval <= 0 {
+		policy.SnapshotInterval = defaultSnapshotInterval
+	}
+	m.mu.Lock()
+	m.supervision = policy
+	m.mu.Unlock()
+	return m
+}
+
+// Restarts returns how many times the simulation loop has been restarted after a failure.
+func (m *Manager) Restarts() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.restarts
+}
+
+// Failure returns the error that stopped the simulation for good once restarts are used up, or nil.
+func (m *Manager) Failure() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.failure
+}
+
+// recoverLoop is deferred by every simulation goroutine. Under supervision it turns a panic into a restart;
+// otherwise it leaves the panic alone.
+func (m *Manager) recoverLoop() {
+	m.mu.RLock()
+	supervised := m.supervision.MaxRestarts > 0
+	m.mu.RUnlock()
+	if !supervised {
+		return
+	}
+	if r := recover(); r != nil {
+		m.loopFailed(fmt.Errorf("simulation loop panicked: %v\n%s", r, debug.Stack()))
+	}
+}
+
+// loopFailed stops the remaining simulation goroutines and hands over to restart. Only the first failure
+// of a run is acted on.
+func (m *Manager) loopFailed(err error) {
+	m.mu.Lock()
+	if m.restartCancel != nil || !m.started {
+		m.mu.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.restartCancel = cancel
+	stop := m.cancel
+	m.mu.Unlock()
+
+	stop()
+	go m.restart(ctx, err)
+}
+
+// restart waits for the failed run's goroutines to exit, backs off, restores the last snapshot, and starts
+// the loop again. Stop cancels ctx to abandon the restart.
+func (m *Manager) restart(ctx context.Context, cause error) {
+	m.wg.Wait()
+
+	m.mu.Lock()
+	m.started = false
+	if m.ticker != nil {
+		m.ticker.Stop()
+	}
+	policy := m.supervision
+	restarting := m.restarts < policy.MaxRestarts
+	if !restarting {
+		m.failure = cause
+		m.restartCancel = nil
+	} else {
+		m.restarts++
+	}
+	attempt := m.restarts
+	snapshot := m.snapshot
+	m.mu.Unlock()
+
+	if policy.OnFailure != nil {
+		policy.OnFailure(cause, restarting)
+	}
+	if !restarting {
+		return
+	}
+	simulationRestarts.Inc()
+
+	backoff := policy.Backoff
+	for i := 1; i < attempt && backoff < policy.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(min(backoff, policy.MaxBackoff)):
+	}
+
+	if snapshot != nil {
+		if err := m.LoadState(bytes.NewReader(snapshot)); err != nil {
+			m.mu.Lock()
+			m.failure = fmt.Errorf("restore snapshot after %v: %w", cause, err)
+			m.restartCancel = nil
+			m.mu.Unlock()
+			return
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.restartCancel = nil
+	if ctx.Err() != nil {
+		return
+	}
+	m.startLocked(m.baseCtx)
+}
+
+// runSnapshots keeps m.snapshot up to date for restarts while the simulation runs under supervision.
+func (m *Manager) runSnapshots(interval time.Duration) {
+	defer m.wg.Done()
+	defer m.recoverLoop()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		var buf bytes.Buffer
+		if err := m.SaveState(&buf); err == nil {
+			m.mu.Lock()
+			m.snapshot = buf.Bytes()
+			m.mu.Unlock()
+		}
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
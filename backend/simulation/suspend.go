@@ -0,0 +1,89 @@
+package simulation
+
+import (
+	"sort"
+	"time"
+)
+
+// suspendIdleLocked takes a stationary truck out of per-tick processing when it will stay put for at
+// least Config.SuspendAfter. Parked trucks sleep until an override wakes them; held and dwelling trucks
+// wake on the tick their hold or dwell ends, so suspension does not change when they move again.
+func (m *Manager) suspendIdleLocked(truck *Truck, state *routeState) {
+	if m.cfg.SuspendAfter <= 0 {
+		return
+	}
+
+	var wake time.Time
+	switch {
+	case len(state.waypoints) < 2 || state.parked:
+	case m.clock.Before(state.holdUntil):
+		wake = state.holdUntil
+	case state.dwellStatus != "" && truck.Status == state.dwellStatus:
+		wake = state.dwellUntil
+	default:
+		// Queued and charging trucks depend on other trucks and on each tick.
+		return
+	}
+	if !wake.IsZero() && wake.Sub(m.clock) < m.cfg.SuspendAfter {
+		return
+	}
+
+	state.suspended = true
+	state.wakeAt = wake
+	m.suspended[truck.ID] = truck
+	m.unshardLocked(truck)
+	suspendedTrucks.Set(float64(len(m.suspended)))
+}
+
+// wakeDueLocked resumes suspended trucks whose hold or dwell ends by the current clock.
+func (m *Manager) wakeDueLocked() {
+	var due []string
+	for id := range m.suspended {
+		if wake := m.routes[id].wakeAt; !wake.IsZero() && !m.clock.Before(wake) {
+			due = append(due, id)
+		}
+	}
+	sort.Strings(due)
+	for _, id := range due {
+		m.wakeLocked(id)
+		// The truck is awake for the tick that woke it, as if it had never slept.
+		m.routes[id].steppedAt = m.clock.Add(-m.tickDuration())
+	}
+}
+
+// wakeLocked puts a suspended truck back into per-tick processing. It is a no-op for other trucks.
+func (m *Manager) wakeLocked(id string) {
+	truck, ok := m.suspended[id]
+	if !ok {
+		return
+	}
+	delete(m.suspended, id)
+	if state := m.routes[id]; state != nil {
+		state.suspended = false
+		state.wakeAt = time.Time{}
+		// Time asleep is not owed to the truck, or its first step would cover the whole nap.
+		state.steppedAt = m.clock
+	}
+	m.assignShardLocked(truck)
+	suspendedTrucks.Set(float64(len(m.suspended)))
+}
+
+// unshardLocked removes a truck from its worker's shard.
+func (m *Manager) unshardLocked(truck *Truck) {
+	for i, shard := range m.shards {
+		for j, t := range shard {
+			if t == truck {
+				m.shards[i] = append(shard[:j:j], shard[j+1:]...)
+				m.shardEpoch++
+				return
+			}
+		}
+	}
+}
+
+// SuspendedTrucks returns the number of trucks currently suspended.
+func (m *Manager) SuspendedTrucks() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.suspended)
+}
@@ -0,0 +1,168 @@
+package simulation
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxTimetableRecords bounds how many timetabled departures the manager keeps; the oldest are dropped first.
+const maxTimetableRecords = 10000
+
+// Timetable runs a catalog route to a schedule, transit style. Trucks starting the route at its first
+// waypoint each take the next departure and wait there until it is due. The trucks are those built on the
+// route through Config.RouteAssignments, plus those on a looping route each time they get back to the
+// first waypoint. A departure that falls due with no truck waiting is missed. A truck that finds no
+// departures left stays parked at the first waypoint.
+type Timetable struct {
+	// Departures are when trips leave the first waypoint, on the simulated clock, earliest first.
+	Departures []time.Time
+	// StopTimes[i] is the scheduled running time from departure to waypoint i. Waypoints past the end of
+	// StopTimes are unscheduled.
+	StopTimes []time.Duration
+}
+
+func (t *Timetable) validate(waypoints int) error {
+	switch {
+	case len(t.Departures) == 0:
+		return fmt.Errorf("timetable needs at least one departure")
+	case len(t.StopTimes) > waypoints:
+		return fmt.Errorf("timetable has %d stop times for %d waypoints", len(t.StopTimes), waypoints)
+	}
+	for i := 1; i < len(t.Departures); i++ {
+		if t.Departures[i].Before(t.Departures[i-1]) {
+			return fmt.Errorf("timetable departure %d is earlier than the one before it", i)
+		}
+	}
+	for i, d := range t.StopTimes {
+		if d < 0 || i > 0 && d < t.StopTimes[i-1] {
+			return fmt.Errorf("timetable stop time %d is earlier than the one before it", i)
+		}
+	}
+	return nil
+}
+
+// scheduledAt returns when the trip leaving at departure is due at waypoint i, if the stop is scheduled.
+func (t *Timetable) scheduledAt(departure time.Time, i int) (time.Time, bool) {
+	if i <= 0 || i >= len(t.StopTimes) {
+		return time.Time{}, false
+	}
+	return departure.Add(t.StopTimes[i]), true
+}
+
+// TimetableRecord is one scheduled departure on a timetabled route and how it ran.
+type TimetableRecord struct {
+	Route string
+	// Departure is the index of the departure in the route's timetable.
+	Departure          int
+	ScheduledDeparture time.Time
+	// TruckID is the truck that ran the trip. It is empty when the departure was missed.
+	TruckID string
+	Missed  bool
+	// ActualDeparture is when the truck left the first waypoint, to within a tick. It is zero when the
+	// truck never left.
+	ActualDeparture time.Time
+	Arrivals        []StopArrival
+	// Completed is set when the truck reached the last waypoint. Trips cut short by a new route are kept
+	// without it.
+	Completed bool
+}
+
+// StopArrival is a truck arriving at a scheduled stop of a timetabled trip.
+type StopArrival struct {
+	Waypoint  int
+	Scheduled time.Time
+	Actual    time.Time
+}
+
+// timetabledTrip is the timetabled departure a truck has taken.
+type timetabledTrip struct {
+	Record    TimetableRecord
+	StopTimes []time.Duration
+}
+
+// takeDepartureLocked gives a truck waiting at the first waypoint of a timetabled catalog route the next
+// departure and holds it until then. Departures already due with no truck waiting are recorded as missed.
+func (m *Manager) takeDepartureLocked(truck *Truck, state *routeState) {
+	route, ok := m.namedRouteLocked(state.routeName)
+	if !ok || route.Timetable == nil {
+		return
+	}
+	departures := route.Timetable.Departures
+	next := m.timetableNext[route.Name]
+	for next < len(departures) && departures[next].Before(m.clock) {
+		m.recordTimetableLocked(TimetableRecord{Route: route.Name, Departure: next, ScheduledDeparture: departures[next], Missed: true})
+		next++
+	}
+	if next == len(departures) {
+		m.timetableNext[route.Name] = next
+		state.parked = true
+		return
+	}
+	m.timetableNext[route.Name] = next + 1
+	state.timetable = &timetabledTrip{
+		Record:    TimetableRecord{Route: route.Name, Departure: next, ScheduledDeparture: departures[next], TruckID: truck.ID},
+		StopTimes: route.Timetable.StopTimes,
+	}
+	if departures[next].After(state.holdUntil) {
+		state.holdUntil = departures[next]
+	}
+}
+
+// departTimetableLocked notes when a truck on a timetabled trip first moves.
+func (m *Manager) departTimetableLocked(state *routeState) {
+	if state.timetable != nil && state.timetable.Record.ActualDeparture.IsZero() {
+		state.timetable.Record.ActualDeparture = m.clock
+	}
+}
+
+// scheduledArrival returns when the truck's timetabled trip is due at waypoint i, if it is scheduled.
+func (r *routeState) scheduledArrival(i int) (time.Time, bool) {
+	if r.timetable == nil || r.timetable.Record.ActualDeparture.IsZero() {
+		return time.Time{}, false
+	}
+	tt := Timetable{StopTimes: r.timetable.StopTimes}
+	return tt.scheduledAt(r.timetable.Record.ScheduledDeparture, i)
+}
+
+// arriveTimetableLocked records the truck reaching the waypoint it was heading for on a timetabled trip.
+// The last waypoint completes the trip.
+func (m *Manager) arriveTimetableLocked(state *routeState, last bool) {
+	if state.timetable == nil {
+		return
+	}
+	if scheduled, ok := state.scheduledArrival(state.legIndex); ok {
+		state.timetable.Record.Arrivals = append(state.timetable.Record.Arrivals, StopArrival{Waypoint: state.legIndex, Scheduled: scheduled, Actual: m.clock})
+	}
+	state.timetable.Record.Completed = last
+}
+
+// endTimetableLocked records the truck's timetabled trip, if any, as it stands.
+func (m *Manager) endTimetableLocked(state *routeState) {
+	if state == nil || state.timetable == nil {
+		return
+	}
+	m.recordTimetableLocked(state.timetable.Record)
+	state.timetable = nil
+}
+
+func (m *Manager) recordTimetableLocked(record TimetableRecord) {
+	m.timetable = append(m.timetable, record)
+	if len(m.timetable) > maxTimetableRecords {
+		m.timetable = append(m.timetable[:0], m.timetable[len(m.timetable)-maxTimetableRecords:]...)
+	}
+}
+
+// TimetableRecords returns the departures recorded on timetabled routes in the order they finished,
+// optionally limited to one route. Trips still under way are not included.
+func (m *Manager) TimetableRecords(route string) []TimetableRecord {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	records := make([]TimetableRecord, 0, len(m.timetable))
+	for _, r := range m.timetable {
+		if route == "" || r.Route == route {
+			records = append(records, r)
+		}
+	}
+	return records
+}
@@ -0,0 +1,110 @@
+package simulation
+
+import "time"
+
+// DefaultTrackDepth is how many recent reported positions each truck keeps for its track and motion
+// trails unless Config.TrackDepth says otherwise. A stationary truck adds no positions.
+const DefaultTrackDepth = 64
+
+// TrackPoint is a position a truck reported and the simulated time it reported it.
+type TrackPoint struct {
+	Time time.Time
+	Lat  float64
+	Lon  float64
+}
+
+// trackEntry is a ring slot, holding the time as Unix nanoseconds to keep the ring small.
+type trackEntry struct {
+	at int64
+	p  Point
+}
+
+// trail is a ring of a truck's most recent reported positions, allocated on the first position. It is not
+// saved with the state.
+type trail struct {
+	points []trackEntry
+	next   int
+	size   int
+}
+
+// add appends p, reported at at, to a ring of depth positions unless the truck has not moved since the
+// last position.
+func (t *trail) add(p Point, at time.Time, depth int) {
+	if t.points == nil {
+		t.points = make([]trackEntry, depth)
+	}
+	n := len(t.points)
+	if t.size > 0 && t.points[(t.next+n-1)%n].p == p {
+		return
+	}
+	t.points[t.next] = trackEntry{at: at.UnixNano(), p: p}
+	t.next = (t.next + 1) % n
+	t.size = min(t.size+1, n)
+}
+
+// at returns the entry back positions before the newest.
+func (t *trail) at(back int) trackEntry {
+	n := len(t.points)
+	return t.points[(t.next-1-back+2*n)%n]
+}
+
+// recent returns up to k positions, keeping every nth counting back from the newest, oldest first.
+func (t *trail) recent(k, every int) []Point {
+	n := min(k, (t.size+every-1)/every)
+	points := make([]Point, n)
+	for i := 0; i < n; i++ {
+		points[i] = t.at((n - 1 - i) * every).p
+	}
+	return points
+}
+
+// track returns up to k positions with their times, oldest first.
+func (t *trail) track(k int) []TrackPoint {
+	n := min(k, t.size)
+	points := make([]TrackPoint, n)
+	for i := 0; i < n; i++ {
+		e := t.at(n - 1 - i)
+		points[i] = TrackPoint{Time: time.Unix(0, e.at).UTC(), Lat: e.p.Lat, Lon: e.p.Lon}
+	}
+	return points
+}
+
+// TrackDepth returns how many recent positions each truck keeps.
+func (m *Manager) TrackDepth() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.cfg.TrackDepth
+}
+
+// Trails returns recent reported positions for each truck in the order of ids, for drawing short motion
+// trails: up to k positions, oldest first, keeping every nth update counting back from the newest. Trails
+// cover at most the last TrackDepth updates. Unknown trucks get nil.
+func (m *Manager) Trails(ids []string, k, every int) [][]Point {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	trails := make([][]Point, len(ids))
+	if k <= 0 || every <= 0 {
+		return trails
+	}
+	for i, id := range ids {
+		if state := m.routes[id]; state != nil {
+			trails[i] = state.trail.recent(k, every)
+		}
+	}
+	return trails
+}
+
+// Track returns a truck's recent track: up to its last k reported positions with the simulated times they
+// were reported, oldest first. A truck that stood still keeps the time it arrived. It reports false for an
+// unknown truck.
+func (m *Manager) Track(id string, k int) ([]TrackPoint, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	state := m.routes[id]
+	if state == nil {
+		return nil, false
+	}
+	return state.trail.track(max(k, 0)), true
+}
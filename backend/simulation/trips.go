@@ -0,0 +1,138 @@
+package simulation
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxTripRecords bounds how many completed trips the manager keeps; the oldest are dropped first.
+const maxTripRecords = 10000
+
+// Trip is a completed journey from departure at a route's origin to the end of the dwell at its
+// destination.
+type Trip struct {
+	ID          string
+	TruckID     string
+	RouteID     string
+	Start       time.Time
+	End         time.Time
+	Origin      Point
+	Destination Point
+	// DistanceMeters is the distance driven, including any detours.
+	DistanceMeters float64
+	// AverageSpeed is the distance over the time spent moving, in metres per second.
+	AverageSpeed float64
+	// Stops counts the times the truck stopped before reaching its destination, e.g. to refuel or rest.
+	Stops int
+	// PlannedDuration is the undisturbed trip time: the planned route at the truck's speed plus the unloading
+	// dwell.
+	PlannedDuration time.Duration
+	// EnergyUsed is the fuel in litres, or for electric trucks the energy in kWh, consumed on the trip.
+	EnergyUsed float64
+	// Efficiency is the rated consumption for the distance divided by EnergyUsed; 1 means the truck drove at
+	// its rated consumption and lower values mean it burned more.
+	Efficiency float64
+}
+
+type tripProgress struct {
+	start       time.Time
+	origin      Point
+	destination Point
+	distance    float64
+	moving      time.Duration
+	planned     time.Duration
+	energy      float64
+	stops       int
+	arrived     bool
+}
+
+// recordMovementLocked opens a trip on the first moving tick and accumulates distance and driving time.
+// It must run after the truck has moved.
+func (m *Manager) recordMovementLocked(truck *Truck, state *routeState, from Point, distance float64) {
+	if state.trip == nil {
+		state.trip = &tripProgress{start: m.clock.Add(-state.step), origin: from}
+		if truck.Speed > 0 {
+			seconds := (distance + remainingDistance(truck, state)) / truck.Speed
+			state.trip.planned = time.Duration(seconds*float64(time.Second)) + m.cfg.Dwell[TruckStatusUnloading]
+		}
+	}
+	state.trip.distance += distance
+	state.trip.moving += state.step
+}
+
+// recordStopLocked counts a stop when a truck that was moving comes to rest before its destination.
+func (m *Manager) recordStopLocked(truck *Truck, state *routeState, wasMoving bool) {
+	if state.trip != nil && !state.trip.arrived && wasMoving && truck.Status != TruckStatusEnRoute {
+		state.trip.stops++
+	}
+}
+
+// recordEnergyLocked adds fuel or battery energy used to the open trip.
+func (m *Manager) recordEnergyLocked(state *routeState, used float64) {
+	if state.trip != nil {
+		state.trip.energy += used
+	}
+}
+
+// markArrivalLocked flags the open trip as having reached its destination; it completes once the arrival
+// dwell finishes.
+func (m *Manager) markArrivalLocked(state *routeState, at Point) {
+	if state.trip == nil {
+		return
+	}
+	state.trip.arrived = true
+	state.trip.destination = at
+}
+
+// completeTripLocked records the open trip if it has arrived.
+func (m *Manager) completeTripLocked(truck *Truck, state *routeState) {
+	progress := state.trip
+	if progress == nil || !progress.arrived {
+		return
+	}
+	state.trip = nil
+	state.tripsCompleted++
+
+	trip := Trip{
+		ID:             fmt.Sprintf("%s-trip-%d", truck.ID, state.tripsCompleted),
+		TruckID:        truck.ID,
+		RouteID:        truck.RouteID,
+		Start:          progress.start,
+		End:            m.clock,
+		Origin:         progress.origin,
+		Destination:    progress.destination,
+		DistanceMeters: progress.distance,
+		Stops:          progress.stops,
+	}
+	trip.PlannedDuration = progress.planned
+	trip.EnergyUsed = progress.energy
+	if progress.moving > 0 {
+		trip.AverageSpeed = progress.distance / progress.moving.Seconds()
+	}
+	if progress.energy > 0 {
+		rated := m.cfg.FuelPerKm
+		if truck.Electric {
+			rated = m.cfg.EnergyPerKm
+		}
+		trip.Efficiency = progress.distance / 1000 * rated / progress.energy
+	}
+
+	m.trips = append(m.trips, trip)
+	if len(m.trips) > maxTripRecords {
+		m.trips = append(m.trips[:0], m.trips[len(m.trips)-maxTripRecords:]...)
+	}
+}
+
+// Trips returns completed trips in completion order, optionally limited to one truck.
+func (m *Manager) Trips(truckID string) []Trip {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	trips := make([]Trip, 0, len(m.trips))
+	for _, trip := range m.trips {
+		if truckID == "" || trip.TruckID == truckID {
+			trips = append(trips, trip)
+		}
+	}
+	return trips
+}
@@ -0,0 +1,108 @@
+package simulation
+
+import (
+	"math"
+	"sort"
+)
+
+// TruckType is a vehicle class with its own speed range and route preferences.
+type TruckType string
+
+const (
+	TruckTypeVan    TruckType = "van"
+	TruckTypeBox    TruckType = "box"
+	TruckTypeSemi   TruckType = "semi"
+	TruckTypeTanker TruckType = "tanker"
+)
+
+// TruckProfile describes how trucks of one type drive.
+type TruckProfile struct {
+	// SpeedMin and SpeedMax bound the cruising speed in metres per second.
+	SpeedMin float64
+	SpeedMax float64
+	// WaypointsPerRoute overrides Config.WaypointsPerRoute, e.g. vans make more delivery stops.
+	WaypointsPerRoute int
+	// MaxRouteDistance keeps trucks on end points within this many metres of the route start when any
+	// are in reach. Zero means any end point.
+	MaxRouteDistance float64
+}
+
+// DefaultTruckProfiles are used for types in Config.TypeMix without an entry in Config.TruckProfiles.
+var DefaultTruckProfiles = map[TruckType]TruckProfile{
+	TruckTypeVan:    {SpeedMin: 8, SpeedMax: 20, WaypointsPerRoute: 6, MaxRouteDistance: 50000},
+	TruckTypeBox:    {SpeedMin: 10, SpeedMax: 22, WaypointsPerRoute: 4, MaxRouteDistance: 200000},
+	TruckTypeSemi:   {SpeedMin: 18, SpeedMax: 27, WaypointsPerRoute: 2},
+	TruckTypeTanker: {SpeedMin: 15, SpeedMax: 24, WaypointsPerRoute: 2, MaxRouteDistance: 300000},
+}
+
+// profileLocked returns the profile for typ. Untyped trucks and types without a profile use the fleet-wide
+// settings from Config.
+func (m *Manager) profileLocked(typ TruckType) TruckProfile {
+	fleet := TruckProfile{SpeedMin: m.cfg.SpeedMin, SpeedMax: m.cfg.SpeedMax, WaypointsPerRoute: m.cfg.WaypointsPerRoute}
+	if typ == "" {
+		return fleet
+	}
+	profile, ok := m.cfg.TruckProfiles[typ]
+	if !ok {
+		profile, ok = DefaultTruckProfiles[typ]
+	}
+	if !ok {
+		return fleet
+	}
+	if profile.SpeedMin <= 0 {
+		profile.SpeedMin = fleet.SpeedMin
+	}
+	if profile.SpeedMax < profile.SpeedMin {
+		profile.SpeedMax = profile.SpeedMin
+	}
+	if profile.WaypointsPerRoute < 2 {
+		profile.WaypointsPerRoute = fleet.WaypointsPerRoute
+	}
+	return profile
+}
+
+// truckTypesLocked assigns a type to each of the NumTrucks trucks in proportion to Config.TypeMix, using
+// largest remainders so the counts are exact when the mix sums to NumTrucks. The order is shuffled so types
+// are spread across truck IDs. It returns nil when no mix is configured.
+func (m *Manager) truckTypesLocked() []TruckType {
+	total := 0
+	names := make([]TruckType, 0, len(m.cfg.TypeMix))
+	for typ, weight := range m.cfg.TypeMix {
+		if weight > 0 {
+			total += weight
+			names = append(names, typ)
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+
+	counts := make([]int, len(names))
+	remainders := make([]float64, len(names))
+	assigned := 0
+	for i, typ := range names {
+		share := float64(m.cfg.NumTrucks) * float64(m.cfg.TypeMix[typ]) / float64(total)
+		counts[i] = int(math.Floor(share))
+		remainders[i] = share - float64(counts[i])
+		assigned += counts[i]
+	}
+	order := make([]int, len(names))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+	for i := 0; assigned < m.cfg.NumTrucks; i++ {
+		counts[order[i%len(order)]]++
+		assigned++
+	}
+
+	types := make([]TruckType, 0, m.cfg.NumTrucks)
+	for i, typ := range names {
+		for j := 0; j < counts[i]; j++ {
+			types = append(types, typ)
+		}
+	}
+	m.rand.Shuffle(len(types), func(i, j int) { types[i], types[j] = types[j], types[i] })
+	return types
+}
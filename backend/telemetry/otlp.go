@@ -0,0 +1,175 @@
+// Package telemetry pushes the Prometheus metrics registry to OpenTelemetry collectors over OTLP.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+
+	"orbit/backend/version"
+)
+
+const scopeName = "orbit/backend/telemetry"
+
+// OTLPOptions configures the OTLP push exporter. An empty Endpoint falls back to the standard
+// OTEL_EXPORTER_OTLP_* environment variables.
+type OTLPOptions struct {
+	Endpoint string
+	Interval time.Duration
+}
+
+// StartOTLP exports every metric in the gatherer on a fixed interval. The returned function flushes pending
+// data and stops the exporter.
+func StartOTLP(ctx context.Context, gatherer prometheus.Gatherer, opts OTLPOptions) (func(context.Context) error, error) {
+	var exporterOpts []otlpmetrichttp.Option
+	if opts.Endpoint != "" {
+		exporterOpts = append(exporterOpts, otlpmetrichttp.WithEndpointURL(opts.Endpoint))
+	}
+	exporter, err := otlpmetrichttp.New(ctx, exporterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP exporter: %w", err)
+	}
+
+	var readerOpts []sdkmetric.PeriodicReaderOption
+	if opts.Interval > 0 {
+		readerOpts = append(readerOpts, sdkmetric.WithInterval(opts.Interval))
+	}
+	readerOpts = append(readerOpts, sdkmetric.WithProducer(NewProducer(gatherer)))
+
+	res := resource.NewSchemaless(
+		attribute.String("service.name", "orbit"),
+		attribute.String("service.version", version.Get().Version),
+	)
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, readerOpts...)),
+	)
+	return provider.Shutdown, nil
+}
+
+// Producer converts a Prometheus gatherer into OpenTelemetry metric data so the same counters, gauges, and
+// histograms reach both exporters.
+type Producer struct {
+	gatherer prometheus.Gatherer
+	start    time.Time
+}
+
+// NewProducer creates a producer whose cumulative series start now.
+func NewProducer(gatherer prometheus.Gatherer) *Producer {
+	return &Producer{gatherer: gatherer, start: time.Now()}
+}
+
+// Produce gathers the registry and converts each family. Families of unsupported types are skipped.
+func (p *Producer) Produce(context.Context) ([]metricdata.ScopeMetrics, error) {
+	families, err := p.gatherer.Gather()
+	if err != nil && len(families) == 0 {
+		return nil, err
+	}
+
+	now := time.Now()
+	scope := metricdata.ScopeMetrics{Scope: instrumentation.Scope{Name: scopeName}}
+	for _, family := range families {
+		if m, ok := p.convert(family, now); ok {
+			scope.Metrics = append(scope.Metrics, m)
+		}
+	}
+	return []metricdata.ScopeMetrics{scope}, nil
+}
+
+func (p *Producer) convert(family *dto.MetricFamily, now time.Time) (metricdata.Metrics, bool) {
+	m := metricdata.Metrics{Name: family.GetName(), Description: family.GetHelp()}
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		sum := metricdata.Sum[float64]{Temporality: metricdata.CumulativeTemporality, IsMonotonic: true}
+		for _, metric := range family.GetMetric() {
+			sum.DataPoints = append(sum.DataPoints, metricdata.DataPoint[float64]{
+				Attributes: labels(metric),
+				StartTime:  p.startTime(metric.GetCounter().GetCreatedTimestamp().AsTime()),
+				Time:       now,
+				Value:      metric.GetCounter().GetValue(),
+			})
+		}
+		m.Data = sum
+	case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+		gauge := metricdata.Gauge[float64]{}
+		for _, metric := range family.GetMetric() {
+			value := metric.GetGauge().GetValue()
+			if family.GetType() == dto.MetricType_UNTYPED {
+				value = metric.GetUntyped().GetValue()
+			}
+			gauge.DataPoints = append(gauge.DataPoints, metricdata.DataPoint[float64]{
+				Attributes: labels(metric),
+				Time:       now,
+				Value:      value,
+			})
+		}
+		m.Data = gauge
+	case dto.MetricType_HISTOGRAM:
+		histogram := metricdata.Histogram[float64]{Temporality: metricdata.CumulativeTemporality}
+		for _, metric := range family.GetMetric() {
+			h := metric.GetHistogram()
+			point := metricdata.HistogramDataPoint[float64]{
+				Attributes: labels(metric),
+				StartTime:  p.startTime(h.GetCreatedTimestamp().AsTime()),
+				Time:       now,
+				Count:      h.GetSampleCount(),
+				Sum:        h.GetSampleSum(),
+			}
+			// Prometheus buckets are cumulative; OTLP wants per-bucket counts with an implicit +Inf bucket.
+			var previous uint64
+			for _, bucket := range h.GetBucket() {
+				point.Bounds = append(point.Bounds, bucket.GetUpperBound())
+				point.BucketCounts = append(point.BucketCounts, bucket.GetCumulativeCount()-previous)
+				previous = bucket.GetCumulativeCount()
+			}
+			point.BucketCounts = append(point.BucketCounts, h.GetSampleCount()-previous)
+			histogram.DataPoints = append(histogram.DataPoints, point)
+		}
+		m.Data = histogram
+	case dto.MetricType_SUMMARY:
+		summary := metricdata.Summary{}
+		for _, metric := range family.GetMetric() {
+			s := metric.GetSummary()
+			point := metricdata.SummaryDataPoint{
+				Attributes: labels(metric),
+				StartTime:  p.startTime(s.GetCreatedTimestamp().AsTime()),
+				Time:       now,
+				Count:      s.GetSampleCount(),
+				Sum:        s.GetSampleSum(),
+			}
+			for _, q := range s.GetQuantile() {
+				point.QuantileValues = append(point.QuantileValues, metricdata.QuantileValue{Quantile: q.GetQuantile(), Value: q.GetValue()})
+			}
+			summary.DataPoints = append(summary.DataPoints, point)
+		}
+		m.Data = summary
+	default:
+		return m, false
+	}
+	return m, true
+}
+
+// startTime prefers the series' created timestamp and falls back to when the producer started.
+func (p *Producer) startTime(created time.Time) time.Time {
+	if created.Unix() <= 0 {
+		return p.start
+	}
+	return created
+}
+
+func labels(metric *dto.Metric) attribute.Set {
+	kvs := make([]attribute.KeyValue, 0, len(metric.GetLabel()))
+	for _, label := range metric.GetLabel() {
+		kvs = append(kvs, attribute.String(label.GetName(), label.GetValue()))
+	}
+	return attribute.NewSet(kvs...)
+}
@@ -0,0 +1,62 @@
+package telemetry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestProducerConvertsPrometheusFamilies(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_events_total", Help: "Events."}, []string{"kind"})
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_trucks", Help: "Trucks."})
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_latency_seconds", Help: "Latency.", Buckets: []float64{1, 2}})
+	registry.MustRegister(counter, gauge, histogram)
+
+	counter.WithLabelValues("move").Add(3)
+	gauge.Set(42)
+	for _, v := range []float64{0.5, 1.5, 1.5, 5} {
+		histogram.Observe(v)
+	}
+
+	scopes, err := NewProducer(registry).Produce(context.Background())
+	if err != nil {
+		t.Fatalf("produce: %v", err)
+	}
+	if len(scopes) != 1 || len(scopes[0].Metrics) != 3 {
+		t.Fatalf("expected three metrics, got %+v", scopes)
+	}
+
+	byName := map[string]metricdata.Aggregation{}
+	for _, m := range scopes[0].Metrics {
+		byName[m.Name] = m.Data
+	}
+
+	sum, ok := byName["test_events_total"].(metricdata.Sum[float64])
+	if !ok || !sum.IsMonotonic || sum.DataPoints[0].Value != 3 {
+		t.Fatalf("unexpected counter conversion: %+v", byName["test_events_total"])
+	}
+	if kind, _ := sum.DataPoints[0].Attributes.Value("kind"); kind.AsString() != "move" {
+		t.Fatalf("expected kind label to carry over, got %v", kind)
+	}
+	if g, ok := byName["test_trucks"].(metricdata.Gauge[float64]); !ok || g.DataPoints[0].Value != 42 {
+		t.Fatalf("unexpected gauge conversion: %+v", byName["test_trucks"])
+	}
+
+	h, ok := byName["test_latency_seconds"].(metricdata.Histogram[float64])
+	if !ok {
+		t.Fatalf("expected a histogram, got %T", byName["test_latency_seconds"])
+	}
+	point := h.DataPoints[0]
+	want := []uint64{1, 2, 1}
+	if point.Count != 4 || len(point.BucketCounts) != len(want) {
+		t.Fatalf("unexpected histogram point: %+v", point)
+	}
+	for i := range want {
+		if point.BucketCounts[i] != want[i] {
+			t.Fatalf("expected per-bucket counts %v, got %v", want, point.BucketCounts)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Package version reports build metadata for the Orbit binaries.
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Version is the release version, set at build time with
+// -ldflags "-X orbit/backend/version.Version=v1.2.3".
+var Version = "dev"
+
+// Info describes the running build.
+type Info struct {
+	Version   string
+	Revision  string
+	GoVersion string
+	// Platform is the GOOS/GOARCH pair the binary was built for.
+	Platform string
+}
+
+// String formats the build for -version output, e.g. "orbit v1.2.3 (abc123, go1.22.1, linux/arm64)".
+func (i Info) String() string {
+	return fmt.Sprintf("orbit %s (%s, %s, %s)", i.Version, i.Revision, i.GoVersion, i.Platform)
+}
+
+// Get returns the build metadata, filling the VCS revision from the embedded build info when available.
+func Get() Info {
+	info := Info{Version: Version, Revision: "unknown", GoVersion: runtime.Version(), Platform: runtime.GOOS + "/" + runtime.GOARCH}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			if setting.Key == "vcs.revision" {
+				info.Revision = setting.Value
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,30 @@
+package version
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestGetReportsVersionAndGoRuntime(t *testing.T) {
+	previous := Version
+	Version = "v9.9.9"
+	defer func() { Version = previous }()
+
+	info := Get()
+	if info.Version != "v9.9.9" {
+		t.Fatalf("expected injected version, got %q", info.Version)
+	}
+	if info.GoVersion != runtime.Version() {
+		t.Fatalf("expected go version %q, got %q", runtime.Version(), info.GoVersion)
+	}
+	if info.Revision == "" {
+		t.Fatalf("expected a revision placeholder")
+	}
+	if want := runtime.GOOS + "/" + runtime.GOARCH; info.Platform != want {
+		t.Fatalf("expected platform %q, got %q", want, info.Platform)
+	}
+	if got := info.String(); !strings.HasPrefix(got, "orbit v9.9.9 (") {
+		t.Fatalf("unexpected version string %q", got)
+	}
+}
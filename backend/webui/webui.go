@@ -0,0 +1,25 @@
+// Package webui embeds the built dashboard so that a single binary can serve it without a separate web
+// server. `make ui` copies web/dist here before the Go build; a binary built without it holds only a
+// placeholder and Assets reports no dashboard.
+package webui
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed all:dist
+var dist embed.FS
+
+// Assets returns the embedded dashboard, rooted at its index.html, and false when the binary was built
+// without one.
+func Assets() (fs.FS, bool) {
+	assets, err := fs.Sub(dist, "dist")
+	if err != nil {
+		return nil, false
+	}
+	if _, err := fs.Stat(assets, "index.html"); err != nil {
+		return nil, false
+	}
+	return assets, true
+}
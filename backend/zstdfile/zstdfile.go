@@ -0,0 +1,73 @@
+// Package zstdfile reads and writes recordings and state snapshots that may be Zstandard-compressed.
+// Readers detect compression from the zstd frame magic, so compressed and plain JSON files load alike.
+// Writers compress when asked, which by convention is when the file name ends in .zst.
+package zstdfile
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Ext is the file name extension of compressed files.
+const Ext = ".zst"
+
+// magic starts every zstd frame.
+var magic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// Compressed reports whether a file named path should be written compressed.
+func Compressed(path string) bool {
+	return strings.HasSuffix(path, Ext)
+}
+
+// NewReader returns a reader of r's contents, decompressing them as they are read when r starts with a
+// zstd frame. It reports whether r was compressed.
+func NewReader(r io.Reader) (io.ReadCloser, bool, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(len(magic))
+	if err != nil && err != io.EOF {
+		return nil, false, err
+	}
+	if !bytes.Equal(head, magic) {
+		return io.NopCloser(br), false, nil
+	}
+	dec, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, false, err
+	}
+	return dec.IOReadCloser(), true, nil
+}
+
+// NewWriter returns a writer to w that compresses what is written to it when compress is set. Close
+// flushes the final frame but does not close w.
+func NewWriter(w io.Writer, compress bool) (io.WriteCloser, error) {
+	if !compress {
+		return nopWriteCloser{w}, nil
+	}
+	return zstd.NewWriter(w)
+}
+
+// ReadFile reads the named file, decompressing it when it is compressed.
+func ReadFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, _, err := NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
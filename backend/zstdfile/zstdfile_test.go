@@ -0,0 +1,57 @@
+package zstdfile
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRoundTripDetectsCompression(t *testing.T) {
+	payload := []byte(strings.Repeat(`{"ID":"truck-0001","Lat":47.6,"Lon":-122.3}`+"\n", 1000))
+
+	for _, compress := range []bool{false, true} {
+		var buf bytes.Buffer
+		w, err := NewWriter(&buf, compress)
+		if err != nil {
+			t.Fatalf("new writer: %v", err)
+		}
+		if _, err := w.Write(payload); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("close: %v", err)
+		}
+		if compress && buf.Len() >= len(payload)/10 {
+			t.Fatalf("expected repetitive JSON to compress well, got %d of %d bytes", buf.Len(), len(payload))
+		}
+
+		path := filepath.Join(t.TempDir(), "recording.json")
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+		got, err := ReadFile(path)
+		if err != nil || !bytes.Equal(got, payload) {
+			t.Fatalf("compress=%v: expected the payload back, got %d bytes, %v", compress, len(got), err)
+		}
+
+		r, compressed, err := NewReader(bytes.NewReader(buf.Bytes()))
+		if err != nil || compressed != compress {
+			t.Fatalf("expected compressed=%v, got %v, %v", compress, compressed, err)
+		}
+		if got, _ := io.ReadAll(r); !bytes.Equal(got, payload) {
+			t.Fatalf("expected the payload back from the stream")
+		}
+	}
+
+	if r, compressed, err := NewReader(bytes.NewReader(nil)); err != nil || compressed {
+		t.Fatalf("expected an empty file to read as plain, got %v, %v", compressed, err)
+	} else if got, _ := io.ReadAll(r); len(got) != 0 {
+		t.Fatalf("expected nothing from an empty file, got %q", got)
+	}
+	if !Compressed("fleet.json.zst") || Compressed("fleet.json") {
+		t.Fatalf("expected only .zst names to be written compressed")
+	}
+}
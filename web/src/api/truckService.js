@@ -66,6 +66,20 @@ export async function updateSimulationConfig(body) {
   return response.json()
 }
 
+// reconnectDelay honours the retry hint the server puts in the close reason when it sheds load or restarts,
+// and otherwise backs off exponentially with full jitter so that dashboards do not reconnect in lockstep.
+export function reconnectDelay(event, retryCount) {
+  try {
+    const hint = JSON.parse(event?.reason || '')
+    if (typeof hint?.retryAfterMs === 'number') {
+      return hint.retryAfterMs
+    }
+  } catch {
+    // Not a retry hint.
+  }
+  return Math.random() * Math.min(30000, 1000 * 2 ** retryCount)
+}
+
 export function createTruckSubscriber({ onMessage, onError }) {
   let retryCount = 0
   let socket
@@ -90,11 +104,10 @@ export function createTruckSubscriber({ onMessage, onError }) {
       onError?.(event)
     }
 
-    socket.onclose = () => {
+    socket.onclose = (event) => {
       if (closed) return
       retryCount += 1
-      const delay = Math.min(30000, 1000 * 2 ** retryCount)
-      setTimeout(start, delay)
+      setTimeout(start, reconnectDelay(event, retryCount))
     }
   }
 